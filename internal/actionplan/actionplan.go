@@ -0,0 +1,56 @@
+// Package actionplan turns the steps of an action plan into the tasks of a
+// contact.
+//
+// A step is due a number of days after the plan is applied, and its note
+// template may name fields of the contact in double braces, as in
+// "Ask {{first_name}} about homes in {{city}}".
+package actionplan
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Fields lists the contact fields note templates can use.
+var Fields = []string{"first_name", "last_name", "source", "city", "zip_code", "price_range", "timeframe"}
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// Validate checks that a note template only names known fields.
+func Validate(template string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if !knownField(m[1]) {
+			return fmt.Errorf("unknown field %q in note template", m[1])
+		}
+	}
+	return nil
+}
+
+// Render fills in a note template with the contact's values. Fields the
+// contact has no value for are left blank.
+func Render(template string, values map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(template, func(placeholder string) string {
+		field := placeholderRe.FindStringSubmatch(placeholder)[1]
+		if !knownField(field) {
+			return placeholder
+		}
+		return values[field]
+	})
+}
+
+// Due returns when a step offsetDays into a plan started at start is due.
+// Days are calendar days, so steps keep the start's time of day across
+// daylight saving changes in its location.
+func Due(start time.Time, offsetDays int) time.Time {
+	return start.AddDate(0, 0, offsetDays)
+}
+
+func knownField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
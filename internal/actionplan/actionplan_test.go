@@ -0,0 +1,50 @@
+package actionplan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	values := map[string]string{"first_name": "Ana", "city": "Tacoma"}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"Call {{first_name}}", "Call Ana"},
+		{"Homes in {{ city }} for {{first_name}}", "Homes in Tacoma for Ana"},
+		{"Timeframe: {{timeframe}}", "Timeframe: "},
+		{"Keep {{unknown}} and {single}", "Keep {{unknown}} and {single}"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.template, values); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("Hi {{first_name}} {{last_name}}, still looking in {{zip_code}}?"); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if err := Validate("Hi {{nickname}}"); err == nil {
+		t.Error("Validate() should reject unknown fields")
+	}
+}
+
+func TestDueKeepsTimeOfDayAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Daylight saving time starts on March 10, 2024
+	start := time.Date(2024, 3, 8, 9, 0, 0, 0, loc)
+	due := Due(start, 3)
+	if want := time.Date(2024, 3, 11, 9, 0, 0, 0, loc); !due.Equal(want) {
+		t.Errorf("Due() = %v, want %v", due, want)
+	}
+	if !Due(start, 0).Equal(start) {
+		t.Error("Due() on day 0 should be the start")
+	}
+}
@@ -0,0 +1,116 @@
+// Package audit computes what changed in a record for the audit log.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Action is what was done to the audited record.
+type Action string
+
+const (
+	Create  Action = "create"
+	Update  Action = "update"
+	Delete  Action = "delete"
+	Restore Action = "restore"
+	Purge   Action = "purge"
+)
+
+// Auth methods an actor can be signed in with. Changes an import job makes
+// are attributed to the user who uploaded the file, with AuthImport.
+const (
+	AuthSession = "session"
+	AuthAPIKey  = "api_key"
+	AuthImport  = "import"
+)
+
+// ignored lists fields that change on every write and say nothing about
+// what the actor did.
+var ignored = map[string]bool{
+	"updated_at": true,
+}
+
+// Change is the value of one field before and after a write. A field that
+// didn't exist on one side, like every field of a created record, is null
+// there.
+type Change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Diff compares two JSON object snapshots of a record and returns the
+// fields whose values differ. A nil snapshot stands for a record that didn't
+// exist, before a create or after a purge.
+func Diff(before, after json.RawMessage) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := map[string]Change{}
+	for _, k := range keys {
+		if ignored[k] {
+			continue
+		}
+		bv, av := b[k], a[k]
+		if equal(bv, av) {
+			continue
+		}
+		changes[k] = Change{Before: orNull(bv), After: orNull(av)}
+	}
+	return changes, nil
+}
+
+func fields(snapshot json.RawMessage) (map[string]json.RawMessage, error) {
+	m := map[string]json.RawMessage{}
+	if len(snapshot) == 0 || bytes.Equal(snapshot, []byte("null")) {
+		return m, nil
+	}
+	if err := json.Unmarshal(snapshot, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// equal compares JSON values ignoring formatting. A missing value equals
+// null.
+func equal(a, b json.RawMessage) bool {
+	var av, bv any
+	if len(a) > 0 {
+		if err := json.Unmarshal(a, &av); err != nil {
+			return false
+		}
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &bv); err != nil {
+			return false
+		}
+	}
+	ja, _ := json.Marshal(av)
+	jb, _ := json.Marshal(bv)
+	return bytes.Equal(ja, jb)
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if len(v) == 0 {
+		return json.RawMessage("null")
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffUpdate(t *testing.T) {
+	before := json.RawMessage(`{"id": "d1", "price": 100, "title": "House", "updated_at": "2024-01-01", "tags": ["a", "b"]}`)
+	after := json.RawMessage(`{"id":"d1","price":250,"title":"House","updated_at":"2024-02-01","tags":["a"]}`)
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want price and tags: %v", len(changes), changes)
+	}
+	if c := changes["price"]; string(c.Before) != "100" || string(c.After) != "250" {
+		t.Errorf("price change = %s -> %s", c.Before, c.After)
+	}
+	if c := changes["tags"]; string(c.Before) != `["a", "b"]` || string(c.After) != `["a"]` {
+		t.Errorf("tags change = %s -> %s", c.Before, c.After)
+	}
+	if _, ok := changes["updated_at"]; ok {
+		t.Error("updated_at should be ignored")
+	}
+}
+
+func TestDiffCreateAndPurge(t *testing.T) {
+	row := json.RawMessage(`{"id": "t1", "title": "Call back", "note": null}`)
+
+	created, err := Diff(nil, row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := created["title"]; string(c.Before) != "null" || string(c.After) != `"Call back"` {
+		t.Errorf("title on create = %s -> %s", c.Before, c.After)
+	}
+	// A null field is no change from a field that didn't exist
+	if _, ok := created["note"]; ok {
+		t.Error("null note shouldn't be reported on create")
+	}
+
+	purged, err := Diff(row, json.RawMessage("null"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 2 || string(purged["id"].After) != "null" {
+		t.Errorf("purge changes = %v", purged)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	changes, err := Diff(json.RawMessage(`{"a": 1.0, "b": {"x": 1}}`), json.RawMessage(`{"b":{"x":1},"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("got changes %v for equal snapshots", changes)
+	}
+}
+
+func TestDiffInvalidSnapshot(t *testing.T) {
+	if _, err := Diff(json.RawMessage(`[1]`), nil); err == nil {
+		t.Error("expected an error for a non-object snapshot")
+	}
+}
@@ -0,0 +1,307 @@
+// Package authz decides what the signed-in user may do with a contact, the
+// records attached to it (notes, tasks, deals, ...) and the records a user
+// owns outright (tags, stages, smart lists, ...).
+//
+// Records the caller has no access to at all are reported as ErrNotFound so
+// that handlers answer 404 and don't reveal that the ID exists.
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrForbidden = errors.New("not allowed")
+	ErrInvalidID = errors.New("missing or invalid record ID")
+)
+
+// Action is what the caller wants to do with a record.
+type Action int
+
+const (
+	// View reads the record.
+	View Action = iota + 1
+	// Edit changes the contact's details or adds and changes its notes,
+	// logs, tasks and appointments.
+	Edit
+	// EditDeals adds and changes the contact's deals.
+	EditDeals
+	// ManageCollaborators adds and removes collaborators on the contact.
+	ManageCollaborators
+	// Manage merges the contact or changes other records only their owner
+	// may.
+	Manage
+)
+
+func (a Action) String() string {
+	switch a {
+	case View:
+		return "view"
+	case Edit:
+		return "edit"
+	case EditDeals:
+		return "edit_deals"
+	case ManageCollaborators:
+		return "manage_collaborators"
+	case Manage:
+		return "manage"
+	}
+	return "unknown"
+}
+
+// Role is what a collaborator was added to a contact as.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"
+	RoleEditor  Role = "editor"
+	RoleManager Role = "manager"
+)
+
+// Roles lists the collaborator roles from least to most access.
+var Roles = []Role{RoleViewer, RoleEditor, RoleManager}
+
+// ValidRole reports whether role is a known collaborator role.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[Role(role)]
+	return ok
+}
+
+// rolePermissions is what each collaborator role may do with a contact and
+// its records. Manage is left to the contact's owner and org admins.
+var rolePermissions = map[Role][]Action{
+	RoleViewer:  {View},
+	RoleEditor:  {View, Edit},
+	RoleManager: {View, Edit, EditDeals, ManageCollaborators},
+}
+
+// Permissions returns the actions role allows, or nil for an unknown role.
+func (r Role) Permissions() []Action {
+	return rolePermissions[r]
+}
+
+// Allows reports whether role may perform action.
+func (r Role) Allows(action Action) bool {
+	for _, a := range rolePermissions[r] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind is a type of record access can be checked for.
+type Kind string
+
+// Records that belong to a contact.
+const (
+	KindContact     Kind = "contact"
+	KindNote        Kind = "note"
+	KindContactLog  Kind = "contact_log"
+	KindTask        Kind = "task"
+	KindAppointment Kind = "appointment"
+	KindDeal        Kind = "deal"
+	KindEmail       Kind = "email"
+	KindPhoneNumber Kind = "phone_number"
+)
+
+// Records that belong to a user, or to an organization when they are shared
+// with its members.
+const (
+	KindSmartList      Kind = "smart_list"
+	KindTag            Kind = "tag"
+	KindStage          Kind = "stage"
+	KindNotification   Kind = "notification"
+	KindGoal           Kind = "goal"
+	KindImportJob      Kind = "import_job"
+	KindImportMapping  Kind = "import_mapping"
+	KindActionPlan     Kind = "action_plan"
+	KindRoutingRule    Kind = "routing_rule"
+	KindLeadAssignment Kind = "lead_assignment"
+)
+
+// A user's own settings, keyed by their user ID. Only that user reads or
+// changes them, so there is no access to check.
+const (
+	KindReminderPreferences Kind = "reminder_preferences"
+	KindDigestPreferences   Kind = "digest_preferences"
+	KindUser                Kind = "user"
+)
+
+// KindOrganization is an organization itself. Its members may view it and
+// its admins manage it, which includes seeing every member's records.
+const KindOrganization Kind = "organization"
+
+// Access is how the caller is related to a record.
+type Access struct {
+	// Owner is set when the caller owns the record or its contact.
+	Owner bool
+	// OrgAdmin is set when the caller administers the organization the
+	// contact or shared record belongs to.
+	OrgAdmin bool
+	// Collaborator is set when the caller collaborates on the contact, with
+	// the role they were added with.
+	Collaborator     bool
+	CollaboratorRole Role
+	// Assigned is set when a task, appointment or deal is assigned to the
+	// caller, who may then work on that record whatever their role.
+	Assigned bool
+	// Shared is set for records without an owner, like the default stages,
+	// and for records shared with an organization the caller is a member of.
+	Shared bool
+}
+
+// Visible reports whether the caller may know the record exists.
+func (a Access) Visible() bool {
+	return a.Owner || a.OrgAdmin || a.Collaborator || a.Assigned || a.Shared
+}
+
+// Can reports whether the caller may perform action on the record.
+func (a Access) Can(action Action) bool {
+	switch {
+	case a.Owner || a.OrgAdmin:
+		return true
+	case a.Assigned && (action == View || action == Edit || action == EditDeals):
+		return true
+	case a.Collaborator && a.CollaboratorRole.Allows(action):
+		return true
+	case a.Shared:
+		return action == View
+	}
+	return false
+}
+
+// Ownership is who a record belongs to. Contact records carry ContactID;
+// user records carry OwnerID, and OrganizationID too when they are shared
+// with an organization. Tasks, appointments and deals can carry both
+// ContactID and OwnerID, OwnerID being the user they are assigned to.
+type Ownership struct {
+	ContactID      uuid.NullUUID
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
+}
+
+// ContactAccess is the raw relationship between a user and a contact.
+type ContactAccess struct {
+	OwnerID          uuid.NullUUID
+	CollaboratorRole sql.NullString
+	OrgAdmin         bool
+}
+
+// Store loads record ownership, contact access and organization membership
+// for the Authorizer, returning sql.ErrNoRows when the record doesn't exist
+// or, from OrganizationRole, when the user isn't a member.
+type Store interface {
+	RecordOwnership(ctx context.Context, kind Kind, id uuid.UUID) (Ownership, error)
+	ContactAccess(ctx context.Context, userID, contactID uuid.UUID) (ContactAccess, error)
+	OrganizationRole(ctx context.Context, userID, organizationID uuid.UUID) (string, error)
+}
+
+// IsOrgAdmin reports whether an organization member role may manage the
+// organization's contacts and shared definitions.
+func IsOrgAdmin(role string) bool {
+	return role == "owner" || role == "admin"
+}
+
+// Authorizer resolves access to records.
+type Authorizer struct {
+	store Store
+}
+
+func New(store Store) *Authorizer {
+	return &Authorizer{store: store}
+}
+
+// Access resolves the caller's access to a record. It returns ErrNotFound
+// when the record doesn't exist or the caller can't see it.
+func (a *Authorizer) Access(ctx context.Context, userID uuid.UUID, kind Kind, id uuid.UUID) (Access, error) {
+	switch kind {
+	case KindContact:
+		return a.contactAccess(ctx, userID, id)
+	case KindOrganization:
+		return a.organizationAccess(ctx, userID, id)
+	}
+
+	own, err := a.store.RecordOwnership(ctx, kind, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Access{}, ErrNotFound
+	}
+	if err != nil {
+		return Access{}, err
+	}
+
+	var access Access
+	if own.ContactID.Valid {
+		access, err = a.contactAccess(ctx, userID, own.ContactID.UUID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return Access{}, err
+		}
+		access.Assigned = own.OwnerID.Valid && own.OwnerID.UUID == userID
+	} else if own.OrganizationID.Valid {
+		access, err = a.organizationAccess(ctx, userID, own.OrganizationID.UUID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return Access{}, err
+		}
+	} else {
+		access.Owner = own.OwnerID.Valid && own.OwnerID.UUID == userID
+		access.Shared = !own.OwnerID.Valid
+	}
+
+	if !access.Visible() {
+		return Access{}, ErrNotFound
+	}
+	return access, nil
+}
+
+func (a *Authorizer) contactAccess(ctx context.Context, userID, contactID uuid.UUID) (Access, error) {
+	ca, err := a.store.ContactAccess(ctx, userID, contactID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Access{}, ErrNotFound
+	}
+	if err != nil {
+		return Access{}, err
+	}
+
+	access := Access{
+		Owner:            ca.OwnerID.Valid && ca.OwnerID.UUID == userID,
+		OrgAdmin:         ca.OrgAdmin,
+		Collaborator:     ca.CollaboratorRole.Valid,
+		CollaboratorRole: Role(ca.CollaboratorRole.String),
+	}
+	if !access.Visible() {
+		return Access{}, ErrNotFound
+	}
+	return access, nil
+}
+
+// organizationAccess makes the organization's admins OrgAdmin and its other
+// members Shared.
+func (a *Authorizer) organizationAccess(ctx context.Context, userID, organizationID uuid.UUID) (Access, error) {
+	role, err := a.store.OrganizationRole(ctx, userID, organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Access{}, ErrNotFound
+	}
+	if err != nil {
+		return Access{}, err
+	}
+	return Access{OrgAdmin: IsOrgAdmin(role), Shared: true}, nil
+}
+
+// Check returns nil when the caller may perform action on the record,
+// ErrNotFound when they can't see it and ErrForbidden when they can see it
+// but not perform action.
+func (a *Authorizer) Check(ctx context.Context, userID uuid.UUID, kind Kind, id uuid.UUID, action Action) error {
+	access, err := a.Access(ctx, userID, kind, id)
+	if err != nil {
+		return err
+	}
+	if !access.Can(action) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeContact is a contact in fakeStore.
+type fakeContact struct {
+	owner         uuid.UUID
+	collaborators map[uuid.UUID]string
+	orgAdmins     map[uuid.UUID]bool
+}
+
+type fakeStore struct {
+	contacts map[uuid.UUID]fakeContact
+	records  map[Kind]map[uuid.UUID]Ownership
+	// members maps an organization to its members' roles
+	members map[uuid.UUID]map[uuid.UUID]string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		contacts: map[uuid.UUID]fakeContact{},
+		records:  map[Kind]map[uuid.UUID]Ownership{},
+		members:  map[uuid.UUID]map[uuid.UUID]string{},
+	}
+}
+
+func (s *fakeStore) addRecord(kind Kind, id uuid.UUID, own Ownership) {
+	if s.records[kind] == nil {
+		s.records[kind] = map[uuid.UUID]Ownership{}
+	}
+	s.records[kind][id] = own
+}
+
+func (s *fakeStore) RecordOwnership(ctx context.Context, kind Kind, id uuid.UUID) (Ownership, error) {
+	own, ok := s.records[kind][id]
+	if !ok {
+		return Ownership{}, sql.ErrNoRows
+	}
+	return own, nil
+}
+
+func (s *fakeStore) ContactAccess(ctx context.Context, userID, contactID uuid.UUID) (ContactAccess, error) {
+	c, ok := s.contacts[contactID]
+	if !ok {
+		return ContactAccess{}, sql.ErrNoRows
+	}
+	role, isCollaborator := c.collaborators[userID]
+	return ContactAccess{
+		OwnerID:          uuid.NullUUID{UUID: c.owner, Valid: true},
+		CollaboratorRole: sql.NullString{String: role, Valid: isCollaborator},
+		OrgAdmin:         c.orgAdmins[userID],
+	}, nil
+}
+
+func (s *fakeStore) OrganizationRole(ctx context.Context, userID, organizationID uuid.UUID) (string, error) {
+	role, ok := s.members[organizationID][userID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role Role
+		want map[Action]bool
+	}{
+		{RoleViewer, map[Action]bool{View: true, Edit: false, EditDeals: false, ManageCollaborators: false, Manage: false}},
+		{RoleEditor, map[Action]bool{View: true, Edit: true, EditDeals: false, ManageCollaborators: false, Manage: false}},
+		{RoleManager, map[Action]bool{View: true, Edit: true, EditDeals: true, ManageCollaborators: true, Manage: false}},
+		{"collaborator", map[Action]bool{View: false, Edit: false, EditDeals: false, ManageCollaborators: false, Manage: false}},
+	}
+	for _, tt := range tests {
+		if got, want := ValidRole(string(tt.role)), tt.role != "collaborator"; got != want {
+			t.Errorf("ValidRole(%q) = %v, want %v", tt.role, got, want)
+		}
+		for action, want := range tt.want {
+			if got := tt.role.Allows(action); got != want {
+				t.Errorf("%s: Allows(%s) = %v, want %v", tt.role, action, got, want)
+			}
+		}
+	}
+}
+
+func TestAccessCan(t *testing.T) {
+	all := map[Action]bool{View: true, Edit: true, EditDeals: true, ManageCollaborators: true, Manage: true}
+	tests := []struct {
+		name   string
+		access Access
+		want   map[Action]bool
+	}{
+		{"owner", Access{Owner: true}, all},
+		{"org admin", Access{OrgAdmin: true}, all},
+		{"viewer", Access{Collaborator: true, CollaboratorRole: RoleViewer}, map[Action]bool{View: true, Edit: false, EditDeals: false}},
+		{"manager", Access{Collaborator: true, CollaboratorRole: RoleManager}, map[Action]bool{EditDeals: true, ManageCollaborators: true, Manage: false}},
+		{"assigned", Access{Assigned: true}, map[Action]bool{View: true, Edit: true, EditDeals: true, ManageCollaborators: false, Manage: false}},
+		{"assigned viewer", Access{Assigned: true, Collaborator: true, CollaboratorRole: RoleViewer}, map[Action]bool{Edit: true, ManageCollaborators: false}},
+		{"shared", Access{Shared: true}, map[Action]bool{View: true, Edit: false, Manage: false}},
+		{"none", Access{}, map[Action]bool{View: false, Edit: false, Manage: false}},
+	}
+	for _, tt := range tests {
+		for action, want := range tt.want {
+			if got := tt.access.Can(action); got != want {
+				t.Errorf("%s: Can(%s) = %v, want %v", tt.name, action, got, want)
+			}
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var (
+		owner    = uuid.New()
+		viewer   = uuid.New()
+		collab   = uuid.New()
+		manager  = uuid.New()
+		admin    = uuid.New()
+		assignee = uuid.New()
+		stranger = uuid.New()
+
+		contact     = uuid.New()
+		note        = uuid.New()
+		task        = uuid.New()
+		tag         = uuid.New()
+		sharedStage = uuid.New()
+
+		org       = uuid.New()
+		orgTag    = uuid.New()
+		orgStage  = uuid.New()
+		orgList   = uuid.New()
+		orgOwner  = uuid.New()
+		orgAdmin  = uuid.New()
+		orgMember = uuid.New()
+	)
+
+	store := newFakeStore()
+	store.contacts[contact] = fakeContact{
+		owner:         owner,
+		collaborators: map[uuid.UUID]string{viewer: "viewer", collab: "editor", manager: "manager"},
+		orgAdmins:     map[uuid.UUID]bool{admin: true},
+	}
+	store.addRecord(KindNote, note, Ownership{ContactID: uuid.NullUUID{UUID: contact, Valid: true}})
+	store.addRecord(KindTask, task, Ownership{
+		ContactID: uuid.NullUUID{UUID: contact, Valid: true},
+		OwnerID:   uuid.NullUUID{UUID: assignee, Valid: true},
+	})
+	store.addRecord(KindTag, tag, Ownership{OwnerID: uuid.NullUUID{UUID: owner, Valid: true}})
+	store.addRecord(KindStage, sharedStage, Ownership{})
+
+	store.members[org] = map[uuid.UUID]string{orgOwner: "owner", orgAdmin: "admin", orgMember: "member"}
+	orgOwned := Ownership{
+		OwnerID:        uuid.NullUUID{UUID: orgAdmin, Valid: true},
+		OrganizationID: uuid.NullUUID{UUID: org, Valid: true},
+	}
+	store.addRecord(KindTag, orgTag, orgOwned)
+	store.addRecord(KindStage, orgStage, orgOwned)
+	store.addRecord(KindSmartList, orgList, orgOwned)
+
+	a := New(store)
+	tests := []struct {
+		name   string
+		user   uuid.UUID
+		kind   Kind
+		id     uuid.UUID
+		action Action
+		want   error
+	}{
+		{"owner manages contact", owner, KindContact, contact, Manage, nil},
+		{"admin manages contact", admin, KindContact, contact, Manage, nil},
+		{"viewer views contact", viewer, KindContact, contact, View, nil},
+		{"viewer can't edit contact", viewer, KindContact, contact, Edit, ErrForbidden},
+		{"editor edits contact", collab, KindContact, contact, Edit, nil},
+		{"editor can't edit deals", collab, KindContact, contact, EditDeals, ErrForbidden},
+		{"manager edits deals", manager, KindContact, contact, EditDeals, nil},
+		{"manager manages collaborators", manager, KindContact, contact, ManageCollaborators, nil},
+		{"manager can't merge contact", manager, KindContact, contact, Manage, ErrForbidden},
+		{"stranger can't see contact", stranger, KindContact, contact, View, ErrNotFound},
+		{"missing contact", owner, KindContact, uuid.New(), View, ErrNotFound},
+
+		{"editor edits note", collab, KindNote, note, Edit, nil},
+		{"viewer can't edit note", viewer, KindNote, note, Edit, ErrForbidden},
+		{"stranger can't see note", stranger, KindNote, note, View, ErrNotFound},
+		{"missing note", owner, KindNote, uuid.New(), View, ErrNotFound},
+
+		{"assignee edits task", assignee, KindTask, task, Edit, nil},
+		{"assignee can't manage task", assignee, KindTask, task, Manage, ErrForbidden},
+		{"owner edits task", owner, KindTask, task, Edit, nil},
+
+		{"owner deletes tag", owner, KindTag, tag, Edit, nil},
+		{"collaborator can't see tag", collab, KindTag, tag, View, ErrNotFound},
+
+		{"anyone views shared stage", stranger, KindStage, sharedStage, View, nil},
+		{"nobody edits shared stage", owner, KindStage, sharedStage, Edit, ErrForbidden},
+
+		{"org member views org tag", orgMember, KindTag, orgTag, View, nil},
+		{"org member can't delete org tag", orgMember, KindTag, orgTag, Edit, ErrForbidden},
+		{"org admin deletes org tag", orgAdmin, KindTag, orgTag, Edit, nil},
+		{"org owner edits org stage", orgOwner, KindStage, orgStage, Edit, nil},
+		{"org member views org stage", orgMember, KindStage, orgStage, View, nil},
+		{"org member can't edit org smart list", orgMember, KindSmartList, orgList, Edit, ErrForbidden},
+		{"org admin edits org smart list", orgAdmin, KindSmartList, orgList, Edit, nil},
+		{"outsider can't see org smart list", owner, KindSmartList, orgList, View, ErrNotFound},
+
+		{"org admin manages org", orgAdmin, KindOrganization, org, Manage, nil},
+		{"org owner manages org", orgOwner, KindOrganization, org, Manage, nil},
+		{"org member views org", orgMember, KindOrganization, org, View, nil},
+		{"org member can't manage org", orgMember, KindOrganization, org, Manage, ErrForbidden},
+		{"outsider can't see org", owner, KindOrganization, org, View, ErrNotFound},
+	}
+	for _, tt := range tests {
+		err := a.Check(context.Background(), tt.user, tt.kind, tt.id, tt.action)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Check() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// maxPeekBody caps how much of a request body is read to find IDs in it.
+const maxPeekBody = 1 << 20
+
+// Rule requires the caller to be allowed to perform Action on the record of
+// kind Kind whose ID is in the path wildcard Path or the JSON body field
+// Body. IDs that don't parse are refused, as are missing body fields unless
+// the rule is Optional, in which case a missing, null or empty field isn't
+// checked.
+type Rule struct {
+	Kind     Kind
+	Path     string
+	Body     string
+	Optional bool
+	Action   Action
+}
+
+func path(kind Kind, wildcard string, action Action) Rule {
+	return Rule{Kind: kind, Path: wildcard, Action: action}
+}
+
+func body(kind Kind, field string, action Action) Rule {
+	return Rule{Kind: kind, Body: field, Action: action}
+}
+
+func optionalBody(kind Kind, field string, action Action) Rule {
+	return Rule{Kind: kind, Body: field, Optional: true, Action: action}
+}
+
+// Routes lists the rules for every route the API serves, keyed by the
+// ServeMux pattern. Routes with no rules only need a signed-in user (or API
+// key) and scope everything they read or write to that user themselves.
+var Routes = map[string][]Rule{
+	// Dashboard
+	"GET /api/dashboard/new-contacts":            nil,
+	"GET /api/dashboard/appointments":            nil,
+	"GET /api/dashboard/tasks-today":             nil,
+	"GET /api/dashboard/5-newest-contacts":       nil,
+	"GET /api/dashboard/5-upcoming-appointments": nil,
+	"GET /api/dashboard/contacts-count":          nil,
+	"GET /api/dashboard/contacts-by-source":      nil,
+
+	// Contacts
+	"POST /api/contacts":                                 nil,
+	"POST /api/contacts/import":                          nil,
+	"POST /api/contacts/transfer":                        {optionalBody(KindSmartList, "smart_list_id", View)},
+	"POST /api/contacts/bulk":                            {optionalBody(KindSmartList, "smart_list_id", View)},
+	"POST /api/contacts/import/upload":                   nil,
+	"POST /api/contacts/import/preview":                  nil,
+	"GET /api/contacts/import/mappings":                  nil,
+	"POST /api/contacts/import/mappings":                 nil,
+	"DELETE /api/contacts/import/mappings/{mappingID}":   {path(KindImportMapping, "mappingID", Manage)},
+	"GET /api/contacts/contact/{contactID}":              {path(KindContact, "contactID", View)},
+	"GET /api/contacts/contact/{contactID}/timeline":     {path(KindContact, "contactID", View)},
+	"GET /api/contacts/contact/{contactID}/history":      {path(KindContact, "contactID", View)},
+	"GET /api/contacts/contact/{contactID}/action-plans": {path(KindContact, "contactID", View)},
+	"GET /api/contacts":                                  nil,
+	"GET /api/contacts/search":                           nil,
+	"GET /api/contacts/smart-list/{smartListID}":         {path(KindSmartList, "smartListID", View)},
+	"PUT /api/contacts/{contactID}":                      {path(KindContact, "contactID", Edit)},
+	"GET /api/contacts/duplicates":                       nil,
+	"GET /api/contacts/export":                           nil,
+	"POST /api/contacts/{contactID}/merge":               {path(KindContact, "contactID", Manage)},
+	"POST /api/contacts/{contactID}/action-plans":        {path(KindContact, "contactID", Edit), body(KindActionPlan, "action_plan_id", View)},
+	"GET /api/contact-merges/{contactID}":                {path(KindContact, "contactID", View)},
+
+	// Imports
+	"GET /api/imports/{jobID}":         {path(KindImportJob, "jobID", View)},
+	"POST /api/imports/{jobID}/cancel": {path(KindImportJob, "jobID", Edit)},
+	"POST /api/imports/{jobID}/retry":  {path(KindImportJob, "jobID", Edit)},
+
+	// Notes
+	"POST /api/notes":            {body(KindContact, "contact_id", Edit)},
+	"GET /api/notes/{contactID}": {path(KindContact, "contactID", View)},
+
+	// Contact logs
+	"POST /api/contact-logs":            {body(KindContact, "contact_id", Edit)},
+	"GET /api/contact-logs/{contactID}": {path(KindContact, "contactID", View)},
+
+	// Tasks
+	"POST /api/tasks":                    {body(KindContact, "contact_id", Edit)},
+	"GET /api/tasks/contact/{contactID}": {path(KindContact, "contactID", View)},
+	"GET /api/tasks/assigned":            nil,
+	"GET /api/tasks/{taskID}":            {path(KindTask, "taskID", View)},
+	"DELETE /api/tasks/{taskID}":         {path(KindTask, "taskID", Edit)},
+	"PUT /api/tasks/{taskID}":            {path(KindTask, "taskID", Edit), body(KindContact, "contact_id", Edit)},
+	"GET /api/tasks/late":                nil,
+	"PUT /api/tasks/status/{taskID}":     {path(KindTask, "taskID", Edit)},
+	"GET /api/tasks/today":               nil,
+
+	// Appointments
+	"POST /api/appointments":                    {body(KindContact, "contact_id", Edit)},
+	"GET /api/appointments/{AppointmentID}":     {path(KindAppointment, "AppointmentID", View)},
+	"PUT /api/appointments/{AppointmentID}":     {path(KindAppointment, "AppointmentID", Edit), body(KindContact, "contact_id", Edit)},
+	"DELETE /api/appointments/{AppointmentID}":  {path(KindAppointment, "AppointmentID", Edit)},
+	"GET /api/appointments/contact/{ContactID}": {path(KindContact, "ContactID", View)},
+	"GET /api/appointments/upcoming":            nil,
+	"GET /api/appointments/today":               nil,
+	"GET /api/appointments":                     nil,
+
+	// Deals
+	"POST /api/deals":                             {body(KindContact, "contact_id", EditDeals), body(KindStage, "stage_id", View)},
+	"GET /api/deals/{dealID}":                     {path(KindDeal, "dealID", View)},
+	"PUT /api/deals/{dealID}":                     {path(KindDeal, "dealID", EditDeals), body(KindContact, "contact_id", EditDeals), body(KindStage, "stage_id", View)},
+	"DELETE /api/deals/{dealID}":                  {path(KindDeal, "dealID", EditDeals)},
+	"GET /api/deals/{dealID}/history":             {path(KindDeal, "dealID", View)},
+	"GET /api/deals":                              nil,
+	"GET /api/contacts/contact/{contactID}/deals": {path(KindContact, "contactID", View)},
+	"GET /api/stages/{stageID}/deals":             {path(KindStage, "stageID", View)},
+
+	// Goals
+	"POST /api/goals":         nil,
+	"GET /api/goals":          nil,
+	"PUT /api/goals/{GoalID}": {path(KindGoal, "GoalID", Edit)},
+
+	// Smart lists
+	"GET /api/smart-lists":                               nil,
+	"POST /api/smart-lists":                              nil,
+	"PUT /api/smart-lists/{smartListID}/filter":          {path(KindSmartList, "smartListID", Edit)},
+	"PUT /api/smart-lists/{smartListID}/name":            {path(KindSmartList, "smartListID", Edit)},
+	"POST /api/smart-lists/{smartListID}/subscription":   {path(KindSmartList, "smartListID", View)},
+	"DELETE /api/smart-lists/{smartListID}/subscription": {path(KindSmartList, "smartListID", View)},
+
+	// Stages
+	"POST /api/stages":             nil,
+	"GET /api/stages":              nil,
+	"GET /api/stages/client-type":  nil,
+	"PUT /api/stages/{stageID}":    {path(KindStage, "stageID", Edit)},
+	"DELETE /api/stages/{stageID}": {path(KindStage, "stageID", Edit)},
+
+	// Tags
+	"POST /api/tags":                               nil,
+	"GET /api/tags":                                nil,
+	"DELETE /api/tags/{tagID}":                     {path(KindTag, "tagID", Edit)},
+	"POST /api/tags/{tagID}/contact/{contactID}":   {path(KindTag, "tagID", View), path(KindContact, "contactID", Edit)},
+	"DELETE /api/tags/{tagID}/contact/{contactID}": {path(KindTag, "tagID", View), path(KindContact, "contactID", Edit)},
+
+	// Action Plans
+	"GET /api/action-plans":                   nil,
+	"POST /api/action-plans":                  {optionalBody(KindTag, "trigger_tag_id", View)},
+	"GET /api/action-plans/{actionPlanID}":    {path(KindActionPlan, "actionPlanID", View)},
+	"PUT /api/action-plans/{actionPlanID}":    {path(KindActionPlan, "actionPlanID", Edit), optionalBody(KindTag, "trigger_tag_id", View)},
+	"DELETE /api/action-plans/{actionPlanID}": {path(KindActionPlan, "actionPlanID", Edit)},
+
+	// Webhooks, authenticated with an API key
+	"POST /webhooks/landing-page-form": nil,
+
+	// Emails
+	"GET /api/verify":                      nil,
+	"POST /api/resend-verification":        nil,
+	"POST /api/emails/contact/{contactID}": {path(KindContact, "contactID", Edit)},
+	"PUT /api/emails/{emailID}":            {path(KindEmail, "emailID", Edit)},
+	"DELETE /api/emails/{emailID}":         {path(KindEmail, "emailID", Edit)},
+
+	// Phone numbers
+	"POST /api/phone-numbers/contact/{contactID}": {path(KindContact, "contactID", Edit)},
+	"PUT /api/phone-numbers/{phoneNumberID}":      {path(KindPhoneNumber, "phoneNumberID", Edit)},
+	"DELETE /api/phone-numbers/{phoneNumberID}":   {path(KindPhoneNumber, "phoneNumberID", Edit)},
+
+	// S3
+	"PUT /api/upload-profile-picture": nil,
+
+	// Collaborators
+	"POST /api/collaborators":                                        {body(KindContact, "contact_id", ManageCollaborators)},
+	"GET /api/collaborators/contact/{contactID}":                     {path(KindContact, "contactID", View)},
+	"DELETE /api/collaborators/{collaboratorID}/contact/{contactID}": {path(KindContact, "contactID", ManageCollaborators)},
+
+	// Members. The handler checks the caller belongs to every organization
+	// in the body.
+	"POST /api/members/organizations": nil,
+
+	// Organizations
+	"GET /api/organizations/{organizationID}/rollup":          {path(KindOrganization, "organizationID", Manage)},
+	"GET /api/organizations/{organizationID}/rollup/{metric}": {path(KindOrganization, "organizationID", Manage)},
+
+	// Lead routing
+	"GET /api/organizations/{organizationID}/routing-rules":  {path(KindOrganization, "organizationID", Manage)},
+	"POST /api/organizations/{organizationID}/routing-rules": {path(KindOrganization, "organizationID", Manage)},
+	"PUT /api/routing-rules/{ruleID}":                        {path(KindRoutingRule, "ruleID", Manage)},
+	"DELETE /api/routing-rules/{ruleID}":                     {path(KindRoutingRule, "ruleID", Manage)},
+	"GET /api/leads/pending":                                 nil,
+	"POST /api/leads/{assignmentID}/claim":                   {path(KindLeadAssignment, "assignmentID", View)},
+
+	// Trash. Trashed records can't be resolved by the rules, so the handlers
+	// only find those the caller owns, is assigned or administers.
+	"GET /api/trash": nil,
+	"POST /api/trash/{kind}/{recordID}/restore": nil,
+	"DELETE /api/trash/{kind}/{recordID}":       nil,
+
+	// Audit log. The handler scopes the events to the caller.
+	"GET /api/audit": nil,
+
+	// Notifications
+	"GET /api/notifications": nil,
+	"POST /api/notifications": {
+		optionalBody(KindContact, "contact_id", View),
+		optionalBody(KindAppointment, "appointment_id", View),
+		optionalBody(KindTask, "task_id", View),
+	},
+	"PUT /api/notifications/mark-as-read/{notificationID}": {path(KindNotification, "notificationID", Edit)},
+	"PUT /api/notifications/read-all":                      nil,
+	"DELETE /api/notifications/{notificationID}":           {path(KindNotification, "notificationID", Edit)},
+	"GET /api/reminder-preferences":                        nil,
+	"PUT /api/reminder-preferences":                        nil,
+	"GET /api/digest-preferences":                          nil,
+	"PUT /api/digest-preferences":                          nil,
+	"GET /api/digest/preview":                              nil,
+	"GET /api/time-zone":                                   nil,
+	"PUT /api/time-zone":                                   nil,
+}
+
+// Authorize checks every rule against the request. The request body is read
+// when a rule needs it and replaced so the handler can still decode it. It
+// returns ErrInvalidID when an ID a rule names is malformed, or missing and
+// not optional.
+func (a *Authorizer) Authorize(r *http.Request, userID uuid.UUID, rules []Rule) error {
+	var fields map[string]json.RawMessage
+	for _, rule := range rules {
+		var raw string
+		switch {
+		case rule.Path != "":
+			raw = r.PathValue(rule.Path)
+		case rule.Body != "":
+			if fields == nil {
+				var err error
+				if fields, err = peekBody(r, rules); err != nil {
+					return err
+				}
+			}
+			value := fields[rule.Body]
+			if len(value) > 0 && string(value) != "null" {
+				if err := json.Unmarshal(value, &raw); err != nil {
+					return fmt.Errorf("%w: %s", ErrInvalidID, rule.Body)
+				}
+			}
+			if raw == "" && rule.Optional {
+				continue
+			}
+		}
+
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidID, rule.Path+rule.Body)
+		}
+		if err := a.Check(r.Context(), userID, rule.Kind, id, rule.Action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peekBody decodes the body fields rules name and puts the body back for
+// the handler. The fields are decoded into a struct tagged like the
+// handler's request struct, so keys match the way encoding/json matches
+// them there: case-insensitively, with the last duplicate winning.
+func peekBody(r *http.Request, rules []Rule) (map[string]json.RawMessage, error) {
+	var structFields []reflect.StructField
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Body != "" && !seen[rule.Body] {
+			seen[rule.Body] = true
+			structFields = append(structFields, reflect.StructField{
+				Name: fmt.Sprintf("Field%d", len(structFields)),
+				Type: reflect.TypeFor[json.RawMessage](),
+				Tag:  reflect.StructTag(fmt.Sprintf(`json:%q`, rule.Body)),
+			})
+		}
+	}
+
+	var data []byte
+	if r.Body != nil {
+		var err error
+		data, err = io.ReadAll(io.LimitReader(r.Body, maxPeekBody))
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+	}
+
+	decoded := reflect.New(reflect.StructOf(structFields))
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(decoded.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	for i, field := range structFields {
+		fields[field.Tag.Get("json")] = decoded.Elem().Field(i).Interface().(json.RawMessage)
+	}
+	return fields, nil
+}
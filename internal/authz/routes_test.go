@@ -0,0 +1,295 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var wildcardRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// unchecked are path wildcards that don't name a record the caller needs
+// access to.
+var unchecked = map[string]bool{
+	// The user being removed from a contact, which the contact rule covers
+	"collaboratorID": true,
+	// The rollup metric to drill into
+	"metric": true,
+	// Trashed records, which the trash handlers scope to the caller
+	"kind":     true,
+	"recordID": true,
+}
+
+func isContactKind(kind Kind) bool {
+	switch kind {
+	case KindContact, KindNote, KindContactLog, KindTask, KindAppointment, KindDeal, KindEmail, KindPhoneNumber:
+		return true
+	}
+	return false
+}
+
+func TestRoutesCheckEveryWildcard(t *testing.T) {
+	for pattern, rules := range Routes {
+		checked := map[string]bool{}
+		for _, rule := range rules {
+			if rule.Path != "" {
+				checked[rule.Path] = true
+				if !strings.Contains(pattern, "{"+rule.Path+"}") {
+					t.Errorf("%s: rule uses unknown wildcard %q", pattern, rule.Path)
+				}
+			}
+			if rule.Kind == "" || rule.Action == 0 || (rule.Path == "") == (rule.Body == "") {
+				t.Errorf("%s: malformed rule %+v", pattern, rule)
+			}
+		}
+		for _, m := range wildcardRe.FindAllStringSubmatch(pattern, -1) {
+			if !checked[m[1]] && !unchecked[m[1]] {
+				t.Errorf("%s: wildcard %q isn't checked", pattern, m[1])
+			}
+		}
+	}
+}
+
+// routeFixture builds a request for pattern where every record a rule
+// names exists, belongs to owner and, for contact records, has
+// collaborators. Organizations are owned by owner.
+func routeFixture(store *fakeStore, pattern string, rules []Rule, owner uuid.UUID, collaborators map[uuid.UUID]string) *http.Request {
+	method, path, _ := strings.Cut(pattern, " ")
+	fields := map[string]string{}
+
+	for _, rule := range rules {
+		id := uuid.New()
+		if isContactKind(rule.Kind) {
+			contact := id
+			if rule.Kind != KindContact {
+				contact = uuid.New()
+				store.addRecord(rule.Kind, id, Ownership{ContactID: uuid.NullUUID{UUID: contact, Valid: true}})
+			}
+			store.contacts[contact] = fakeContact{
+				owner:         owner,
+				collaborators: collaborators,
+			}
+		} else if rule.Kind == KindOrganization {
+			store.members[id] = map[uuid.UUID]string{owner: "owner"}
+		} else {
+			store.addRecord(rule.Kind, id, Ownership{OwnerID: uuid.NullUUID{UUID: owner, Valid: true}})
+		}
+
+		if rule.Path != "" {
+			path = strings.Replace(path, "{"+rule.Path+"}", id.String(), 1)
+		} else {
+			fields[rule.Body] = id.String()
+		}
+	}
+	path = wildcardRe.ReplaceAllStringFunc(path, func(string) string { return uuid.NewString() })
+
+	var body io.Reader
+	if len(fields) > 0 {
+		data, _ := json.Marshal(fields)
+		body = strings.NewReader(string(data))
+	}
+	return httptest.NewRequest(method, path, body)
+}
+
+// authorizeRoute runs Authorize for r as user inside a ServeMux registered
+// with pattern, so path values are set the way they are in production. It
+// also returns what the handler reads from the body.
+func authorizeRoute(a *Authorizer, pattern string, rules []Rule, r *http.Request, user uuid.UUID) (string, error) {
+	var (
+		err  error
+		body string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		err = a.Authorize(r, user, rules)
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	return body, err
+}
+
+// wantForRole is what a collaborator with role on every contact involved
+// should get: user records stay hidden and actions outside the role are
+// refused.
+func wantForRole(rules []Rule, role Role) error {
+	for _, rule := range rules {
+		if !isContactKind(rule.Kind) {
+			return ErrNotFound
+		}
+		if !role.Allows(rule.Action) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+func TestRoutes(t *testing.T) {
+	owner, stranger := uuid.New(), uuid.New()
+	collaborators := map[uuid.UUID]string{}
+	roleUsers := map[Role]uuid.UUID{}
+	for _, role := range Roles {
+		id := uuid.New()
+		collaborators[id] = string(role)
+		roleUsers[role] = id
+	}
+
+	for pattern, rules := range Routes {
+		var wantStranger error
+		if len(rules) > 0 {
+			wantStranger = ErrNotFound
+		}
+
+		type caller struct {
+			who  string
+			user uuid.UUID
+			want error
+		}
+		callers := []caller{
+			{"owner", owner, nil},
+			{"stranger", stranger, wantStranger},
+		}
+		for _, role := range Roles {
+			callers = append(callers, caller{string(role), roleUsers[role], wantForRole(rules, role)})
+		}
+
+		for _, tt := range callers {
+			store := newFakeStore()
+			r := routeFixture(store, pattern, rules, owner, collaborators)
+			sent := ""
+			if r.Body != nil {
+				data, _ := io.ReadAll(r.Body)
+				sent = string(data)
+				r.Body = io.NopCloser(strings.NewReader(sent))
+			}
+
+			got, err := authorizeRoute(New(store), pattern, rules, r, tt.user)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s as %s: Authorize() = %v, want %v", pattern, tt.who, err, tt.want)
+			}
+			if got != sent {
+				t.Errorf("%s as %s: handler read body %q, want %q", pattern, tt.who, got, sent)
+			}
+		}
+	}
+}
+
+// TestRoutesRoleMatrix pins what each role may do with the contact records
+// on representative routes.
+func TestRoutesRoleMatrix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		allowed []Role
+	}{
+		{"GET /api/contacts/contact/{contactID}", []Role{RoleViewer, RoleEditor, RoleManager}},
+		{"GET /api/contacts/contact/{contactID}/timeline", []Role{RoleViewer, RoleEditor, RoleManager}},
+		{"POST /api/notes", []Role{RoleEditor, RoleManager}},
+		{"POST /api/contact-logs", []Role{RoleEditor, RoleManager}},
+		{"POST /api/tasks", []Role{RoleEditor, RoleManager}},
+		{"DELETE /api/tasks/{taskID}", []Role{RoleEditor, RoleManager}},
+		{"POST /api/appointments", []Role{RoleEditor, RoleManager}},
+		{"GET /api/deals/{dealID}", []Role{RoleViewer, RoleEditor, RoleManager}},
+		{"PUT /api/deals/{dealID}", []Role{RoleManager}},
+		{"POST /api/collaborators", []Role{RoleManager}},
+		{"DELETE /api/collaborators/{collaboratorID}/contact/{contactID}", []Role{RoleManager}},
+		{"POST /api/contacts/{contactID}/merge", nil},
+	}
+	for _, tt := range tests {
+		all, ok := Routes[tt.pattern]
+		if !ok {
+			t.Errorf("no route %q", tt.pattern)
+			continue
+		}
+		var rules []Rule
+		for _, rule := range all {
+			if isContactKind(rule.Kind) {
+				rules = append(rules, rule)
+			}
+		}
+		for _, role := range Roles {
+			want := slices.Contains(tt.allowed, role)
+			if got := wantForRole(rules, role) == nil; got != want {
+				t.Errorf("%s as %s: allowed = %v, want %v", tt.pattern, role, got, want)
+			}
+		}
+	}
+}
+
+func TestAuthorizeRejectsMissingAndInvalidIDs(t *testing.T) {
+	a := New(newFakeStore())
+	rules := Routes["POST /api/deals"]
+
+	for _, body := range []string{``, `{}`, `{"contact_id":"","stage_id":""}`, `{"contact_id":"nope"}`, `{"contact_id":1}`, `{"contact_id":null}`, `not json`} {
+		r := httptest.NewRequest(http.MethodPost, "/api/deals", strings.NewReader(body))
+		got, err := authorizeRoute(a, "POST /api/deals", rules, r, uuid.New())
+		if !errors.Is(err, ErrInvalidID) {
+			t.Errorf("body %q: Authorize() = %v, want %v", body, err, ErrInvalidID)
+		}
+		if got != body {
+			t.Errorf("body %q: handler read %q", body, got)
+		}
+	}
+}
+
+func TestAuthorizeSkipsMissingOptionalIDs(t *testing.T) {
+	a := New(newFakeStore())
+	rules := Routes["POST /api/notifications"]
+
+	for _, body := range []string{`{}`, `{"contact_id":"","task_id":null}`} {
+		r := httptest.NewRequest(http.MethodPost, "/api/notifications", strings.NewReader(body))
+		if _, err := authorizeRoute(a, "POST /api/notifications", rules, r, uuid.New()); err != nil {
+			t.Errorf("body %q: Authorize() = %v, want nil", body, err)
+		}
+	}
+	for _, body := range []string{`{"contact_id":"nope"}`, `{"task_id":1}`} {
+		r := httptest.NewRequest(http.MethodPost, "/api/notifications", strings.NewReader(body))
+		if _, err := authorizeRoute(a, "POST /api/notifications", rules, r, uuid.New()); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("body %q: Authorize() = %v, want %v", body, err, ErrInvalidID)
+		}
+	}
+}
+
+// TestAuthorizeChecksDecodedID sends bodies that hide another user's
+// contact behind key case and duplicate keys, which encoding/json still
+// decodes into the handler's contact_id.
+func TestAuthorizeChecksDecodedID(t *testing.T) {
+	store := newFakeStore()
+	user, victim := uuid.New(), uuid.New()
+	mine, theirs := uuid.New(), uuid.New()
+	store.contacts[mine] = fakeContact{owner: user}
+	store.contacts[theirs] = fakeContact{owner: victim}
+	a := New(store)
+	rules := Routes["POST /api/notes"]
+
+	for _, body := range []string{
+		`{"CONTACT_ID":"` + theirs.String() + `"}`,
+		`{"contact_id":"` + mine.String() + `","Contact_ID":"` + theirs.String() + `"}`,
+		`{"contact_id":"` + mine.String() + `","contact_id":"` + theirs.String() + `"}`,
+	} {
+		var handler struct {
+			ContactID string `json:"contact_id"`
+		}
+		if err := json.Unmarshal([]byte(body), &handler); err != nil || handler.ContactID != theirs.String() {
+			t.Fatalf("body %q: handler decodes contact_id %q, %v", body, handler.ContactID, err)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/notes", strings.NewReader(body))
+		if _, err := authorizeRoute(a, "POST /api/notes", rules, r, user); !errors.Is(err, ErrNotFound) {
+			t.Errorf("body %q: Authorize() = %v, want %v", body, err, ErrNotFound)
+		}
+	}
+
+	body := `{"Contact_ID":"` + mine.String() + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/api/notes", strings.NewReader(body))
+	if _, err := authorizeRoute(a, "POST /api/notes", rules, r, user); err != nil {
+		t.Errorf("body %q: Authorize() = %v, want nil", body, err)
+	}
+}
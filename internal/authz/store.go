@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// dbStore is the Store backed by the application database.
+type dbStore struct {
+	db *database.Queries
+}
+
+// NewStore returns a Store reading from the application database.
+func NewStore(db *database.Queries) Store {
+	return dbStore{db: db}
+}
+
+func (s dbStore) RecordOwnership(ctx context.Context, kind Kind, id uuid.UUID) (Ownership, error) {
+	row, err := s.db.GetRecordOwnership(ctx, database.GetRecordOwnershipParams{
+		Kind: string(kind),
+		ID:   id,
+	})
+	if err != nil {
+		return Ownership{}, err
+	}
+	return Ownership{
+		ContactID:      row.ContactID,
+		OwnerID:        row.OwnerID,
+		OrganizationID: row.OrganizationID,
+	}, nil
+}
+
+func (s dbStore) ContactAccess(ctx context.Context, userID, contactID uuid.UUID) (ContactAccess, error) {
+	row, err := s.db.GetContactAccess(ctx, database.GetContactAccessParams{
+		UserID:    userID,
+		ContactID: contactID,
+	})
+	if err != nil {
+		return ContactAccess{}, err
+	}
+	return ContactAccess{
+		OwnerID:          row.OwnerID,
+		CollaboratorRole: row.CollaboratorRole,
+		OrgAdmin:         row.OrgAdmin,
+	}, nil
+}
+
+func (s dbStore) OrganizationRole(ctx context.Context, userID, organizationID uuid.UUID) (string, error) {
+	return s.db.GetMemberRole(ctx, database.GetMemberRoleParams{
+		UserID:         userID,
+		OrganizationID: organizationID,
+	})
+}
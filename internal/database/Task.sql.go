@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -27,7 +28,7 @@ INSERT INTO
 VALUES
     ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 `
 
 type CreateTaskParams struct {
@@ -65,17 +66,25 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.SeriesID,
+		&i.OccurrenceDate,
+		&i.ContactActionPlanID,
 	)
 	return i, err
 }
 
 const deleteTask = `-- name: DeleteTask :exec
-DELETE FROM
+UPDATE
     tasks
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
+// Moves the task to the trash
 func (q *Queries) DeleteTask(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteTask, id)
 	return err
@@ -83,19 +92,35 @@ func (q *Queries) DeleteTask(ctx context.Context, id uuid.UUID) error {
 
 const getOverdueTasks = `-- name: GetOverdueTasks :many
 SELECT
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 FROM
     tasks
 WHERE
-    date::date < current_date
-    AND STATUS != 'completed'
-    AND assigned_to_id = $1
+    date < $1::timestamptz
+    AND STATUS NOT IN ('completed', 'cancelled')
+    AND assigned_to_id = $2
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC
 `
 
-func (q *Queries) GetOverdueTasks(ctx context.Context, assignedToID uuid.NullUUID) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getOverdueTasks, assignedToID)
+type GetOverdueTasksParams struct {
+	DayStart     time.Time
+	AssignedToID uuid.NullUUID
+}
+
+// Tasks due before day_start, the start of the user's day
+func (q *Queries) GetOverdueTasks(ctx context.Context, arg GetOverdueTasksParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, getOverdueTasks, arg.DayStart, arg.AssignedToID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +140,10 @@ func (q *Queries) GetOverdueTasks(ctx context.Context, assignedToID uuid.NullUUI
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SeriesID,
+			&i.OccurrenceDate,
+			&i.ContactActionPlanID,
 		); err != nil {
 			return nil, err
 		}
@@ -131,11 +160,21 @@ func (q *Queries) GetOverdueTasks(ctx context.Context, assignedToID uuid.NullUUI
 
 const getTaskByAssignedToID = `-- name: GetTaskByAssignedToID :many
 SELECT
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 FROM
     tasks
 WHERE
     assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC
 `
@@ -161,6 +200,10 @@ func (q *Queries) GetTaskByAssignedToID(ctx context.Context, assignedToID uuid.N
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SeriesID,
+			&i.OccurrenceDate,
+			&i.ContactActionPlanID,
 		); err != nil {
 			return nil, err
 		}
@@ -177,11 +220,12 @@ func (q *Queries) GetTaskByAssignedToID(ctx context.Context, assignedToID uuid.N
 
 const getTaskByID = `-- name: GetTaskByID :one
 SELECT
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 FROM
     tasks
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) GetTaskByID(ctx context.Context, id uuid.UUID) (Task, error) {
@@ -199,25 +243,47 @@ func (q *Queries) GetTaskByID(ctx context.Context, id uuid.UUID) (Task, error) {
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.SeriesID,
+		&i.OccurrenceDate,
+		&i.ContactActionPlanID,
 	)
 	return i, err
 }
 
 const getTaskDueToday = `-- name: GetTaskDueToday :many
 SELECT
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 FROM
     tasks
 WHERE
-    date::date = current_date
-    AND assigned_to_id = $1
-    AND STATUS != 'completed'
+    date >= $1::timestamptz
+    AND date < $2::timestamptz
+    AND assigned_to_id = $3
+    AND STATUS NOT IN ('completed', 'cancelled')
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC
 `
 
-func (q *Queries) GetTaskDueToday(ctx context.Context, assignedToID uuid.NullUUID) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, getTaskDueToday, assignedToID)
+type GetTaskDueTodayParams struct {
+	DayStart     time.Time
+	DayEnd       time.Time
+	AssignedToID uuid.NullUUID
+}
+
+// Tasks due between day_start and day_end, the bounds of the user's day
+func (q *Queries) GetTaskDueToday(ctx context.Context, arg GetTaskDueTodayParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, getTaskDueToday, arg.DayStart, arg.DayEnd, arg.AssignedToID)
 	if err != nil {
 		return nil, err
 	}
@@ -237,6 +303,10 @@ func (q *Queries) GetTaskDueToday(ctx context.Context, assignedToID uuid.NullUUI
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SeriesID,
+			&i.OccurrenceDate,
+			&i.ContactActionPlanID,
 		); err != nil {
 			return nil, err
 		}
@@ -253,11 +323,12 @@ func (q *Queries) GetTaskDueToday(ctx context.Context, assignedToID uuid.NullUUI
 
 const getTasksByContactID = `-- name: GetTasksByContactID :many
 SELECT
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 FROM
     tasks
 WHERE
     contact_id = $1
+    AND deleted_at IS NULL
 ORDER BY
     date DESC
 `
@@ -283,6 +354,10 @@ func (q *Queries) GetTasksByContactID(ctx context.Context, contactID uuid.NullUU
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SeriesID,
+			&i.OccurrenceDate,
+			&i.ContactActionPlanID,
 		); err != nil {
 			return nil, err
 		}
@@ -313,7 +388,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 `
 
 type UpdateTaskParams struct {
@@ -353,6 +428,10 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.SeriesID,
+		&i.OccurrenceDate,
+		&i.ContactActionPlanID,
 	)
 	return i, err
 }
@@ -366,7 +445,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, type, date, status, priority, note, created_at, updated_at, deleted_at, series_id, occurrence_date, contact_action_plan_id
 `
 
 type UpdateTaskStatusParams struct {
@@ -389,6 +468,10 @@ func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusPara
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.SeriesID,
+		&i.OccurrenceDate,
+		&i.ContactActionPlanID,
 	)
 	return i, err
 }
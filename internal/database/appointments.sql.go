@@ -37,7 +37,7 @@ VALUES
         $8
     )
 RETURNING
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 `
 
 type CreateAppointmentParams struct {
@@ -75,17 +75,22 @@ func (q *Queries) CreateAppointment(ctx context.Context, arg CreateAppointmentPa
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteAppointment = `-- name: DeleteAppointment :exec
-DELETE FROM
+UPDATE
     appointments
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
+// Moves the appointment to the trash
 func (q *Queries) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteAppointment, id)
 	return err
@@ -93,11 +98,12 @@ func (q *Queries) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
 
 const getAppointmentById = `-- name: GetAppointmentById :one
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) GetAppointmentById(ctx context.Context, id uuid.UUID) (Appointment, error) {
@@ -115,17 +121,28 @@ func (q *Queries) GetAppointmentById(ctx context.Context, id uuid.UUID) (Appoint
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const listAppointments = `-- name: ListAppointments :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
     assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC
 `
@@ -151,6 +168,7 @@ func (q *Queries) ListAppointments(ctx context.Context, assignedToID uuid.NullUU
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -167,11 +185,12 @@ func (q *Queries) ListAppointments(ctx context.Context, assignedToID uuid.NullUU
 
 const listAppointmentsByContactId = `-- name: ListAppointmentsByContactId :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
     contact_id = $1
+    AND deleted_at IS NULL
 ORDER BY
     scheduled_at ASC
 `
@@ -197,6 +216,7 @@ func (q *Queries) ListAppointmentsByContactId(ctx context.Context, contactID uui
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -213,12 +233,22 @@ func (q *Queries) ListAppointmentsByContactId(ctx context.Context, contactID uui
 
 const listPastAppointments = `-- name: ListPastAppointments :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
     scheduled_at < NOW()
     AND assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at DESC
 `
@@ -244,6 +274,7 @@ func (q *Queries) ListPastAppointments(ctx context.Context, assignedToID uuid.Nu
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -260,18 +291,36 @@ func (q *Queries) ListPastAppointments(ctx context.Context, assignedToID uuid.Nu
 
 const listTodaysAppointments = `-- name: ListTodaysAppointments :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
-    scheduled_at::date = current_date
-    AND assigned_to_id = $1
+    scheduled_at >= $1::timestamptz
+    AND scheduled_at < $2::timestamptz
+    AND assigned_to_id = $3
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC
 `
 
-func (q *Queries) ListTodaysAppointments(ctx context.Context, assignedToID uuid.NullUUID) ([]Appointment, error) {
-	rows, err := q.db.QueryContext(ctx, listTodaysAppointments, assignedToID)
+type ListTodaysAppointmentsParams struct {
+	DayStart     time.Time
+	DayEnd       time.Time
+	AssignedToID uuid.NullUUID
+}
+
+// Appointments between day_start and day_end, the bounds of the user's day
+func (q *Queries) ListTodaysAppointments(ctx context.Context, arg ListTodaysAppointmentsParams) ([]Appointment, error) {
+	rows, err := q.db.QueryContext(ctx, listTodaysAppointments, arg.DayStart, arg.DayEnd, arg.AssignedToID)
 	if err != nil {
 		return nil, err
 	}
@@ -291,6 +340,7 @@ func (q *Queries) ListTodaysAppointments(ctx context.Context, assignedToID uuid.
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -307,12 +357,22 @@ func (q *Queries) ListTodaysAppointments(ctx context.Context, assignedToID uuid.
 
 const listUpcomingAppointments = `-- name: ListUpcomingAppointments :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments
 WHERE
     scheduled_at > NOW()
     AND assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC
 `
@@ -338,6 +398,7 @@ func (q *Queries) ListUpcomingAppointments(ctx context.Context, assignedToID uui
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -366,7 +427,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 `
 
 type UpdateAppointmentParams struct {
@@ -404,6 +465,7 @@ func (q *Queries) UpdateAppointment(ctx context.Context, arg UpdateAppointmentPa
 		&i.Note,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
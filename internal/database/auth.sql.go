@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,22 +15,35 @@ import (
 
 const checkSessionByID = `-- name: CheckSessionByID :one
 SELECT
-    "userId",
-    "expiresAt"
+    s."userId",
+    s."expiresAt",
+    m."organizationId" AS active_organization_id,
+    m.role AS organization_role
 FROM
-    SESSION
+    SESSION s
+    LEFT JOIN member m ON m."organizationId" = s."activeOrganizationId"
+    AND m."userId" = s."userId"
 WHERE
-    token = $1
+    s.token = $1
 `
 
 type CheckSessionByIDRow struct {
-	UserId    uuid.UUID
-	ExpiresAt time.Time
+	UserId               uuid.UUID
+	ExpiresAt            time.Time
+	ActiveOrganizationID uuid.NullUUID
+	OrganizationRole     sql.NullString
 }
 
+// The session's active organization is only returned while the user is
+// still a member of it.
 func (q *Queries) CheckSessionByID(ctx context.Context, token string) (CheckSessionByIDRow, error) {
 	row := q.db.QueryRowContext(ctx, checkSessionByID, token)
 	var i CheckSessionByIDRow
-	err := row.Scan(&i.UserId, &i.ExpiresAt)
+	err := row.Scan(
+		&i.UserId,
+		&i.ExpiresAt,
+		&i.ActiveOrganizationID,
+		&i.OrganizationRole,
+	)
 	return i, err
 }
@@ -15,7 +15,10 @@ const addCollaborator = `-- name: AddCollaborator :exec
 INSERT INTO
     collaborators (contact_id, user_id, role)
 VALUES
-    ($1, $2, $3)
+    ($1, $2, $3) ON CONFLICT (contact_id, user_id) DO
+UPDATE
+SET
+    role = EXCLUDED.role
 `
 
 type AddCollaboratorParams struct {
@@ -39,6 +42,8 @@ FROM
     JOIN users u ON c.user_id = u.id
 WHERE
     c.contact_id = $1
+ORDER BY
+    u.name
 `
 
 type ListCollaboratorsRow struct {
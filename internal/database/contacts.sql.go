@@ -9,64 +9,141 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
 const bulkInsertContacts = `-- name: BulkInsertContacts :many
-INSERT INTO
-    contacts (
+-- INSERT ... RETURNING doesn't keep the order of the input arrays, so each
+-- contact is returned with the position of its row in them
+WITH input AS (
+    SELECT
+        gen_random_uuid() AS id,
+        u.*
+    FROM
+        unnest(
+            $1::text [],
+            $2::text [],
+            $3::text [],
+            $4::text [],
+            $5::text [],
+            $6::text [],
+            $7::text [],
+            $8::text [],
+            $9::text [],
+            $10::text [],
+            $11::text [],
+            $12::text [],
+            $13::uuid []
+        ) WITH ORDINALITY AS u (
+            first_name,
+            last_name,
+            birthdate,
+            source,
+            STATUS,
+            address,
+            city,
+            state,
+            zip_code,
+            lender,
+            price_range,
+            timeframe,
+            owner_id,
+            ordinal
+        )
+),
+inserted AS (
+    INSERT INTO
+        contacts (
+            id,
+            first_name,
+            last_name,
+            birthdate,
+            source,
+            STATUS,
+            address,
+            city,
+            state,
+            zip_code,
+            lender,
+            price_range,
+            timeframe,
+            owner_id,
+            organization_id
+        )
+    SELECT
+        id,
         first_name,
         last_name,
-        birthdate,
-        source,
-        STATUS,
-        address,
-        city,
-        state,
-        zip_code,
-        lender,
-        price_range,
-        timeframe,
-        owner_id
-    )
+        NULLIF(birthdate, '')::date,
+        NULLIF(source, ''),
+        NULLIF(STATUS, ''),
+        NULLIF(address, ''),
+        NULLIF(city, ''),
+        NULLIF(state, ''),
+        NULLIF(zip_code, ''),
+        NULLIF(lender, ''),
+        NULLIF(price_range, ''),
+        NULLIF(timeframe, ''),
+        owner_id,
+        $14::uuid
+    FROM
+        input
+    RETURNING
+        id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at, organization_id, deleted_at
+)
 SELECT
-    unnest($1::text []),
-    unnest($2::text []),
-    unnest($3::date []),
-    unnest($4::text []),
-    unnest($5::text []),
-    unnest($6::text []),
-    unnest($7::text []),
-    unnest($8::text []),
-    unnest($9::text []),
-    unnest($10::text []),
-    unnest($11::text []),
-    unnest($12::text []),
-    unnest($13::uuid [])
-RETURNING
-    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at
+    inserted.id, inserted.first_name, inserted.last_name, inserted.birthdate, inserted.source, inserted.status, inserted.address, inserted.city, inserted.state, inserted.zip_code, inserted.lender, inserted.price_range, inserted.timeframe, inserted.owner_id, inserted.created_at, inserted.updated_at, inserted.last_contacted_at, inserted.organization_id, inserted.deleted_at,
+    input.ordinal
+FROM
+    inserted
+    JOIN input ON input.id = inserted.id
+ORDER BY
+    input.ordinal
 `
 
 type BulkInsertContactsParams struct {
-	FirstNames  []string
-	LastNames   []string
-	Birthdates  []time.Time
-	Sources     []string
-	Statuses    []string
-	Addresses   []string
-	Cities      []string
-	States      []string
-	ZipCodes    []string
-	Lenders     []string
-	PriceRanges []string
-	Timeframes  []string
-	OwnerIds    []uuid.UUID
+	FirstNames     []string
+	LastNames      []string
+	Birthdates     []string
+	Sources        []string
+	Statuses       []string
+	Addresses      []string
+	Cities         []string
+	States         []string
+	ZipCodes       []string
+	Lenders        []string
+	PriceRanges    []string
+	Timeframes     []string
+	OwnerIds       []uuid.UUID
+	OrganizationID uuid.NullUUID
+}
+
+type BulkInsertContactsRow struct {
+	ID              uuid.UUID
+	FirstName       string
+	LastName        string
+	Birthdate       sql.NullTime
+	Source          sql.NullString
+	Status          sql.NullString
+	Address         sql.NullString
+	City            sql.NullString
+	State           sql.NullString
+	ZipCode         sql.NullString
+	Lender          sql.NullString
+	PriceRange      sql.NullString
+	Timeframe       sql.NullString
+	OwnerID         uuid.NullUUID
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	LastContactedAt sql.NullTime
+	OrganizationID  uuid.NullUUID
+	DeletedAt       sql.NullTime
+	Ordinal         int64
 }
 
-func (q *Queries) BulkInsertContacts(ctx context.Context, arg BulkInsertContactsParams) ([]Contact, error) {
+func (q *Queries) BulkInsertContacts(ctx context.Context, arg BulkInsertContactsParams) ([]BulkInsertContactsRow, error) {
 	rows, err := q.db.QueryContext(ctx, bulkInsertContacts,
 		pq.Array(arg.FirstNames),
 		pq.Array(arg.LastNames),
@@ -81,14 +158,15 @@ func (q *Queries) BulkInsertContacts(ctx context.Context, arg BulkInsertContacts
 		pq.Array(arg.PriceRanges),
 		pq.Array(arg.Timeframes),
 		pq.Array(arg.OwnerIds),
+		arg.OrganizationID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Contact
+	var items []BulkInsertContactsRow
 	for rows.Next() {
-		var i Contact
+		var i BulkInsertContactsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.FirstName,
@@ -107,6 +185,9 @@ func (q *Queries) BulkInsertContacts(ctx context.Context, arg BulkInsertContacts
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastContactedAt,
+			&i.OrganizationID,
+			&i.DeletedAt,
+			&i.Ordinal,
 		); err != nil {
 			return nil, err
 		}
@@ -121,6 +202,53 @@ func (q *Queries) BulkInsertContacts(ctx context.Context, arg BulkInsertContacts
 	return items, nil
 }
 
+const canViewContact = `-- name: CanViewContact :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = $1
+            AND c.deleted_at IS NULL
+            AND (
+                c.owner_id = $2
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        collaborators col
+                    WHERE
+                        col.contact_id = c.id
+                        AND col.user_id = $2
+                )
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        member admin
+                    WHERE
+                        admin."userId" = $2
+                        AND admin."organizationId" = c.organization_id
+                        AND admin.role IN ('owner', 'admin')
+                )
+            )
+    )
+`
+
+type CanViewContactParams struct {
+	ContactID uuid.UUID
+	UserID    uuid.NullUUID
+}
+
+func (q *Queries) CanViewContact(ctx context.Context, arg CanViewContactParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, canViewContact, arg.ContactID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createContact = `-- name: CreateContact :one
 INSERT INTO
     contacts (
@@ -136,7 +264,8 @@ INSERT INTO
         lender,
         price_range,
         timeframe,
-        owner_id
+        owner_id,
+        organization_id
     )
 VALUES
     (
@@ -152,26 +281,28 @@ VALUES
         $10,
         $11,
         $12,
-        $13
+        $13,
+        $14
     )
 RETURNING
-    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at
+    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at, organization_id, deleted_at
 `
 
 type CreateContactParams struct {
-	FirstName  string
-	LastName   string
-	Birthdate  sql.NullTime
-	Source     sql.NullString
-	Status     sql.NullString
-	Address    sql.NullString
-	City       sql.NullString
-	State      sql.NullString
-	ZipCode    sql.NullString
-	Lender     sql.NullString
-	PriceRange sql.NullString
-	Timeframe  sql.NullString
-	OwnerID    uuid.NullUUID
+	FirstName      string
+	LastName       string
+	Birthdate      sql.NullTime
+	Source         sql.NullString
+	Status         sql.NullString
+	Address        sql.NullString
+	City           sql.NullString
+	State          sql.NullString
+	ZipCode        sql.NullString
+	Lender         sql.NullString
+	PriceRange     sql.NullString
+	Timeframe      sql.NullString
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
 }
 
 func (q *Queries) CreateContact(ctx context.Context, arg CreateContactParams) (Contact, error) {
@@ -189,6 +320,7 @@ func (q *Queries) CreateContact(ctx context.Context, arg CreateContactParams) (C
 		arg.PriceRange,
 		arg.Timeframe,
 		arg.OwnerID,
+		arg.OrganizationID,
 	)
 	var i Contact
 	err := row.Scan(
@@ -209,6 +341,8 @@ func (q *Queries) CreateContact(ctx context.Context, arg CreateContactParams) (C
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastContactedAt,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -331,7 +465,7 @@ func (q *Queries) CreateContactWithDetails(ctx context.Context, arg CreateContac
 
 const getAllContacts = `-- name: GetAllContacts :many
 SELECT
-    c.id, c.first_name, c.last_name, c.birthdate, c.source, c.status, c.address, c.city, c.state, c.zip_code, c.lender, c.price_range, c.timeframe, c.owner_id, c.created_at, c.updated_at, c.last_contacted_at,
+    c.id, c.first_name, c.last_name, c.birthdate, c.source, c.status, c.address, c.city, c.state, c.zip_code, c.lender, c.price_range, c.timeframe, c.owner_id, c.created_at, c.updated_at, c.last_contacted_at, c.organization_id, c.deleted_at,
     coalesce(
         (
             SELECT
@@ -347,17 +481,30 @@ SELECT
 FROM
     contacts c
 WHERE
-    -- user owns the contact
-    c.owner_id = $3
-    -- OR user is a collaborator on the contact
-    OR EXISTS (
-        SELECT
-            1
-        FROM
-            collaborators col
-        WHERE
-            col.contact_id = c.id
-            AND col.user_id = $3
+    c.deleted_at IS NULL
+    AND (
+        -- user owns the contact
+        c.owner_id = $3
+        -- OR user is a collaborator on the contact
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = $3
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = $3
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
     )
 ORDER BY
     c.created_at DESC
@@ -389,6 +536,8 @@ type GetAllContactsRow struct {
 	CreatedAt       sql.NullTime
 	UpdatedAt       sql.NullTime
 	LastContactedAt sql.NullTime
+	OrganizationID  uuid.NullUUID
+	DeletedAt       sql.NullTime
 	PhoneNumbers    interface{}
 	TotalCount      int64
 }
@@ -420,6 +569,8 @@ func (q *Queries) GetAllContacts(ctx context.Context, arg GetAllContactsParams)
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastContactedAt,
+			&i.OrganizationID,
+			&i.DeletedAt,
 			&i.PhoneNumbers,
 			&i.TotalCount,
 		); err != nil {
@@ -436,9 +587,25 @@ func (q *Queries) GetAllContacts(ctx context.Context, arg GetAllContactsParams)
 	return items, nil
 }
 
+const getContactOrganization = `-- name: GetContactOrganization :one
+SELECT
+    organization_id
+FROM
+    contacts
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetContactOrganization(ctx context.Context, id uuid.UUID) (uuid.NullUUID, error) {
+	row := q.db.QueryRowContext(ctx, getContactOrganization, id)
+	var organization_id uuid.NullUUID
+	err := row.Scan(&organization_id)
+	return organization_id, err
+}
+
 const getContactWithDetails = `-- name: GetContactWithDetails :one
 SELECT
-    c.id, c.first_name, c.last_name, c.birthdate, c.source, c.status, c.address, c.city, c.state, c.zip_code, c.lender, c.price_range, c.timeframe, c.owner_id, c.created_at, c.updated_at, c.last_contacted_at,
+    c.id, c.first_name, c.last_name, c.birthdate, c.source, c.status, c.address, c.city, c.state, c.zip_code, c.lender, c.price_range, c.timeframe, c.owner_id, c.created_at, c.updated_at, c.last_contacted_at, c.organization_id, c.deleted_at,
     coalesce(
         (
             SELECT
@@ -486,7 +653,25 @@ SELECT
                 cb.contact_id = c.id
         ),
         '[]'
-    ) AS collaborators
+    ) AS collaborators,
+    (
+        SELECT
+            e.email_address
+        FROM
+            emails e
+        WHERE
+            e.contact_id = c.id
+            AND e.is_primary
+    ) AS primary_email,
+    (
+        SELECT
+            p.phone_number
+        FROM
+            phone_numbers p
+        WHERE
+            p.contact_id = c.id
+            AND p.is_primary
+    ) AS primary_phone
 FROM
     contacts c
 WHERE
@@ -511,10 +696,14 @@ type GetContactWithDetailsRow struct {
 	CreatedAt       sql.NullTime
 	UpdatedAt       sql.NullTime
 	LastContactedAt sql.NullTime
+	OrganizationID  uuid.NullUUID
+	DeletedAt       sql.NullTime
 	Emails          interface{}
 	PhoneNumbers    interface{}
 	Tags            interface{}
 	Collaborators   interface{}
+	PrimaryEmail    sql.NullString
+	PrimaryPhone    sql.NullString
 }
 
 func (q *Queries) GetContactWithDetails(ctx context.Context, id uuid.UUID) (GetContactWithDetailsRow, error) {
@@ -538,10 +727,14 @@ func (q *Queries) GetContactWithDetails(ctx context.Context, id uuid.UUID) (GetC
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastContactedAt,
+		&i.OrganizationID,
+		&i.DeletedAt,
 		&i.Emails,
 		&i.PhoneNumbers,
 		&i.Tags,
 		&i.Collaborators,
+		&i.PrimaryEmail,
+		&i.PrimaryPhone,
 	)
 	return i, err
 }
@@ -567,12 +760,12 @@ SELECT
     c.updated_at,
     count(*) over () AS total_count
 FROM
-    contacts c
-    LEFT JOIN contact_tags ct ON ct.contact_id = c.id
-    LEFT JOIN tags t ON t.id = ct.tag_id
-    JOIN smart_lists s ON s.id = $1
+    smart_list_members m
+    JOIN contacts c ON c.id = m.contact_id
 WHERE
-    (
+    m.smart_list_id = $1
+    AND c.deleted_at IS NULL
+    AND (
         c.owner_id = $4
         OR EXISTS (
             SELECT
@@ -583,100 +776,17 @@ WHERE
                 col.contact_id = c.id
                 AND col.user_id = $4
         )
-    )
-    -- 🔽 ALL YOUR EXISTING FILTERS 🔽
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'first_name' IS NULL
-        OR c.first_name ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'first_name'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_name' IS NULL
-        OR c.last_name ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_name'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'birthdate' IS NULL
-        OR c.birthdate = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'birthdate'
-        )::date
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'source' IS NULL
-        OR c.source ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'source'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'status' IS NULL
-        OR c.status = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'status'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'address' IS NULL
-        OR c.address ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'address'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'city' IS NULL
-        OR c.city ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'city'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'state' IS NULL
-        OR c.state ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'state'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'zip_code' IS NULL
-        OR c.zip_code = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'zip_code'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'lender' IS NULL
-        OR c.lender ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'lender'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'price_range' IS NULL
-        OR c.price_range = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'price_range'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'timeframe' IS NULL
-        OR c.timeframe = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'timeframe'
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = $4
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
         )
     )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'owner_id' IS NULL
-        OR c.owner_id = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'owner_id'
-        )::uuid
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'tag_id' IS NULL
-        OR t.id = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'tag_id'
-        )::uuid
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_contacted_days' IS NULL
-        OR c.last_contacted_at <= NOW() - (
-            (
-                coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_contacted_days'
-            ) || ' days'
-        )::INTERVAL
-    )
 ORDER BY
     c.last_contacted_at ASC nulls FIRST,
     c.created_at DESC
@@ -759,44 +869,231 @@ func (q *Queries) GetContactsBySmartList(ctx context.Context, arg GetContactsByS
 	return items, nil
 }
 
-const searchContacts = `-- name: SearchContacts :many
+const lockContact = `-- name: LockContact :exec
 SELECT
-    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at
+    id
 FROM
     contacts
 WHERE
-    owner_id = $1
-    AND (
-        first_name ilike $2
-        OR last_name ilike $2
-        OR concat(first_name, ' ', last_name) ilike $2
-        OR address ilike $2
-        OR city ilike $2
-        OR state ilike $2
-        OR lender ilike $2
-        OR source ilike $2
-    )
+    id = $1
+FOR UPDATE
+`
+
+// Serializes changes to a contact's primary email and phone number.
+func (q *Queries) LockContact(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockContact, id)
+	return err
+}
+
+const searchContacts = `-- name: SearchContacts :many
+WITH visible AS MATERIALIZED (
+    SELECT
+        c.id
+    FROM
+        contacts c
+    WHERE
+        c.owner_id = $1
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = $1
+        )
+),
+matches AS (
+    SELECT
+        c.id AS contact_id,
+        'name' AS field,
+        c.first_name || ' ' || c.last_name AS matched_text,
+        (
+            2 * ts_rank(
+                to_tsvector('simple', c.first_name || ' ' || c.last_name),
+                websearch_to_tsquery('simple', $2::text)
+            ) + 2 * word_similarity($2::text, c.first_name || ' ' || c.last_name)
+        )::float8 AS score
+    FROM
+        contacts c
+    WHERE
+        c.id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            to_tsvector('simple', c.first_name || ' ' || c.last_name) @@ websearch_to_tsquery('simple', $2::text)
+            OR (c.first_name || ' ' || c.last_name) ILIKE $3::text
+            OR $2::text <% (c.first_name || ' ' || c.last_name)
+        )
+    UNION ALL
+    SELECT
+        e.contact_id,
+        'email',
+        e.email_address,
+        (0.5 + 1.5 * similarity(e.email_address, $2::text))::float8
+    FROM
+        emails e
+    WHERE
+        e.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND e.email_address ILIKE $3::text
+    UNION ALL
+    SELECT
+        p.contact_id,
+        'phone',
+        p.phone_number,
+        1.5::float8
+    FROM
+        phone_numbers p
+    WHERE
+        $4::text <> ''
+        AND p.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND regexp_replace(p.phone_number, '\D', '', 'g') LIKE $4::text
+    UNION ALL
+    SELECT
+        c.id,
+        'address',
+        concat_ws(', ', c.address, c.city, c.state, c.zip_code),
+        word_similarity(
+            $2::text,
+            coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+        )::float8
+    FROM
+        contacts c
+    WHERE
+        c.id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            (
+                coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+            ) ILIKE $3::text
+            OR $2::text <% (
+                coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+            )
+        )
+    UNION ALL
+    SELECT
+        n.contact_id,
+        'note',
+        n.note,
+        (
+            0.2 + ts_rank(
+                to_tsvector('english', n.note),
+                websearch_to_tsquery('english', $2::text)
+            )
+        )::float8
+    FROM
+        contact_notes n
+    WHERE
+        n.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            to_tsvector('english', n.note) @@ websearch_to_tsquery('english', $2::text)
+            OR n.note ILIKE $3::text
+        )
+),
+ranked AS (
+    SELECT
+        m.contact_id,
+        sum(m.score)::float8 AS rank,
+        (array_agg(m.field ORDER BY m.score DESC))[1]::text AS matched_field,
+        (array_agg(m.matched_text ORDER BY m.score DESC))[1]::text AS matched_text
+    FROM
+        matches m
+    GROUP BY
+        m.contact_id
+)
+SELECT
+    c.id, c.first_name, c.last_name, c.birthdate, c.source, c.status, c.address, c.city, c.state, c.zip_code, c.lender, c.price_range, c.timeframe, c.owner_id, c.created_at, c.updated_at, c.last_contacted_at, c.organization_id, c.deleted_at,
+    r.rank,
+    r.matched_field,
+    r.matched_text,
+    count(*) over () AS total_count
+FROM
+    ranked r
+    JOIN contacts c ON c.id = r.contact_id
+WHERE
+    c.deleted_at IS NULL
 ORDER BY
-    last_name,
-    first_name
+    r.rank DESC,
+    c.last_name,
+    c.first_name,
+    c.id
 LIMIT
-    50
+    $5 OFFSET $6
 `
 
 type SearchContactsParams struct {
-	OwnerID   uuid.NullUUID
-	FirstName string
+	UserID        uuid.NullUUID
+	Query         string
+	Pattern       string
+	DigitsPattern string
+	PageSize      int32
+	PageOffset    int32
+}
+
+type SearchContactsRow struct {
+	ID              uuid.UUID
+	FirstName       string
+	LastName        string
+	Birthdate       sql.NullTime
+	Source          sql.NullString
+	Status          sql.NullString
+	Address         sql.NullString
+	City            sql.NullString
+	State           sql.NullString
+	ZipCode         sql.NullString
+	Lender          sql.NullString
+	PriceRange      sql.NullString
+	Timeframe       sql.NullString
+	OwnerID         uuid.NullUUID
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	LastContactedAt sql.NullTime
+	OrganizationID  uuid.NullUUID
+	DeletedAt       sql.NullTime
+	Rank            float64
+	MatchedField    string
+	MatchedText     string
+	TotalCount      int64
 }
 
-func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams) ([]Contact, error) {
-	rows, err := q.db.QueryContext(ctx, searchContacts, arg.OwnerID, arg.FirstName)
+func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams) ([]SearchContactsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchContacts,
+		arg.UserID,
+		arg.Query,
+		arg.Pattern,
+		arg.DigitsPattern,
+		arg.PageSize,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Contact
+	var items []SearchContactsRow
 	for rows.Next() {
-		var i Contact
+		var i SearchContactsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.FirstName,
@@ -815,6 +1112,12 @@ func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams)
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastContactedAt,
+			&i.OrganizationID,
+			&i.DeletedAt,
+			&i.Rank,
+			&i.MatchedField,
+			&i.MatchedText,
+			&i.TotalCount,
 		); err != nil {
 			return nil, err
 		}
@@ -923,7 +1226,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at
+    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at, organization_id, deleted_at
 `
 
 type UpdateContactParams struct {
@@ -977,6 +1280,8 @@ func (q *Queries) UpdateContact(ctx context.Context, arg UpdateContactParams) (C
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastContactedAt,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
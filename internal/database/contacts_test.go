@@ -0,0 +1,52 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestGetAllContactsListsWhatTheUserCanView(t *testing.T) {
+	q, tx := testQueries(t)
+	ctx := context.Background()
+
+	owner, admin, member := insertUser(t, tx), insertUser(t, tx), insertUser(t, tx)
+	org := insertOrganization(t, tx)
+	addMember(t, tx, org, owner, "member")
+	addMember(t, tx, org, admin, "admin")
+	addMember(t, tx, org, member, "member")
+	contact := insertContact(t, tx, owner, org)
+
+	tests := []struct {
+		name string
+		user uuid.UUID
+		want bool
+	}{
+		{"owner", owner, true},
+		{"organization admin", admin, true},
+		{"organization member", member, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := uuid.NullUUID{UUID: tt.user, Valid: true}
+			contacts, err := q.GetAllContacts(ctx, database.GetAllContactsParams{Limit: 10, OwnerID: user})
+			if err != nil {
+				t.Fatal(err)
+			}
+			listed := len(contacts) == 1 && contacts[0].ID == contact
+			if listed != tt.want || (!tt.want && len(contacts) != 0) {
+				t.Errorf("listed %d contacts, want the organization's contact listed = %v", len(contacts), tt.want)
+			}
+
+			canView, err := q.CanViewContact(ctx, database.CanViewContactParams{ContactID: contact, UserID: user})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if canView != tt.want {
+				t.Errorf("CanViewContact() = %v, want %v to agree with the list", canView, tt.want)
+			}
+		})
+	}
+}
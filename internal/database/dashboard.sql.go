@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -18,14 +19,30 @@ SELECT
 FROM
     appointments
 WHERE
-    scheduled_at >= date_trunc('week', current_date)
-    AND scheduled_at < date_trunc('week', current_date) + INTERVAL '7 days'
+    scheduled_at >= $1::timestamptz
+    AND scheduled_at < $2::timestamptz
     AND outcome = 'no-outcome'
-    AND assigned_to_id = $1
+    AND assigned_to_id = $3
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 `
 
-func (q *Queries) AppointmentsThisWeek(ctx context.Context, assignedToID uuid.NullUUID) (int64, error) {
-	row := q.db.QueryRowContext(ctx, appointmentsThisWeek, assignedToID)
+type AppointmentsThisWeekParams struct {
+	WeekStart    time.Time
+	WeekEnd      time.Time
+	AssignedToID uuid.NullUUID
+}
+
+func (q *Queries) AppointmentsThisWeek(ctx context.Context, arg AppointmentsThisWeekParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, appointmentsThisWeek, arg.WeekStart, arg.WeekEnd, arg.AssignedToID)
 	var appointments_this_week int64
 	err := row.Scan(&appointments_this_week)
 	return appointments_this_week, err
@@ -39,6 +56,7 @@ FROM
     contacts
 WHERE
     owner_id = $1
+    AND deleted_at IS NULL
 GROUP BY
     source
 ORDER BY
@@ -80,6 +98,7 @@ FROM
     contacts
 WHERE
     owner_id = $1
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) ContactsCount(ctx context.Context, ownerID uuid.NullUUID) (int64, error) {
@@ -152,6 +171,7 @@ FROM
     LEFT JOIN phone_numbers p ON p.contact_id = c.id
 WHERE
     c.owner_id = $1
+    AND c.deleted_at IS NULL
 GROUP BY
     c.id
 ORDER BY
@@ -227,21 +247,36 @@ func (q *Queries) Get5NewestContacts(ctx context.Context, ownerID uuid.NullUUID)
 
 const getUpcomingAppointments = `-- name: GetUpcomingAppointments :many
 SELECT
-    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at
+    id, contact_id, assigned_to_id, title, scheduled_at, location, type, outcome, note, created_at, updated_at, deleted_at
 FROM
     appointments a
 WHERE
-    a.scheduled_at >= current_date
+    a.scheduled_at >= $1::timestamptz
     AND a.status = 'scheduled'
-    AND a.assigned_to_id = $1
+    AND a.assigned_to_id = $2
+    AND a.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = a.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     a.scheduled_at ASC
 LIMIT
     5
 `
 
-func (q *Queries) GetUpcomingAppointments(ctx context.Context, assignedToID uuid.NullUUID) ([]Appointment, error) {
-	rows, err := q.db.QueryContext(ctx, getUpcomingAppointments, assignedToID)
+type GetUpcomingAppointmentsParams struct {
+	DayStart     time.Time
+	AssignedToID uuid.NullUUID
+}
+
+func (q *Queries) GetUpcomingAppointments(ctx context.Context, arg GetUpcomingAppointmentsParams) ([]Appointment, error) {
+	rows, err := q.db.QueryContext(ctx, getUpcomingAppointments, arg.DayStart, arg.AssignedToID)
 	if err != nil {
 		return nil, err
 	}
@@ -261,6 +296,7 @@ func (q *Queries) GetUpcomingAppointments(ctx context.Context, assignedToID uuid
 			&i.Note,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -281,12 +317,18 @@ SELECT
 FROM
     contacts
 WHERE
-    created_at >= date_trunc('month', current_date)
-    AND owner_id = $1
+    created_at >= $1::timestamptz
+    AND owner_id = $2
+    AND deleted_at IS NULL
 `
 
-func (q *Queries) NewContactsThisMonth(ctx context.Context, ownerID uuid.NullUUID) (int64, error) {
-	row := q.db.QueryRowContext(ctx, newContactsThisMonth, ownerID)
+type NewContactsThisMonthParams struct {
+	MonthStart time.Time
+	OwnerID    uuid.NullUUID
+}
+
+func (q *Queries) NewContactsThisMonth(ctx context.Context, arg NewContactsThisMonthParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, newContactsThisMonth, arg.MonthStart, arg.OwnerID)
 	var new_contacts int64
 	err := row.Scan(&new_contacts)
 	return new_contacts, err
@@ -298,13 +340,30 @@ SELECT
 FROM
     tasks
 WHERE
-    date = current_date
+    date >= $1::timestamptz
+    AND date < $2::timestamptz
     AND STATUS = 'pending'
-    AND assigned_to_id = $1
+    AND assigned_to_id = $3
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 `
 
-func (q *Queries) TasksDueTodayCount(ctx context.Context, assignedToID uuid.NullUUID) (int64, error) {
-	row := q.db.QueryRowContext(ctx, tasksDueTodayCount, assignedToID)
+type TasksDueTodayCountParams struct {
+	DayStart     time.Time
+	DayEnd       time.Time
+	AssignedToID uuid.NullUUID
+}
+
+func (q *Queries) TasksDueTodayCount(ctx context.Context, arg TasksDueTodayCountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, tasksDueTodayCount, arg.DayStart, arg.DayEnd, arg.AssignedToID)
 	var tasks_due_today int64
 	err := row.Scan(&tasks_due_today)
 	return tasks_due_today, err
@@ -0,0 +1,131 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// These tests run the queries against a real Postgres, which is where their
+// WHERE clauses can actually be checked. Point TEST_DATABASE_URL at a database
+// the tests may create schemas in; without it they are skipped.
+
+var (
+	testDB     *sql.DB
+	testSchema string
+)
+
+func TestMain(m *testing.M) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		os.Exit(m.Run())
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open test database:", err)
+		os.Exit(1)
+	}
+	testDB = db
+	testSchema = "crm_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := migrate(db, testSchema); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate test database:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	if _, err := db.Exec("DROP SCHEMA " + testSchema + " CASCADE"); err != nil {
+		fmt.Fprintln(os.Stderr, "drop test schema:", err)
+	}
+	db.Close()
+	os.Exit(code)
+}
+
+// migrate creates schema and applies the Up section of every migration in
+// sql/schema to it.
+func migrate(db *sql.DB, schema string) error {
+	files, err := filepath.Glob("../../sql/schema/*.sql")
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("CREATE SCHEMA " + schema + "; SET LOCAL search_path TO " + schema + ", public"); err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		_, up, _ := strings.Cut(string(data), "-- +goose Up")
+		up, _, _ = strings.Cut(up, "-- +goose Down")
+		if _, err := tx.Exec(up); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+	return tx.Commit()
+}
+
+// testQueries returns queries running in a transaction on the migrated test
+// schema, rolled back when the test ends.
+func testQueries(t *testing.T) (*database.Queries, *sql.Tx) {
+	t.Helper()
+
+	if testDB == nil {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	tx, err := testDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	if _, err := tx.Exec("SET LOCAL search_path TO " + testSchema + ", public"); err != nil {
+		t.Fatal(err)
+	}
+	return database.New(tx), tx
+}
+
+// insertID runs an INSERT ... RETURNING id and fails the test on error.
+func insertID(t *testing.T, tx *sql.Tx, query string, args ...any) uuid.UUID {
+	t.Helper()
+
+	var id uuid.UUID
+	if err := tx.QueryRowContext(context.Background(), query, args...).Scan(&id); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return id
+}
+
+func insertUser(t *testing.T, tx *sql.Tx) uuid.UUID {
+	t.Helper()
+	return insertID(t, tx, `INSERT INTO users (name, email, "emailVerified") VALUES ('Test', $1, TRUE) RETURNING id`, uuid.NewString()+"@example.com")
+}
+
+func insertOrganization(t *testing.T, tx *sql.Tx) uuid.UUID {
+	t.Helper()
+	return insertID(t, tx, `INSERT INTO organization (name, slug, "createdAt") VALUES ('Test', $1, now()) RETURNING id`, uuid.NewString())
+}
+
+func addMember(t *testing.T, tx *sql.Tx, orgID, userID uuid.UUID, role string) {
+	t.Helper()
+	insertID(t, tx, `INSERT INTO member ("organizationId", "userId", role, "createdAt") VALUES ($1, $2, $3, now()) RETURNING id`, orgID, userID, role)
+}
+
+func insertContact(t *testing.T, tx *sql.Tx, ownerID, orgID uuid.UUID) uuid.UUID {
+	t.Helper()
+	return insertID(t, tx, `INSERT INTO contacts (first_name, last_name, owner_id, organization_id) VALUES ('Ann', 'Lee', $1, $2) RETURNING id`, ownerID, orgID)
+}
@@ -19,6 +19,16 @@ FROM
     deals
 WHERE
     assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 `
 
 func (q *Queries) CountDeals(ctx context.Context, assignedToID uuid.NullUUID) (int64, error) {
@@ -36,6 +46,16 @@ FROM
 WHERE
     stage_id = $1
     AND assigned_to_id = $2
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 `
 
 type CountDealsByStageParams struct {
@@ -98,7 +118,7 @@ VALUES
         $20
     )
 RETURNING
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 `
 
 type CreateDealParams struct {
@@ -172,19 +192,22 @@ func (q *Queries) CreateDeal(ctx context.Context, arg CreateDealParams) (Deal, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ClosedDate,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteDeal = `-- name: DeleteDeal :exec
-DELETE FROM
+UPDATE
     deals
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
     id = $1
-RETURNING
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    AND deleted_at IS NULL
 `
 
+// Moves the deal to the trash
 func (q *Queries) DeleteDeal(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteDeal, id)
 	return err
@@ -192,11 +215,12 @@ func (q *Queries) DeleteDeal(ctx context.Context, id uuid.UUID) error {
 
 const getDealById = `-- name: GetDealById :one
 SELECT
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 FROM
     deals
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) GetDealById(ctx context.Context, id uuid.UUID) (Deal, error) {
@@ -226,17 +250,28 @@ func (q *Queries) GetDealById(ctx context.Context, id uuid.UUID) (Deal, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ClosedDate,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const listDeals = `-- name: ListDeals :many
 SELECT
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 FROM
     deals
 WHERE
     assigned_to_id = $3
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     created_at DESC
 LIMIT
@@ -282,6 +317,7 @@ func (q *Queries) ListDeals(ctx context.Context, arg ListDealsParams) ([]Deal, e
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ClosedDate,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -298,12 +334,22 @@ func (q *Queries) ListDeals(ctx context.Context, arg ListDealsParams) ([]Deal, e
 
 const listDealsByContactID = `-- name: ListDealsByContactID :many
 SELECT
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 FROM
     deals
 WHERE
     contact_id = $1
     AND assigned_to_id = $2
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 `
 
 type ListDealsByContactIDParams struct {
@@ -344,6 +390,7 @@ func (q *Queries) ListDealsByContactID(ctx context.Context, arg ListDealsByConta
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ClosedDate,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -360,12 +407,22 @@ func (q *Queries) ListDealsByContactID(ctx context.Context, arg ListDealsByConta
 
 const listDealsByStage = `-- name: ListDealsByStage :many
 SELECT
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 FROM
     deals
 WHERE
     stage_id = $1
     AND assigned_to_id = $4
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     created_at DESC
 LIMIT
@@ -417,6 +474,7 @@ func (q *Queries) ListDealsByStage(ctx context.Context, arg ListDealsByStagePara
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ClosedDate,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -431,6 +489,22 @@ func (q *Queries) ListDealsByStage(ctx context.Context, arg ListDealsByStagePara
 	return items, nil
 }
 
+const lockDeal = `-- name: LockDeal :exec
+SELECT
+    id
+FROM
+    deals
+WHERE
+    id = $1
+FOR UPDATE
+`
+
+// Serializes updates to a deal so its field history stays in order.
+func (q *Queries) LockDeal(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockDeal, id)
+	return err
+}
+
 const updateDeal = `-- name: UpdateDeal :one
 UPDATE
     deals
@@ -458,7 +532,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date
+    id, contact_id, assigned_to_id, title, price, closing_date, earnest_money_due_date, mutual_acceptance_date, inspection_date, appraisal_date, final_walkthrough_date, possession_date, commission, commission_split, property_address, property_city, property_state, property_zip_code, description, stage_id, created_at, updated_at, closed_date, deleted_at
 `
 
 type UpdateDealParams struct {
@@ -534,6 +608,7 @@ func (q *Queries) UpdateDeal(ctx context.Context, arg UpdateDealParams) (Deal, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ClosedDate,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -16,11 +16,11 @@ import (
 
 const bulkEnterEmails = `-- name: BulkEnterEmails :exec
 INSERT INTO
-    emails (contact_id, email, TYPE, is_primary)
+    emails (contact_id, email_address, TYPE, is_primary)
 SELECT
     unnest($1::uuid []),
     unnest($2::text []),
-    unnest($3::text []),
+    NULLIF(unnest($3::text []), ''),
     unnest($4::boolean [])
 `
 
@@ -53,11 +53,29 @@ func (q *Queries) DeleteEmail(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const enterEmail = `-- name: EnterEmail :exec
+const demotePrimaryEmails = `-- name: DemotePrimaryEmails :exec
+UPDATE
+    emails
+SET
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = $1
+    AND is_primary
+`
+
+func (q *Queries) DemotePrimaryEmails(ctx context.Context, contactID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, demotePrimaryEmails, contactID)
+	return err
+}
+
+const enterEmail = `-- name: EnterEmail :one
 INSERT INTO
     emails (contact_id, email_address, TYPE, is_primary)
 VALUES
     ($1, $2, $3, $4)
+RETURNING
+    id
 `
 
 type EnterEmailParams struct {
@@ -67,13 +85,78 @@ type EnterEmailParams struct {
 	IsPrimary    sql.NullBool
 }
 
-func (q *Queries) EnterEmail(ctx context.Context, arg EnterEmailParams) error {
-	_, err := q.db.ExecContext(ctx, enterEmail,
+func (q *Queries) EnterEmail(ctx context.Context, arg EnterEmailParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, enterEmail,
 		arg.ContactID,
 		arg.EmailAddress,
 		arg.Type,
 		arg.IsPrimary,
 	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getEmailByID = `-- name: GetEmailByID :one
+SELECT
+    id, contact_id, email_address, type, is_primary, created_at, updated_at, is_verified, is_subscribed
+FROM
+    emails
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetEmailByID(ctx context.Context, id uuid.UUID) (Email, error) {
+	row := q.db.QueryRowContext(ctx, getEmailByID, id)
+	var i Email
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.EmailAddress,
+		&i.Type,
+		&i.IsPrimary,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsVerified,
+		&i.IsSubscribed,
+	)
+	return i, err
+}
+
+const promotePrimaryEmail = `-- name: PromotePrimaryEmail :exec
+UPDATE
+    emails
+SET
+    is_primary = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = (
+        SELECT
+            o.id
+        FROM
+            emails o
+        WHERE
+            o.contact_id = $1
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            emails p
+        WHERE
+            p.contact_id = $1
+            AND p.is_primary
+    )
+`
+
+// Makes the contact's oldest email primary when it has none.
+func (q *Queries) PromotePrimaryEmail(ctx context.Context, contactID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, promotePrimaryEmail, contactID)
 	return err
 }
 
@@ -7,6 +7,7 @@ package database
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -295,6 +296,30 @@ type Account struct {
 	UpdatedAt             time.Time
 }
 
+type ActionPlan struct {
+	ID             uuid.UUID
+	UserID         uuid.NullUUID
+	OrganizationID uuid.NullUUID
+	Name           string
+	Description    sql.NullString
+	TriggerTagID   uuid.NullUUID
+	ApplyToLeads   bool
+	LeadSources    []string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ActionPlanStep struct {
+	ID           uuid.UUID
+	ActionPlanID uuid.UUID
+	Position     int32
+	Title        string
+	TaskType     TaskType
+	OffsetDays   int32
+	Priority     TaskPriority
+	NoteTemplate string
+}
+
 type Apikey struct {
 	ID                  uuid.UUID
 	Name                sql.NullString
@@ -331,6 +356,19 @@ type Appointment struct {
 	Note         sql.NullString
 	CreatedAt    sql.NullTime
 	UpdatedAt    sql.NullTime
+	DeletedAt    sql.NullTime
+}
+
+type AuditEvent struct {
+	ID         uuid.UUID
+	ActorID    uuid.NullUUID
+	AuthMethod string
+	EntityType string
+	EntityID   uuid.UUID
+	Action     string
+	Changes    json.RawMessage
+	RequestID  sql.NullString
+	CreatedAt  time.Time
 }
 
 type Collaborator struct {
@@ -359,6 +397,30 @@ type Contact struct {
 	CreatedAt       sql.NullTime
 	UpdatedAt       sql.NullTime
 	LastContactedAt sql.NullTime
+	OrganizationID  uuid.NullUUID
+	DeletedAt       sql.NullTime
+}
+
+type ContactActionPlan struct {
+	ID           uuid.UUID
+	ContactID    uuid.UUID
+	ActionPlanID uuid.UUID
+	Status       string
+	StartedAt    time.Time
+	AppliedBy    uuid.NullUUID
+	PausedAt     sql.NullTime
+	PauseReason  sql.NullString
+	CreatedAt    time.Time
+}
+
+type ContactEvent struct {
+	ID        uuid.UUID
+	ContactID uuid.UUID
+	EventType string
+	DealID    uuid.NullUUID
+	TagID     uuid.NullUUID
+	Details   json.RawMessage
+	CreatedAt time.Time
 }
 
 type ContactLog struct {
@@ -371,6 +433,16 @@ type ContactLog struct {
 	UpdatedAt     sql.NullTime
 }
 
+type ContactMerge struct {
+	ID                 uuid.UUID
+	SurvivingContactID uuid.NullUUID
+	MergedContactID    uuid.UUID
+	MergedContact      json.RawMessage
+	MovedRecords       json.RawMessage
+	MergedBy           uuid.NullUUID
+	CreatedAt          time.Time
+}
+
 type ContactNote struct {
 	ID        uuid.UUID
 	ContactID uuid.NullUUID
@@ -409,6 +481,15 @@ type Deal struct {
 	CreatedAt            sql.NullTime
 	UpdatedAt            sql.NullTime
 	ClosedDate           sql.NullTime
+	DeletedAt            sql.NullTime
+}
+
+type DigestPreference struct {
+	UserID     uuid.UUID
+	Enabled    bool
+	SendHour   int32
+	LastSentOn sql.NullTime
+	UpdatedAt  time.Time
 }
 
 type Email struct {
@@ -423,6 +504,17 @@ type Email struct {
 	IsSubscribed sql.NullBool
 }
 
+type FieldHistory struct {
+	ID        uuid.UUID
+	ContactID uuid.NullUUID
+	DealID    uuid.NullUUID
+	Field     string
+	OldValue  json.RawMessage
+	NewValue  json.RawMessage
+	ChangedBy uuid.NullUUID
+	ChangedAt time.Time
+}
+
 type Goal struct {
 	ID                             uuid.UUID
 	UserID                         uuid.NullUUID
@@ -436,6 +528,42 @@ type Goal struct {
 	UpdatedAt                      sql.NullTime
 }
 
+type ImportJob struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Status         string
+	Format         string
+	FileName       sql.NullString
+	Mapping        pqtype.NullRawMessage
+	DefaultSource  sql.NullString
+	Payload        []byte
+	TotalRows      int32
+	ProcessedRows  int32
+	ImportedRows   int32
+	SkippedRows    int32
+	FailedRows     int32
+	Errors         json.RawMessage
+	Error          sql.NullString
+	Attempts       int32
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	StartedAt      sql.NullTime
+	FinishedAt     sql.NullTime
+	OrganizationID uuid.NullUUID
+	RouteLeads     bool
+	LeaseID        uuid.NullUUID
+	LockedUntil    sql.NullTime
+}
+
+type ImportMapping struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Mapping   json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type Invitation struct {
 	ID             uuid.UUID
 	OrganizationId uuid.UUID
@@ -447,6 +575,19 @@ type Invitation struct {
 	InviterId      uuid.UUID
 }
 
+type LeadAssignment struct {
+	ID             uuid.UUID
+	ContactID      uuid.UUID
+	OrganizationID uuid.UUID
+	RuleID         uuid.NullUUID
+	UserID         uuid.NullUUID
+	Status         string
+	ExpiresAt      sql.NullTime
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	CreatedAt      time.Time
+}
+
 type Member struct {
 	ID             uuid.UUID
 	OrganizationId uuid.UUID
@@ -498,6 +639,53 @@ type PhoneNumber struct {
 	IsPrimary   sql.NullBool
 	CreatedAt   sql.NullTime
 	UpdatedAt   sql.NullTime
+	E164        sql.NullString
+}
+
+type Reminder struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	TaskID         uuid.NullUUID
+	AppointmentID  uuid.NullUUID
+	DueAt          time.Time
+	LeadMinutes    int32
+	NotificationID uuid.NullUUID
+	SendEmail      bool
+	EmailSentAt    sql.NullTime
+	EmailAttempts  int32
+	CreatedAt      time.Time
+}
+
+type ReminderPreference struct {
+	UserID                 uuid.UUID
+	Enabled                bool
+	Email                  bool
+	TaskLeadMinutes        []int32
+	AppointmentLeadMinutes []int32
+	UpdatedAt              time.Time
+}
+
+type RoutingRule struct {
+	ID                  uuid.UUID
+	OrganizationID      uuid.UUID
+	Name                string
+	Priority            int32
+	Strategy            string
+	ZipCodes            []string
+	Cities              []string
+	Sources             []string
+	ClaimTimeoutMinutes sql.NullInt32
+	Enabled             bool
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type RoutingRuleAgent struct {
+	RuleID         uuid.UUID
+	UserID         uuid.UUID
+	Capacity       int32
+	AssignedCount  int64
+	LastAssignedAt sql.NullTime
 }
 
 type Session struct {
@@ -514,13 +702,32 @@ type Session struct {
 }
 
 type SmartList struct {
-	ID             uuid.UUID
-	Name           string
-	Description    sql.NullString
-	UserID         uuid.NullUUID
-	FilterCriteria pqtype.NullRawMessage
-	CreatedAt      sql.NullTime
-	UpdatedAt      sql.NullTime
+	ID                 uuid.UUID
+	Name               string
+	Description        sql.NullString
+	UserID             uuid.NullUUID
+	FilterCriteria     pqtype.NullRawMessage
+	CreatedAt          sql.NullTime
+	UpdatedAt          sql.NullTime
+	MembersRefreshedAt sql.NullTime
+	OrganizationID     uuid.NullUUID
+}
+
+type SmartListDirtyContact struct {
+	ContactID uuid.UUID
+	QueuedAt  time.Time
+}
+
+type SmartListMember struct {
+	SmartListID uuid.UUID
+	ContactID   uuid.UUID
+	AddedAt     time.Time
+}
+
+type SmartListSubscription struct {
+	SmartListID uuid.UUID
+	UserID      uuid.UUID
+	CreatedAt   time.Time
 }
 
 type Stage struct {
@@ -534,6 +741,8 @@ type Stage struct {
 	CreatedAt            sql.NullTime
 	UpdatedAt            sql.NullTime
 	OwnerID              uuid.NullUUID
+	OrganizationID       uuid.NullUUID
+	DeletedAt            sql.NullTime
 }
 
 type Subscription struct {
@@ -552,26 +761,57 @@ type Subscription struct {
 }
 
 type Tag struct {
-	ID          uuid.UUID
-	Name        string
-	Description sql.NullString
-	UserID      uuid.NullUUID
-	CreatedAt   sql.NullTime
-	UpdatedAt   sql.NullTime
+	ID             uuid.UUID
+	Name           string
+	Description    sql.NullString
+	UserID         uuid.NullUUID
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	OrganizationID uuid.NullUUID
 }
 
 type Task struct {
+	ID                  uuid.UUID
+	ContactID           uuid.NullUUID
+	AssignedToID        uuid.NullUUID
+	Title               string
+	Type                NullTaskType
+	Date                sql.NullTime
+	Status              NullTaskStatus
+	Priority            NullTaskPriority
+	Note                sql.NullString
+	CreatedAt           sql.NullTime
+	UpdatedAt           sql.NullTime
+	DeletedAt           sql.NullTime
+	SeriesID            uuid.NullUUID
+	OccurrenceDate      sql.NullTime
+	ContactActionPlanID uuid.NullUUID
+}
+
+type TaskSeries struct {
 	ID           uuid.UUID
+	Rrule        string
+	Dtstart      time.Time
 	ContactID    uuid.NullUUID
 	AssignedToID uuid.NullUUID
 	Title        string
 	Type         NullTaskType
-	Date         sql.NullTime
-	Status       NullTaskStatus
 	Priority     NullTaskPriority
 	Note         sql.NullString
-	CreatedAt    sql.NullTime
-	UpdatedAt    sql.NullTime
+	CancelledAt  sql.NullTime
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type TrashItem struct {
+	Kind           string
+	ID             uuid.UUID
+	Title          string
+	ContactID      uuid.NullUUID
+	OwnerID        uuid.NullUUID
+	ContactOwnerID uuid.NullUUID
+	OrganizationID uuid.NullUUID
+	DeletedAt      sql.NullTime
 }
 
 type TwoFactor struct {
@@ -596,6 +836,7 @@ type User struct {
 	BanExpires       sql.NullTime
 	StripeCustomerId sql.NullString
 	TrialAllowed     bool
+	TimeZone         string
 }
 
 type Verification struct {
@@ -124,18 +124,41 @@ func (q *Queries) GetNotificationsByUserID(ctx context.Context, arg GetNotificat
 	return items, nil
 }
 
-const markAllNotificationsAsRead = `-- name: MarkAllNotificationsAsRead :exec
-UPDATE
+const listUnreadNotificationIDs = `-- name: ListUnreadNotificationIDs :many
+SELECT
+    id
+FROM
     notifications
-SET
-    READ = TRUE
 WHERE
     user_id = $1
+    AND READ IS NOT TRUE
+ORDER BY
+    created_at FOR UPDATE
 `
 
-func (q *Queries) MarkAllNotificationsAsRead(ctx context.Context, userID uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markAllNotificationsAsRead, userID)
-	return err
+// The user's unread notifications, locked so they can be marked read and
+// audited one at a time.
+func (q *Queries) ListUnreadNotificationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listUnreadNotificationIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markNotificationAsRead = `-- name: MarkNotificationAsRead :exec
@@ -16,12 +16,13 @@ import (
 
 const bulkEnterPhoneNumbers = `-- name: BulkEnterPhoneNumbers :exec
 INSERT INTO
-    phone_numbers (contact_id, phone_number, TYPE, is_primary)
+    phone_numbers (contact_id, phone_number, TYPE, is_primary, e164)
 SELECT
     unnest($1::uuid []),
     unnest($2::text []),
-    unnest($3::text []),
-    unnest($4::boolean [])
+    NULLIF(unnest($3::text []), ''),
+    unnest($4::boolean []),
+    NULLIF(unnest($5::text []), '')
 `
 
 type BulkEnterPhoneNumbersParams struct {
@@ -29,6 +30,7 @@ type BulkEnterPhoneNumbersParams struct {
 	PhoneNumbers []string
 	Types        []string
 	IsPrimary    []bool
+	E164s        []string
 }
 
 func (q *Queries) BulkEnterPhoneNumbers(ctx context.Context, arg BulkEnterPhoneNumbersParams) error {
@@ -37,6 +39,7 @@ func (q *Queries) BulkEnterPhoneNumbers(ctx context.Context, arg BulkEnterPhoneN
 		pq.Array(arg.PhoneNumbers),
 		pq.Array(arg.Types),
 		pq.Array(arg.IsPrimary),
+		pq.Array(arg.E164s),
 	)
 	return err
 }
@@ -53,11 +56,29 @@ func (q *Queries) DeletePhoneNumber(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const enterPhoneNumber = `-- name: EnterPhoneNumber :exec
+const demotePrimaryPhoneNumbers = `-- name: DemotePrimaryPhoneNumbers :exec
+UPDATE
+    phone_numbers
+SET
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = $1
+    AND is_primary
+`
+
+func (q *Queries) DemotePrimaryPhoneNumbers(ctx context.Context, contactID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, demotePrimaryPhoneNumbers, contactID)
+	return err
+}
+
+const enterPhoneNumber = `-- name: EnterPhoneNumber :one
 INSERT INTO
-    phone_numbers (contact_id, phone_number, TYPE, is_primary)
+    phone_numbers (contact_id, phone_number, TYPE, is_primary, e164)
 VALUES
-    ($1, $2, $3, $4)
+    ($1, $2, $3, $4, $5)
+RETURNING
+    id
 `
 
 type EnterPhoneNumberParams struct {
@@ -65,21 +86,25 @@ type EnterPhoneNumberParams struct {
 	PhoneNumber string
 	Type        sql.NullString
 	IsPrimary   sql.NullBool
+	E164        sql.NullString
 }
 
-func (q *Queries) EnterPhoneNumber(ctx context.Context, arg EnterPhoneNumberParams) error {
-	_, err := q.db.ExecContext(ctx, enterPhoneNumber,
+func (q *Queries) EnterPhoneNumber(ctx context.Context, arg EnterPhoneNumberParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, enterPhoneNumber,
 		arg.ContactID,
 		arg.PhoneNumber,
 		arg.Type,
 		arg.IsPrimary,
+		arg.E164,
 	)
-	return err
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const getPhoneNumberByID = `-- name: GetPhoneNumberByID :one
 SELECT
-    id, contact_id, phone_number, type, is_primary, created_at, updated_at
+    id, contact_id, phone_number, type, is_primary, created_at, updated_at, e164
 FROM
     phone_numbers
 WHERE
@@ -97,13 +122,14 @@ func (q *Queries) GetPhoneNumberByID(ctx context.Context, id uuid.UUID) (PhoneNu
 		&i.IsPrimary,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.E164,
 	)
 	return i, err
 }
 
 const getPhoneNumbersByContactID = `-- name: GetPhoneNumbersByContactID :many
 SELECT
-    id, contact_id, phone_number, type, is_primary, created_at, updated_at
+    id, contact_id, phone_number, type, is_primary, created_at, updated_at, e164
 FROM
     phone_numbers
 WHERE
@@ -130,6 +156,7 @@ func (q *Queries) GetPhoneNumbersByContactID(ctx context.Context, contactID uuid
 			&i.IsPrimary,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.E164,
 		); err != nil {
 			return nil, err
 		}
@@ -144,6 +171,43 @@ func (q *Queries) GetPhoneNumbersByContactID(ctx context.Context, contactID uuid
 	return items, nil
 }
 
+const promotePrimaryPhoneNumber = `-- name: PromotePrimaryPhoneNumber :exec
+UPDATE
+    phone_numbers
+SET
+    is_primary = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = (
+        SELECT
+            o.id
+        FROM
+            phone_numbers o
+        WHERE
+            o.contact_id = $1
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            phone_numbers p
+        WHERE
+            p.contact_id = $1
+            AND p.is_primary
+    )
+`
+
+// Makes the contact's oldest phone number primary when it has none.
+func (q *Queries) PromotePrimaryPhoneNumber(ctx context.Context, contactID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, promotePrimaryPhoneNumber, contactID)
+	return err
+}
+
 const testBulkInsertPhoneNumbers = `-- name: TestBulkInsertPhoneNumbers :exec
 INSERT INTO
     phone_numbers (contact_id, number, TYPE, is_primary)
@@ -173,6 +237,7 @@ SET
     phone_number = $1,
     TYPE = $2,
     is_primary = $3,
+    e164 = $5,
     updated_at = CURRENT_TIMESTAMP
 WHERE
     id = $4
@@ -183,6 +248,7 @@ type UpdatePhoneNumberParams struct {
 	Type        sql.NullString
 	IsPrimary   sql.NullBool
 	ID          uuid.UUID
+	E164        sql.NullString
 }
 
 func (q *Queries) UpdatePhoneNumber(ctx context.Context, arg UpdatePhoneNumberParams) error {
@@ -191,6 +257,7 @@ func (q *Queries) UpdatePhoneNumber(ctx context.Context, arg UpdatePhoneNumberPa
 		arg.Type,
 		arg.IsPrimary,
 		arg.ID,
+		arg.E164,
 	)
 	return err
 }
@@ -8,32 +8,117 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/sqlc-dev/pqtype"
 )
 
+const claimDirtySmartListContacts = `-- name: ClaimDirtySmartListContacts :many
+DELETE FROM
+    smart_list_dirty_contacts
+WHERE
+    contact_id IN (
+        SELECT
+            d.contact_id
+        FROM
+            smart_list_dirty_contacts d
+        ORDER BY
+            d.queued_at ASC
+        LIMIT
+            $1 FOR
+        UPDATE
+            SKIP LOCKED
+    )
+RETURNING
+    contact_id
+`
+
+func (q *Queries) ClaimDirtySmartListContacts(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, claimDirtySmartListContacts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var contact_id uuid.UUID
+		if err := rows.Scan(&contact_id); err != nil {
+			return nil, err
+		}
+		items = append(items, contact_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimStaleSmartList = `-- name: ClaimStaleSmartList :one
+SELECT
+    id, name, description, user_id, filter_criteria, created_at, updated_at, members_refreshed_at, organization_id
+FROM
+    smart_lists
+WHERE
+    members_refreshed_at IS NULL
+    OR members_refreshed_at < $1::timestamptz
+ORDER BY
+    members_refreshed_at ASC nulls FIRST
+LIMIT
+    1 FOR
+UPDATE
+    SKIP LOCKED
+`
+
+func (q *Queries) ClaimStaleSmartList(ctx context.Context, staleBefore time.Time) (SmartList, error) {
+	row := q.db.QueryRowContext(ctx, claimStaleSmartList, staleBefore)
+	var i SmartList
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.UserID,
+		&i.FilterCriteria,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MembersRefreshedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
 const createSmartList = `-- name: CreateSmartList :one
 INSERT INTO
     smart_lists (
         name,
         description,
-        user_id
+        user_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3)
+    ($1, $2, $3, $4)
 RETURNING
-    id, name, description, user_id, filter_criteria, created_at, updated_at
+    id, name, description, user_id, filter_criteria, created_at, updated_at, members_refreshed_at, organization_id
 `
 
 type CreateSmartListParams struct {
-	Name        string
-	Description sql.NullString
-	UserID      uuid.NullUUID
+	Name           string
+	Description    sql.NullString
+	UserID         uuid.NullUUID
+	OrganizationID uuid.NullUUID
 }
 
 func (q *Queries) CreateSmartList(ctx context.Context, arg CreateSmartListParams) (SmartList, error) {
-	row := q.db.QueryRowContext(ctx, createSmartList, arg.Name, arg.Description, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createSmartList,
+		arg.Name,
+		arg.Description,
+		arg.UserID,
+		arg.OrganizationID,
+	)
 	var i SmartList
 	err := row.Scan(
 		&i.ID,
@@ -43,21 +128,213 @@ func (q *Queries) CreateSmartList(ctx context.Context, arg CreateSmartListParams
 		&i.FilterCriteria,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MembersRefreshedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
 
 const getAllSmartLists = `-- name: GetAllSmartLists :many
 SELECT
-    id, name, description, user_id, filter_criteria, created_at, updated_at
+    s.id, s.name, s.description, s.user_id, s.filter_criteria, s.created_at, s.updated_at, s.members_refreshed_at, s.organization_id,
+    (
+        SELECT
+            count(*)
+        FROM
+            smart_list_members m
+            JOIN contacts c ON c.id = m.contact_id
+        WHERE
+            m.smart_list_id = s.id
+            AND c.deleted_at IS NULL
+            AND (
+                c.owner_id = $1
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        collaborators col
+                    WHERE
+                        col.contact_id = c.id
+                        AND col.user_id = $1
+                )
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        member admin
+                    WHERE
+                        admin."userId" = $1
+                        AND admin."organizationId" = c.organization_id
+                        AND admin.role IN ('owner', 'admin')
+                )
+            )
+    ) AS member_count,
+    EXISTS (
+        SELECT
+            1
+        FROM
+            smart_list_subscriptions sub
+        WHERE
+            sub.smart_list_id = s.id
+            AND sub.user_id = $1
+    ) AS subscribed
+FROM
+    smart_lists s
+WHERE
+    (
+        s.organization_id IS NULL
+        AND s.user_id = $1
+    )
+    OR s.organization_id = $2
+`
+
+type GetAllSmartListsParams struct {
+	UserID         uuid.NullUUID
+	OrganizationID uuid.NullUUID
+}
+
+type GetAllSmartListsRow struct {
+	ID                 uuid.UUID
+	Name               string
+	Description        sql.NullString
+	UserID             uuid.NullUUID
+	FilterCriteria     pqtype.NullRawMessage
+	CreatedAt          sql.NullTime
+	UpdatedAt          sql.NullTime
+	MembersRefreshedAt sql.NullTime
+	OrganizationID     uuid.NullUUID
+	MemberCount        int64
+	Subscribed         bool
+}
+
+// The user's personal lists and those of their active organization. A list
+// only counts the members the user can see.
+func (q *Queries) GetAllSmartLists(ctx context.Context, arg GetAllSmartListsParams) ([]GetAllSmartListsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSmartLists, arg.UserID, arg.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllSmartListsRow
+	for rows.Next() {
+		var i GetAllSmartListsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.UserID,
+			&i.FilterCriteria,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MembersRefreshedAt,
+			&i.OrganizationID,
+			&i.MemberCount,
+			&i.Subscribed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSmartListByID = `-- name: GetSmartListByID :one
+SELECT
+    id, name, description, user_id, filter_criteria, created_at, updated_at, members_refreshed_at, organization_id
 FROM
     smart_lists
 WHERE
-    user_id = $1
+    id = $1
+    AND (
+        (
+            organization_id IS NULL
+            AND user_id = $2
+        )
+        OR organization_id IN (
+            SELECT
+                "organizationId"
+            FROM
+                member
+            WHERE
+                "userId" = $2
+        )
+    )
 `
 
-func (q *Queries) GetAllSmartLists(ctx context.Context, userID uuid.NullUUID) ([]SmartList, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSmartLists, userID)
+type GetSmartListByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.NullUUID
+}
+
+// Personal lists are visible to their owner and shared lists to every
+// member of the organization
+func (q *Queries) GetSmartListByID(ctx context.Context, arg GetSmartListByIDParams) (SmartList, error) {
+	row := q.db.QueryRowContext(ctx, getSmartListByID, arg.ID, arg.UserID)
+	var i SmartList
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.UserID,
+		&i.FilterCriteria,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MembersRefreshedAt,
+		&i.OrganizationID,
+	)
+	return i, err
+}
+
+const listSmartListsForContacts = `-- name: ListSmartListsForContacts :many
+SELECT
+    s.id, s.name, s.description, s.user_id, s.filter_criteria, s.created_at, s.updated_at, s.members_refreshed_at, s.organization_id
+FROM
+    smart_lists s
+WHERE
+    s.user_id IN (
+        SELECT
+            c.owner_id
+        FROM
+            contacts c
+        WHERE
+            c.id = ANY($1::uuid[])
+        UNION
+        SELECT
+            col.user_id
+        FROM
+            collaborators col
+        WHERE
+            col.contact_id = ANY($1::uuid[])
+    )
+    OR s.organization_id IN (
+        SELECT
+            c.organization_id
+        FROM
+            contacts c
+        WHERE
+            c.id = ANY($1::uuid[])
+    )
+    OR EXISTS (
+        SELECT
+            1
+        FROM
+            smart_list_members m
+        WHERE
+            m.smart_list_id = s.id
+            AND m.contact_id = ANY($1::uuid[])
+    )
+`
+
+// Lists whose owner or organization can see one of the contacts, or that
+// currently contain one of them
+func (q *Queries) ListSmartListsForContacts(ctx context.Context, contactIds []uuid.UUID) ([]SmartList, error) {
+	rows, err := q.db.QueryContext(ctx, listSmartListsForContacts, pq.Array(contactIds))
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +350,8 @@ func (q *Queries) GetAllSmartLists(ctx context.Context, userID uuid.NullUUID) ([
 			&i.FilterCriteria,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MembersRefreshedAt,
+			&i.OrganizationID,
 		); err != nil {
 			return nil, err
 		}
@@ -87,6 +366,65 @@ func (q *Queries) GetAllSmartLists(ctx context.Context, userID uuid.NullUUID) ([
 	return items, nil
 }
 
+const notifySmartListSubscribers = `-- name: NotifySmartListSubscribers :execrows
+INSERT INTO
+    notifications (
+        user_id,
+        TYPE,
+        message,
+        contact_id
+    )
+SELECT
+    sub.user_id,
+    'smart_list_entered',
+    c.first_name || ' ' || c.last_name || ' entered smart list "' || s.name || '"',
+    c.id
+FROM
+    smart_list_subscriptions sub
+    JOIN smart_lists s ON s.id = sub.smart_list_id
+    JOIN contacts c ON c.id = ANY($1::uuid[])
+WHERE
+    sub.smart_list_id = $2
+    AND c.deleted_at IS NULL
+    AND (
+        c.owner_id = sub.user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = sub.user_id
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = sub.user_id
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
+    )
+`
+
+type NotifySmartListSubscribersParams struct {
+	ContactIds  []uuid.UUID
+	SmartListID uuid.UUID
+}
+
+// Subscribers are only told about contacts they can see: their own, ones they
+// collaborate on and, for admins, their organization's.
+func (q *Queries) NotifySmartListSubscribers(ctx context.Context, arg NotifySmartListSubscribersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, notifySmartListSubscribers, pq.Array(arg.ContactIds), arg.SmartListID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const setSmartListFilterCriteria = `-- name: SetSmartListFilterCriteria :one
 UPDATE
     smart_lists
@@ -96,7 +434,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, name, description, user_id, filter_criteria, created_at, updated_at
+    id, name, description, user_id, filter_criteria, created_at, updated_at, members_refreshed_at, organization_id
 `
 
 type SetSmartListFilterCriteriaParams struct {
@@ -115,10 +453,65 @@ func (q *Queries) SetSmartListFilterCriteria(ctx context.Context, arg SetSmartLi
 		&i.FilterCriteria,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MembersRefreshedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
 
+const setSmartListMembersRefreshed = `-- name: SetSmartListMembersRefreshed :exec
+UPDATE
+    smart_lists
+SET
+    members_refreshed_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+`
+
+func (q *Queries) SetSmartListMembersRefreshed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, setSmartListMembersRefreshed, id)
+	return err
+}
+
+const subscribeToSmartList = `-- name: SubscribeToSmartList :exec
+INSERT INTO
+    smart_list_subscriptions (smart_list_id, user_id)
+VALUES
+    ($1, $2)
+ON CONFLICT (smart_list_id, user_id) DO NOTHING
+`
+
+type SubscribeToSmartListParams struct {
+	SmartListID uuid.UUID
+	UserID      uuid.UUID
+}
+
+func (q *Queries) SubscribeToSmartList(ctx context.Context, arg SubscribeToSmartListParams) error {
+	_, err := q.db.ExecContext(ctx, subscribeToSmartList, arg.SmartListID, arg.UserID)
+	return err
+}
+
+const unsubscribeFromSmartList = `-- name: UnsubscribeFromSmartList :execrows
+DELETE FROM
+    smart_list_subscriptions
+WHERE
+    smart_list_id = $1
+    AND user_id = $2
+`
+
+type UnsubscribeFromSmartListParams struct {
+	SmartListID uuid.UUID
+	UserID      uuid.UUID
+}
+
+func (q *Queries) UnsubscribeFromSmartList(ctx context.Context, arg UnsubscribeFromSmartListParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsubscribeFromSmartList, arg.SmartListID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateSmartList = `-- name: UpdateSmartList :one
 UPDATE
     smart_lists
@@ -130,7 +523,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, name, description, user_id, filter_criteria, created_at, updated_at
+    id, name, description, user_id, filter_criteria, created_at, updated_at, members_refreshed_at, organization_id
 `
 
 type UpdateSmartListParams struct {
@@ -156,6 +549,8 @@ func (q *Queries) UpdateSmartList(ctx context.Context, arg UpdateSmartListParams
 		&i.FilterCriteria,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MembersRefreshedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
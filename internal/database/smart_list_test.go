@@ -0,0 +1,108 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestNotifySmartListSubscribersOnlyTellsWhoCanSeeTheContact(t *testing.T) {
+	q, tx := testQueries(t)
+	ctx := context.Background()
+
+	owner, admin, collaborator, member := insertUser(t, tx), insertUser(t, tx), insertUser(t, tx), insertUser(t, tx)
+	org := insertOrganization(t, tx)
+	addMember(t, tx, org, owner, "member")
+	addMember(t, tx, org, admin, "admin")
+	addMember(t, tx, org, collaborator, "member")
+	addMember(t, tx, org, member, "member")
+
+	contact := insertContact(t, tx, owner, org)
+	if _, err := tx.Exec(`INSERT INTO collaborators (contact_id, user_id, role) VALUES ($1, $2, 'viewer')`, contact, collaborator); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := q.CreateSmartList(ctx, database.CreateSmartListParams{
+		Name:           "Hot leads",
+		UserID:         uuid.NullUUID{UUID: owner, Valid: true},
+		OrganizationID: uuid.NullUUID{UUID: org, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []uuid.UUID{owner, admin, collaborator, member} {
+		if err := q.SubscribeToSmartList(ctx, database.SubscribeToSmartListParams{SmartListID: list.ID, UserID: user}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sent, err := q.NotifySmartListSubscribers(ctx, database.NotifySmartListSubscribersParams{
+		ContactIds:  []uuid.UUID{contact},
+		SmartListID: list.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 3 {
+		t.Errorf("sent %d notifications, want the owner, the admin and the collaborator", sent)
+	}
+
+	var told bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM notifications WHERE user_id = $1)`, member).Scan(&told); err != nil {
+		t.Fatal(err)
+	}
+	if told {
+		t.Error("notified a member who can't see the contact")
+	}
+}
+
+func TestGetAllSmartListsCountsOnlyMembersTheUserCanSee(t *testing.T) {
+	q, tx := testQueries(t)
+	ctx := context.Background()
+
+	owner, admin, member := insertUser(t, tx), insertUser(t, tx), insertUser(t, tx)
+	org := insertOrganization(t, tx)
+	addMember(t, tx, org, owner, "member")
+	addMember(t, tx, org, admin, "admin")
+	addMember(t, tx, org, member, "member")
+
+	list, err := q.CreateSmartList(ctx, database.CreateSmartListParams{
+		Name:           "Everyone",
+		UserID:         uuid.NullUUID{UUID: owner, Valid: true},
+		OrganizationID: uuid.NullUUID{UUID: org, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, contact := range []uuid.UUID{insertContact(t, tx, owner, org), insertContact(t, tx, owner, org), insertContact(t, tx, member, org)} {
+		if _, err := tx.Exec(`INSERT INTO smart_list_members (smart_list_id, contact_id) VALUES ($1, $2)`, list.ID, contact); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		user uuid.UUID
+		want int64
+	}{
+		{"owner of two", owner, 2},
+		{"member with one", member, 1},
+		{"admin", admin, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists, err := q.GetAllSmartLists(ctx, database.GetAllSmartListsParams{
+				UserID:         uuid.NullUUID{UUID: tt.user, Valid: true},
+				OrganizationID: uuid.NullUUID{UUID: org, Valid: true},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lists) != 1 || lists[0].MemberCount != tt.want {
+				t.Errorf("lists = %+v, want one list counting %d members", lists, tt.want)
+			}
+		})
+	}
+}
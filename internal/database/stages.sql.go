@@ -19,20 +19,22 @@ INSERT INTO
         description,
         client_type,
         order_index,
-        owner_id
+        owner_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3, $4, $5)
+    ($1, $2, $3, $4, $5, $6)
 RETURNING
-    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id
+    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id, organization_id, deleted_at
 `
 
 type CreateStageParams struct {
-	Name        string
-	Description sql.NullString
-	ClientType  ClientType
-	OrderIndex  int32
-	OwnerID     uuid.NullUUID
+	Name           string
+	Description    sql.NullString
+	ClientType     ClientType
+	OrderIndex     int32
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
 }
 
 func (q *Queries) CreateStage(ctx context.Context, arg CreateStageParams) (Stage, error) {
@@ -42,6 +44,7 @@ func (q *Queries) CreateStage(ctx context.Context, arg CreateStageParams) (Stage
 		arg.ClientType,
 		arg.OrderIndex,
 		arg.OwnerID,
+		arg.OrganizationID,
 	)
 	var i Stage
 	err := row.Scan(
@@ -55,17 +58,24 @@ func (q *Queries) CreateStage(ctx context.Context, arg CreateStageParams) (Stage
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.OwnerID,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteStage = `-- name: DeleteStage :exec
-DELETE FROM
+UPDATE
     stages
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
+// Moves the stage to the trash. Its deals keep pointing at it until it is
+// purged.
 func (q *Queries) DeleteStage(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteStage, id)
 	return err
@@ -73,18 +83,31 @@ func (q *Queries) DeleteStage(ctx context.Context, id uuid.UUID) error {
 
 const getAllStages = `-- name: GetAllStages :many
 SELECT
-    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id
+    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id, organization_id, deleted_at
 FROM
     stages
 WHERE
-    owner_id = $1
+    deleted_at IS NULL
+    AND (
+        (
+            organization_id IS NULL
+            AND owner_id = $1
+        )
+        OR organization_id = $2
+    )
 ORDER BY
     client_type ASC,
     order_index ASC
 `
 
-func (q *Queries) GetAllStages(ctx context.Context, ownerID uuid.NullUUID) ([]Stage, error) {
-	rows, err := q.db.QueryContext(ctx, getAllStages, ownerID)
+type GetAllStagesParams struct {
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
+}
+
+// The owner's personal stages and those of their active organization
+func (q *Queries) GetAllStages(ctx context.Context, arg GetAllStagesParams) ([]Stage, error) {
+	rows, err := q.db.QueryContext(ctx, getAllStages, arg.OwnerID, arg.OrganizationID)
 	if err != nil {
 		return nil, err
 	}
@@ -103,6 +126,8 @@ func (q *Queries) GetAllStages(ctx context.Context, ownerID uuid.NullUUID) ([]St
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.OwnerID,
+			&i.OrganizationID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -119,11 +144,12 @@ func (q *Queries) GetAllStages(ctx context.Context, ownerID uuid.NullUUID) ([]St
 
 const getStageByID = `-- name: GetStageByID :one
 SELECT
-    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id
+    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id, organization_id, deleted_at
 FROM
     stages
 WHERE
     id = $1
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) GetStageByID(ctx context.Context, id uuid.UUID) (Stage, error) {
@@ -140,29 +166,40 @@ func (q *Queries) GetStageByID(ctx context.Context, id uuid.UUID) (Stage, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.OwnerID,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getStagesByClientType = `-- name: GetStagesByClientType :many
 SELECT
-    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id
+    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id, organization_id, deleted_at
 FROM
     stages
 WHERE
     client_type = $1
-    AND owner_id = $2
+    AND deleted_at IS NULL
+    AND (
+        (
+            organization_id IS NULL
+            AND owner_id = $2
+        )
+        OR organization_id = $3
+    )
 ORDER BY
     order_index ASC
 `
 
 type GetStagesByClientTypeParams struct {
-	ClientType ClientType
-	OwnerID    uuid.NullUUID
+	ClientType     ClientType
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
 }
 
+// The owner's personal stages and those of their active organization
 func (q *Queries) GetStagesByClientType(ctx context.Context, arg GetStagesByClientTypeParams) ([]Stage, error) {
-	rows, err := q.db.QueryContext(ctx, getStagesByClientType, arg.ClientType, arg.OwnerID)
+	rows, err := q.db.QueryContext(ctx, getStagesByClientType, arg.ClientType, arg.OwnerID, arg.OrganizationID)
 	if err != nil {
 		return nil, err
 	}
@@ -181,6 +218,8 @@ func (q *Queries) GetStagesByClientType(ctx context.Context, arg GetStagesByClie
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.OwnerID,
+			&i.OrganizationID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -206,7 +245,7 @@ SET
 WHERE
     id = $1
 RETURNING
-    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id
+    id, name, description, client_type, number_of_deals, total_potential_income, order_index, created_at, updated_at, owner_id, organization_id, deleted_at
 `
 
 type UpdateStageParams struct {
@@ -237,6 +276,8 @@ func (q *Queries) UpdateStage(ctx context.Context, arg UpdateStageParams) (Stage
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.OwnerID,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -38,16 +38,40 @@ func (q *Queries) AssignTagToContact(ctx context.Context, arg AssignTagToContact
 const assignTagsToContact = `-- name: AssignTagsToContact :exec
 WITH input_tags AS (
     SELECT
-        unnest($2::text []) AS tag_name
+        DISTINCT unnest($1::text []) AS tag_name
+),
+organization_tags AS (
+    SELECT
+        t.id,
+        t.name
+    FROM
+        tags t
+    WHERE
+        t.organization_id = $2::uuid
+        AND t.name IN (
+            SELECT
+                tag_name
+            FROM
+                input_tags
+        )
 ),
 inserted_tags AS (
     INSERT INTO
         tags(name, user_id)
     SELECT
         tag_name,
-        $1
+        $3
     FROM
-        input_tags ON conflict ON CONSTRAINT unique_user_tag DO nothing
+        input_tags
+    WHERE
+        tag_name NOT IN (
+            SELECT
+                name
+            FROM
+                organization_tags
+        ) ON conflict (user_id, name)
+    WHERE
+        organization_id IS NULL DO nothing
     RETURNING
         id,
         name
@@ -57,38 +81,60 @@ all_tags AS (
         id,
         name
     FROM
-        inserted_tags
+        organization_tags
     UNION
     SELECT
         id,
         name
+    FROM
+        inserted_tags
+    UNION
+    SELECT
+        t.id,
+        t.name
     FROM
         tags t
     WHERE
-        t.name IN (
+        t.organization_id IS NULL
+        AND t.user_id = $3
+        AND t.name IN (
             SELECT
                 tag_name
             FROM
                 input_tags
         )
+        AND t.name NOT IN (
+            SELECT
+                name
+            FROM
+                organization_tags
+        )
 )
 INSERT INTO
     contact_tags(contact_id, tag_id)
 SELECT
-    $3,
+    $4,
     id
 FROM
     all_tags ON conflict DO nothing
 `
 
 type AssignTagsToContactParams struct {
-	UserID    uuid.NullUUID
-	Column2   []string
-	ContactID uuid.UUID
+	TagNames       []string
+	OrganizationID uuid.NullUUID
+	UserID         uuid.NullUUID
+	ContactID      uuid.UUID
 }
 
+// Tags are matched by name against the organization's tags first, then the
+// user's personal tags. Names that match neither become personal tags.
 func (q *Queries) AssignTagsToContact(ctx context.Context, arg AssignTagsToContactParams) error {
-	_, err := q.db.ExecContext(ctx, assignTagsToContact, arg.UserID, pq.Array(arg.Column2), arg.ContactID)
+	_, err := q.db.ExecContext(ctx, assignTagsToContact,
+		pq.Array(arg.TagNames),
+		arg.OrganizationID,
+		arg.UserID,
+		arg.ContactID,
+	)
 	return err
 }
 
@@ -97,22 +143,29 @@ INSERT INTO
     tags (
         name,
         description,
-        user_id
+        user_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3)
+    ($1, $2, $3, $4)
 RETURNING
-    id, name, description, user_id, created_at, updated_at
+    id, name, description, user_id, created_at, updated_at, organization_id
 `
 
 type CreateTagParams struct {
-	Name        string
-	Description sql.NullString
-	UserID      uuid.NullUUID
+	Name           string
+	Description    sql.NullString
+	UserID         uuid.NullUUID
+	OrganizationID uuid.NullUUID
 }
 
 func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
-	row := q.db.QueryRowContext(ctx, createTag, arg.Name, arg.Description, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createTag,
+		arg.Name,
+		arg.Description,
+		arg.UserID,
+		arg.OrganizationID,
+	)
 	var i Tag
 	err := row.Scan(
 		&i.ID,
@@ -121,6 +174,7 @@ func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, erro
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrganizationID,
 	)
 	return i, err
 }
@@ -131,7 +185,7 @@ DELETE FROM
 WHERE
     id = $1
 RETURNING
-    id, name, description, user_id, created_at, updated_at
+    id, name, description, user_id, created_at, updated_at, organization_id
 `
 
 func (q *Queries) DeleteTag(ctx context.Context, id uuid.UUID) error {
@@ -141,15 +195,27 @@ func (q *Queries) DeleteTag(ctx context.Context, id uuid.UUID) error {
 
 const getAllTags = `-- name: GetAllTags :many
 SELECT
-    id, name, description, user_id, created_at, updated_at
+    id, name, description, user_id, created_at, updated_at, organization_id
 FROM
     tags
 WHERE
-    user_id = $1
+    (
+        organization_id IS NULL
+        AND user_id = $1
+    )
+    OR organization_id = $2
+ORDER BY
+    name
 `
 
-func (q *Queries) GetAllTags(ctx context.Context, userID uuid.NullUUID) ([]Tag, error) {
-	rows, err := q.db.QueryContext(ctx, getAllTags, userID)
+type GetAllTagsParams struct {
+	UserID         uuid.NullUUID
+	OrganizationID uuid.NullUUID
+}
+
+// The user's personal tags and those of their active organization
+func (q *Queries) GetAllTags(ctx context.Context, arg GetAllTagsParams) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, getAllTags, arg.UserID, arg.OrganizationID)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +230,7 @@ func (q *Queries) GetAllTags(ctx context.Context, userID uuid.NullUUID) ([]Tag,
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.OrganizationID,
 		); err != nil {
 			return nil, err
 		}
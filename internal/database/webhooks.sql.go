@@ -14,18 +14,29 @@ import (
 
 const landingPageEmails = `-- name: LandingPageEmails :one
 INSERT INTO
-    contacts (first_name, last_name, source, owner_id)
+    contacts (
+        first_name,
+        last_name,
+        source,
+        owner_id,
+        organization_id,
+        city,
+        zip_code
+    )
 VALUES
-    ($1, $2, $3, $4)
+    ($1, $2, $3, $4, $5, $6, $7)
 RETURNING
-    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at
+    id, first_name, last_name, birthdate, source, status, address, city, state, zip_code, lender, price_range, timeframe, owner_id, created_at, updated_at, last_contacted_at, organization_id, deleted_at
 `
 
 type LandingPageEmailsParams struct {
-	FirstName string
-	LastName  string
-	Source    sql.NullString
-	OwnerID   uuid.NullUUID
+	FirstName      string
+	LastName       string
+	Source         sql.NullString
+	OwnerID        uuid.NullUUID
+	OrganizationID uuid.NullUUID
+	City           sql.NullString
+	ZipCode        sql.NullString
 }
 
 func (q *Queries) LandingPageEmails(ctx context.Context, arg LandingPageEmailsParams) (Contact, error) {
@@ -34,6 +45,9 @@ func (q *Queries) LandingPageEmails(ctx context.Context, arg LandingPageEmailsPa
 		arg.LastName,
 		arg.Source,
 		arg.OwnerID,
+		arg.OrganizationID,
+		arg.City,
+		arg.ZipCode,
 	)
 	var i Contact
 	err := row.Scan(
@@ -54,6 +68,8 @@ func (q *Queries) LandingPageEmails(ctx context.Context, arg LandingPageEmailsPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastContactedAt,
+		&i.OrganizationID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,186 @@
+// Package digest renders the daily agenda email: an agent's appointments and
+// tasks for the day, overdue tasks, contact birthdays and upcoming deal
+// milestones.
+//
+// Times are shown in the location of the digest's day, so callers convert
+// them to the user's time zone first.
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+	"time"
+)
+
+// MilestoneDays is how many days ahead, counting today, deal milestones are
+// listed.
+const MilestoneDays = 7
+
+// Appointment is an appointment on the day.
+type Appointment struct {
+	Title    string
+	At       time.Time
+	Location string
+}
+
+// Task is a task due on the day or overdue. Due is zero for tasks without
+// a due time.
+type Task struct {
+	Title    string
+	Due      time.Time
+	Priority string
+}
+
+// Birthday is a contact's birthday on the day. Age is zero when unknown.
+type Birthday struct {
+	Name string
+	Age  int
+}
+
+// Milestone is a key date of a deal, such as its closing or inspection.
+type Milestone struct {
+	Deal string
+	Kind string
+	At   time.Time
+}
+
+// Digest is one user's agenda for a day.
+type Digest struct {
+	Name         string
+	Day          time.Time
+	Appointments []Appointment
+	TasksDue     []Task
+	Overdue      []Task
+	Birthdays    []Birthday
+	Milestones   []Milestone
+}
+
+// Empty reports whether there is nothing on the agenda. Empty digests
+// aren't sent.
+func (d Digest) Empty() bool {
+	return len(d.Appointments) == 0 && len(d.TasksDue) == 0 && len(d.Overdue) == 0 &&
+		len(d.Birthdays) == 0 && len(d.Milestones) == 0
+}
+
+// Email is a rendered digest.
+type Email struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html_body"`
+	Text    string `json:"text_body"`
+}
+
+// Render renders the digest as an email.
+func Render(d Digest) (Email, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return Email{}, err
+	}
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return Email{}, err
+	}
+	return Email{
+		Subject: "Your agenda for " + d.Day.Format("Monday, January 2"),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+// Age returns how old someone born on birthdate turns on their birthday in
+// day's year, or zero for birthdates in that year or later.
+func Age(birthdate, day time.Time) int {
+	age := day.Year() - birthdate.Year()
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+var milestoneLabels = map[string]string{
+	"closing":           "Closing",
+	"earnest_money":     "Earnest money due",
+	"inspection":        "Inspection",
+	"appraisal":         "Appraisal",
+	"final_walkthrough": "Final walkthrough",
+	"possession":        "Possession",
+}
+
+var funcs = map[string]any{
+	"clock": func(t time.Time) string { return t.Format("3:04 PM") },
+	"date":  func(t time.Time) string { return t.Format("Mon, Jan 2") },
+	"label": func(kind string) string {
+		if label, ok := milestoneLabels[kind]; ok {
+			return label
+		}
+		return kind
+	},
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(funcs).Parse(`Good morning{{with .Name}} {{.}}{{end}},
+
+Here is your agenda for {{.Day.Format "Monday, January 2"}}.
+{{if .Appointments}}
+Appointments
+{{range .Appointments}}- {{clock .At}} {{.Title}}{{with .Location}} ({{.}}){{end}}
+{{end}}{{end}}{{if .TasksDue}}
+Tasks due today
+{{range .TasksDue}}- {{if not .Due.IsZero}}{{clock .Due}} {{end}}{{.Title}}{{with .Priority}} [{{.}}]{{end}}
+{{end}}{{end}}{{if .Overdue}}
+Overdue tasks
+{{range .Overdue}}- {{.Title}}{{if not .Due.IsZero}}, due {{date .Due}}{{end}}
+{{end}}{{end}}{{if .Birthdays}}
+Birthdays
+{{range .Birthdays}}- {{.Name}}{{if .Age}} turns {{.Age}}{{end}}
+{{end}}{{end}}{{if .Milestones}}
+Deal milestones this week
+{{range .Milestones}}- {{date .At}} {{label .Kind}}: {{.Deal}}
+{{end}}{{end}}{{if .Empty}}
+Nothing is scheduled today.
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<p>Good morning{{with .Name}} {{.}}{{end}},</p>
+<p>Here is your agenda for {{.Day.Format "Monday, January 2"}}.</p>
+{{- if .Appointments}}
+<h3>Appointments</h3>
+<ul>
+{{- range .Appointments}}
+<li><strong>{{clock .At}}</strong> {{.Title}}{{with .Location}} ({{.}}){{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .TasksDue}}
+<h3>Tasks due today</h3>
+<ul>
+{{- range .TasksDue}}
+<li>{{if not .Due.IsZero}}<strong>{{clock .Due}}</strong> {{end}}{{.Title}}{{with .Priority}} [{{.}}]{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Overdue}}
+<h3>Overdue tasks</h3>
+<ul>
+{{- range .Overdue}}
+<li>{{.Title}}{{if not .Due.IsZero}}, due {{date .Due}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Birthdays}}
+<h3>Birthdays</h3>
+<ul>
+{{- range .Birthdays}}
+<li>{{.Name}}{{if .Age}} turns {{.Age}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Milestones}}
+<h3>Deal milestones this week</h3>
+<ul>
+{{- range .Milestones}}
+<li><strong>{{date .At}}</strong> {{label .Kind}}: {{.Deal}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Empty}}
+<p>Nothing is scheduled today.</p>
+{{- end}}
+`))
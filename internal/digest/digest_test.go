@@ -0,0 +1,62 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAge(t *testing.T) {
+	day := time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)
+	if got := Age(time.Date(1992, 2, 29, 0, 0, 0, 0, time.UTC), day); got != 33 {
+		t.Errorf("Age() = %d, want 33", got)
+	}
+	if got := Age(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), day); got != 0 {
+		t.Errorf("Age() = %d, want 0", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	d := Digest{
+		Name:         "Ana",
+		Day:          day,
+		Appointments: []Appointment{{Title: "Showing <Elm St>", At: day.Add(14 * time.Hour), Location: "123 Elm St"}},
+		TasksDue:     []Task{{Title: "Call Bob", Priority: "high"}},
+		Overdue:      []Task{{Title: "Send CMA", Due: day.AddDate(0, 0, -2)}},
+		Birthdays:    []Birthday{{Name: "Carla Diaz", Age: 40}},
+		Milestones:   []Milestone{{Deal: "Elm St", Kind: "final_walkthrough", At: day.AddDate(0, 0, 2)}},
+	}
+	email, err := Render(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.Subject != "Your agenda for Monday, June 3" {
+		t.Errorf("Subject = %q", email.Subject)
+	}
+	for _, want := range []string{
+		"2:00 PM Showing <Elm St> (123 Elm St)",
+		"- Call Bob [high]",
+		"- Send CMA, due Sat, Jun 1",
+		"- Carla Diaz turns 40",
+		"- Wed, Jun 5 Final walkthrough: Elm St",
+	} {
+		if !strings.Contains(email.Text, want) {
+			t.Errorf("text body is missing %q:\n%s", want, email.Text)
+		}
+	}
+	if !strings.Contains(email.HTML, "Showing &lt;Elm St&gt;") {
+		t.Errorf("HTML body should escape titles:\n%s", email.HTML)
+	}
+	if strings.Contains(email.Text, "Nothing is scheduled") {
+		t.Error("text body says nothing is scheduled")
+	}
+
+	empty, err := Render(Digest{Day: day})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(empty.Text, "Nothing is scheduled today.") {
+		t.Errorf("empty text body:\n%s", empty.Text)
+	}
+}
@@ -0,0 +1,224 @@
+// Package duplicates groups contacts that are likely the same person.
+package duplicates
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Match reasons, in the order they are reported.
+const (
+	ReasonEmail = "email"
+	ReasonPhone = "phone"
+	ReasonName  = "name"
+)
+
+// Candidate is the subset of a contact needed to look for duplicates.
+type Candidate struct {
+	ID        uuid.UUID
+	FirstName string
+	LastName  string
+	Emails    []string
+	Phones    []string
+}
+
+// Group is a set of contacts that matched each other on one or more reasons.
+// Contacts keep the order they were passed to Find in.
+type Group struct {
+	ContactIDs []uuid.UUID
+	Reasons    []string
+}
+
+// NormalizeEmail lower-cases and trims an email address.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone keeps only the digits of a phone number and drops a leading
+// US country code, so "+1 (555) 123-4567" and "555.123.4567" compare equal.
+// Numbers too short to be meaningful normalize to "".
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) == 11 && digits[0] == '1' {
+		digits = digits[1:]
+	}
+	if len(digits) < 7 {
+		return ""
+	}
+	return digits
+}
+
+// normalizeName keeps only lower-cased letters.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// SimilarNames reports whether two people's names are close enough to flag
+// them as a possible duplicate: the last names must match and the first names
+// must match, one must be a prefix of the other ("Jon" and "Jonathan"),
+// or they must be one edit apart ("Jon" and "John").
+func SimilarNames(firstA, lastA, firstB, lastB string) bool {
+	la, lb := normalizeName(lastA), normalizeName(lastB)
+	fa, fb := normalizeName(firstA), normalizeName(firstB)
+	if la == "" || la != lb || fa == "" || fb == "" {
+		return false
+	}
+	if fa == fb {
+		return true
+	}
+	// Prefixes need a few letters so a lone initial doesn't match everyone
+	if min(len(fa), len(fb)) >= 3 && (strings.HasPrefix(fa, fb) || strings.HasPrefix(fb, fa)) {
+		return true
+	}
+	return editDistance(fa, fb) <= 1
+}
+
+// Find groups candidates that share a normalized email or phone number or have
+// similar names. Matches are transitive: if A shares an email with B and B a
+// phone with C, all three are one group.
+func Find(candidates []Candidate) []Group {
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// reasons are collected per pair and attached to the group root at the end
+	type link struct {
+		a, b   int
+		reason string
+	}
+	var links []link
+
+	linkBy := func(reason string, keysOf func(Candidate) []string) {
+		seen := map[string]int{}
+		for i, c := range candidates {
+			for _, key := range keysOf(c) {
+				if key == "" {
+					continue
+				}
+				if j, ok := seen[key]; ok && j != i {
+					links = append(links, link{a: j, b: i, reason: reason})
+					continue
+				}
+				seen[key] = i
+			}
+		}
+	}
+
+	linkBy(ReasonEmail, func(c Candidate) []string {
+		keys := make([]string, len(c.Emails))
+		for i, e := range c.Emails {
+			keys[i] = NormalizeEmail(e)
+		}
+		return keys
+	})
+	linkBy(ReasonPhone, func(c Candidate) []string {
+		keys := make([]string, len(c.Phones))
+		for i, p := range c.Phones {
+			keys[i] = NormalizePhone(p)
+		}
+		return keys
+	})
+
+	// Names are only compared within the same last name to avoid comparing
+	// every pair of contacts.
+	byLastName := map[string][]int{}
+	for i, c := range candidates {
+		if key := normalizeName(c.LastName); key != "" {
+			byLastName[key] = append(byLastName[key], i)
+		}
+	}
+	for _, idx := range byLastName {
+		for x := 0; x < len(idx); x++ {
+			for y := x + 1; y < len(idx); y++ {
+				a, b := candidates[idx[x]], candidates[idx[y]]
+				if SimilarNames(a.FirstName, a.LastName, b.FirstName, b.LastName) {
+					links = append(links, link{a: idx[x], b: idx[y], reason: ReasonName})
+				}
+			}
+		}
+	}
+
+	for _, l := range links {
+		if ra, rb := find(l.a), find(l.b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	reasons := map[int]map[string]bool{}
+	for _, l := range links {
+		root := find(l.a)
+		if reasons[root] == nil {
+			reasons[root] = map[string]bool{}
+		}
+		reasons[root][l.reason] = true
+	}
+
+	members := map[int][]uuid.UUID{}
+	var roots []int
+	for i, c := range candidates {
+		root := find(i)
+		if _, ok := reasons[root]; !ok {
+			continue
+		}
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], c.ID)
+	}
+	sort.Ints(roots)
+
+	groups := make([]Group, 0, len(roots))
+	for _, root := range roots {
+		g := Group{ContactIDs: members[root]}
+		for _, reason := range []string{ReasonEmail, ReasonPhone, ReasonName} {
+			if reasons[root][reason] {
+				g.Reasons = append(g.Reasons, reason)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
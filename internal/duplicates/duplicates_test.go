@@ -0,0 +1,65 @@
+package duplicates
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"+1 (555) 123-4567": "5551234567",
+		"555.123.4567":      "5551234567",
+		"15551234567":       "5551234567",
+		"123":               "",
+	}
+	for input, want := range tests {
+		if got := NormalizePhone(input); got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSimilarNames(t *testing.T) {
+	tests := []struct {
+		a, b [2]string
+		want bool
+	}{
+		{[2]string{"Jon", "Smith"}, [2]string{"John", "Smith"}, true},
+		{[2]string{"Jon", "Smith"}, [2]string{"Jonathan", "smith"}, true},
+		{[2]string{"J", "Smith"}, [2]string{"Jane", "Smith"}, false},
+		{[2]string{"Jane", "Smith"}, [2]string{"Jane", "Smyth"}, false},
+		{[2]string{"Jane", "Smith"}, [2]string{"Mary", "Smith"}, false},
+	}
+	for _, tt := range tests {
+		if got := SimilarNames(tt.a[0], tt.a[1], tt.b[0], tt.b[1]); got != tt.want {
+			t.Errorf("SimilarNames(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	candidates := []Candidate{
+		{ID: ids[0], FirstName: "Jane", LastName: "Doe", Emails: []string{"Jane@Example.com"}},
+		{ID: ids[1], FirstName: "J.", LastName: "Roe", Emails: []string{"jane@example.com "}, Phones: []string{"555-123-4567"}},
+		{ID: ids[2], FirstName: "Unknown", LastName: "Lead", Phones: []string{"+1 555 123 4567"}},
+		{ID: ids[3], FirstName: "Bob", LastName: "Stone"},
+		{ID: ids[4], FirstName: "Robert", LastName: "Stone"},
+	}
+
+	groups := Find(candidates)
+	if len(groups) != 1 {
+		t.Fatalf("Find() returned %d groups, want 1: %+v", len(groups), groups)
+	}
+	g := groups[0]
+	if len(g.ContactIDs) != 3 || g.ContactIDs[0] != ids[0] || g.ContactIDs[2] != ids[2] {
+		t.Errorf("group contacts = %v", g.ContactIDs)
+	}
+	if len(g.Reasons) != 2 || g.Reasons[0] != ReasonEmail || g.Reasons[1] != ReasonPhone {
+		t.Errorf("group reasons = %v", g.Reasons)
+	}
+}
@@ -0,0 +1,33 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter writes the header row immediately and one row per record.
+func NewCSVWriter(w io.Writer) (Writer, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(rec Record) error {
+	if err := c.w.Write(rec.values()); err != nil {
+		return err
+	}
+	// Flush per record so rows reach the client as they are produced
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,165 @@
+// Package exporter writes contacts out as CSV, XLSX or vCard one record at a
+// time, so an export can be streamed straight into an HTTP response.
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Phone struct {
+	Number    string `json:"phone_number"`
+	Type      string `json:"type"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type Email struct {
+	Address   string `json:"email_address"`
+	Type      string `json:"type"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type Record struct {
+	FirstName  string
+	LastName   string
+	Birthdate  time.Time
+	Source     string
+	Status     string
+	Address    string
+	City       string
+	State      string
+	ZipCode    string
+	Lender     string
+	PriceRange string
+	Timeframe  string
+	CreatedAt  time.Time
+	Phones     []Phone
+	Emails     []Email
+	Tags       []string
+}
+
+// Writer writes records in one export format. Close must be called once all
+// records are written to flush any trailing data.
+type Writer interface {
+	Write(Record) error
+	Close() error
+}
+
+// Format describes an export format's HTTP content type and file extension.
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+}
+
+var formats = map[string]Format{
+	"csv":  {Name: "csv", ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	"xlsx": {Name: "xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: "xlsx"},
+	"vcf":  {Name: "vcf", ContentType: "text/vcard; charset=utf-8", Extension: "vcf"},
+}
+
+// LookupFormat returns the format for a name such as "csv", "xlsx" or "vcf"
+// ("vcard" is accepted as an alias).
+func LookupFormat(name string) (Format, error) {
+	name = strings.ToLower(name)
+	if name == "vcard" {
+		name = "vcf"
+	}
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unsupported export format %q, use csv, xlsx or vcf", name)
+	}
+	return f, nil
+}
+
+// NewWriter returns a Writer for format. version is only used by vCard
+// exports and must be "3.0" or "4.0".
+func NewWriter(w io.Writer, format Format, version string) (Writer, error) {
+	switch format.Name {
+	case "csv":
+		return NewCSVWriter(w)
+	case "xlsx":
+		return NewXLSXWriter(w)
+	case "vcf":
+		return NewVCardWriter(w, version)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format.Name)
+	}
+}
+
+// columns are shared by the CSV and XLSX exports. The names match what the
+// CSV importer maps automatically, so an export can be imported again.
+var columns = []string{
+	"first_name",
+	"last_name",
+	"email",
+	"phone",
+	"emails",
+	"phone_numbers",
+	"birthdate",
+	"source",
+	"status",
+	"address",
+	"city",
+	"state",
+	"zip_code",
+	"lender",
+	"price_range",
+	"timeframe",
+	"tags",
+	"created_at",
+}
+
+func (rec Record) values() []string {
+	var email, phone string
+	emails := make([]string, len(rec.Emails))
+	for i, e := range rec.Emails {
+		emails[i] = e.Address
+		if e.IsPrimary && email == "" {
+			email = e.Address
+		}
+	}
+	if email == "" && len(emails) > 0 {
+		email = emails[0]
+	}
+	phones := make([]string, len(rec.Phones))
+	for i, p := range rec.Phones {
+		phones[i] = p.Number
+		if p.IsPrimary && phone == "" {
+			phone = p.Number
+		}
+	}
+	if phone == "" && len(phones) > 0 {
+		phone = phones[0]
+	}
+
+	return []string{
+		rec.FirstName,
+		rec.LastName,
+		email,
+		phone,
+		strings.Join(emails, "; "),
+		strings.Join(phones, "; "),
+		formatDate(rec.Birthdate, "2006-01-02"),
+		rec.Source,
+		rec.Status,
+		rec.Address,
+		rec.City,
+		rec.State,
+		rec.ZipCode,
+		rec.Lender,
+		rec.PriceRange,
+		rec.Timeframe,
+		strings.Join(rec.Tags, "; "),
+		formatDate(rec.CreatedAt, time.RFC3339),
+	}
+}
+
+func formatDate(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,134 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/importer"
+)
+
+var testRecord = Record{
+	FirstName: "Jane",
+	LastName:  "Doe",
+	Birthdate: time.Date(1985, 4, 15, 0, 0, 0, 0, time.UTC),
+	Address:   "123 Main St, Apt 4",
+	City:      "Denver",
+	State:     "CO",
+	ZipCode:   "80202",
+	Phones: []Phone{
+		{Number: "555-123-4567", Type: "mobile"},
+		{Number: "555-765-4321", Type: "work", IsPrimary: true},
+	},
+	Emails: []Email{{Address: "jane@example.com", Type: "home", IsPrimary: true}},
+	Tags:   []string{"buyer", "hot; lead"},
+}
+
+func export(t *testing.T, format, version string) []byte {
+	t.Helper()
+	f, err := LookupFormat(format)
+	if err != nil {
+		t.Fatalf("LookupFormat(%q) error = %v", format, err)
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, f, version)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.Write(testRecord); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	rows, err := importer.ParseCSV(bytes.NewReader(export(t, "csv", "")), nil)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("ParseCSV() rows = %+v", rows)
+	}
+	c := rows[0].Contact
+	if c.FirstName != "Jane" || c.Address != "123 Main St, Apt 4" || !c.Birthdate.Equal(testRecord.Birthdate) {
+		t.Errorf("contact = %+v", c)
+	}
+	if len(c.Phones) != 1 || c.Phones[0].Number != "555-765-4321" {
+		t.Errorf("primary phone = %+v", c.Phones)
+	}
+}
+
+func TestVCardRoundTrip(t *testing.T) {
+	for _, version := range []string{"3.0", "4.0"} {
+		out := export(t, "vcard", version)
+		if !strings.Contains(string(out), "VERSION:"+version+"\r\n") {
+			t.Errorf("%s: missing VERSION line in %q", version, out)
+		}
+
+		rows, err := importer.ParseVCard(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("%s: ParseVCard() error = %v", version, err)
+		}
+		if len(rows) != 1 || rows[0].Err != nil {
+			t.Fatalf("%s: ParseVCard() rows = %+v", version, rows)
+		}
+		c := rows[0].Contact
+		if c.LastName != "Doe" || c.City != "Denver" || !c.Birthdate.Equal(testRecord.Birthdate) {
+			t.Errorf("%s: contact = %+v", version, c)
+		}
+		if len(c.Phones) != 2 || c.Phones[0].Type != "mobile" || !c.Phones[1].IsPrimary {
+			t.Errorf("%s: phones = %+v", version, c.Phones)
+		}
+		if len(c.Tags) != 2 || c.Tags[1] != "hot; lead" {
+			t.Errorf("%s: tags = %q", version, c.Tags)
+		}
+	}
+
+	if _, err := NewVCardWriter(io.Discard, "2.1"); err == nil {
+		t.Errorf("NewVCardWriter() with version 2.1 should fail")
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	out := export(t, "xlsx", "")
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("open sheet: %v", err)
+			}
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			sheet = string(b)
+		}
+	}
+	if len(zr.File) != 5 || sheet == "" {
+		t.Fatalf("workbook has %d parts, sheet = %q", len(zr.File), sheet)
+	}
+	for _, want := range []string{`<c r="A1" t="inlineStr"><is><t xml:space="preserve">first_name</t>`, `<c r="A2"`, "jane@example.com", "hot; lead"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet is missing %q", want)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for i, want := range tests {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type vcardWriter struct {
+	w       *bufio.Writer
+	version string
+}
+
+// NewVCardWriter writes one card per record in vCard 3.0 (RFC 2426) or 4.0
+// (RFC 6350).
+func NewVCardWriter(w io.Writer, version string) (Writer, error) {
+	if version == "" {
+		version = "3.0"
+	}
+	if version != "3.0" && version != "4.0" {
+		return nil, fmt.Errorf("unsupported vCard version %q, use 3.0 or 4.0", version)
+	}
+	return &vcardWriter{w: bufio.NewWriter(w), version: version}, nil
+}
+
+func (v *vcardWriter) Write(rec Record) error {
+	v3 := v.version == "3.0"
+
+	v.line("BEGIN:VCARD")
+	v.line("VERSION:" + v.version)
+	v.line("N:" + escapeValue(rec.LastName) + ";" + escapeValue(rec.FirstName) + ";;;")
+	v.line("FN:" + escapeValue(strings.TrimSpace(rec.FirstName+" "+rec.LastName)))
+
+	for _, e := range rec.Emails {
+		v.line("EMAIL" + typeParams(v3, e.Type, e.IsPrimary, "INTERNET") + ":" + escapeValue(e.Address))
+	}
+	for _, p := range rec.Phones {
+		phoneType := p.Type
+		if phoneType == "mobile" {
+			phoneType = "cell"
+		}
+		v.line("TEL" + typeParams(v3, phoneType, p.IsPrimary, "") + ":" + escapeValue(p.Number))
+	}
+	if rec.Address != "" || rec.City != "" || rec.State != "" || rec.ZipCode != "" {
+		adr := []string{"", "", escapeValue(rec.Address), escapeValue(rec.City), escapeValue(rec.State), escapeValue(rec.ZipCode), ""}
+		v.line("ADR" + typeParams(v3, "home", false, "") + ":" + strings.Join(adr, ";"))
+	}
+	if !rec.Birthdate.IsZero() {
+		// 4.0 only allows the basic ISO 8601 date format
+		if v3 {
+			v.line("BDAY:" + rec.Birthdate.Format("2006-01-02"))
+		} else {
+			v.line("BDAY:" + rec.Birthdate.Format("20060102"))
+		}
+	}
+	if len(rec.Tags) > 0 {
+		tags := make([]string, len(rec.Tags))
+		for i, t := range rec.Tags {
+			tags[i] = escapeValue(t)
+		}
+		v.line("CATEGORIES:" + strings.Join(tags, ","))
+	}
+	v.line("END:VCARD")
+
+	return v.w.Flush()
+}
+
+func (v *vcardWriter) Close() error {
+	return v.w.Flush()
+}
+
+// line writes a content line folded at 75 octets, without splitting a UTF-8
+// sequence, as both RFCs require.
+func (v *vcardWriter) line(s string) {
+	const limit = 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		v.w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+	}
+	v.w.WriteString(s + "\r\n")
+}
+
+// typeParams builds the TYPE and preference parameters. 3.0 uses upper-case
+// TYPE values with PREF as a type; 4.0 uses lower-case values and PREF=1.
+func typeParams(v3 bool, kind string, preferred bool, extra string) string {
+	var types []string
+	if v3 && extra != "" {
+		types = append(types, extra)
+	}
+	if kind != "" {
+		types = append(types, kind)
+	}
+	if v3 && preferred {
+		types = append(types, "pref")
+	}
+
+	params := ""
+	if len(types) > 0 {
+		joined := strings.Join(types, ",")
+		if v3 {
+			joined = strings.ToUpper(joined)
+		} else {
+			joined = strings.ToLower(joined)
+		}
+		params = ";TYPE=" + joined
+	}
+	if !v3 && preferred {
+		params += ";PREF=1"
+	}
+	return params
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeValue(s string) string {
+	return vcardEscaper.Replace(s)
+}
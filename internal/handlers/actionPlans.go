@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/actionplan"
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// pauseContacted is why a plan paused when its contact was reached.
+const pauseContacted = "contacted"
+
+var errActionPlanActive = errors.New("action plan is already active for the contact")
+
+// --------------------------------------------------------------
+// Plans
+// --------------------------------------------------------------
+
+type actionPlanStepRequest struct {
+	Title        string `json:"title"`
+	TaskType     string `json:"task_type"`
+	OffsetDays   int    `json:"offset_days"`
+	Priority     string `json:"priority"`
+	NoteTemplate string `json:"note_template"`
+}
+
+type actionPlanRequest struct {
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description"`
+	Scope        string                  `json:"scope"`
+	TriggerTagID string                  `json:"trigger_tag_id"`
+	ApplyToLeads bool                    `json:"apply_to_leads"`
+	LeadSources  []string                `json:"lead_sources"`
+	Steps        []actionPlanStepRequest `json:"steps"`
+
+	triggerTag uuid.NullUUID
+}
+
+type actionPlanResponse struct {
+	database.ActionPlan
+	Steps []database.ActionPlanStep
+}
+
+// validate checks the request and fills in defaults. It writes the error
+// response and returns false when the plan can't be saved.
+func (req *actionPlanRequest) validate(w http.ResponseWriter) bool {
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required", nil)
+		return false
+	}
+	if len(req.Steps) == 0 {
+		respondWithError(w, http.StatusBadRequest, "An action plan needs at least one step", nil)
+		return false
+	}
+	if req.TriggerTagID != "" {
+		tagUUID, err := uuid.Parse(req.TriggerTagID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid trigger_tag_id", err)
+			return false
+		}
+		req.triggerTag = uuid.NullUUID{UUID: tagUUID, Valid: true}
+	}
+	for i, step := range req.Steps {
+		if step.Title == "" {
+			respondWithError(w, http.StatusBadRequest, "Every step needs a title", nil)
+			return false
+		}
+		if step.OffsetDays < 0 {
+			respondWithError(w, http.StatusBadRequest, "Step offsets can't be negative", nil)
+			return false
+		}
+		if err := actionplan.Validate(step.NoteTemplate); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid note template: "+err.Error(), err)
+			return false
+		}
+		if step.TaskType == "" {
+			req.Steps[i].TaskType = string(database.TaskTypeFollowUp)
+		}
+		if step.Priority == "" {
+			req.Steps[i].Priority = string(database.TaskPriorityNormal)
+		}
+	}
+	if req.LeadSources == nil {
+		req.LeadSources = []string{}
+	}
+	return true
+}
+
+// setActionPlanSteps replaces the steps of a plan. Tasks already created
+// from the old steps are kept.
+func setActionPlanSteps(ctx context.Context, qtx *database.Queries, planID uuid.UUID, steps []actionPlanStepRequest) error {
+	if err := qtx.DeleteActionPlanSteps(ctx, planID); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		err := qtx.CreateActionPlanStep(ctx, database.CreateActionPlanStepParams{
+			ActionPlanID: planID,
+			Position:     int32(i),
+			Title:        step.Title,
+			TaskType:     database.TaskType(step.TaskType),
+			OffsetDays:   int32(step.OffsetDays),
+			Priority:     database.TaskPriority(step.Priority),
+			NoteTemplate: step.NoteTemplate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// respondWithActionPlan responds with the plan and its steps as saved.
+func (cfg *apiCfg) respondWithActionPlan(w http.ResponseWriter, r *http.Request, code int, plan database.ActionPlan) {
+	steps, err := cfg.DB.ListActionPlanSteps(r.Context(), []uuid.UUID{plan.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve action plan steps", err)
+		return
+	}
+	if steps == nil {
+		steps = []database.ActionPlanStep{}
+	}
+	respondWithJSON(w, code, actionPlanResponse{ActionPlan: plan, Steps: steps})
+}
+
+func (cfg *apiCfg) GetActionPlans(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	orgID, _ := GetActiveOrganization(r.Context())
+	plans, err := cfg.DB.ListActionPlans(r.Context(), database.ListActionPlansParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		OrganizationID: orgID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve action plans", err)
+		return
+	}
+
+	planIDs := make([]uuid.UUID, 0, len(plans))
+	for _, plan := range plans {
+		planIDs = append(planIDs, plan.ID)
+	}
+	steps, err := cfg.DB.ListActionPlanSteps(r.Context(), planIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve action plan steps", err)
+		return
+	}
+
+	byPlan := map[uuid.UUID][]database.ActionPlanStep{}
+	for _, step := range steps {
+		byPlan[step.ActionPlanID] = append(byPlan[step.ActionPlanID], step)
+	}
+
+	response := make([]actionPlanResponse, 0, len(plans))
+	for _, plan := range plans {
+		planSteps := byPlan[plan.ID]
+		if planSteps == nil {
+			planSteps = []database.ActionPlanStep{}
+		}
+		response = append(response, actionPlanResponse{ActionPlan: plan, Steps: planSteps})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiCfg) GetActionPlan(w http.ResponseWriter, r *http.Request) {
+	planUUID, err := GetUUIDFromUrl("actionPlanID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid action plan ID", err)
+		return
+	}
+
+	plan, err := cfg.DB.GetActionPlanByID(r.Context(), planUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Action plan not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve action plan", err)
+		return
+	}
+
+	cfg.respondWithActionPlan(w, r, http.StatusOK, plan)
+}
+
+// CreateActionPlan saves a plan template. Steps are applied in the order
+// given, each due offset_days after the plan is applied.
+func (cfg *apiCfg) CreateActionPlan(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req actionPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	orgID, ok := definitionScope(w, r, req.Scope)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	plan, err := qtx.CreateActionPlan(r.Context(), database.CreateActionPlanParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Description:    sql.NullString{String: req.Description, Valid: req.Description != ""},
+		TriggerTagID:   req.triggerTag,
+		ApplyToLeads:   req.ApplyToLeads,
+		LeadSources:    req.LeadSources,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create action plan", err)
+		return
+	}
+
+	if err := setActionPlanSteps(r.Context(), qtx, plan.ID, req.Steps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save action plan steps", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindActionPlan, plan.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithActionPlan(w, r, http.StatusCreated, plan)
+}
+
+// UpdateActionPlan replaces a plan and its steps. Contacts the plan was
+// already applied to keep the tasks they got.
+func (cfg *apiCfg) UpdateActionPlan(w http.ResponseWriter, r *http.Request) {
+	planUUID, err := GetUUIDFromUrl("actionPlanID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid action plan ID", err)
+		return
+	}
+
+	var req actionPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindActionPlan, planUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	plan, err := qtx.UpdateActionPlan(r.Context(), database.UpdateActionPlanParams{
+		ID:           planUUID,
+		Name:         req.Name,
+		Description:  sql.NullString{String: req.Description, Valid: req.Description != ""},
+		TriggerTagID: req.triggerTag,
+		ApplyToLeads: req.ApplyToLeads,
+		LeadSources:  req.LeadSources,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Action plan not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update action plan", err)
+		return
+	}
+
+	if err := setActionPlanSteps(r.Context(), qtx, plan.ID, req.Steps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save action plan steps", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindActionPlan, planUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithActionPlan(w, r, http.StatusOK, plan)
+}
+
+func (cfg *apiCfg) DeleteActionPlan(w http.ResponseWriter, r *http.Request) {
+	planUUID, err := GetUUIDFromUrl("actionPlanID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid action plan ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindActionPlan, planUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	if err := qtx.DeleteActionPlan(r.Context(), planUUID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete action plan", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindActionPlan, planUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// --------------------------------------------------------------
+// Contacts
+// --------------------------------------------------------------
+
+// applyActionPlan starts a plan for a contact, adding a task for each of its
+// steps. The tasks go to the contact's owner, or to the user applying the
+// plan when the contact has none, and keep the start's time of day in the
+// assignee's time zone. It returns errActionPlanActive when the plan is
+// already running for the contact.
+func (cfg *apiCfg) applyActionPlan(ctx context.Context, q *database.Queries, planID, contactID uuid.UUID, start time.Time) (database.ContactActionPlan, []database.Task, error) {
+	contact, err := q.GetActionPlanContact(ctx, contactID)
+	if err != nil {
+		return database.ContactActionPlan{}, nil, err
+	}
+
+	var appliedBy uuid.NullUUID
+	if userUUID, err := GetUserUUID(ctx); err == nil {
+		appliedBy = uuid.NullUUID{UUID: userUUID, Valid: true}
+	}
+	assignee := contact.OwnerID
+	if !assignee.Valid {
+		assignee = appliedBy
+	}
+	loc, err := cfg.assigneeLocation(ctx, assignee)
+	if err != nil {
+		return database.ContactActionPlan{}, nil, err
+	}
+	start = start.In(loc)
+
+	applied, err := q.CreateContactActionPlan(ctx, database.CreateContactActionPlanParams{
+		ContactID:    contactID,
+		ActionPlanID: planID,
+		StartedAt:    start,
+		AppliedBy:    appliedBy,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.ContactActionPlan{}, nil, errActionPlanActive
+	}
+	if err != nil {
+		return database.ContactActionPlan{}, nil, err
+	}
+
+	steps, err := q.ListActionPlanSteps(ctx, []uuid.UUID{planID})
+	if err != nil {
+		return database.ContactActionPlan{}, nil, err
+	}
+
+	values := map[string]string{
+		"first_name":  contact.FirstName,
+		"last_name":   contact.LastName,
+		"source":      contact.Source.String,
+		"city":        contact.City.String,
+		"zip_code":    contact.ZipCode.String,
+		"price_range": contact.PriceRange.String,
+		"timeframe":   contact.Timeframe.String,
+	}
+
+	tasks := make([]database.Task, 0, len(steps))
+	for _, step := range steps {
+		note := actionplan.Render(step.NoteTemplate, values)
+		task, err := q.CreateActionPlanTask(ctx, database.CreateActionPlanTaskParams{
+			ContactID:           uuid.NullUUID{UUID: contactID, Valid: true},
+			AssignedToID:        assignee,
+			Title:               step.Title,
+			Type:                database.NullTaskType{TaskType: step.TaskType, Valid: true},
+			Date:                sql.NullTime{Time: actionplan.Due(start, int(step.OffsetDays)), Valid: true},
+			Priority:            database.NullTaskPriority{TaskPriority: step.Priority, Valid: true},
+			Note:                sql.NullString{String: note, Valid: note != ""},
+			ContactActionPlanID: uuid.NullUUID{UUID: applied.ID, Valid: true},
+		})
+		if err != nil {
+			return database.ContactActionPlan{}, nil, err
+		}
+		if err := cfg.recordAudit(ctx, q, authz.KindTask, task.ID, audit.Create, nil); err != nil {
+			return database.ContactActionPlan{}, nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return applied, tasks, nil
+}
+
+// applyTriggeredActionPlans applies the plans triggered by a change to a
+// contact, starting now. Plans already running for the contact are left as
+// they are.
+func (cfg *apiCfg) applyTriggeredActionPlans(ctx context.Context, q *database.Queries, plans []database.ActionPlan, contactID uuid.UUID) error {
+	for _, plan := range plans {
+		_, _, err := cfg.applyActionPlan(ctx, q, plan.ID, contactID, time.Now())
+		if err != nil && !errors.Is(err, errActionPlanActive) {
+			return err
+		}
+	}
+	return nil
+}
+
+// pauseActionPlans pauses the plans running for a contact, cancelling their
+// pending tasks. Completed tasks are kept.
+func (cfg *apiCfg) pauseActionPlans(ctx context.Context, q *database.Queries, contactID uuid.UUID, reason string) error {
+	tasks, err := q.ListActiveActionPlanTasks(ctx, contactID)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		before, err := cfg.auditSnapshot(ctx, q, authz.KindTask, task.ID)
+		if err != nil {
+			return err
+		}
+		_, err = q.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
+			ID:     task.ID,
+			Status: database.NullTaskStatus{TaskStatus: database.TaskStatusCancelled, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if err := cfg.recordAudit(ctx, q, authz.KindTask, task.ID, audit.Update, before); err != nil {
+			return err
+		}
+	}
+
+	return q.PauseContactActionPlans(ctx, database.PauseContactActionPlansParams{
+		ContactID:   contactID,
+		PauseReason: sql.NullString{String: reason, Valid: true},
+	})
+}
+
+// ApplyActionPlan starts a plan for a contact, creating its steps as tasks.
+// The plan starts now unless start_date is given, in the caller's time zone.
+func (cfg *apiCfg) ApplyActionPlan(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		ActionPlanID string `json:"action_plan_id"`
+		StartDate    string `json:"start_date"`
+	}
+	type response struct {
+		database.ContactActionPlan
+		Tasks []database.Task
+	}
+
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	planUUID, err := uuid.Parse(req.ActionPlanID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid action_plan_id", err)
+		return
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+	loc, err := cfg.userLocation(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	start := time.Now()
+	if req.StartDate != "" {
+		start, err = time.ParseInLocation("2006-01-02T15:04", req.StartDate, loc)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid date format", err)
+			return
+		}
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	applied, tasks, err := cfg.applyActionPlan(r.Context(), cfg.DB.WithTx(tx), planUUID, contactUUID, start)
+	if errors.Is(err, errActionPlanActive) {
+		respondWithError(w, http.StatusConflict, "Action plan is already active for this contact", err)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Contact not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to apply action plan", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{ContactActionPlan: applied, Tasks: tasks})
+}
+
+// GetContactActionPlans lists the plans applied to a contact, newest first.
+func (cfg *apiCfg) GetContactActionPlans(w http.ResponseWriter, r *http.Request) {
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	plans, err := cfg.DB.ListContactActionPlans(r.Context(), contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve action plans", err)
+		return
+	}
+	if plans == nil {
+		plans = []database.ListContactActionPlansRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, plans)
+}
@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
 	"github.com/google/uuid"
 )
 
@@ -41,14 +44,29 @@ func (cfg *apiCfg) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse ScheduledAt to time.Time
-	scheduledAt, err := time.Parse("2006-01-02T15:04", req.ScheduledAt)
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	// Parse ScheduledAt to time.Time, in the user's time zone
+	scheduledAt, err := time.ParseInLocation("2006-01-02T15:04", req.ScheduledAt, loc)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid scheduled at format", err)
 		return
 	}
 
-	appointment, err := cfg.DB.CreateAppointment(r.Context(), database.CreateAppointmentParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	appointment, err := qtx.CreateAppointment(r.Context(), database.CreateAppointmentParams{
 		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
 		ContactID:    uuid.NullUUID{UUID: contactID, Valid: true},
 		Title:        req.Title,
@@ -62,6 +80,15 @@ func (cfg *apiCfg) CreateAppointment(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create appointment", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindAppointment, appointment.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, appointment)
 }
@@ -112,7 +139,21 @@ func (cfg *apiCfg) UpdateAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	appointment, err := cfg.DB.UpdateAppointment(r.Context(), database.UpdateAppointmentParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindAppointment, appointmentUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	appointment, err := qtx.UpdateAppointment(r.Context(), database.UpdateAppointmentParams{
 		ID:          appointmentUUID,
 		ContactID:   uuid.NullUUID{UUID: uuid.MustParse(req.ContactID), Valid: req.ContactID != ""},
 		Title:       req.Title,
@@ -126,6 +167,15 @@ func (cfg *apiCfg) UpdateAppointment(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update appointment", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindAppointment, appointmentUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, appointment)
 }
@@ -137,11 +187,34 @@ func (cfg *apiCfg) DeleteAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeleteAppointment(r.Context(), appointmentUUID)
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindAppointment, appointmentUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteAppointment(r.Context(), appointmentUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete appointment", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindAppointment, appointmentUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -152,7 +225,18 @@ func (cfg *apiCfg) ListAppointmentsToday(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Invalid assigned to ID", err)
 		return
 	}
-	appointments, err := cfg.DB.ListTodaysAppointments(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	today := timezone.Day(time.Now(), loc)
+	appointments, err := cfg.DB.ListTodaysAppointments(r.Context(), database.ListTodaysAppointmentsParams{
+		DayStart:     today.Start,
+		DayEnd:       today.End,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to list today's appointments", err)
 		return
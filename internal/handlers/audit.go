@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// auditSnapshot returns the record as the audit log compares it, or nil when
+// it doesn't exist.
+func (cfg *apiCfg) auditSnapshot(ctx context.Context, q *database.Queries, kind authz.Kind, id uuid.UUID) (json.RawMessage, error) {
+	snapshot, err := q.GetAuditSnapshot(ctx, database.GetAuditSnapshotParams{Kind: string(kind), ID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s %s for audit: %w", kind, id, err)
+	}
+	return snapshot, nil
+}
+
+// recordAudit writes the audit event for a change the caller made to a
+// record. before is the snapshot taken ahead of the change, nil for creates.
+// The record is read again through q for the after side, so handlers run
+// the change and recordAudit in one transaction and fail the request when
+// it returns an error, leaving no change unrecorded. Updates that changed
+// nothing aren't recorded.
+func (cfg *apiCfg) recordAudit(ctx context.Context, q *database.Queries, kind authz.Kind, id uuid.UUID, action audit.Action, before json.RawMessage) error {
+	after, err := cfg.auditSnapshot(ctx, q, kind, id)
+	if err != nil {
+		return err
+	}
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return fmt.Errorf("diff %s %s for audit: %w", kind, id, err)
+	}
+	if action == audit.Update && len(changes) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("encode audit changes: %w", err)
+	}
+
+	var actor uuid.NullUUID
+	if userUUID, err := GetUserUUID(ctx); err == nil {
+		actor = uuid.NullUUID{UUID: userUUID, Valid: true}
+	}
+	method, _ := ctx.Value(authMethodKey).(string)
+	requestID, _ := ctx.Value(requestIDKey).(string)
+
+	err = q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		ActorID:    actor,
+		AuthMethod: method,
+		EntityType: string(kind),
+		EntityID:   id,
+		Action:     string(action),
+		Changes:    data,
+		RequestID:  sql.NullString{String: requestID, Valid: requestID != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEvents lists audit events filtered by entity_type and entity_id,
+// actor_id and a from/until time range (RFC 3339), newest first. Callers see
+// their own changes and those of members of organizations they administer,
+// plus every change to a record they filter on and may view.
+func (cfg *apiCfg) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	query := r.URL.Query()
+	params := database.ListAuditEventsParams{
+		UserID:    uuid.NullUUID{UUID: userUUID, Valid: true},
+		RowLimit:  defaultAuditPageSize,
+		RowOffset: 0,
+	}
+
+	if v := query.Get("entity_type"); v != "" {
+		params.EntityType = sql.NullString{String: v, Valid: true}
+	}
+	for name, dst := range map[string]*uuid.NullUUID{"entity_id": &params.EntityID, "actor_id": &params.ActorID} {
+		if v := query.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid "+name, err)
+				return
+			}
+			*dst = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+	for name, dst := range map[string]*sql.NullTime{"from": &params.FromAt, "until": &params.UntilAt} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid "+name+" time, use RFC 3339", err)
+				return
+			}
+			*dst = sql.NullTime{Time: t, Valid: true}
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditPageSize {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit parameter", err)
+			return
+		}
+		params.RowLimit = int32(limit)
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid offset parameter", err)
+			return
+		}
+		params.RowOffset = int32(offset)
+	}
+
+	// A record's full history is open to anyone who may view it. Records
+	// that are gone or hidden fall back to the caller's own scope.
+	if params.EntityType.Valid && params.EntityID.Valid {
+		err := cfg.authz.Check(r.Context(), userUUID, authz.Kind(params.EntityType.String), params.EntityID.UUID, authz.View)
+		switch {
+		case err == nil:
+			params.EntityVisible = true
+		case !errors.Is(err, authz.ErrNotFound) && !errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+	}
+
+	events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list audit events", err)
+		return
+	}
+
+	type auditEvent struct {
+		ID         uuid.UUID       `json:"id"`
+		ActorID    uuid.NullUUID   `json:"actor_id"`
+		AuthMethod string          `json:"auth_method"`
+		EntityType string          `json:"entity_type"`
+		EntityID   uuid.UUID       `json:"entity_id"`
+		Action     string          `json:"action"`
+		Changes    json.RawMessage `json:"changes"`
+		RequestID  string          `json:"request_id"`
+		CreatedAt  time.Time       `json:"created_at"`
+	}
+	response := make([]auditEvent, 0, len(events))
+	for _, e := range events {
+		response = append(response, auditEvent{
+			ID:         e.ID,
+			ActorID:    e.ActorID,
+			AuthMethod: e.AuthMethod,
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			Action:     e.Action,
+			Changes:    e.Changes,
+			RequestID:  e.RequestID.String,
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -35,6 +39,12 @@ func (cfg *apiCfg) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate role
+	if !authz.ValidRole(request.Role) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid role %q. Use one of: %s", request.Role, roleNames()), nil)
+		return
+	}
+
 	// Start DB transaction
 	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
 	if err != nil {
@@ -45,7 +55,12 @@ func (cfg *apiCfg) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 
 	qtx := cfg.DB.WithTx(tx)
 
-	// Add collaborator to database
+	// Add collaborator to database, or change their role if they already are one
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
 	err = qtx.AddCollaborator(r.Context(), database.AddCollaboratorParams{
 		ContactID: contactUUID,
 		UserID:    userUUID,
@@ -55,6 +70,10 @@ func (cfg *apiCfg) AddCollaborator(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to add collaborator", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contactUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
 
 	// Notify the collaborator
 	_, err = qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
@@ -92,8 +111,22 @@ func (cfg *apiCfg) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
 	// Remove collaborator from database
-	err = cfg.DB.RemoveCollaborator(r.Context(), database.RemoveCollaboratorParams{
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.RemoveCollaborator(r.Context(), database.RemoveCollaboratorParams{
 		UserID:    collaboratorUUID,
 		ContactID: contactUUID,
 	})
@@ -101,6 +134,62 @@ func (cfg *apiCfg) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to remove collaborator", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contactUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
+
+func (cfg *apiCfg) ListCollaborators(w http.ResponseWriter, r *http.Request) {
+	type collaborator struct {
+		ID          uuid.UUID
+		Name        string
+		Role        string
+		Permissions []string
+	}
+
+	// Get contact ID from URL parameters
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListCollaborators(r.Context(), contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list collaborators", err)
+		return
+	}
+
+	collaborators := make([]collaborator, 0, len(rows))
+	for _, row := range rows {
+		permissions := []string{}
+		for _, action := range authz.Role(row.Role).Permissions() {
+			permissions = append(permissions, action.String())
+		}
+		collaborators = append(collaborators, collaborator{
+			ID:          row.ID,
+			Name:        row.Name,
+			Role:        row.Role,
+			Permissions: permissions,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, collaborators)
+}
+
+// roleNames lists the valid collaborator roles for error messages.
+func roleNames() string {
+	names := make([]string, len(authz.Roles))
+	for i, role := range authz.Roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ", ")
+}
@@ -18,6 +18,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
@@ -40,9 +42,12 @@ type apiCfg struct {
 	betterAuthSecret string
 	BaseURL          string
 	FromEmail        string
+	phoneRegion      string
+	trashRetention   time.Duration
+	authz            *authz.Authorizer
 }
 
-func New(port, JWTSecret string, db *database.Queries, dbSQL *sql.DB, dev bool, logger *slog.Logger, s3Client *s3.Client, s3Bucket string, s3Region string, postmarkClient *postmark.Client, emailSecret []byte, betterAuthSecret string, baseURL string, fromEmail string) *apiCfg {
+func New(port, JWTSecret string, db *database.Queries, dbSQL *sql.DB, dev bool, logger *slog.Logger, s3Client *s3.Client, s3Bucket string, s3Region string, postmarkClient *postmark.Client, emailSecret []byte, betterAuthSecret string, baseURL string, fromEmail string, phoneRegion string, trashRetention time.Duration) *apiCfg {
 	return &apiCfg{
 		Port:             port,
 		JWTSecret:        JWTSecret,
@@ -58,6 +63,9 @@ func New(port, JWTSecret string, db *database.Queries, dbSQL *sql.DB, dev bool,
 		betterAuthSecret: betterAuthSecret,
 		BaseURL:          baseURL,
 		FromEmail:        fromEmail,
+		phoneRegion:      phoneRegion,
+		trashRetention:   trashRetention,
+		authz:            authz.New(authz.NewStore(db)),
 	}
 }
 
@@ -103,7 +111,18 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 // key type for context
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey       contextKey = "userID"
+	organizationKey contextKey = "organization"
+	authMethodKey   contextKey = "authMethod"
+)
+
+// activeOrganization is the organization selected in the caller's session and
+// their role in it.
+type activeOrganization struct {
+	ID   uuid.UUID
+	Role string
+}
 
 func VerifySignedCookie(cookieValue, secret string) (string, error) {
 	parts := strings.Split(cookieValue, ".")
@@ -193,6 +212,7 @@ func (cfg *apiCfg) AuthMiddleware() func(http.Handler) http.Handler {
 
 				// Add userID to request context
 				ctx := context.WithValue(r.Context(), userIDKey, dbKey.UserId.String())
+				ctx = context.WithValue(ctx, authMethodKey, audit.AuthAPIKey)
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
@@ -228,13 +248,64 @@ func (cfg *apiCfg) AuthMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Add userID to request context
+			// Add userID and the active organization to request context. The
+			// join only finds the organization while the user is a member.
 			ctx := context.WithValue(r.Context(), userIDKey, dbToken.UserId.String())
+			ctx = context.WithValue(ctx, authMethodKey, audit.AuthSession)
+			if dbToken.ActiveOrganizationID.Valid {
+				ctx = context.WithValue(ctx, organizationKey, activeOrganization{
+					ID:   dbToken.ActiveOrganizationID.UUID,
+					Role: dbToken.OrganizationRole.String,
+				})
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// --------------------------------------------------------------
+// Record authorization
+// --------------------------------------------------------------
+
+// Authorize wraps the handler registered for pattern with the record checks
+// listed for it in authz.Routes. Every route must be listed there, so a new
+// route can't be served without someone deciding who may call it.
+func (cfg *apiCfg) Authorize(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	rules, ok := authz.Routes[pattern]
+	if !ok {
+		panic(fmt.Sprintf("no authorization rules for route %q", pattern))
+	}
+	if len(rules) == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userUUID, err := GetUserUUID(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+			return
+		}
+
+		err = cfg.authz.Authorize(r, userUUID, rules)
+		switch {
+		case errors.Is(err, authz.ErrInvalidID):
+			respondWithError(w, http.StatusBadRequest, "Missing or invalid record ID", err)
+			return
+		case errors.Is(err, authz.ErrNotFound):
+			respondWithError(w, http.StatusNotFound, "Not found", nil)
+			return
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "You don't have permission to do that", nil)
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
 // --------------------------------------------------------------
 // Logger middleware
 // --------------------------------------------------------------
@@ -260,6 +331,12 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing and per-request deadlines.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (cfg *apiCfg) LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -317,3 +394,14 @@ func GetUserUUID(ctx context.Context) (uuid.UUID, error) {
 
 	return userUUID, nil
 }
+
+// GetActiveOrganization returns the organization selected in the caller's
+// session and their role in it. The ID isn't valid for API keys and for
+// sessions without an active organization.
+func GetActiveOrganization(ctx context.Context) (uuid.NullUUID, string) {
+	org, ok := ctx.Value(organizationKey).(activeOrganization)
+	if !ok {
+		return uuid.NullUUID{}, ""
+	}
+	return uuid.NullUUID{UUID: org.ID, Valid: true}, org.Role
+}
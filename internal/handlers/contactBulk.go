@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	bulkBatchSize   = 100
+	maxBulkContacts = 10000
+)
+
+// Bulk actions and the permission each needs on every contact it touches.
+var bulkActions = map[string]authz.Action{
+	"add_tags":         authz.Edit,
+	"remove_tags":      authz.Edit,
+	"set_fields":       authz.Edit,
+	"add_collaborator": authz.ManageCollaborators,
+	"create_task":      authz.Edit,
+	"delete":           authz.Manage,
+}
+
+type bulkContactsRequest struct {
+	ContactIDs  []uuid.UUID   `json:"contact_ids"`
+	SmartListID uuid.NullUUID `json:"smart_list_id"`
+	Action      string        `json:"action"`
+	Tags        []string      `json:"tags"`
+	Fields      struct {
+		Status    string `json:"status"`
+		Source    string `json:"source"`
+		Timeframe string `json:"timeframe"`
+	} `json:"fields"`
+	Collaborator struct {
+		UserID uuid.UUID `json:"user_id"`
+		Role   string    `json:"role"`
+	} `json:"collaborator"`
+	Task struct {
+		Title    string `json:"title"`
+		Type     string `json:"type"`
+		Date     string `json:"date"`
+		Priority string `json:"priority"`
+		Note     string `json:"note"`
+	} `json:"task"`
+
+	taskDate time.Time
+}
+
+type bulkFailure struct {
+	ContactID uuid.UUID `json:"contact_id"`
+	Error     string    `json:"error"`
+}
+
+type bulkContactsResult struct {
+	Action    string        `json:"action"`
+	Requested int           `json:"requested"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Failures  []bulkFailure `json:"failures"`
+}
+
+func (res *bulkContactsResult) fail(contactID uuid.UUID, message string) {
+	res.Failed++
+	res.Failures = append(res.Failures, bulkFailure{ContactID: contactID, Error: message})
+}
+
+// validate checks the options of the request's action, reading dates in
+// loc. It writes the error response and returns false when the action can't
+// run.
+func (req *bulkContactsRequest) validate(w http.ResponseWriter, loc *time.Location) bool {
+	if _, ok := bulkActions[req.Action]; !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid action. Use add_tags, remove_tags, set_fields, add_collaborator, create_task or delete", nil)
+		return false
+	}
+	if (len(req.ContactIDs) > 0) == req.SmartListID.Valid {
+		respondWithError(w, http.StatusBadRequest, "Provide either contact_ids or smart_list_id", nil)
+		return false
+	}
+
+	switch req.Action {
+	case "add_tags", "remove_tags":
+		if len(req.Tags) == 0 {
+			respondWithError(w, http.StatusBadRequest, "tags is required", nil)
+			return false
+		}
+	case "set_fields":
+		if req.Fields.Status == "" && req.Fields.Source == "" && req.Fields.Timeframe == "" {
+			respondWithError(w, http.StatusBadRequest, "Set at least one of status, source or timeframe", nil)
+			return false
+		}
+	case "add_collaborator":
+		if req.Collaborator.UserID == uuid.Nil {
+			respondWithError(w, http.StatusBadRequest, "collaborator.user_id is required", nil)
+			return false
+		}
+		if !authz.ValidRole(req.Collaborator.Role) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid role %q. Use one of: %s", req.Collaborator.Role, roleNames()), nil)
+			return false
+		}
+	case "create_task":
+		if req.Task.Title == "" {
+			respondWithError(w, http.StatusBadRequest, "task.title is required", nil)
+			return false
+		}
+		if req.Task.Date != "" {
+			date, err := time.ParseInLocation("2006-01-02T15:04", req.Task.Date, loc)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid date format", err)
+				return false
+			}
+			req.taskDate = date
+		}
+	}
+	return true
+}
+
+// BulkUpdateContacts applies one action to a selection of contacts: the
+// contacts in contact_ids, or the members of smart_list_id as its filter
+// matches them now. Contacts are processed in batches of bulkBatchSize, each
+// in its own transaction; a contact that fails, or that the caller isn't
+// allowed to change, is reported in failures without affecting the others.
+func (cfg *apiCfg) BulkUpdateContacts(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	var req bulkContactsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	loc, err := cfg.userLocation(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+	if !req.validate(w, loc) {
+		return
+	}
+
+	contactIDs, ok := cfg.bulkSelection(w, r, userUUID, req)
+	if !ok {
+		return
+	}
+	if len(contactIDs) > maxBulkContacts {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Select at most %d contacts", maxBulkContacts), nil)
+		return
+	}
+
+	result := bulkContactsResult{Action: req.Action, Requested: len(contactIDs), Failures: []bulkFailure{}}
+	for start := 0; start < len(contactIDs); start += bulkBatchSize {
+		batch := contactIDs[start:min(start+bulkBatchSize, len(contactIDs))]
+		if err := cfg.bulkBatch(r.Context(), userUUID, req, batch, &result); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update contacts", err)
+			return
+		}
+	}
+
+	// One notification for the whole selection rather than one per contact
+	if req.Action == "add_collaborator" && result.Succeeded > 0 {
+		message := fmt.Sprintf("You have been added as a collaborator on %d contacts.", result.Succeeded)
+		var contactID uuid.NullUUID
+		if result.Succeeded == 1 {
+			message = "You have been added as a collaborator."
+			for _, id := range contactIDs {
+				if !result.hasFailed(id) {
+					contactID = uuid.NullUUID{UUID: id, Valid: true}
+				}
+			}
+		}
+		_, err = cfg.DB.CreateNotification(r.Context(), database.CreateNotificationParams{
+			UserID:    req.Collaborator.UserID,
+			Type:      "collaborator_added",
+			Message:   message,
+			ContactID: contactID,
+		})
+		if err != nil {
+			cfg.logger.Error("Failed to create notification", "error", err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (res *bulkContactsResult) hasFailed(contactID uuid.UUID) bool {
+	for _, f := range res.Failures {
+		if f.ContactID == contactID {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkSelection resolves the contacts a bulk request targets, without
+// duplicates. It writes the error response and returns false on failure.
+func (cfg *apiCfg) bulkSelection(w http.ResponseWriter, r *http.Request, userUUID uuid.UUID, req bulkContactsRequest) ([]uuid.UUID, bool) {
+	if !req.SmartListID.Valid {
+		seen := map[uuid.UUID]bool{}
+		var ids []uuid.UUID
+		for _, id := range req.ContactIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, true
+	}
+
+	list, err := cfg.DB.GetSmartListByID(r.Context(), database.GetSmartListByIDParams{
+		ID:     req.SmartListID.UUID,
+		UserID: uuid.NullUUID{UUID: userUUID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Smart list not found", err)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get smart list", err)
+		return nil, false
+	}
+
+	// Re-evaluate the filter so the action sees the list as it is now, not
+	// as the worker last left it
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return nil, false
+	}
+	defer tx.Rollback()
+
+	if err := cfg.refreshSmartListMembers(r.Context(), tx, list, nil, false); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh smart list", err)
+		return nil, false
+	}
+	ids, err := cfg.DB.WithTx(tx).ListSmartListMemberIDs(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list smart list members", err)
+		return nil, false
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return nil, false
+	}
+	return ids, true
+}
+
+// bulkBatch applies the action to one batch of contacts in a transaction.
+// Each contact runs under its own savepoint so its failure only rolls back
+// its own changes. The returned error is for failures of the batch itself.
+func (cfg *apiCfg) bulkBatch(ctx context.Context, userUUID uuid.UUID, req bulkContactsRequest, batch []uuid.UUID, result *bulkContactsResult) error {
+	action := bulkActions[req.Action]
+
+	var allowed []uuid.UUID
+	for _, id := range batch {
+		err := cfg.authz.Check(ctx, userUUID, authz.KindContact, id, action)
+		switch {
+		case errors.Is(err, authz.ErrNotFound):
+			result.fail(id, "contact not found")
+		case errors.Is(err, authz.ErrForbidden):
+			result.fail(id, "you don't have permission to do that")
+		case err != nil:
+			return err
+		default:
+			allowed = append(allowed, id)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	succeeded := 0
+	for _, id := range allowed {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_contact"); err != nil {
+			return err
+		}
+
+		if err := cfg.applyAuditedBulkAction(ctx, qtx, userUUID, req, id); err != nil {
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_contact"); rbErr != nil {
+				return rbErr
+			}
+			cfg.logger.Warn("Bulk action failed for contact", "action", req.Action, "contact_id", id, "error", err)
+			result.fail(id, err.Error())
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_contact"); err != nil {
+			return err
+		}
+		succeeded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	result.Succeeded += succeeded
+	return nil
+}
+
+// applyAuditedBulkAction applies the action to one contact and records the
+// change in the audit log.
+func (cfg *apiCfg) applyAuditedBulkAction(ctx context.Context, qtx *database.Queries, userUUID uuid.UUID, req bulkContactsRequest, contactID uuid.UUID) error {
+	before, err := cfg.auditSnapshot(ctx, qtx, authz.KindContact, contactID)
+	if err != nil {
+		return err
+	}
+	if err := cfg.applyBulkAction(ctx, qtx, userUUID, req, contactID); err != nil {
+		return err
+	}
+
+	action := audit.Update
+	if req.Action == "delete" {
+		action = audit.Delete
+	}
+	return cfg.recordAudit(ctx, qtx, authz.KindContact, contactID, action, before)
+}
+
+func (cfg *apiCfg) applyBulkAction(ctx context.Context, qtx *database.Queries, userUUID uuid.UUID, req bulkContactsRequest, contactID uuid.UUID) error {
+	switch req.Action {
+	case "add_tags":
+		// Tags are looked up among the contact's organization's tags. The
+		// caller picked them from their active organization's, so contacts
+		// in any other organization are refused
+		orgID, err := qtx.GetContactOrganization(ctx, contactID)
+		if err != nil {
+			return err
+		}
+		if activeOrgID, _ := GetActiveOrganization(ctx); orgID != activeOrgID {
+			return errors.New("contact belongs to another organization")
+		}
+		return qtx.AssignTagsToContact(ctx, database.AssignTagsToContactParams{
+			TagNames:       req.Tags,
+			OrganizationID: orgID,
+			UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+			ContactID:      contactID,
+		})
+	case "remove_tags":
+		return qtx.RemoveTagsFromContact(ctx, database.RemoveTagsFromContactParams{
+			ContactID: contactID,
+			TagNames:  req.Tags,
+		})
+	case "set_fields":
+		before, err := cfg.auditSnapshot(ctx, qtx, authz.KindContact, contactID)
+		if err != nil {
+			return err
+		}
+		err = qtx.SetContactFields(ctx, database.SetContactFieldsParams{
+			Status:    sql.NullString{String: req.Fields.Status, Valid: req.Fields.Status != ""},
+			Source:    sql.NullString{String: req.Fields.Source, Valid: req.Fields.Source != ""},
+			Timeframe: sql.NullString{String: req.Fields.Timeframe, Valid: req.Fields.Timeframe != ""},
+			ID:        contactID,
+		})
+		if err != nil {
+			return err
+		}
+		return cfg.recordFieldHistory(ctx, qtx, authz.KindContact, contactID, before)
+	case "add_collaborator":
+		return qtx.AddCollaborator(ctx, database.AddCollaboratorParams{
+			ContactID: contactID,
+			UserID:    req.Collaborator.UserID,
+			Role:      req.Collaborator.Role,
+		})
+	case "create_task":
+		task, err := qtx.CreateTask(ctx, database.CreateTaskParams{
+			ContactID:    uuid.NullUUID{UUID: contactID, Valid: true},
+			AssignedToID: uuid.NullUUID{UUID: userUUID, Valid: true},
+			Title:        req.Task.Title,
+			Type:         database.NullTaskType{TaskType: database.TaskType(req.Task.Type), Valid: req.Task.Type != ""},
+			Date:         sql.NullTime{Time: req.taskDate, Valid: req.Task.Date != ""},
+			Priority:     database.NullTaskPriority{TaskPriority: database.TaskPriority(req.Task.Priority), Valid: req.Task.Priority != ""},
+			Note:         sql.NullString{String: req.Task.Note, Valid: req.Task.Note != ""},
+		})
+		if err != nil {
+			return err
+		}
+		return cfg.recordAudit(ctx, qtx, authz.KindTask, task.ID, audit.Create, nil)
+	case "delete":
+		return qtx.SoftDeleteContact(ctx, contactID)
+	}
+	return fmt.Errorf("unknown action %q", req.Action)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// stubContactAccess gives the caller access to each contact: "owner",
+// "org_admin", a collaborator role, or nothing for contacts missing from
+// access.
+func stubContactAccess(db *fakeDB, access map[uuid.UUID]string) {
+	db.stub("GetContactAccess", func(args []any) (any, error) {
+		user, contact := args[0].(uuid.UUID), args[1].(uuid.UUID)
+		switch relation, ok := access[contact]; {
+		case !ok:
+			return nil, nil
+		case relation == "owner":
+			return database.GetContactAccessRow{OwnerID: uuid.NullUUID{UUID: user, Valid: true}}, nil
+		case relation == "org_admin":
+			return database.GetContactAccessRow{OwnerID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, OrgAdmin: true}, nil
+		default:
+			return database.GetContactAccessRow{
+				OwnerID:          uuid.NullUUID{UUID: uuid.New(), Valid: true},
+				CollaboratorRole: sql.NullString{String: relation, Valid: true},
+			}, nil
+		}
+	})
+}
+
+func bulkUpdate(t *testing.T, cfg *apiCfg, body map[string]any) bulkContactsResult {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/contacts/bulk", strings.NewReader(string(data)))
+	w := httptest.NewRecorder()
+	cfg.BulkUpdateContacts(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var result bulkContactsResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBulkUpdateContactsChecksEveryContact(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.stub("GetUserTimeZone", func(args []any) (any, error) {
+		return "America/Chicago", nil
+	})
+
+	owned, editing, viewing, hidden, broken := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stubContactAccess(db, map[uuid.UUID]string{
+		owned:   "owner",
+		editing: "editor",
+		viewing: "viewer",
+		broken:  "owner",
+	})
+	db.stub("GetContactOrganization", func(args []any) (any, error) {
+		return uuid.NullUUID{}, nil
+	})
+	db.stub("AssignTagsToContact", func(args []any) (any, error) {
+		if args[3] == broken {
+			return nil, errors.New("tag name too long")
+		}
+		return nil, nil
+	})
+
+	result := bulkUpdate(t, cfg, map[string]any{
+		"action":      "add_tags",
+		"tags":        []string{"Buyer"},
+		"contact_ids": []uuid.UUID{owned, editing, viewing, hidden, broken, owned},
+	})
+
+	if result.Requested != 5 || result.Succeeded != 2 || result.Failed != 3 {
+		t.Errorf("requested %d, succeeded %d, failed %d, want 5, 2 and 3", result.Requested, result.Succeeded, result.Failed)
+	}
+	failures := map[uuid.UUID]string{}
+	for _, f := range result.Failures {
+		failures[f.ContactID] = f.Error
+	}
+	if got := failures[viewing]; got != "you don't have permission to do that" {
+		t.Errorf("viewer's contact failed with %q, want a permission error", got)
+	}
+	if got := failures[hidden]; got != "contact not found" {
+		t.Errorf("hidden contact failed with %q, want not found", got)
+	}
+	if got := failures[broken]; got == "" {
+		t.Error("contact whose update failed isn't reported")
+	}
+
+	var tagged []uuid.UUID
+	for _, args := range db.callsTo("AssignTagsToContact") {
+		tagged = append(tagged, args[3].(uuid.UUID))
+	}
+	if want := []uuid.UUID{owned, editing, broken}; !slices.Equal(tagged, want) {
+		t.Errorf("tagged %v, want only the contacts the caller may edit %v", tagged, want)
+	}
+	if got := len(db.callsTo("ROLLBACK TO SAVEPOINT bulk_contact")); got != 1 {
+		t.Errorf("rolled back %d contacts, want only the broken one", got)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
+
+func TestBulkAddTagsResolvesTagsInTheContactsOrganization(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.stub("GetUserTimeZone", func(args []any) (any, error) {
+		return "America/Chicago", nil
+	})
+
+	org := uuid.New()
+	inOrg, elsewhere, personal := uuid.New(), uuid.New(), uuid.New()
+	stubContactAccess(db, map[uuid.UUID]string{inOrg: "owner", elsewhere: "owner", personal: "owner"})
+	db.stub("GetContactOrganization", func(args []any) (any, error) {
+		switch args[0] {
+		case inOrg:
+			return uuid.NullUUID{UUID: org, Valid: true}, nil
+		case elsewhere:
+			return uuid.NullUUID{UUID: uuid.New(), Valid: true}, nil
+		}
+		return uuid.NullUUID{}, nil
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"action":      "add_tags",
+		"tags":        []string{"Buyer"},
+		"contact_ids": []uuid.UUID{inOrg, elsewhere, personal},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/contacts/bulk", strings.NewReader(string(data)))
+	w := httptest.NewRecorder()
+	cfg.BulkUpdateContacts(w, inOrganization(asUser(r, uuid.New()), org, "member"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var result bulkContactsResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 1 || result.Failed != 2 {
+		t.Errorf("succeeded %d, failed %d, want 1 and 2: %+v", result.Succeeded, result.Failed, result.Failures)
+	}
+	for _, f := range result.Failures {
+		if f.ContactID == inOrg || f.Error != "contact belongs to another organization" {
+			t.Errorf("contact %v failed with %q, want only contacts outside the active organization rejected", f.ContactID, f.Error)
+		}
+	}
+
+	tagged := db.callsTo("AssignTagsToContact")
+	if len(tagged) != 1 || tagged[0][1] != (uuid.NullUUID{UUID: org, Valid: true}) || tagged[0][3] != inOrg {
+		t.Errorf("tagged with %v, want the organization's contact with its organization's tags", tagged)
+	}
+}
+
+func TestBulkUpdateContactsNeedsEachActionsPermission(t *testing.T) {
+	tests := []struct {
+		action   string
+		relation string
+		allowed  bool
+	}{
+		{"set_fields", "viewer", false},
+		{"set_fields", "editor", true},
+		{"add_collaborator", "editor", false},
+		{"add_collaborator", "manager", true},
+		{"delete", "manager", false},
+		{"delete", "org_admin", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action+" as "+tt.relation, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			db.stub("GetUserTimeZone", func(args []any) (any, error) {
+				return "America/Chicago", nil
+			})
+			contact := uuid.New()
+			stubContactAccess(db, map[uuid.UUID]string{contact: tt.relation})
+
+			result := bulkUpdate(t, cfg, map[string]any{
+				"action":       tt.action,
+				"contact_ids":  []uuid.UUID{contact},
+				"fields":       map[string]string{"status": "Hot"},
+				"collaborator": map[string]any{"user_id": uuid.New(), "role": "viewer"},
+			})
+
+			if succeeded := result.Succeeded == 1; succeeded != tt.allowed {
+				t.Errorf("succeeded = %v, want %v: %+v", succeeded, tt.allowed, result.Failures)
+			}
+			if began := len(db.callsTo("BEGIN")) > 0; began != tt.allowed {
+				t.Errorf("started a transaction = %v, want %v", began, tt.allowed)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -33,7 +35,16 @@ func (cfg *apiCfg) LogContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log, err := cfg.DB.LogContact(r.Context(), database.LogContactParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	log, err := qtx.LogContact(r.Context(), database.LogContactParams{
 		ContactID:     uuid.NullUUID{UUID: contactUUID, Valid: true},
 		ContactMethod: req.ContactMethod,
 		CreatedBy:     uuid.NullUUID{UUID: createdBy, Valid: createdBy != uuid.Nil},
@@ -43,6 +54,23 @@ func (cfg *apiCfg) LogContact(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create contact log", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContactLog, log.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Reaching the contact, or logging their reply, ends the drip of
+	// action plan tasks
+	err = cfg.pauseActionPlans(r.Context(), qtx, contactUUID, pauseContacted)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to pause action plans", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, log)
 }
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/importer"
+	"github.com/DiegoGarciaCo/CRM/internal/phone"
+	"github.com/DiegoGarciaCo/CRM/internal/search"
 	"github.com/google/uuid"
 )
 
@@ -58,6 +66,16 @@ func (cfg *apiCfg) CreateContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Normalize phone numbers before writing anything
+	phones := make([]phone.Number, len(newContact.PhoneNumbers))
+	for i, p := range newContact.PhoneNumbers {
+		phones[i], err = cfg.parsePhone(p.Number)
+		if err != nil {
+			respondWithPhoneError(w, p.Number, err)
+			return
+		}
+	}
+
 	// Start DB transaction to create contact
 	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
 	if err != nil {
@@ -68,34 +86,42 @@ func (cfg *apiCfg) CreateContact(w http.ResponseWriter, r *http.Request) {
 
 	qtx := cfg.DB.WithTx(tx)
 
+	// Contacts belong to the organization active in the session, if any
+	orgID, _ := GetActiveOrganization(r.Context())
+
 	// Create contact
 	contact, err := qtx.CreateContact(r.Context(), database.CreateContactParams{
-		FirstName:  newContact.FirstName,
-		LastName:   newContact.LastName,
-		Birthdate:  sql.NullTime{Time: parsedDate, Valid: newContact.Birthdate != ""},
-		Source:     sql.NullString{String: newContact.Source, Valid: newContact.Source != ""},
-		Status:     sql.NullString{String: newContact.Status, Valid: newContact.Status != ""},
-		Address:    sql.NullString{String: newContact.Address, Valid: newContact.Address != ""},
-		City:       sql.NullString{String: newContact.City, Valid: newContact.City != ""},
-		State:      sql.NullString{String: newContact.State, Valid: newContact.State != ""},
-		ZipCode:    sql.NullString{String: newContact.Zipcode, Valid: newContact.Zipcode != ""},
-		Lender:     sql.NullString{String: newContact.Lender, Valid: newContact.Lender != ""},
-		PriceRange: sql.NullString{String: newContact.PriceRange, Valid: newContact.PriceRange != ""},
-		Timeframe:  sql.NullString{String: newContact.Timeframe, Valid: newContact.Timeframe != ""},
-		OwnerID:    uuid.NullUUID{UUID: ownerUUID, Valid: ownerUUID != uuid.Nil},
+		FirstName:      newContact.FirstName,
+		LastName:       newContact.LastName,
+		Birthdate:      sql.NullTime{Time: parsedDate, Valid: newContact.Birthdate != ""},
+		Source:         sql.NullString{String: newContact.Source, Valid: newContact.Source != ""},
+		Status:         sql.NullString{String: newContact.Status, Valid: newContact.Status != ""},
+		Address:        sql.NullString{String: newContact.Address, Valid: newContact.Address != ""},
+		City:           sql.NullString{String: newContact.City, Valid: newContact.City != ""},
+		State:          sql.NullString{String: newContact.State, Valid: newContact.State != ""},
+		ZipCode:        sql.NullString{String: newContact.Zipcode, Valid: newContact.Zipcode != ""},
+		Lender:         sql.NullString{String: newContact.Lender, Valid: newContact.Lender != ""},
+		PriceRange:     sql.NullString{String: newContact.PriceRange, Valid: newContact.PriceRange != ""},
+		Timeframe:      sql.NullString{String: newContact.Timeframe, Valid: newContact.Timeframe != ""},
+		OwnerID:        uuid.NullUUID{UUID: ownerUUID, Valid: ownerUUID != uuid.Nil},
+		OrganizationID: orgID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create contact", err)
 		return
 	}
 
-	// Insert phone numbers
-	for _, phone := range newContact.PhoneNumbers {
-		err = qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
+	// Insert phone numbers. Only the first one flagged primary stays primary.
+	hasPrimary := false
+	for i, p := range newContact.PhoneNumbers {
+		isPrimary := p.IsPrimary && !hasPrimary
+		hasPrimary = hasPrimary || isPrimary
+		_, err = qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
 			ContactID:   uuid.NullUUID{UUID: contact.ID, Valid: contact.ID != uuid.Nil},
-			PhoneNumber: phone.Number,
-			Type:        sql.NullString{String: phone.Type, Valid: phone.Type != ""},
-			IsPrimary:   sql.NullBool{Bool: phone.IsPrimary, Valid: true},
+			PhoneNumber: phones[i].Display,
+			E164:        sql.NullString{String: phones[i].E164, Valid: true},
+			Type:        sql.NullString{String: p.Type, Valid: p.Type != ""},
+			IsPrimary:   sql.NullBool{Bool: isPrimary, Valid: true},
 		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to add phone number", err)
@@ -104,12 +130,15 @@ func (cfg *apiCfg) CreateContact(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Insert emails
+	hasPrimary = false
 	for _, email := range newContact.Emails {
-		err = qtx.EnterEmail(r.Context(), database.EnterEmailParams{
+		isPrimary := email.IsPrimary && !hasPrimary
+		hasPrimary = hasPrimary || isPrimary
+		_, err = qtx.EnterEmail(r.Context(), database.EnterEmailParams{
 			ContactID:    uuid.NullUUID{UUID: contact.ID, Valid: contact.ID != uuid.Nil},
 			EmailAddress: email.Email,
 			Type:         sql.NullString{String: email.Type, Valid: email.Type != ""},
-			IsPrimary:    sql.NullBool{Bool: email.IsPrimary, Valid: true},
+			IsPrimary:    sql.NullBool{Bool: isPrimary, Valid: true},
 		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to add email", err)
@@ -117,6 +146,22 @@ func (cfg *apiCfg) CreateContact(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Make the first email and phone number primary when none was flagged
+	contactID := uuid.NullUUID{UUID: contact.ID, Valid: true}
+	if err := qtx.PromotePrimaryPhoneNumber(r.Context(), contactID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to set primary phone number", err)
+		return
+	}
+	if err := qtx.PromotePrimaryEmail(r.Context(), contactID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to set primary email", err)
+		return
+	}
+
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contact.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
@@ -181,6 +226,13 @@ func (cfg *apiCfg) GetAllContacts(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, contacts)
 }
 
+// ContactSearchResult is a matching contact with the field that matched best
+// and an HTML snippet with the match wrapped in <mark>.
+type ContactSearchResult struct {
+	database.SearchContactsRow
+	Snippet string
+}
+
 func (cfg *apiCfg) SearchContacts(w http.ResponseWriter, r *http.Request) {
 	ownerUUID, err := GetUserUUID(r.Context())
 	if err != nil {
@@ -189,22 +241,44 @@ func (cfg *apiCfg) SearchContacts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get search query from URL
-	query := r.URL.Query().Get("q")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	if query == "" {
 		respondWithError(w, http.StatusBadRequest, "Search query cannot be empty", nil)
 		return
 	}
 
-	contacts, err := cfg.DB.SearchContacts(r.Context(), database.SearchContactsParams{
-		OwnerID:   uuid.NullUUID{UUID: ownerUUID, Valid: true},
-		FirstName: query,
+	// Get query parameters from url for pagination (limit and offset)
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	rows, err := cfg.DB.SearchContacts(r.Context(), database.SearchContactsParams{
+		UserID:        uuid.NullUUID{UUID: ownerUUID, Valid: true},
+		Query:         query,
+		Pattern:       search.Pattern(query),
+		DigitsPattern: search.DigitsPattern(query),
+		PageSize:      int32(limit),
+		PageOffset:    int32(offset),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to search contacts", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, contacts)
+	results := make([]ContactSearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = ContactSearchResult{
+			SearchContactsRow: row,
+			Snippet:           search.Snippet(row.MatchedField, row.MatchedText, query),
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
 }
 
 func (cfg *apiCfg) GetContactsBySmartList(w http.ResponseWriter, r *http.Request) {
@@ -232,6 +306,19 @@ func (cfg *apiCfg) GetContactsBySmartList(w http.ResponseWriter, r *http.Request
 		offset = 0
 	}
 
+	_, err = cfg.DB.GetSmartListByID(r.Context(), database.GetSmartListByIDParams{
+		ID:     smartListUUID,
+		UserID: uuid.NullUUID{UUID: userUUID, Valid: true},
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Smart list not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get smart list", err)
+		return
+	}
+
 	contacts, err := cfg.DB.GetContactsBySmartList(r.Context(), database.GetContactsBySmartListParams{
 		ID:     smartListUUID,
 		Limit:  int32(limit),
@@ -257,39 +344,6 @@ func (cfg *apiCfg) GetContactsBySmartList(w http.ResponseWriter, r *http.Request
 
 func (cfg *apiCfg) ImportContacts(w http.ResponseWriter, r *http.Request) {
 	cfg.logger.Info("ImportContacts endpoint called")
-	type requestContact struct {
-		FirstName    string `json:"first_name"`
-		LastName     string `json:"last_name"`
-		Birthdate    string `json:"birthdate"`
-		PhoneNumbers []struct {
-			Number    string `json:"number"`
-			Type      string `json:"type"`
-			IsPrimary bool   `json:"is_primary"`
-		} `json:"phone_numbers"`
-		Emails []struct {
-			Email     string `json:"email"`
-			Type      string `json:"type"`
-			IsPrimary bool   `json:"is_primary"`
-		} `json:"emails"`
-		Source     string   `json:"source"`
-		Status     string   `json:"status"`
-		Address    string   `json:"address"`
-		City       string   `json:"city"`
-		State      string   `json:"state"`
-		Zipcode    string   `json:"zipCode"`
-		Lender     string   `json:"lender"`
-		PriceRange string   `json:"price_range"`
-		Timeframe  string   `json:"timeframe"`
-		Tags       []string `json:"tags"`
-	}
-
-	var newContacts []requestContact
-	err := json.NewDecoder(r.Body).Decode(&newContacts)
-	if err != nil {
-		cfg.logger.Error("Failed to decode import contacts payload", "error", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
-		return
-	}
 
 	// Get ownerUUID from context
 	ownerUUID, err := GetUserUUID(r.Context())
@@ -298,124 +352,39 @@ func (cfg *apiCfg) ImportContacts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Start DB transaction to create contacts
-	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		respondWithUploadError(w, "Could not read request body", err)
 		return
 	}
-	defer tx.Rollback()
-
-	qtx := cfg.DB.WithTx(tx)
-
-	for _, newContact := range newContacts {
-		// Collect all string fields in a map
-		fields := map[string]string{
-			"FirstName":  newContact.FirstName,
-			"LastName":   newContact.LastName,
-			"Source":     newContact.Source,
-			"Status":     newContact.Status,
-			"Address":    newContact.Address,
-			"City":       newContact.City,
-			"State":      newContact.State,
-			"Zipcode":    newContact.Zipcode,
-			"Lender":     newContact.Lender,
-			"PriceRange": newContact.PriceRange,
-			"Timeframe":  newContact.Timeframe,
-		}
-
-		// Filter fields >= 90 characters
-		longFields := map[string]string{}
-		for k, v := range fields {
-			if len(v) >= 90 {
-				longFields[k] = v
-			}
-		}
-
-		if len(longFields) > 0 {
-			cfg.logger.Warn("Contact has long fields (>=90 chars)", "contact", longFields)
-		}
-		// Parse the birthdate string to time.Time
-		parsedDate, err := time.Parse("2006-01-02", newContact.Birthdate)
-		if err != nil && newContact.Birthdate != "" {
-			cfg.logger.Error("Invalid birthdate format", "error", err)
-			respondWithError(w, http.StatusBadRequest, "Invalid birthdate format. Use YYYY-MM-DD.", err)
-			return
-		}
-
-		// Create contact
-		contact, err := qtx.CreateContact(r.Context(), database.CreateContactParams{
-			FirstName:  newContact.FirstName,
-			LastName:   newContact.LastName,
-			Birthdate:  sql.NullTime{Time: parsedDate, Valid: newContact.Birthdate != ""},
-			Source:     sql.NullString{String: newContact.Source, Valid: newContact.Source != ""},
-			Status:     sql.NullString{String: newContact.Status, Valid: newContact.Status != ""},
-			Address:    sql.NullString{String: newContact.Address, Valid: newContact.Address != ""},
-			City:       sql.NullString{String: newContact.City, Valid: newContact.City != ""},
-			State:      sql.NullString{String: newContact.State, Valid: newContact.State != ""},
-			ZipCode:    sql.NullString{String: newContact.Zipcode, Valid: newContact.Zipcode != ""},
-			Lender:     sql.NullString{String: newContact.Lender, Valid: newContact.Lender != ""},
-			PriceRange: sql.NullString{String: newContact.PriceRange, Valid: newContact.PriceRange != ""},
-			Timeframe:  sql.NullString{String: newContact.Timeframe, Valid: newContact.Timeframe != ""},
-			OwnerID:    uuid.NullUUID{UUID: ownerUUID, Valid: ownerUUID != uuid.Nil},
-		})
-		if err != nil {
-			cfg.logger.Error("Failed to create contact", "error", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to create contact", err)
-			return
-		}
-
-		// Insert phone numbers
-		for _, phone := range newContact.PhoneNumbers {
-			err = qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
-				ContactID:   uuid.NullUUID{UUID: contact.ID, Valid: contact.ID != uuid.Nil},
-				PhoneNumber: phone.Number,
-				Type:        sql.NullString{String: phone.Type, Valid: phone.Type != ""},
-				IsPrimary:   sql.NullBool{Bool: phone.IsPrimary, Valid: true},
-			})
-			if err != nil {
-				cfg.logger.Error("Failed to add phone number", "error", err)
-				respondWithError(w, http.StatusInternalServerError, "Failed to add phone number", err)
-				return
-			}
-		}
-
-		// Insert emails
-		for _, email := range newContact.Emails {
-			err = qtx.EnterEmail(r.Context(), database.EnterEmailParams{
-				ContactID:    uuid.NullUUID{UUID: contact.ID, Valid: contact.ID != uuid.Nil},
-				EmailAddress: email.Email,
-				Type:         sql.NullString{String: email.Type, Valid: email.Type != ""},
-				IsPrimary:    sql.NullBool{Bool: email.IsPrimary, Valid: true},
-			})
-			if err != nil {
-				cfg.logger.Error("Failed to add email", "error", err)
-				respondWithError(w, http.StatusInternalServerError, "Failed to add email", err)
-				return
-			}
-		}
-
-		// Insert tags
-		err = qtx.AssignTagsToContact(r.Context(), database.AssignTagsToContactParams{
-			ContactID: contact.ID,
-			UserID:    contact.OwnerID,
-			Column2:   newContact.Tags,
-		})
-		if err != nil {
-			cfg.logger.Error("Failed to assign tags to contact", "error", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to assign tags to contact", err)
-			return
-		}
 
+	// Reject malformed payloads now instead of failing the job later
+	if _, err := importer.ParseJSON(bytes.NewReader(payload)); err != nil {
+		cfg.logger.Error("Failed to decode import contacts payload", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+	// Large imports run past the server write timeout, so they are queued
+	// and processed by the import worker
+	orgID, _ := GetActiveOrganization(r.Context())
+	routeLeads, ok := routeLeadsOption(w, r.URL.Query().Get("route_leads"), orgID)
+	if !ok {
+		return
+	}
+	job, err := cfg.enqueueImportJob(r.Context(), database.CreateImportJobParams{
+		UserID:         ownerUUID,
+		Format:         "json",
+		Payload:        payload,
+		OrganizationID: orgID,
+		RouteLeads:     routeLeads,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to queue import", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusNoContent, nil)
+	respondWithJSON(w, http.StatusCreated, job)
 }
 
 // func (cfg *apiCfg) TestImportContacts(w http.ResponseWriter, r *http.Request) {
@@ -665,7 +634,28 @@ func (cfg *apiCfg) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contact, err := cfg.DB.UpdateContact(r.Context(), database.UpdateContactParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Lock the contact so its field history is recorded in order
+	err = qtx.LockContact(r.Context(), contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+		return
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	contact, err := qtx.UpdateContact(r.Context(), database.UpdateContactParams{
 		ID:         contactUUID,
 		FirstName:  updatedData.FirstName,
 		LastName:   updatedData.LastName,
@@ -684,5 +674,20 @@ func (cfg *apiCfg) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = cfg.recordFieldHistory(r.Context(), qtx, authz.KindContact, contactUUID, before)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record contact history", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contactUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, contact)
 }
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
 	"github.com/google/uuid"
 )
 
@@ -28,7 +31,16 @@ func (cfg *apiCfg) GetNewContactsCount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, err := cfg.DB.NewContactsThisMonth(r.Context(), uuid.NullUUID{UUID: ownerUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), ownerUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	count, err := cfg.DB.NewContactsThisMonth(r.Context(), database.NewContactsThisMonthParams{
+		MonthStart: timezone.Month(time.Now(), loc).Start,
+		OwnerID:    uuid.NullUUID{UUID: ownerUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve new contacts count", err)
 		return
@@ -43,7 +55,18 @@ func (cfg *apiCfg) GetAppointmentsCount(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Invalid assignedTo ID", err)
 		return
 	}
-	count, err := cfg.DB.AppointmentsThisWeek(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	week := timezone.Week(time.Now(), loc)
+	count, err := cfg.DB.AppointmentsThisWeek(r.Context(), database.AppointmentsThisWeekParams{
+		WeekStart:    week.Start,
+		WeekEnd:      week.End,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve appointments count", err)
 		return
@@ -59,7 +82,18 @@ func (cfg *apiCfg) GetTasksDueTodayCount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	count, err := cfg.DB.TasksDueTodayCount(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	today := timezone.Day(time.Now(), loc)
+	count, err := cfg.DB.TasksDueTodayCount(r.Context(), database.TasksDueTodayCountParams{
+		DayStart:     today.Start,
+		DayEnd:       today.End,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve tasks due today count", err)
 		return
@@ -76,7 +110,16 @@ func (cfg *apiCfg) Get5UpcomingAppointments(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	appointments, err := cfg.DB.GetUpcomingAppointments(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	appointments, err := cfg.DB.GetUpcomingAppointments(r.Context(), database.GetUpcomingAppointmentsParams{
+		DayStart:     timezone.Day(time.Now(), loc).Start,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve upcoming appointments", err)
 		return
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -150,7 +152,16 @@ func (cfg *apiCfg) CreateDeal(w http.ResponseWriter, r *http.Request) {
 	commissionStr := strconv.FormatFloat(req.Commission, 'f', 2, 64)
 	commissionSplitStr := strconv.FormatFloat(req.CommissionSplit, 'f', 2, 64)
 
-	deal, err := cfg.DB.CreateDeal(r.Context(), database.CreateDealParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	deal, err := qtx.CreateDeal(r.Context(), database.CreateDealParams{
 		ContactID:            uuid.NullUUID{UUID: contactUUID, Valid: true},
 		AssignedToID:         uuid.NullUUID{UUID: assignedToUUID, Valid: true},
 		Title:                req.Title,
@@ -176,6 +187,15 @@ func (cfg *apiCfg) CreateDeal(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create deal", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindDeal, deal.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, deal)
 }
@@ -345,7 +365,28 @@ func (cfg *apiCfg) UpdateDeal(w http.ResponseWriter, r *http.Request) {
 	commissionStr := strconv.FormatFloat(req.Commission, 'f', 2, 64)
 	commissionSplitStr := strconv.FormatFloat(req.CommissionSplit, 'f', 2, 64)
 
-	deal, err := cfg.DB.UpdateDeal(r.Context(), database.UpdateDealParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Lock the deal so its field history is recorded in order
+	err = qtx.LockDeal(r.Context(), dealUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to lock deal", err)
+		return
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindDeal, dealUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	deal, err := qtx.UpdateDeal(r.Context(), database.UpdateDealParams{
 		ID:                   dealUUID,
 		ContactID:            uuid.NullUUID{UUID: contactUUID, Valid: true},
 		AssignedToID:         uuid.NullUUID{UUID: assignedToUUID, Valid: true},
@@ -373,6 +414,21 @@ func (cfg *apiCfg) UpdateDeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = cfg.recordFieldHistory(r.Context(), qtx, authz.KindDeal, dealUUID, before)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record deal history", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindDeal, dealUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, deal)
 }
 
@@ -383,11 +439,34 @@ func (cfg *apiCfg) DeleteDeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeleteDeal(r.Context(), dealUUID)
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindDeal, dealUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteDeal(r.Context(), dealUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete deal", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindDeal, dealUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
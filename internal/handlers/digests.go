@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/digest"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
+	"github.com/google/uuid"
+	"github.com/keighl/postmark"
+)
+
+const (
+	digestPollInterval = 5 * time.Minute
+	defaultDigestHour  = 7
+)
+
+// --------------------------------------------------------------
+// Preferences
+// --------------------------------------------------------------
+
+// GetDigestPreferences returns the caller's daily digest preferences, or
+// the defaults when they haven't set any.
+func (cfg *apiCfg) GetDigestPreferences(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	prefs, err := cfg.DB.GetDigestPreferences(r.Context(), userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs = database.DigestPreference{
+			UserID:   userUUID,
+			Enabled:  true,
+			SendHour: defaultDigestHour,
+		}
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get digest preferences", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// SetDigestPreferences saves whether the caller gets the daily digest, and
+// the hour it is sent at in their time zone.
+func (cfg *apiCfg) SetDigestPreferences(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Enabled  *bool `json:"enabled"`
+		SendHour *int  `json:"send_hour"`
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	sendHour := defaultDigestHour
+	if req.SendHour != nil {
+		sendHour = *req.SendHour
+	}
+	if sendHour < 0 || sendHour > 23 {
+		respondWithError(w, http.StatusBadRequest, "send_hour must be between 0 and 23", nil)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindDigestPreferences, userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	prefs, err := qtx.SetDigestPreferences(r.Context(), database.SetDigestPreferencesParams{
+		UserID:   userUUID,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+		SendHour: int32(sendHour),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save digest preferences", err)
+		return
+	}
+	// Users start without a row, so their first save creates it
+	action := audit.Update
+	if before == nil {
+		action = audit.Create
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindDigestPreferences, userUUID, action, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// PreviewDigest renders the caller's digest for today without sending it.
+func (cfg *apiCfg) PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	recipient, err := cfg.DB.GetDigestRecipient(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get user", err)
+		return
+	}
+	loc, err := cfg.userLocation(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	d, err := buildDigest(r.Context(), cfg.DB, userUUID, recipient.Name, loc, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to build digest", err)
+		return
+	}
+	email, err := digest.Render(d)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render digest", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, email)
+}
+
+// buildDigest gathers the user's agenda for the day now falls on in loc.
+func buildDigest(ctx context.Context, q *database.Queries, userID uuid.UUID, name string, loc *time.Location, now time.Time) (digest.Digest, error) {
+	today := timezone.Day(now, loc)
+	d := digest.Digest{Name: name, Day: today.Start}
+	assignee := uuid.NullUUID{UUID: userID, Valid: true}
+
+	appointments, err := q.ListTodaysAppointments(ctx, database.ListTodaysAppointmentsParams{
+		DayStart:     today.Start,
+		DayEnd:       today.End,
+		AssignedToID: assignee,
+	})
+	if err != nil {
+		return d, err
+	}
+	for _, a := range appointments {
+		d.Appointments = append(d.Appointments, digest.Appointment{
+			Title:    a.Title,
+			At:       a.ScheduledAt.In(loc),
+			Location: a.Location.String,
+		})
+	}
+
+	dueToday, err := q.GetTaskDueToday(ctx, database.GetTaskDueTodayParams{
+		DayStart:     today.Start,
+		DayEnd:       today.End,
+		AssignedToID: assignee,
+	})
+	if err != nil {
+		return d, err
+	}
+	d.TasksDue = digestTasks(dueToday, loc)
+
+	overdue, err := q.GetOverdueTasks(ctx, database.GetOverdueTasksParams{
+		DayStart:     today.Start,
+		AssignedToID: assignee,
+	})
+	if err != nil {
+		return d, err
+	}
+	d.Overdue = digestTasks(overdue, loc)
+
+	birthdays, err := q.ListDigestBirthdays(ctx, database.ListDigestBirthdaysParams{
+		UserID: assignee,
+		Day:    today.Start,
+	})
+	if err != nil {
+		return d, err
+	}
+	for _, b := range birthdays {
+		d.Birthdays = append(d.Birthdays, digest.Birthday{
+			Name: strings.TrimSpace(b.FirstName + " " + b.LastName),
+			Age:  digest.Age(b.Birthdate.Time, today.Start),
+		})
+	}
+
+	milestones, err := q.ListDigestDealMilestones(ctx, database.ListDigestDealMilestonesParams{
+		UserID:   assignee,
+		FromTime: today.Start,
+		ToTime:   timezone.Day(today.Start.AddDate(0, 0, digest.MilestoneDays), loc).Start,
+	})
+	if err != nil {
+		return d, err
+	}
+	for _, m := range milestones {
+		d.Milestones = append(d.Milestones, digest.Milestone{
+			Deal: m.Title,
+			Kind: m.Milestone,
+			At:   m.DueAt.In(loc),
+		})
+	}
+
+	return d, nil
+}
+
+func digestTasks(tasks []database.Task, loc *time.Location) []digest.Task {
+	out := make([]digest.Task, 0, len(tasks))
+	for _, t := range tasks {
+		task := digest.Task{Title: t.Title}
+		if t.Date.Valid {
+			task.Due = t.Date.Time.In(loc)
+		}
+		if t.Priority.Valid {
+			task.Priority = string(t.Priority.TaskPriority)
+		}
+		out = append(out, task)
+	}
+	return out
+}
+
+// --------------------------------------------------------------
+// Digest worker
+// --------------------------------------------------------------
+
+// StartDigestWorker emails each user their agenda once a day, after their
+// send hour in their time zone, until ctx is cancelled. It checks every
+// digestPollInterval, so a digest missed while the server was down goes out
+// later the same day.
+func (cfg *apiCfg) StartDigestWorker(ctx context.Context) {
+	ticker := time.NewTicker(digestPollInterval)
+	defer ticker.Stop()
+
+	for {
+		cfg.sendDueDigests(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueDigests sends the digests whose send hour has come.
+func (cfg *apiCfg) sendDueDigests(ctx context.Context) {
+	due, err := cfg.DB.ListDueDigests(ctx)
+	if err != nil {
+		cfg.logger.Error("Failed to list due digests", "error", err)
+		return
+	}
+
+	for _, recipient := range due {
+		if err := cfg.sendDigest(ctx, recipient); err != nil {
+			cfg.logger.Error("Failed to send digest", "user_id", recipient.UserID, "error", err)
+		}
+	}
+}
+
+// sendDigest records and sends a user's digest for today. Empty digests
+// aren't sent, and one that fails to send is skipped for the day rather
+// than retried.
+func (cfg *apiCfg) sendDigest(ctx context.Context, recipient database.ListDueDigestsRow) error {
+	loc, err := timezone.Load(recipient.TimeZone)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	today := timezone.Day(now, loc)
+
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	_, err = qtx.ClaimDigest(ctx, database.ClaimDigestParams{
+		UserID: recipient.UserID,
+		SentOn: today.Start,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	d, err := buildDigest(ctx, qtx, recipient.UserID, recipient.Name, loc, now)
+	if err != nil {
+		return err
+	}
+	if !d.Empty() {
+		email, err := digest.Render(d)
+		if err != nil {
+			return err
+		}
+		_, err = cfg.postmarkClient.SendEmail(postmark.Email{
+			From:     cfg.FromEmail,
+			To:       recipient.Email,
+			Subject:  email.Subject,
+			HtmlBody: email.HTML,
+			TextBody: email.Text,
+		})
+		if err != nil {
+			cfg.logger.Error("Failed to email digest", "user_id", recipient.UserID, "error", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/duplicates"
+	"github.com/google/uuid"
+)
+
+type duplicateContact struct {
+	ID           uuid.UUID `json:"id"`
+	FirstName    string    `json:"first_name"`
+	LastName     string    `json:"last_name"`
+	Emails       []string  `json:"emails"`
+	PhoneNumbers []string  `json:"phone_numbers"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type duplicateGroup struct {
+	Reasons  []string           `json:"reasons"`
+	Contacts []duplicateContact `json:"contacts"`
+}
+
+func (cfg *apiCfg) FindDuplicateContacts(w http.ResponseWriter, r *http.Request) {
+	ownerUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListDuplicateCandidates(r.Context(), uuid.NullUUID{UUID: ownerUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contacts", err)
+		return
+	}
+
+	candidates := make([]duplicates.Candidate, len(rows))
+	byID := make(map[uuid.UUID]duplicateContact, len(rows))
+	for i, row := range rows {
+		candidates[i] = duplicates.Candidate{
+			ID:        row.ID,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Emails:    row.Emails,
+			Phones:    row.PhoneNumbers,
+		}
+		byID[row.ID] = duplicateContact{
+			ID:           row.ID,
+			FirstName:    row.FirstName,
+			LastName:     row.LastName,
+			Emails:       row.Emails,
+			PhoneNumbers: row.PhoneNumbers,
+			CreatedAt:    row.CreatedAt.Time,
+		}
+	}
+
+	groups := []duplicateGroup{}
+	for _, g := range duplicates.Find(candidates) {
+		group := duplicateGroup{Reasons: g.Reasons}
+		for _, id := range g.ContactIDs {
+			group.Contacts = append(group.Contacts, byID[id])
+		}
+		groups = append(groups, group)
+	}
+
+	respondWithJSON(w, http.StatusOK, groups)
+}
+
+func (cfg *apiCfg) MergeContacts(w http.ResponseWriter, r *http.Request) {
+	ownerUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// Get surviving contact ID from URL
+	survivorUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	type request struct {
+		DuplicateIDs []uuid.UUID `json:"duplicate_ids"`
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if len(req.DuplicateIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one duplicate contact ID is required", nil)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+	owner := uuid.NullUUID{UUID: ownerUUID, Valid: true}
+
+	// Lock the surviving contact and make sure the user owns it
+	_, err = qtx.GetContactForMerge(r.Context(), database.GetContactForMergeParams{
+		ID:      survivorUUID,
+		OwnerID: owner,
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Contact not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contact", err)
+		return
+	}
+
+	survivorBefore, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, survivorUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	merges := []database.ContactMerge{}
+	seen := map[uuid.UUID]bool{}
+	for _, duplicateUUID := range req.DuplicateIDs {
+		if duplicateUUID == survivorUUID {
+			respondWithError(w, http.StatusBadRequest, "A contact cannot be merged into itself", nil)
+			return
+		}
+		if seen[duplicateUUID] {
+			continue
+		}
+		seen[duplicateUUID] = true
+
+		_, err = qtx.GetContactForMerge(r.Context(), database.GetContactForMergeParams{
+			ID:      duplicateUUID,
+			OwnerID: owner,
+		})
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Duplicate contact not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get duplicate contact", err)
+			return
+		}
+
+		before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, duplicateUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		merge, err := mergeContact(r.Context(), qtx, ownerUUID, survivorUUID, duplicateUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to merge contacts", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, duplicateUUID, audit.Delete, before); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		merges = append(merges, merge)
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, survivorUUID, audit.Update, survivorBefore); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.logger.Info("Contacts merged", "contact_id", survivorUUID, "merged", len(merges))
+	respondWithJSON(w, http.StatusOK, merges)
+}
+
+func (cfg *apiCfg) ListContactMerges(w http.ResponseWriter, r *http.Request) {
+	ownerUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// Get contact ID from URL
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	_, err = cfg.DB.GetContactForMerge(r.Context(), database.GetContactForMergeParams{
+		ID:      contactUUID,
+		OwnerID: uuid.NullUUID{UUID: ownerUUID, Valid: true},
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Contact not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contact", err)
+		return
+	}
+
+	merges, err := cfg.DB.ListContactMerges(r.Context(), uuid.NullUUID{UUID: contactUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contact merges", err)
+		return
+	}
+
+	if len(merges) == 0 {
+		merges = []database.ContactMerge{}
+	}
+
+	respondWithJSON(w, http.StatusOK, merges)
+}
+
+// mergeContact moves everything attached to the duplicate onto the survivor,
+// fills the survivor's empty fields from the duplicate, deletes the duplicate
+// and records a snapshot of it with the number of rows moved per table.
+func mergeContact(ctx context.Context, qtx *database.Queries, ownerUUID, survivorUUID, duplicateUUID uuid.UUID) (database.ContactMerge, error) {
+	snapshot, err := qtx.GetMergedContactSnapshot(ctx, duplicateUUID)
+	if err != nil {
+		return database.ContactMerge{}, err
+	}
+
+	err = qtx.FillContactFromDuplicate(ctx, database.FillContactFromDuplicateParams{
+		SurvivorID:  survivorUUID,
+		DuplicateID: duplicateUUID,
+	})
+	if err != nil {
+		return database.ContactMerge{}, err
+	}
+
+	// Emails and phone numbers the survivor already has are left behind and
+	// removed with the duplicate
+	moves := []struct {
+		name string
+		move func() (int64, error)
+	}{
+		{"emails", func() (int64, error) {
+			return qtx.MoveContactEmails(ctx, database.MoveContactEmailsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"phone_numbers", func() (int64, error) {
+			return qtx.MoveContactPhoneNumbers(ctx, database.MoveContactPhoneNumbersParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"contact_tags", func() (int64, error) {
+			return qtx.MoveContactTags(ctx, database.MoveContactTagsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"notes", func() (int64, error) {
+			return qtx.MoveContactNotes(ctx, database.MoveContactNotesParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"contact_logs", func() (int64, error) {
+			return qtx.MoveContactLogs(ctx, database.MoveContactLogsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"tasks", func() (int64, error) {
+			return qtx.MoveContactTasks(ctx, database.MoveContactTasksParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"appointments", func() (int64, error) {
+			return qtx.MoveContactAppointments(ctx, database.MoveContactAppointmentsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"deals", func() (int64, error) {
+			return qtx.MoveContactDeals(ctx, database.MoveContactDealsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"collaborators", func() (int64, error) {
+			return qtx.MoveContactCollaborators(ctx, database.MoveContactCollaboratorsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+		{"contact_events", func() (int64, error) {
+			return qtx.MoveContactEvents(ctx, database.MoveContactEventsParams{SurvivorID: survivorUUID, DuplicateID: duplicateUUID})
+		}},
+	}
+
+	moved := map[string]int64{}
+	for _, m := range moves {
+		n, err := m.move()
+		if err != nil {
+			return database.ContactMerge{}, err
+		}
+		moved[m.name] = n
+	}
+
+	// Moved emails and phone numbers are never primary, so promote one if
+	// the survivor had none of its own
+	survivor := uuid.NullUUID{UUID: survivorUUID, Valid: true}
+	if err := qtx.PromotePrimaryEmail(ctx, survivor); err != nil {
+		return database.ContactMerge{}, err
+	}
+	if err := qtx.PromotePrimaryPhoneNumber(ctx, survivor); err != nil {
+		return database.ContactMerge{}, err
+	}
+
+	movedJSON, err := json.Marshal(moved)
+	if err != nil {
+		return database.ContactMerge{}, err
+	}
+
+	err = qtx.DeleteMergedContact(ctx, database.DeleteMergedContactParams{
+		ID:      duplicateUUID,
+		OwnerID: uuid.NullUUID{UUID: ownerUUID, Valid: true},
+	})
+	if err != nil {
+		return database.ContactMerge{}, err
+	}
+
+	return qtx.CreateContactMerge(ctx, database.CreateContactMergeParams{
+		SurvivingContactID: uuid.NullUUID{UUID: survivorUUID, Valid: true},
+		MergedContactID:    duplicateUUID,
+		MergedContact:      snapshot,
+		MovedRecords:       movedJSON,
+		MergedBy:           uuid.NullUUID{UUID: ownerUUID, Valid: true},
+	})
+}
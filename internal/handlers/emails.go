@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -87,18 +89,59 @@ func (cfg *apiCfg) CreateEmailAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+	contactID := uuid.NullUUID{UUID: contactUUID, Valid: true}
+
+	// Lock the contact so concurrent requests can't both leave a primary
+	err = qtx.LockContact(r.Context(), contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+		return
+	}
+
+	if req.IsPrimary {
+		err = qtx.DemotePrimaryEmails(r.Context(), contactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary email address", err)
+			return
+		}
+	}
+
 	// Create email address in DB
-	err = cfg.DB.EnterEmail(r.Context(), database.EnterEmailParams{
-		ContactID:    uuid.NullUUID{UUID: contactUUID, Valid: true},
+	emailID, err := qtx.EnterEmail(r.Context(), database.EnterEmailParams{
+		ContactID:    contactID,
 		EmailAddress: req.Email,
 		Type:         sql.NullString{String: req.Type, Valid: req.Type != ""},
-		IsPrimary:    sql.NullBool{Bool: req.IsPrimary, Valid: req.IsPrimary},
+		IsPrimary:    sql.NullBool{Bool: req.IsPrimary, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create email address", err)
 		return
 	}
 
+	// The contact's first email becomes its primary
+	err = qtx.PromotePrimaryEmail(r.Context(), contactID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update primary email address", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindEmail, emailID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
@@ -124,18 +167,78 @@ func (cfg *apiCfg) UpdateEmailAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	email, err := cfg.DB.GetEmailByID(r.Context(), emailUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Email address not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get email address", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Lock the contact so concurrent requests can't both change its primary
+	if email.ContactID.Valid {
+		err = qtx.LockContact(r.Context(), email.ContactID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+			return
+		}
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindEmail, emailUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if req.IsPrimary && email.ContactID.Valid {
+		err = qtx.DemotePrimaryEmails(r.Context(), email.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary email address", err)
+			return
+		}
+	}
+
 	// Update email address in DB
-	err = cfg.DB.UpdateEmail(r.Context(), database.UpdateEmailParams{
+	err = qtx.UpdateEmail(r.Context(), database.UpdateEmailParams{
 		ID:           emailUUID,
 		EmailAddress: req.Email,
 		Type:         sql.NullString{String: req.Type, Valid: req.Type != ""},
-		IsPrimary:    sql.NullBool{Bool: req.IsPrimary, Valid: req.IsPrimary},
+		IsPrimary:    sql.NullBool{Bool: req.IsPrimary, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update email address", err)
 		return
 	}
 
+	// Unmarking the primary promotes the contact's oldest email instead
+	if email.ContactID.Valid {
+		err = qtx.PromotePrimaryEmail(r.Context(), email.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary email address", err)
+			return
+		}
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindEmail, emailUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
@@ -147,12 +250,62 @@ func (cfg *apiCfg) DeleteEmailAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	email, err := cfg.DB.GetEmailByID(r.Context(), emailUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Email address not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get email address", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	if email.ContactID.Valid {
+		err = qtx.LockContact(r.Context(), email.ContactID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+			return
+		}
+	}
+
 	// Delete email address from DB
-	err = cfg.DB.DeleteEmail(r.Context(), emailUUID)
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindEmail, emailUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteEmail(r.Context(), emailUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete email address", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindEmail, emailUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Promote a remaining email when the primary was deleted
+	if email.ContactID.Valid {
+		err = qtx.PromotePrimaryEmail(r.Context(), email.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary email address", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
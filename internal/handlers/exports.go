@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/exporter"
+	"github.com/google/uuid"
+)
+
+const (
+	exportPageSize = 500
+	// Each page gets a fresh write deadline so large exports aren't cut off by
+	// the server's WriteTimeout while a slow client is still reading
+	exportPageTimeout = 30 * time.Second
+)
+
+func (cfg *apiCfg) ExportContacts(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, err := exporter.LookupFormat(formatName)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// Optional smart list to scope the export to
+	var smartListUUID uuid.UUID
+	if id := r.URL.Query().Get("smart_list_id"); id != "" {
+		smartListUUID, err = uuid.Parse(id)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid smart list ID", err)
+			return
+		}
+		_, err = cfg.DB.GetSmartListByID(r.Context(), database.GetSmartListByIDParams{
+			ID:     smartListUUID,
+			UserID: uuid.NullUUID{UUID: userUUID, Valid: true},
+		})
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Smart list not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get smart list", err)
+			return
+		}
+	}
+
+	// Fetch the first page before writing anything so errors can still be
+	// reported with a proper status code
+	page, err := cfg.exportPage(r, userUUID, smartListUUID, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export contacts", err)
+		return
+	}
+
+	// Headers go first since some writers emit their preamble immediately
+	filename := fmt.Sprintf("contacts-%s.%s", time.Now().Format("2006-01-02"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	writer, err := exporter.NewWriter(w, format, r.URL.Query().Get("version"))
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	exported := 0
+
+	for len(page) > 0 {
+		if err := rc.SetWriteDeadline(time.Now().Add(exportPageTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			cfg.logger.Warn("Failed to extend export write deadline", "error", err)
+		}
+
+		for _, rec := range page {
+			if err := writer.Write(rec.Record); err != nil {
+				cfg.logger.Error("Contact export interrupted", "exported", exported, "error", err)
+				return
+			}
+			exported++
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			cfg.logger.Error("Contact export interrupted", "exported", exported, "error", err)
+			return
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+
+		// The status line is already sent, so a failure now can only be logged
+		page, err = cfg.exportPage(r, userUUID, smartListUUID, page[len(page)-1].ID)
+		if err != nil {
+			cfg.logger.Error("Contact export interrupted", "exported", exported, "error", err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		cfg.logger.Error("Failed to finish contact export", "error", err)
+		return
+	}
+
+	cfg.logger.Info("Contacts exported", "format", format.Name, "exported", exported)
+}
+
+type exportRecord struct {
+	ID     uuid.UUID
+	Record exporter.Record
+}
+
+// exportPage loads the next page of visible contacts after afterID, optionally
+// limited to a smart list.
+func (cfg *apiCfg) exportPage(r *http.Request, userUUID, smartListUUID, afterID uuid.UUID) ([]exportRecord, error) {
+	owner := uuid.NullUUID{UUID: userUUID, Valid: true}
+
+	if smartListUUID == uuid.Nil {
+		rows, err := cfg.DB.ExportContacts(r.Context(), database.ExportContactsParams{
+			UserID:   owner,
+			AfterID:  afterID,
+			PageSize: exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records := make([]exportRecord, len(rows))
+		for i, row := range rows {
+			records[i], err = newExportRecord(database.ExportSmartListContactsRow(row))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return records, nil
+	}
+
+	rows, err := cfg.DB.ExportSmartListContacts(r.Context(), database.ExportSmartListContactsParams{
+		SmartListID: smartListUUID,
+		UserID:      owner,
+		AfterID:     afterID,
+		PageSize:    exportPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]exportRecord, len(rows))
+	for i, row := range rows {
+		records[i], err = newExportRecord(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func newExportRecord(row database.ExportSmartListContactsRow) (exportRecord, error) {
+	rec := exporter.Record{
+		FirstName:  row.FirstName,
+		LastName:   row.LastName,
+		Birthdate:  row.Birthdate.Time,
+		Source:     row.Source.String,
+		Status:     row.Status.String,
+		Address:    row.Address.String,
+		City:       row.City.String,
+		State:      row.State.String,
+		ZipCode:    row.ZipCode.String,
+		Lender:     row.Lender.String,
+		PriceRange: row.PriceRange.String,
+		Timeframe:  row.Timeframe.String,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+
+	if err := json.Unmarshal([]byte(row.Emails), &rec.Emails); err != nil {
+		return exportRecord{}, fmt.Errorf("decode emails: %w", err)
+	}
+	if err := json.Unmarshal([]byte(row.PhoneNumbers), &rec.Phones); err != nil {
+		return exportRecord{}, fmt.Errorf("decode phone numbers: %w", err)
+	}
+	if err := json.Unmarshal([]byte(row.Tags), &rec.Tags); err != nil {
+		return exportRecord{}, fmt.Errorf("decode tags: %w", err)
+	}
+
+	return exportRecord{ID: row.ID, Record: rec}, nil
+}
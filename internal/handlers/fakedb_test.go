@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeStub answers one query. Statements return the number of rows they
+// affected as an int64. Queries return a struct whose fields are the
+// columns of one row in order, a slice of rows, or the single column of a
+// one row result; nil returns no rows.
+type fakeStub func(args []any) (any, error)
+
+type fakeCall struct {
+	name string
+	args []any
+}
+
+// fakeDB is a database/sql driver that answers the queries a test stubs,
+// keyed by their sqlc name. Statements without one, such as savepoints, are
+// keyed by their first line, and transactions by BEGIN, COMMIT and ROLLBACK.
+// Queries that aren't stubbed return no rows and statements affect one row.
+type fakeDB struct {
+	mu    sync.Mutex
+	stubs map[string]fakeStub
+	calls []fakeCall
+}
+
+// newTestConfig returns an apiCfg backed by a fakeDB.
+func newTestConfig(t *testing.T) (*apiCfg, *fakeDB) {
+	t.Helper()
+
+	f := &fakeDB{stubs: map[string]fakeStub{}}
+	db := sql.OpenDB(fakeConnector{f})
+	t.Cleanup(func() { db.Close() })
+
+	queries := database.New(db)
+	return &apiCfg{
+		DB:             queries,
+		RawDB:          db,
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		phoneRegion:    "US",
+		trashRetention: 30 * 24 * time.Hour,
+		authz:          authz.New(authz.NewStore(queries)),
+	}, f
+}
+
+// asUser returns r as signed in by userID.
+func asUser(r *http.Request, userID uuid.UUID) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDKey, userID.String()))
+}
+
+func (f *fakeDB) stub(name string, fn fakeStub) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stubs[name] = fn
+}
+
+// callsTo returns the arguments of every call to the named query, in order.
+func (f *fakeDB) callsTo(name string) [][]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var args [][]any
+	for _, c := range f.calls {
+		if c.name == name {
+			args = append(args, c.args)
+		}
+	}
+	return args
+}
+
+var sqlcName = regexp.MustCompile(`^-- name: (\w+)`)
+
+func (f *fakeDB) answer(query string, named []driver.NamedValue) (any, error) {
+	name, _, _ := strings.Cut(strings.TrimSpace(query), "\n")
+	if m := sqlcName.FindStringSubmatch(name); m != nil {
+		name = m[1]
+	}
+	args := make([]any, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, fakeCall{name: name, args: args})
+	stub := f.stubs[name]
+	f.mu.Unlock()
+
+	if stub == nil {
+		return nil, nil
+	}
+	return stub(args)
+}
+
+type fakeConnector struct{ f *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakeDB is opened with sql.OpenDB")
+}
+
+type fakeConn struct{ f *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB doesn't prepare statements")
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.f.answer("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return fakeTx(c), nil
+}
+
+// CheckNamedValue passes arguments through unconverted, so stubs see the
+// values the queries were called with.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.f.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	switch n := result.(type) {
+	case nil:
+		return driver.RowsAffected(1), nil
+	case int64:
+		return driver.RowsAffected(n), nil
+	default:
+		return nil, fmt.Errorf("stub for a statement returned %T, not int64", result)
+	}
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.f.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newFakeRows(result)
+}
+
+type fakeTx struct{ f *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	_, err := tx.f.answer("COMMIT", nil)
+	return err
+}
+
+func (tx fakeTx) Rollback() error {
+	_, err := tx.f.answer("ROLLBACK", nil)
+	return err
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func newFakeRows(result any) (*fakeRows, error) {
+	rows := &fakeRows{}
+	if result == nil {
+		return rows, nil
+	}
+
+	v := reflect.ValueOf(result)
+	var values []reflect.Value
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	} else {
+		values = append(values, v)
+	}
+
+	for _, value := range values {
+		var row []driver.Value
+		if isFakeColumn(value) {
+			col, err := fakeColumnValue(value)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, col)
+		} else {
+			for i := 0; i < value.NumField(); i++ {
+				col, err := fakeColumnValue(value.Field(i))
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", value.Type(), value.Type().Field(i).Name, err)
+				}
+				row = append(row, col)
+			}
+		}
+		rows.rows = append(rows.rows, row)
+	}
+
+	if len(rows.rows) == 0 {
+		return rows, nil
+	}
+	for i := range rows.rows[0] {
+		rows.columns = append(rows.columns, fmt.Sprintf("column%d", i))
+	}
+	return rows, nil
+}
+
+// isFakeColumn reports whether v is a single column rather than a row.
+func isFakeColumn(v reflect.Value) bool {
+	if _, ok := v.Interface().(driver.Valuer); ok {
+		return true
+	}
+	if _, ok := v.Interface().(time.Time); ok {
+		return true
+	}
+	return v.Kind() != reflect.Struct
+}
+
+func fakeColumnValue(v reflect.Value) (driver.Value, error) {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+		return pq.Array(v.Interface()).Value()
+	default:
+		return nil, fmt.Errorf("unsupported column type %s", v.Type())
+	}
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
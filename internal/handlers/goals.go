@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -42,7 +44,16 @@ func (cfg *apiCfg) SetGoal(w http.ResponseWriter, r *http.Request) {
 
 	// Parse and validate request body
 
-	goal, err := cfg.DB.SetGoal(r.Context(), database.SetGoalParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	goal, err := qtx.SetGoal(r.Context(), database.SetGoalParams{
 		UserID:                         uuid.NullUUID{UUID: userUUID, Valid: true},
 		Year:                           int32(req.Year),
 		Month:                          int32(req.Month),
@@ -55,6 +66,15 @@ func (cfg *apiCfg) SetGoal(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to set goal", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindGoal, goal.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, goal)
 }
@@ -128,7 +148,21 @@ func (cfg *apiCfg) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goal, err := cfg.DB.UpdateGoal(r.Context(), database.UpdateGoalParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindGoal, goalUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	goal, err := qtx.UpdateGoal(r.Context(), database.UpdateGoalParams{
 		ID:                             goalUUID,
 		IncomeGoal:                     sql.NullString{String: req.Income_goal, Valid: true},
 		TransactionGoal:                sql.NullInt32{Int32: int32(transactionGoal), Valid: transactionGoal != 0},
@@ -139,6 +173,15 @@ func (cfg *apiCfg) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update goal", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindGoal, goalUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, goal)
 }
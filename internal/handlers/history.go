@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// historyFields lists the fields whose changes are kept in a record's field
+// history, by the column names of its audit snapshot.
+var historyFields = map[authz.Kind][]string{
+	authz.KindContact: {"status", "source", "timeframe", "price_range", "owner_id"},
+	authz.KindDeal:    {"price", "stage_id", "closing_date", "commission"},
+}
+
+type fieldChange struct {
+	ID            uuid.UUID       `json:"id"`
+	Field         string          `json:"field"`
+	OldValue      json.RawMessage `json:"old_value"`
+	NewValue      json.RawMessage `json:"new_value"`
+	OldLabel      string          `json:"old_label,omitempty"`
+	NewLabel      string          `json:"new_label,omitempty"`
+	ChangedBy     uuid.NullUUID   `json:"changed_by"`
+	ChangedByName string          `json:"changed_by_name"`
+	ChangedAt     time.Time       `json:"changed_at"`
+}
+
+// newFieldChange converts a history row. Deal rows have the same columns and
+// convert to the contact row type.
+func newFieldChange(row database.ListContactFieldHistoryRow) fieldChange {
+	return fieldChange{
+		ID:            row.ID,
+		Field:         row.Field,
+		OldValue:      row.OldValue,
+		NewValue:      row.NewValue,
+		OldLabel:      row.OldLabel.String,
+		NewLabel:      row.NewLabel.String,
+		ChangedBy:     row.ChangedBy,
+		ChangedByName: row.ChangedByName.String,
+		ChangedAt:     row.ChangedAt,
+	}
+}
+
+// recordFieldHistory adds a history row for each followed field of the
+// contact or deal that differs from before, the snapshot taken ahead of the
+// change. Callers hold the record's lock so concurrent changes are recorded
+// in the order they were made.
+func (cfg *apiCfg) recordFieldHistory(ctx context.Context, q *database.Queries, kind authz.Kind, id uuid.UUID, before json.RawMessage) error {
+	fields, ok := historyFields[kind]
+	if !ok {
+		return fmt.Errorf("no field history for %s", kind)
+	}
+
+	after, err := cfg.auditSnapshot(ctx, q, kind, id)
+	if err != nil {
+		return err
+	}
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	params := database.CreateFieldHistoryParams{}
+	if kind == authz.KindContact {
+		params.ContactID = uuid.NullUUID{UUID: id, Valid: true}
+	} else {
+		params.DealID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if userUUID, err := GetUserUUID(ctx); err == nil {
+		params.ChangedBy = uuid.NullUUID{UUID: userUUID, Valid: true}
+	}
+
+	for _, field := range fields {
+		change, ok := changes[field]
+		if !ok {
+			continue
+		}
+		params.Field = field
+		params.OldValue = change.Before
+		params.NewValue = change.After
+		if err := q.CreateFieldHistory(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetContactHistory lists the changes to a contact's status, source,
+// timeframe, price range and owner, newest first.
+func (cfg *apiCfg) GetContactHistory(w http.ResponseWriter, r *http.Request) {
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListContactFieldHistory(r.Context(), uuid.NullUUID{UUID: contactUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contact history", err)
+		return
+	}
+
+	history := make([]fieldChange, 0, len(rows))
+	for _, row := range rows {
+		history = append(history, newFieldChange(row))
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// GetDealHistory lists the changes to a deal's price, stage, closing date and
+// commission, newest first.
+func (cfg *apiCfg) GetDealHistory(w http.ResponseWriter, r *http.Request) {
+	dealUUID, err := GetUUIDFromUrl("dealID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid deal ID", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListDealFieldHistory(r.Context(), uuid.NullUUID{UUID: dealUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get deal history", err)
+		return
+	}
+
+	history := make([]fieldChange, 0, len(rows))
+	for _, row := range rows {
+		history = append(history, newFieldChange(database.ListContactFieldHistoryRow(row)))
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
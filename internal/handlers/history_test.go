@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestRecordFieldHistoryKeepsFollowedFieldsThatChanged(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	deal, user := uuid.New(), uuid.New()
+	before := json.RawMessage(`{"title": "Oak St", "price": 450000, "stage_id": "a", "commission": 2.5, "updated_at": "2026-01-01"}`)
+	db.stub("GetAuditSnapshot", func(args []any) (any, error) {
+		return json.RawMessage(`{"title": "12 Oak St", "price": 430000, "stage_id": "b", "commission": 2.5, "updated_at": "2026-01-02"}`), nil
+	})
+
+	ctx := context.WithValue(context.Background(), userIDKey, user.String())
+	if err := cfg.recordFieldHistory(ctx, cfg.DB, authz.KindDeal, deal, before); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := db.callsTo("CreateFieldHistory")
+	if len(rows) != 2 {
+		t.Fatalf("recorded %d changes, want price and stage_id", len(rows))
+	}
+	want := []struct{ field, before, after string }{
+		{"price", "450000", "430000"},
+		{"stage_id", `"a"`, `"b"`},
+	}
+	for i, row := range rows {
+		if row[0] != (uuid.NullUUID{}) || row[1] != (uuid.NullUUID{UUID: deal, Valid: true}) {
+			t.Errorf("change %d recorded for contact %v and deal %v, want deal %v", i, row[0], row[1], deal)
+		}
+		if row[5] != (uuid.NullUUID{UUID: user, Valid: true}) {
+			t.Errorf("change %d recorded as made by %v, want %v", i, row[5], user)
+		}
+		field, oldValue, newValue := row[2], string(row[3].(json.RawMessage)), string(row[4].(json.RawMessage))
+		if field != want[i].field || oldValue != want[i].before || newValue != want[i].after {
+			t.Errorf("change %d = %v %s -> %s, want %s %s -> %s", i, field, oldValue, newValue, want[i].field, want[i].before, want[i].after)
+		}
+	}
+}
+
+func TestRecordFieldHistoryOnlyFollowsContactsAndDeals(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	if err := cfg.recordFieldHistory(context.Background(), cfg.DB, authz.KindTask, uuid.New(), nil); err == nil {
+		t.Error("recordFieldHistory() for a task = nil, want an error")
+	}
+	if got := len(db.callsTo("CreateFieldHistory")); got != 0 {
+		t.Errorf("recorded %d task changes", got)
+	}
+}
+
+func TestGetDealHistoryLabelsChanges(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	deal := uuid.New()
+	db.stub("ListDealFieldHistory", func(args []any) (any, error) {
+		return database.ListDealFieldHistoryRow{
+			ID:        uuid.New(),
+			Field:     "stage_id",
+			OldValue:  json.RawMessage(`"a"`),
+			NewValue:  json.RawMessage(`"b"`),
+			OldLabel:  sql.NullString{String: "Showing", Valid: true},
+			NewLabel:  sql.NullString{String: "Under contract", Valid: true},
+			ChangedAt: time.Now(),
+		}, nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/deals/"+deal.String()+"/history", nil)
+	r.SetPathValue("dealID", deal.String())
+	w := httptest.NewRecorder()
+	cfg.GetDealHistory(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if queries := db.callsTo("ListDealFieldHistory"); len(queries) != 1 || queries[0][0] != (uuid.NullUUID{UUID: deal, Valid: true}) {
+		t.Errorf("listed history with %v, want deal %v", queries, deal)
+	}
+	var history []fieldChange
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].OldLabel != "Showing" || history[0].NewLabel != "Under contract" {
+		t.Errorf("history = %+v, want the stage change labeled with stage names", history)
+	}
+}
@@ -0,0 +1,679 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/importer"
+	"github.com/google/uuid"
+)
+
+const (
+	importChunkSize    = 500
+	importPollInterval = 2 * time.Second
+	// A running job is claimed again once its worker has failed to extend
+	// the lease for importLeaseDuration
+	importLeaseDuration = 2 * time.Minute
+	importHeartbeat     = importLeaseDuration / 4
+	maxImportBytes      = 32 << 20
+	// Uploads get their own read deadline so a large file isn't cut off by
+	// the server's ReadTimeout on a slow connection
+	importUploadTimeout = 2 * time.Minute
+)
+
+// importRowResult is stored in import_jobs.errors for every row that was not
+// imported, so the user can fix their spreadsheet and upload again.
+type importRowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (cfg *apiCfg) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// Get job ID from URL
+	jobUUID, err := GetUUIDFromUrl("jobID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID", err)
+		return
+	}
+
+	job, err := cfg.DB.GetImportJob(r.Context(), database.GetImportJobParams{
+		ID:     jobUUID,
+		UserID: userUUID,
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Import job not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import job", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+func (cfg *apiCfg) CancelImportJob(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// Get job ID from URL
+	jobUUID, err := GetUUIDFromUrl("jobID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindImportJob, jobUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// A running job notices the cancellation when it saves its next chunk
+	cancelled, err := qtx.CancelImportJob(r.Context(), database.CancelImportJobParams{
+		ID:     jobUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel import job", err)
+		return
+	}
+	if cancelled == 0 {
+		respondWithError(w, http.StatusConflict, "Only pending or running imports can be cancelled", nil)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindImportJob, jobUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithImportJob(w, r, jobUUID, userUUID)
+}
+
+func (cfg *apiCfg) RetryImportJob(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// Get job ID from URL
+	jobUUID, err := GetUUIDFromUrl("jobID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindImportJob, jobUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Retried jobs resume after the last committed chunk
+	retried, err := qtx.RetryImportJob(r.Context(), database.RetryImportJobParams{
+		ID:     jobUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retry import job", err)
+		return
+	}
+	if retried == 0 {
+		respondWithError(w, http.StatusConflict, "Only failed or cancelled imports can be retried", nil)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindImportJob, jobUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithImportJob(w, r, jobUUID, userUUID)
+}
+
+func (cfg *apiCfg) respondWithImportJob(w http.ResponseWriter, r *http.Request, jobUUID, userUUID uuid.UUID) {
+	job, err := cfg.DB.GetImportJob(r.Context(), database.GetImportJobParams{
+		ID:     jobUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import job", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+func (cfg *apiCfg) enqueueImportJob(ctx context.Context, params database.CreateImportJobParams) (database.GetImportJobRow, error) {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.GetImportJobRow{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	jobID, err := qtx.CreateImportJob(ctx, params)
+	if err != nil {
+		return database.GetImportJobRow{}, err
+	}
+	if err := cfg.recordAudit(ctx, qtx, authz.KindImportJob, jobID, audit.Create, nil); err != nil {
+		return database.GetImportJobRow{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return database.GetImportJobRow{}, err
+	}
+
+	cfg.logger.Info("Import job queued", "job_id", jobID, "format", params.Format, "bytes", len(params.Payload))
+
+	return cfg.DB.GetImportJob(ctx, database.GetImportJobParams{
+		ID:     jobID,
+		UserID: params.UserID,
+	})
+}
+
+// --------------------------------------------------------------
+// Import worker
+// --------------------------------------------------------------
+
+// StartImportWorker processes queued import jobs one at a time until ctx is
+// cancelled. A worker holds its job with a lease it keeps extending; jobs
+// whose lease expired because their worker stopped are claimed again and
+// resume after their last committed chunk.
+func (cfg *apiCfg) StartImportWorker(ctx context.Context) {
+	ticker := time.NewTicker(importPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := cfg.DB.ClaimImportJob(ctx, int32(importLeaseDuration/time.Second))
+		switch {
+		case err == nil:
+			cfg.runImportJob(ctx, job)
+			continue
+		case !errors.Is(err, sql.ErrNoRows):
+			cfg.logger.Error("Failed to claim import job", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiCfg) runImportJob(ctx context.Context, job database.ImportJob) {
+	ctx = importJobContext(ctx, job)
+	logger := cfg.logger.With("job_id", job.ID)
+	logger.Info("Import job started", "format", job.Format, "attempt", job.Attempts, "processed_rows", job.ProcessedRows)
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go cfg.extendImportLease(heartbeatCtx, job)
+
+	rows, err := parseImportPayload(job)
+	if err != nil {
+		logger.Warn("Import job payload could not be parsed", "error", err)
+		cfg.finishImportJob(ctx, job, "failed", err.Error())
+		return
+	}
+
+	total := int32(len(rows))
+	processed := job.ProcessedRows
+
+	// Record the row count before the first chunk so progress can be polled
+	if ok, err := cfg.saveImportProgress(ctx, cfg.DB, job, total, processed, 0, 0, 0, nil); err != nil || !ok {
+		if err != nil {
+			logger.Error("Failed to save import progress", "error", err)
+			cfg.finishImportJob(ctx, job, "failed", "could not save progress")
+		}
+		return
+	}
+
+	for processed < total {
+		end := min(processed+importChunkSize, total)
+
+		ok, err := cfg.importChunk(ctx, job, rows[processed:end], total, end)
+		if err != nil {
+			logger.Error("Import chunk failed", "from_row", processed, "error", err)
+			cfg.finishImportJob(ctx, job, "failed", err.Error())
+			return
+		}
+		if !ok {
+			logger.Info("Import job stopped, it was cancelled or its lease expired", "processed_rows", processed)
+			return
+		}
+		processed = end
+	}
+
+	cfg.finishImportJob(ctx, job, "completed", "")
+	logger.Info("Import job completed", "total_rows", total)
+}
+
+// importJobContext attributes the contacts a job imports to the user who
+// uploaded the file, the way a request is attributed to its caller. The
+// job's ID stands in for the request ID, tying the audit events of one
+// import together.
+func importJobContext(ctx context.Context, job database.ImportJob) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, job.UserID.String())
+	ctx = context.WithValue(ctx, authMethodKey, audit.AuthImport)
+	return context.WithValue(ctx, requestIDKey, job.ID.String())
+}
+
+// extendImportLease extends the job's lease every importHeartbeat until ctx
+// is cancelled or the job is no longer this worker's to run.
+func (cfg *apiCfg) extendImportLease(ctx context.Context, job database.ImportJob) {
+	ticker := time.NewTicker(importHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		extended, err := cfg.DB.ExtendImportJobLease(ctx, database.ExtendImportJobLeaseParams{
+			LeaseSeconds: int32(importLeaseDuration / time.Second),
+			ID:           job.ID,
+			LeaseID:      job.LeaseID,
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				cfg.logger.Error("Failed to extend import job lease", "job_id", job.ID, "error", err)
+			}
+			continue
+		}
+		if extended == 0 {
+			return
+		}
+	}
+}
+
+func (cfg *apiCfg) finishImportJob(ctx context.Context, job database.ImportJob, status, message string) {
+	err := cfg.DB.FinishImportJob(ctx, database.FinishImportJobParams{
+		ID:      job.ID,
+		Status:  status,
+		Error:   sql.NullString{String: message, Valid: message != ""},
+		LeaseID: job.LeaseID,
+	})
+	if err != nil {
+		cfg.logger.Error("Failed to finish import job", "job_id", job.ID, "error", err)
+	}
+}
+
+// saveImportProgress reports false when the job is no longer running under
+// this worker's lease, which means it was cancelled, or claimed again after
+// the lease expired, while the chunk was being written.
+func (cfg *apiCfg) saveImportProgress(ctx context.Context, q *database.Queries, job database.ImportJob, total, processed, imported, skipped, failed int32, results []importRowResult) (bool, error) {
+	if results == nil {
+		results = []importRowResult{}
+	}
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := q.UpdateImportJobProgress(ctx, database.UpdateImportJobProgressParams{
+		TotalRows:     total,
+		ProcessedRows: processed,
+		Imported:      imported,
+		Skipped:       skipped,
+		Failed:        failed,
+		Errors:        resultsJSON,
+		ID:            job.ID,
+		LeaseID:       job.LeaseID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
+
+// importChunk writes one chunk of rows and the job's progress in a single
+// transaction, so a crash or cancellation never leaves half a chunk behind.
+// The chunk is inserted with the bulk queries first; if that fails, rows are
+// retried one at a time so a single bad row only fails itself. Jobs that
+// route leads route the chunk's contacts in the same transaction, and every
+// imported contact is recorded in the audit log.
+func (cfg *apiCfg) importChunk(ctx context.Context, job database.ImportJob, rows []importer.Row, total, processed int32) (bool, error) {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	var results []importRowResult
+	var imported, skipped, failed int32
+	var valid []importer.Row
+	var inserted []database.Contact
+
+	for _, row := range rows {
+		switch {
+		case row.Err != nil:
+			results = append(results, importRowResult{Row: row.Line, Status: "failed", Error: row.Err.Error()})
+			failed++
+		case row.Contact.IsEmpty():
+			results = append(results, importRowResult{Row: row.Line, Status: "skipped", Error: "row has no name, email or phone number"})
+			skipped++
+		default:
+			if err := row.Contact.NormalizePhones(cfg.phoneRegion); err != nil {
+				results = append(results, importRowResult{Row: row.Line, Status: "failed", Error: err.Error()})
+				failed++
+				continue
+			}
+			if row.Contact.Source == "" && job.DefaultSource.Valid {
+				row.Contact.Source = job.DefaultSource.String
+			}
+			valid = append(valid, row)
+		}
+	}
+
+	if len(valid) > 0 {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT import_chunk"); err != nil {
+			return false, err
+		}
+
+		contacts, err := bulkInsertImportedContacts(ctx, qtx, job.UserID, job.OrganizationID, valid)
+		if err == nil {
+			imported += int32(len(valid))
+			inserted = contacts
+		} else {
+			cfg.logger.Warn("Bulk import failed, retrying rows one at a time", "job_id", job.ID, "error", err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_chunk"); err != nil {
+				return false, err
+			}
+
+			for _, row := range valid {
+				if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+					return false, err
+				}
+
+				contact, err := insertImportedContact(ctx, qtx, job.UserID, job.OrganizationID, row.Contact)
+				if err != nil {
+					if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+						return false, rbErr
+					}
+					results = append(results, importRowResult{Row: row.Line, Status: "failed", Error: err.Error()})
+					failed++
+					continue
+				}
+
+				if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_row"); err != nil {
+					return false, err
+				}
+				imported++
+				inserted = append(inserted, contact)
+			}
+		}
+	}
+
+	if job.RouteLeads && job.OrganizationID.Valid && len(inserted) > 0 {
+		lr, err := cfg.loadLeadRouter(ctx, qtx, job.OrganizationID.UUID)
+		if err != nil {
+			return false, err
+		}
+		for _, contact := range inserted {
+			if err := lr.route(ctx, qtx, contact); err != nil {
+				return false, fmt.Errorf("route lead: %w", err)
+			}
+		}
+	}
+
+	for _, contact := range inserted {
+		if err := cfg.recordAudit(ctx, qtx, authz.KindContact, contact.ID, audit.Create, nil); err != nil {
+			return false, err
+		}
+	}
+
+	ok, err := cfg.saveImportProgress(ctx, qtx, job, total, processed, imported, skipped, failed, results)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func parseImportPayload(job database.ImportJob) ([]importer.Row, error) {
+	payload := bytes.NewReader(job.Payload)
+
+	switch job.Format {
+	case "csv":
+		var mapping importer.Mapping
+		if job.Mapping.Valid {
+			if err := json.Unmarshal(job.Mapping.RawMessage, &mapping); err != nil {
+				return nil, fmt.Errorf("invalid mapping: %w", err)
+			}
+		}
+		return importer.ParseCSV(payload, mapping)
+	case "vcf":
+		return importer.ParseVCard(payload)
+	case "json":
+		return importer.ParseJSON(payload)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", job.Format)
+	}
+}
+
+// bulkInsertImportedContacts inserts a chunk of contacts with the unnest based
+// bulk queries. Each inserted contact comes back with the position of its row,
+// which is what ties each phone and email to its contact.
+func bulkInsertImportedContacts(ctx context.Context, qtx *database.Queries, ownerUUID uuid.UUID, orgID uuid.NullUUID, rows []importer.Row) ([]database.Contact, error) {
+	params := database.BulkInsertContactsParams{OrganizationID: orgID}
+	for _, row := range rows {
+		c := row.Contact
+		birthdate := ""
+		if !c.Birthdate.IsZero() {
+			birthdate = c.Birthdate.Format("2006-01-02")
+		}
+		params.FirstNames = append(params.FirstNames, c.FirstName)
+		params.LastNames = append(params.LastNames, c.LastName)
+		params.Birthdates = append(params.Birthdates, birthdate)
+		params.Sources = append(params.Sources, c.Source)
+		params.Statuses = append(params.Statuses, c.Status)
+		params.Addresses = append(params.Addresses, c.Address)
+		params.Cities = append(params.Cities, c.City)
+		params.States = append(params.States, c.State)
+		params.ZipCodes = append(params.ZipCodes, c.ZipCode)
+		params.Lenders = append(params.Lenders, c.Lender)
+		params.PriceRanges = append(params.PriceRanges, c.PriceRange)
+		params.Timeframes = append(params.Timeframes, c.Timeframe)
+		params.OwnerIds = append(params.OwnerIds, ownerUUID)
+	}
+
+	insertedRows, err := qtx.BulkInsertContacts(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("insert contacts: %w", err)
+	}
+	if len(insertedRows) != len(rows) {
+		return nil, fmt.Errorf("inserted %d contacts, expected %d", len(insertedRows), len(rows))
+	}
+	contacts := make([]database.Contact, len(rows))
+	for _, c := range insertedRows {
+		i := c.Ordinal - 1
+		if i < 0 || i >= int64(len(rows)) || contacts[i].ID != uuid.Nil {
+			return nil, fmt.Errorf("inserted contact %v has unexpected position %d", c.ID, c.Ordinal)
+		}
+		contacts[i] = database.Contact{
+			ID:              c.ID,
+			FirstName:       c.FirstName,
+			LastName:        c.LastName,
+			Birthdate:       c.Birthdate,
+			Source:          c.Source,
+			Status:          c.Status,
+			Address:         c.Address,
+			City:            c.City,
+			State:           c.State,
+			ZipCode:         c.ZipCode,
+			Lender:          c.Lender,
+			PriceRange:      c.PriceRange,
+			Timeframe:       c.Timeframe,
+			OwnerID:         c.OwnerID,
+			CreatedAt:       c.CreatedAt,
+			UpdatedAt:       c.UpdatedAt,
+			LastContactedAt: c.LastContactedAt,
+			OrganizationID:  c.OrganizationID,
+			DeletedAt:       c.DeletedAt,
+		}
+	}
+
+	phones := database.BulkEnterPhoneNumbersParams{}
+	emails := database.BulkEnterEmailsParams{}
+	for i, row := range rows {
+		for _, p := range row.Contact.Phones {
+			phones.ContactIds = append(phones.ContactIds, contacts[i].ID)
+			phones.PhoneNumbers = append(phones.PhoneNumbers, p.Number)
+			phones.E164s = append(phones.E164s, p.E164)
+			phones.Types = append(phones.Types, p.Type)
+			phones.IsPrimary = append(phones.IsPrimary, p.IsPrimary)
+		}
+		for _, e := range row.Contact.Emails {
+			emails.ContactIds = append(emails.ContactIds, contacts[i].ID)
+			emails.Emails = append(emails.Emails, e.Address)
+			emails.Types = append(emails.Types, e.Type)
+			emails.IsPrimary = append(emails.IsPrimary, e.IsPrimary)
+		}
+	}
+
+	if len(phones.ContactIds) > 0 {
+		if err := qtx.BulkEnterPhoneNumbers(ctx, phones); err != nil {
+			return nil, fmt.Errorf("insert phone numbers: %w", err)
+		}
+	}
+	if len(emails.ContactIds) > 0 {
+		if err := qtx.BulkEnterEmails(ctx, emails); err != nil {
+			return nil, fmt.Errorf("insert emails: %w", err)
+		}
+	}
+
+	for i, row := range rows {
+		if len(row.Contact.Tags) == 0 {
+			continue
+		}
+		err := qtx.AssignTagsToContact(ctx, database.AssignTagsToContactParams{
+			TagNames:       row.Contact.Tags,
+			OrganizationID: contacts[i].OrganizationID,
+			UserID:         contacts[i].OwnerID,
+			ContactID:      contacts[i].ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("assign tags: %w", err)
+		}
+	}
+
+	return contacts, nil
+}
+
+func insertImportedContact(ctx context.Context, qtx *database.Queries, ownerUUID uuid.UUID, orgID uuid.NullUUID, c importer.Contact) (database.Contact, error) {
+	contact, err := qtx.CreateContact(ctx, database.CreateContactParams{
+		FirstName:      c.FirstName,
+		LastName:       c.LastName,
+		Birthdate:      sql.NullTime{Time: c.Birthdate, Valid: !c.Birthdate.IsZero()},
+		Source:         sql.NullString{String: c.Source, Valid: c.Source != ""},
+		Status:         sql.NullString{String: c.Status, Valid: c.Status != ""},
+		Address:        sql.NullString{String: c.Address, Valid: c.Address != ""},
+		City:           sql.NullString{String: c.City, Valid: c.City != ""},
+		State:          sql.NullString{String: c.State, Valid: c.State != ""},
+		ZipCode:        sql.NullString{String: c.ZipCode, Valid: c.ZipCode != ""},
+		Lender:         sql.NullString{String: c.Lender, Valid: c.Lender != ""},
+		PriceRange:     sql.NullString{String: c.PriceRange, Valid: c.PriceRange != ""},
+		Timeframe:      sql.NullString{String: c.Timeframe, Valid: c.Timeframe != ""},
+		OwnerID:        uuid.NullUUID{UUID: ownerUUID, Valid: true},
+		OrganizationID: orgID,
+	})
+	if err != nil {
+		return database.Contact{}, fmt.Errorf("create contact: %w", err)
+	}
+
+	for _, phone := range c.Phones {
+		_, err = qtx.EnterPhoneNumber(ctx, database.EnterPhoneNumberParams{
+			ContactID:   uuid.NullUUID{UUID: contact.ID, Valid: true},
+			PhoneNumber: phone.Number,
+			E164:        sql.NullString{String: phone.E164, Valid: phone.E164 != ""},
+			Type:        sql.NullString{String: phone.Type, Valid: phone.Type != ""},
+			IsPrimary:   sql.NullBool{Bool: phone.IsPrimary, Valid: true},
+		})
+		if err != nil {
+			return database.Contact{}, fmt.Errorf("add phone number %q: %w", phone.Number, err)
+		}
+	}
+
+	for _, email := range c.Emails {
+		_, err = qtx.EnterEmail(ctx, database.EnterEmailParams{
+			ContactID:    uuid.NullUUID{UUID: contact.ID, Valid: true},
+			EmailAddress: email.Address,
+			Type:         sql.NullString{String: email.Type, Valid: email.Type != ""},
+			IsPrimary:    sql.NullBool{Bool: email.IsPrimary, Valid: true},
+		})
+		if err != nil {
+			return database.Contact{}, fmt.Errorf("add email %q: %w", email.Address, err)
+		}
+	}
+
+	if len(c.Tags) > 0 {
+		err = qtx.AssignTagsToContact(ctx, database.AssignTagsToContactParams{
+			TagNames:       c.Tags,
+			OrganizationID: contact.OrganizationID,
+			UserID:         contact.OwnerID,
+			ContactID:      contact.ID,
+		})
+		if err != nil {
+			return database.Contact{}, fmt.Errorf("assign tags: %w", err)
+		}
+	}
+
+	return contact, nil
+}
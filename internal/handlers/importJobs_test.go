@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/importer"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func testImportJob() database.ImportJob {
+	return database.ImportJob{
+		ID:      uuid.New(),
+		UserID:  uuid.New(),
+		Status:  "running",
+		Format:  "json",
+		LeaseID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+}
+
+// stubContactInserts makes the bulk and single row contact inserts return a
+// contact for every row they are given.
+func stubContactInserts(db *fakeDB) {
+	db.stub("BulkInsertContacts", func(args []any) (any, error) {
+		var contacts []database.BulkInsertContactsRow
+		for i, name := range *args[0].(*pq.StringArray) {
+			contacts = append(contacts, database.BulkInsertContactsRow{ID: uuid.New(), FirstName: name, Ordinal: int64(i + 1)})
+		}
+		return contacts, nil
+	})
+	db.stub("CreateContact", func(args []any) (any, error) {
+		return database.Contact{ID: uuid.New(), FirstName: args[0].(string)}, nil
+	})
+}
+
+func TestImportChunkRetriesRowsOneAtATime(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	stubContactInserts(db)
+	db.stub("BulkInsertContacts", func(args []any) (any, error) {
+		return nil, errors.New("duplicate key value violates unique constraint")
+	})
+	db.stub("CreateContact", func(args []any) (any, error) {
+		if args[0] == "Bad" {
+			return nil, errors.New("value too long for type character varying(255)")
+		}
+		return database.Contact{ID: uuid.New(), FirstName: args[0].(string)}, nil
+	})
+
+	job := testImportJob()
+	rows := []importer.Row{
+		{Line: 1, Contact: importer.Contact{FirstName: "Ann"}},
+		{Line: 2, Contact: importer.Contact{FirstName: "Bad"}},
+		{Line: 3, Contact: importer.Contact{FirstName: "Cy"}},
+	}
+
+	ok, err := cfg.importChunk(context.Background(), job, rows, 3, 3)
+	if err != nil || !ok {
+		t.Fatalf("importChunk() = %v, %v, want true, nil", ok, err)
+	}
+
+	if got := len(db.callsTo("ROLLBACK TO SAVEPOINT import_chunk")); got != 1 {
+		t.Errorf("rolled back the bulk insert %d times, want 1", got)
+	}
+	if got := len(db.callsTo("CreateContact")); got != 3 {
+		t.Errorf("inserted %d rows one at a time, want 3", got)
+	}
+	if got := len(db.callsTo("ROLLBACK TO SAVEPOINT import_row")); got != 1 {
+		t.Errorf("rolled back %d rows, want only the bad one", got)
+	}
+	if got := len(db.callsTo("CreateAuditEvent")); got != 2 {
+		t.Errorf("audited %d contacts, want the 2 imported", got)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+
+	progress := db.callsTo("UpdateImportJobProgress")
+	if len(progress) != 1 {
+		t.Fatalf("saved progress %d times, want 1", len(progress))
+	}
+	if imported, failed := progress[0][2], progress[0][4]; imported != int32(2) || failed != int32(1) {
+		t.Errorf("saved imported = %v, failed = %v, want 2 and 1", imported, failed)
+	}
+	var results []importRowResult
+	if err := json.Unmarshal(progress[0][5].(json.RawMessage), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Row != 2 || results[0].Status != "failed" {
+		t.Errorf("saved row results %+v, want row 2 failed", results)
+	}
+}
+
+func TestImportChunkKeepsBulkInsertThatSucceeds(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	stubContactInserts(db)
+
+	rows := []importer.Row{
+		{Line: 1, Contact: importer.Contact{FirstName: "Ann"}},
+		{Line: 2, Contact: importer.Contact{FirstName: "Bo"}},
+	}
+
+	ok, err := cfg.importChunk(context.Background(), testImportJob(), rows, 2, 2)
+	if err != nil || !ok {
+		t.Fatalf("importChunk() = %v, %v, want true, nil", ok, err)
+	}
+	if got := len(db.callsTo("CreateContact")); got != 0 {
+		t.Errorf("inserted %d rows one at a time, want 0", got)
+	}
+	if got := len(db.callsTo("CreateAuditEvent")); got != 2 {
+		t.Errorf("audited %d contacts, want 2", got)
+	}
+}
+
+func TestBulkInsertImportedContactsMatchesRowsByPosition(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	// Postgres may return the inserted contacts in any order
+	ann, bo := uuid.New(), uuid.New()
+	db.stub("BulkInsertContacts", func(args []any) (any, error) {
+		return []database.BulkInsertContactsRow{
+			{ID: bo, FirstName: "Bo", Ordinal: 2},
+			{ID: ann, FirstName: "Ann", Ordinal: 1},
+		}, nil
+	})
+
+	rows := []importer.Row{
+		{Line: 1, Contact: importer.Contact{FirstName: "Ann", Phones: []importer.Phone{{Number: "555-0100"}}}},
+		{Line: 2, Contact: importer.Contact{FirstName: "Bo", Phones: []importer.Phone{{Number: "555-0199"}}}},
+	}
+	contacts, err := bulkInsertImportedContacts(context.Background(), cfg.DB, uuid.New(), uuid.NullUUID{}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 2 || contacts[0].ID != ann || contacts[1].ID != bo {
+		t.Errorf("contacts = %+v, want Ann then Bo", contacts)
+	}
+
+	phones := db.callsTo("BulkEnterPhoneNumbers")
+	if len(phones) != 1 {
+		t.Fatalf("inserted phone numbers %d times, want 1", len(phones))
+	}
+	owners := phones[0][0].(pq.GenericArray).A.([]uuid.UUID)
+	numbers := *phones[0][1].(*pq.StringArray)
+	if !slices.Equal(owners, []uuid.UUID{ann, bo}) || !slices.Equal(numbers, []string{"555-0100", "555-0199"}) {
+		t.Errorf("inserted numbers %v for contacts %v, want 555-0100 for Ann and 555-0199 for Bo", numbers, owners)
+	}
+}
+
+func testImportPayload(n int) []byte {
+	var contacts []string
+	for i := range n {
+		contacts = append(contacts, fmt.Sprintf(`{"first_name": "Contact %d"}`, i))
+	}
+	return []byte("[" + strings.Join(contacts, ",") + "]")
+}
+
+func TestRunImportJobStopsWhenCancelled(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	stubContactInserts(db)
+
+	// The job is cancelled while its first chunk is being written
+	saves := 0
+	db.stub("UpdateImportJobProgress", func(args []any) (any, error) {
+		saves++
+		if saves > 1 {
+			return int64(0), nil
+		}
+		return int64(1), nil
+	})
+
+	job := testImportJob()
+	job.Payload = testImportPayload(importChunkSize + 1)
+	cfg.runImportJob(context.Background(), job)
+
+	if got := len(db.callsTo("BulkInsertContacts")); got != 1 {
+		t.Errorf("inserted %d chunks, want 1", got)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 0 {
+		t.Errorf("committed %d chunks of a cancelled job", got)
+	}
+	if got := len(db.callsTo("FinishImportJob")); got != 0 {
+		t.Errorf("finished a cancelled job %d times", got)
+	}
+}
+
+func TestRunImportJobWritesUnderItsLease(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	stubContactInserts(db)
+
+	job := testImportJob()
+	job.Payload = testImportPayload(importChunkSize + 1)
+	cfg.runImportJob(context.Background(), job)
+
+	progress := db.callsTo("UpdateImportJobProgress")
+	if len(progress) != 3 {
+		t.Fatalf("saved progress %d times, want 3", len(progress))
+	}
+	for _, args := range progress {
+		if args[7] != job.LeaseID {
+			t.Errorf("saved progress under lease %v, want %v", args[7], job.LeaseID)
+		}
+	}
+
+	finish := db.callsTo("FinishImportJob")
+	if len(finish) != 1 {
+		t.Fatalf("finished the job %d times, want 1", len(finish))
+	}
+	if status, lease := finish[0][1], finish[0][3]; status != "completed" || lease != job.LeaseID {
+		t.Errorf("finished with status %v under lease %v, want completed under %v", status, lease, job.LeaseID)
+	}
+}
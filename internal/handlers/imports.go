@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/importer"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+func (cfg *apiCfg) UploadContactsFile(w http.ResponseWriter, r *http.Request) {
+	ownerUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	// The file is read once, into memory, since the job stores it whole in
+	// its payload column; maxImportBytes bounds how much that can be
+	reader, err := importUploadReader(w, r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Expected a multipart/form-data upload", err)
+		return
+	}
+
+	form := url.Values{}
+	var payload bytes.Buffer
+	var fileName string
+	hasFile := false
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondWithUploadError(w, "Could not read upload", err)
+			return
+		}
+
+		if part.FormName() == "file" {
+			if hasFile {
+				respondWithError(w, http.StatusBadRequest, "Upload one file at a time", nil)
+				return
+			}
+			if _, err := payload.ReadFrom(part); err != nil {
+				respondWithUploadError(w, "Could not read file", err)
+				return
+			}
+			fileName = part.FileName()
+			hasFile = true
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			respondWithUploadError(w, "Could not read upload", err)
+			return
+		}
+		form.Add(part.FormName(), string(value))
+	}
+	if !hasFile {
+		respondWithError(w, http.StatusBadRequest, "A file is required", nil)
+		return
+	}
+
+	mapping, err := cfg.importMappingFromForm(r.Context(), form, ownerUUID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	format := strings.ToLower(form.Get("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+	if format == "vcard" {
+		format = "vcf"
+	}
+	if format != "csv" && format != "vcf" {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported file format %q, upload a .csv or .vcf file", format), nil)
+		return
+	}
+
+	// Fail fast on files whose header row can't be read
+	if format == "csv" {
+		if _, err := importer.ReadHeaders(bytes.NewReader(payload.Bytes())); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Could not read CSV headers", err)
+			return
+		}
+	}
+
+	// Store the resolved mapping on the job so later edits to a saved
+	// mapping don't change an import that is already queued
+	var mappingJSON pqtype.NullRawMessage
+	if mapping != nil {
+		mappingJSON.RawMessage, err = json.Marshal(mapping)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to encode mapping", err)
+			return
+		}
+		mappingJSON.Valid = true
+	}
+
+	orgID, _ := GetActiveOrganization(r.Context())
+	routeLeads, ok := routeLeadsOption(w, form.Get("route_leads"), orgID)
+	if !ok {
+		return
+	}
+	job, err := cfg.enqueueImportJob(r.Context(), database.CreateImportJobParams{
+		UserID:         ownerUUID,
+		OrganizationID: orgID,
+		Format:         format,
+		FileName:       sql.NullString{String: fileName, Valid: fileName != ""},
+		Mapping:        mappingJSON,
+		DefaultSource:  sql.NullString{String: form.Get("source"), Valid: form.Get("source") != ""},
+		Payload:        payload.Bytes(),
+		RouteLeads:     routeLeads,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to queue import", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, job)
+}
+
+func (cfg *apiCfg) PreviewContactsFile(w http.ResponseWriter, r *http.Request) {
+	reader, err := importUploadReader(w, r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Expected a multipart/form-data upload", err)
+		return
+	}
+
+	// Only the header row is read, the rest of the file is never buffered
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			respondWithError(w, http.StatusBadRequest, "A file is required", nil)
+			return
+		}
+		if err != nil {
+			respondWithUploadError(w, "Could not read upload", err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		headers, err := importer.ReadHeaders(part)
+		if err != nil {
+			respondWithUploadError(w, "Could not read CSV headers", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"headers": headers,
+			"mapping": importer.DefaultMapping(headers),
+		})
+		return
+	}
+}
+
+func (cfg *apiCfg) SaveImportMapping(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	type request struct {
+		Name    string           `json:"name"`
+		Mapping importer.Mapping `json:"mapping"`
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Mapping name is required", nil)
+		return
+	}
+	if err := req.Mapping.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	mappingJSON, err := json.Marshal(req.Mapping)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to encode mapping", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Saving under an existing name overwrites that mapping
+	action := audit.Create
+	var before json.RawMessage
+	existing, err := qtx.GetImportMappingByName(r.Context(), database.GetImportMappingByNameParams{
+		UserID: userUUID,
+		Name:   req.Name,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import mapping", err)
+		return
+	}
+	if err == nil {
+		action = audit.Update
+		before, err = cfg.auditSnapshot(r.Context(), qtx, authz.KindImportMapping, existing.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+	}
+
+	mapping, err := qtx.SaveImportMapping(r.Context(), database.SaveImportMappingParams{
+		UserID:  userUUID,
+		Name:    req.Name,
+		Mapping: mappingJSON,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save import mapping", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindImportMapping, mapping.ID, action, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mapping)
+}
+
+func (cfg *apiCfg) ListImportMappings(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	mappings, err := cfg.DB.ListImportMappings(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import mappings", err)
+		return
+	}
+
+	if len(mappings) == 0 {
+		mappings = []database.ImportMapping{}
+	}
+
+	respondWithJSON(w, http.StatusOK, mappings)
+}
+
+func (cfg *apiCfg) DeleteImportMapping(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	mappingUUID, err := GetUUIDFromUrl("mappingID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid mapping ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindImportMapping, mappingUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteImportMapping(r.Context(), database.DeleteImportMappingParams{
+		ID:     mappingUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete import mapping", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindImportMapping, mappingUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// importUploadReader streams a multipart upload, limiting the request to
+// maxImportBytes and giving it importUploadTimeout to arrive.
+func importUploadReader(w http.ResponseWriter, r *http.Request) (*multipart.Reader, error) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Now().Add(importUploadTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	return r.MultipartReader()
+}
+
+// respondWithUploadError responds 413 when err comes from reading past
+// maxImportBytes, and 400 with msg otherwise.
+func respondWithUploadError(w http.ResponseWriter, msg string, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Imports are limited to %d MB", maxImportBytes>>20), err)
+		return
+	}
+	respondWithError(w, http.StatusBadRequest, msg, err)
+}
+
+// importMappingFromForm resolves the column mapping for an upload, either from
+// a saved mapping ("mapping_id") or an inline JSON object ("mapping"). A nil
+// mapping means the CSV headers are auto-mapped.
+func (cfg *apiCfg) importMappingFromForm(ctx context.Context, form url.Values, userUUID uuid.UUID) (importer.Mapping, error) {
+	var raw []byte
+
+	if id := form.Get("mapping_id"); id != "" {
+		mappingUUID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("Invalid mapping ID")
+		}
+		saved, err := cfg.DB.GetImportMapping(ctx, database.GetImportMappingParams{
+			ID:     mappingUUID,
+			UserID: userUUID,
+		})
+		if err != nil {
+			return nil, errors.New("Import mapping not found")
+		}
+		raw = saved.Mapping
+	} else if inline := form.Get("mapping"); inline != "" {
+		raw = []byte(inline)
+	} else {
+		return nil, nil
+	}
+
+	var mapping importer.Mapping
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil, errors.New("Invalid mapping, expected a JSON object of header to field")
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
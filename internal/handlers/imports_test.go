@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// uploadRequest returns a multipart upload of file with the given fields.
+func uploadRequest(t *testing.T, target string, fields map[string]string, fileName string, file []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestUploadContactsFileQueuesTheFile(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.stub("CreateImportJob", func(args []any) (any, error) {
+		return uuid.New(), nil
+	})
+	db.stub("GetImportJob", func(args []any) (any, error) {
+		return database.GetImportJobRow{ID: args[0].(uuid.UUID), Status: "pending"}, nil
+	})
+
+	file := []byte("First Name,Email\nAnn,ann@example.com\n")
+	r := uploadRequest(t, "/api/contacts/import/upload", map[string]string{"source": "Open house"}, "leads.csv", file)
+	w := httptest.NewRecorder()
+	cfg.UploadContactsFile(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	created := db.callsTo("CreateImportJob")
+	if len(created) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(created))
+	}
+	args := created[0]
+	if args[1] != "csv" {
+		t.Errorf("format = %v, want csv", args[1])
+	}
+	if name := args[2].(sql.NullString); name.String != "leads.csv" {
+		t.Errorf("file name = %q, want leads.csv", name.String)
+	}
+	if source := args[4].(sql.NullString); source.String != "Open house" {
+		t.Errorf("default source = %q, want Open house", source.String)
+	}
+	if !bytes.Equal(args[5].([]byte), file) {
+		t.Errorf("payload = %q, want %q", args[5], file)
+	}
+}
+
+func TestUploadContactsFileRejectsLargeFiles(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	file := bytes.Repeat([]byte("a"), maxImportBytes+1)
+	r := uploadRequest(t, "/api/contacts/import/upload", nil, "leads.csv", file)
+	w := httptest.NewRecorder()
+	cfg.UploadContactsFile(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if got := len(db.callsTo("CreateImportJob")); got != 0 {
+		t.Errorf("queued %d jobs for a file over the limit", got)
+	}
+}
+
+func TestPreviewContactsFileReadsHeaderRow(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+
+	file := []byte("First Name,Last Name,Email\nAnn,Lee,ann@example.com\n")
+	r := uploadRequest(t, "/api/contacts/import/preview", nil, "leads.csv", file)
+	w := httptest.NewRecorder()
+	cfg.PreviewContactsFile(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Headers []string `json:"headers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Headers) != 3 || resp.Headers[2] != "Email" {
+		t.Errorf("headers = %q, want First Name, Last Name and Email", resp.Headers)
+	}
+}
@@ -2,24 +2,67 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
 
+// Scopes a stage, tag or smart list can be created in.
+const (
+	scopePersonal     = "personal"
+	scopeOrganization = "organization"
+)
+
+// definitionScope resolves the organization a new stage, tag or smart list
+// belongs to. Personal definitions (the default) belong to the caller alone;
+// organization ones are shared with every member of the caller's active
+// organization and only its admins may create them. It writes the error
+// response and returns false when the scope can't be used.
+func definitionScope(w http.ResponseWriter, r *http.Request, scope string) (uuid.NullUUID, bool) {
+	switch scope {
+	case "", scopePersonal:
+		return uuid.NullUUID{}, true
+	case scopeOrganization:
+	default:
+		respondWithError(w, http.StatusBadRequest, `Invalid scope. Use "personal" or "organization"`, nil)
+		return uuid.NullUUID{}, false
+	}
+
+	orgID, role := GetActiveOrganization(r.Context())
+	if !orgID.Valid {
+		respondWithError(w, http.StatusBadRequest, "No active organization", nil)
+		return uuid.NullUUID{}, false
+	}
+	if !authz.IsOrgAdmin(role) {
+		respondWithError(w, http.StatusForbidden, "Only organization admins can create shared definitions", nil)
+		return uuid.NullUUID{}, false
+	}
+	return orgID, true
+}
+
+// GetCollaborators lists the members of the organizations in org_ids. The
+// caller must be a member of each of them.
 func (cfg *apiCfg) GetCollaborators(w http.ResponseWriter, r *http.Request) {
 	type req struct {
 		OrgIDs []string `json:"org_ids"`
 	}
 
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
 	var request req
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
 
-	var collaborators []database.Collaborator
+	collaborators := []database.GetOrganizationMembersRow{}
 	// Fetch collaborators based on organization IDs
 	for _, orgID := range request.OrgIDs {
 		// parse orgID if necessary
@@ -29,21 +72,22 @@ func (cfg *apiCfg) GetCollaborators(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		collaborators, err := cfg.DB.GetOrganizationMembers(r.Context(), orgUUID)
+		err = cfg.authz.Check(r.Context(), userUUID, authz.KindOrganization, orgUUID, authz.View)
+		switch {
+		case errors.Is(err, authz.ErrNotFound), errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusNotFound, "Organization not found", nil)
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+			return
+		}
+
+		members, err := cfg.DB.GetOrganizationMembers(r.Context(), orgUUID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to fetch collaborators", err)
 			return
 		}
-		// Append or process collaborators as needed
-		collaborators = append(collaborators, collaborators...)
-	}
-
-	cfg.logger.Info("collaborators fetched", "collaborators", collaborators)
-
-	if len(collaborators) == 0 {
-		emptyList := []database.Collaborator{}
-		respondWithJSON(w, http.StatusOK, emptyList)
-		return
+		collaborators = append(collaborators, members...)
 	}
 
 	// Respond with the list of collaborators
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// inOrganization returns r with orgID active in the caller's session, where
+// they have role.
+func inOrganization(r *http.Request, orgID uuid.UUID, role string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), organizationKey, activeOrganization{ID: orgID, Role: role}))
+}
+
+func TestCreateTagScopes(t *testing.T) {
+	org := uuid.New()
+
+	tests := []struct {
+		name    string
+		scope   string
+		role    string // empty for no active organization
+		status  int
+		wantOrg bool
+	}{
+		{"personal by default", "", "member", http.StatusCreated, false},
+		{"personal", "personal", "admin", http.StatusCreated, false},
+		{"organization by an admin", "organization", "admin", http.StatusCreated, true},
+		{"organization by the owner", "organization", "owner", http.StatusCreated, true},
+		{"organization by a member", "organization", "member", http.StatusForbidden, false},
+		{"organization without an active organization", "organization", "", http.StatusBadRequest, false},
+		{"unknown scope", "team", "admin", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			db.stub("CreateTag", func(args []any) (any, error) {
+				return database.Tag{ID: uuid.New(), Name: args[0].(string), UserID: args[2].(uuid.NullUUID), OrganizationID: args[3].(uuid.NullUUID)}, nil
+			})
+
+			body := `{"tag_name": "Buyer", "scope": "` + tt.scope + `"}`
+			r := asUser(httptest.NewRequest(http.MethodPost, "/api/tags", strings.NewReader(body)), uuid.New())
+			if tt.role != "" {
+				r = inOrganization(r, org, tt.role)
+			}
+			w := httptest.NewRecorder()
+			cfg.CreateTag(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			created := db.callsTo("CreateTag")
+			if tt.status != http.StatusCreated {
+				if len(created) != 0 {
+					t.Errorf("created the tag on a %d response", w.Code)
+				}
+				return
+			}
+			if len(created) != 1 {
+				t.Fatalf("created %d tags, want 1", len(created))
+			}
+			gotOrg := created[0][3].(uuid.NullUUID)
+			if gotOrg.Valid != tt.wantOrg || (tt.wantOrg && gotOrg.UUID != org) {
+				t.Errorf("tag organization = %v, want shared with %v: %v", gotOrg, org, tt.wantOrg)
+			}
+		})
+	}
+}
+
+// TestSharedDefinitionsAreManagedByAdmins checks that members can use an
+// organization's stages but only its admins can change them.
+func TestSharedDefinitionsAreManagedByAdmins(t *testing.T) {
+	org, admin, member, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stage := uuid.New()
+
+	tests := []struct {
+		name   string
+		route  string
+		user   uuid.UUID
+		status int
+	}{
+		{"admin edits", "PUT /api/stages/{stageID}", admin, http.StatusNoContent},
+		{"admin deletes", "DELETE /api/stages/{stageID}", admin, http.StatusNoContent},
+		{"member edits", "PUT /api/stages/{stageID}", member, http.StatusForbidden},
+		{"member deletes", "DELETE /api/stages/{stageID}", member, http.StatusForbidden},
+		{"member lists deals", "GET /api/stages/{stageID}/deals", member, http.StatusNoContent},
+		{"outsider lists deals", "GET /api/stages/{stageID}/deals", outsider, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			db.stub("GetRecordOwnership", func(args []any) (any, error) {
+				return database.GetRecordOwnershipRow{
+					OwnerID:        uuid.NullUUID{UUID: admin, Valid: true},
+					OrganizationID: uuid.NullUUID{UUID: org, Valid: true},
+				}, nil
+			})
+			db.stub("GetMemberRole", func(args []any) (any, error) {
+				switch args[0] {
+				case admin:
+					return "admin", nil
+				case member:
+					return "member", nil
+				}
+				return nil, nil
+			})
+
+			handler := cfg.Authorize(tt.route, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			method, path, _ := strings.Cut(tt.route, " ")
+			r := httptest.NewRequest(method, strings.Replace(path, "{stageID}", stage.String(), 1), nil)
+			r.SetPathValue("stageID", stage.String())
+			w := httptest.NewRecorder()
+			handler(w, asUser(r, tt.user))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
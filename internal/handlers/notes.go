@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -33,7 +35,16 @@ func (cfg *apiCfg) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	note, err := cfg.DB.CreateNote(r.Context(), database.CreateNoteParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	note, err := qtx.CreateNote(r.Context(), database.CreateNoteParams{
 		ContactID: uuid.NullUUID{UUID: contactUUID, Valid: req.ContactID != ""},
 		Note:      req.Note,
 		CreatedBy: uuid.NullUUID{UUID: createdByUUID, Valid: createdByUUID != uuid.Nil},
@@ -42,6 +53,15 @@ func (cfg *apiCfg) CreateNote(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create note", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindNote, note.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, note)
 }
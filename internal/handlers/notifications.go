@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -114,8 +116,17 @@ func (cfg *apiCfg) CreateNotification(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
 	// Create a new notification
-	notification, err := cfg.DB.CreateNotification(r.Context(), database.CreateNotificationParams{
+	notification, err := qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
 		UserID:        userUUID,
 		Message:       request.Message,
 		Type:          request.Type,
@@ -127,6 +138,15 @@ func (cfg *apiCfg) CreateNotification(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create notification", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindNotification, notification.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	// Respond with the created notification
 	respondWithJSON(w, http.StatusCreated, notification)
@@ -140,12 +160,36 @@ func (cfg *apiCfg) MarkNotificationAsRead(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindNotification, notificationUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
 	// Mark the notification as read
-	err = cfg.DB.MarkNotificationAsRead(r.Context(), notificationUUID)
+	err = qtx.MarkNotificationAsRead(r.Context(), notificationUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to mark notification as read", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindNotification, notificationUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	// Respond with no content
 	respondWithJSON(w, http.StatusNoContent, nil)
@@ -159,12 +203,36 @@ func (cfg *apiCfg) DeleteNotification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindNotification, notificationUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
 	// Delete the notification
-	err = cfg.DB.DeleteNotification(r.Context(), notificationUUID)
+	err = qtx.DeleteNotification(r.Context(), notificationUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete notification", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindNotification, notificationUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	// Respond with no content
 	respondWithJSON(w, http.StatusNoContent, nil)
@@ -178,12 +246,42 @@ func (cfg *apiCfg) MarkAllNotificationsAsRead(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Mark all notifications as read for the user
-	err = cfg.DB.MarkAllNotificationsAsRead(r.Context(), userUUID)
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Mark all notifications as read for the user, one at a time so each
+	// one's change is audited
+	notificationIDs, err := qtx.ListUnreadNotificationIDs(r.Context(), userUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to mark all notifications as read", err)
 		return
 	}
+	for _, notificationID := range notificationIDs {
+		before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindNotification, notificationID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		if err := qtx.MarkNotificationAsRead(r.Context(), notificationID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to mark all notifications as read", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindNotification, notificationID, audit.Update, before); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	// Respond with no content
 	respondWithJSON(w, http.StatusNoContent, nil)
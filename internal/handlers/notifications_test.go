@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMarkAllNotificationsAsReadAuditsEachOne(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	user := uuid.New()
+
+	unread := []uuid.UUID{uuid.New(), uuid.New()}
+	read := map[uuid.UUID]bool{}
+	db.stub("ListUnreadNotificationIDs", func(args []any) (any, error) {
+		return unread, nil
+	})
+	db.stub("MarkNotificationAsRead", func(args []any) (any, error) {
+		read[args[0].(uuid.UUID)] = true
+		return nil, nil
+	})
+	db.stub("GetAuditSnapshot", func(args []any) (any, error) {
+		id := args[1].(uuid.UUID)
+		if read[id] {
+			return json.RawMessage(`{"read": true}`), nil
+		}
+		return json.RawMessage(`{"read": false}`), nil
+	})
+
+	r := asUser(httptest.NewRequest(http.MethodPut, "/api/notifications/read-all", nil), user)
+	w := httptest.NewRecorder()
+	cfg.MarkAllNotificationsAsRead(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	events := db.callsTo("CreateAuditEvent")
+	if len(events) != len(unread) {
+		t.Fatalf("recorded %d audit events, want one per unread notification", len(events))
+	}
+	for i, e := range events {
+		if e[2] != "notification" || e[3] != unread[i] || e[4] != "update" {
+			t.Errorf("audit event %d = %v, want an update of notification %v", i, e, unread[i])
+		}
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
@@ -3,12 +3,28 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/phone"
 	"github.com/google/uuid"
 )
 
+// parsePhone normalizes a phone number from a request, reading national
+// numbers as numbers of the configured default region.
+func (cfg *apiCfg) parsePhone(raw string) (phone.Number, error) {
+	return phone.Parse(raw, cfg.phoneRegion)
+}
+
+// respondWithPhoneError rejects a request carrying a number parsePhone could
+// not read.
+func respondWithPhoneError(w http.ResponseWriter, raw string, err error) {
+	respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid phone number %q: %v", raw, err), err)
+}
+
 func (cfg *apiCfg) CreatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PhoneNumber string `json:"phone_number"`
@@ -35,17 +51,65 @@ func (cfg *apiCfg) CreatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
-		ContactID:   uuid.NullUUID{UUID: contactUUID, Valid: true},
-		PhoneNumber: req.PhoneNumber,
+	number, err := cfg.parsePhone(req.PhoneNumber)
+	if err != nil {
+		respondWithPhoneError(w, req.PhoneNumber, err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+	contactID := uuid.NullUUID{UUID: contactUUID, Valid: true}
+
+	// Lock the contact so concurrent requests can't both leave a primary
+	err = qtx.LockContact(r.Context(), contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+		return
+	}
+
+	if req.IsPrimary {
+		err = qtx.DemotePrimaryPhoneNumbers(r.Context(), contactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary phone number", err)
+			return
+		}
+	}
+
+	phoneID, err := qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
+		ContactID:   contactID,
+		PhoneNumber: number.Display,
+		E164:        sql.NullString{String: number.E164, Valid: true},
 		Type:        sql.NullString{String: req.Type, Valid: req.Type != ""},
-		IsPrimary:   sql.NullBool{Bool: req.IsPrimary, Valid: req.IsPrimary},
+		IsPrimary:   sql.NullBool{Bool: req.IsPrimary, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create phone number", err)
 		return
 	}
 
+	// The contact's first phone number becomes its primary
+	err = qtx.PromotePrimaryPhoneNumber(r.Context(), contactID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update primary phone number", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindPhoneNumber, phoneID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
@@ -57,11 +121,61 @@ func (cfg *apiCfg) DeletePhoneNumber(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeletePhoneNumber(r.Context(), phoneNumberUUID)
+	phoneNumber, err := cfg.DB.GetPhoneNumberByID(r.Context(), phoneNumberUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Phone number not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get phone number", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	if phoneNumber.ContactID.Valid {
+		err = qtx.LockContact(r.Context(), phoneNumber.ContactID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+			return
+		}
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindPhoneNumber, phoneNumberUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeletePhoneNumber(r.Context(), phoneNumberUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete phone number", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindPhoneNumber, phoneNumberUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Promote a remaining phone number when the primary was deleted
+	if phoneNumber.ContactID.Valid {
+		err = qtx.PromotePrimaryPhoneNumber(r.Context(), phoneNumber.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary phone number", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -92,16 +206,83 @@ func (cfg *apiCfg) UpdatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.UpdatePhoneNumber(r.Context(), database.UpdatePhoneNumberParams{
+	number, err := cfg.parsePhone(req.PhoneNumber)
+	if err != nil {
+		respondWithPhoneError(w, req.PhoneNumber, err)
+		return
+	}
+
+	phoneNumber, err := cfg.DB.GetPhoneNumberByID(r.Context(), phoneNumberUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Phone number not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get phone number", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Lock the contact so concurrent requests can't both change its primary
+	if phoneNumber.ContactID.Valid {
+		err = qtx.LockContact(r.Context(), phoneNumber.ContactID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to lock contact", err)
+			return
+		}
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindPhoneNumber, phoneNumberUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if req.IsPrimary && phoneNumber.ContactID.Valid {
+		err = qtx.DemotePrimaryPhoneNumbers(r.Context(), phoneNumber.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary phone number", err)
+			return
+		}
+	}
+
+	err = qtx.UpdatePhoneNumber(r.Context(), database.UpdatePhoneNumberParams{
 		ID:          phoneNumberUUID,
-		PhoneNumber: req.PhoneNumber,
+		PhoneNumber: number.Display,
+		E164:        sql.NullString{String: number.E164, Valid: true},
 		Type:        sql.NullString{String: req.Type, Valid: req.Type != ""},
-		IsPrimary:   sql.NullBool{Bool: req.IsPrimary, Valid: req.IsPrimary},
+		IsPrimary:   sql.NullBool{Bool: req.IsPrimary, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update phone number", err)
 		return
 	}
 
+	// Unmarking the primary promotes the contact's oldest number instead
+	if phoneNumber.ContactID.Valid {
+		err = qtx.PromotePrimaryPhoneNumber(r.Context(), phoneNumber.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update primary phone number", err)
+			return
+		}
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindPhoneNumber, phoneNumberUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/reminder"
+	"github.com/google/uuid"
+	"github.com/keighl/postmark"
+)
+
+const (
+	reminderPollInterval     = time.Minute
+	maxReminderEmailAttempts = 5
+)
+
+// --------------------------------------------------------------
+// Preferences
+// --------------------------------------------------------------
+
+// GetReminderPreferences returns the caller's reminder preferences, or the
+// defaults when they haven't set any.
+func (cfg *apiCfg) GetReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	prefs, err := cfg.DB.GetReminderPreferences(r.Context(), userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs = database.ReminderPreference{
+			UserID:                 userUUID,
+			Enabled:                true,
+			TaskLeadMinutes:        reminder.DefaultLeadMinutes,
+			AppointmentLeadMinutes: reminder.DefaultLeadMinutes,
+		}
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get reminder preferences", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// SetReminderPreferences saves when the caller is reminded of their tasks
+// and appointments, in minutes ahead of the due time, and whether reminders
+// are emailed as well. Lead times left out are the defaults; an empty list
+// turns those reminders off.
+func (cfg *apiCfg) SetReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Enabled                *bool `json:"enabled"`
+		Email                  bool  `json:"email"`
+		TaskLeadMinutes        []int `json:"task_lead_minutes"`
+		AppointmentLeadMinutes []int `json:"appointment_lead_minutes"`
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	taskLeads := reminder.DefaultLeadMinutes
+	if req.TaskLeadMinutes != nil {
+		taskLeads, err = reminder.NormalizeLeadMinutes(req.TaskLeadMinutes)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid task_lead_minutes: "+err.Error(), err)
+			return
+		}
+	}
+	appointmentLeads := reminder.DefaultLeadMinutes
+	if req.AppointmentLeadMinutes != nil {
+		appointmentLeads, err = reminder.NormalizeLeadMinutes(req.AppointmentLeadMinutes)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid appointment_lead_minutes: "+err.Error(), err)
+			return
+		}
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindReminderPreferences, userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	prefs, err := qtx.SetReminderPreferences(r.Context(), database.SetReminderPreferencesParams{
+		UserID:                 userUUID,
+		Enabled:                req.Enabled == nil || *req.Enabled,
+		Email:                  req.Email,
+		TaskLeadMinutes:        taskLeads,
+		AppointmentLeadMinutes: appointmentLeads,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save reminder preferences", err)
+		return
+	}
+	// Users start without a row, so their first save creates it
+	action := audit.Update
+	if before == nil {
+		action = audit.Create
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindReminderPreferences, userUUID, action, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// --------------------------------------------------------------
+// Reminder worker
+// --------------------------------------------------------------
+
+// StartReminderWorker reminds agents of their upcoming tasks and
+// appointments until ctx is cancelled, checking every reminderPollInterval.
+// Each reminder is a notification, and an email for users who asked for
+// one. Sent reminders are recorded, so a reminder fires once across
+// restarts and servers, and ones missed while the server was down are sent
+// on start as long as the record isn't due yet.
+func (cfg *apiCfg) StartReminderWorker(ctx context.Context) {
+	ticker := time.NewTicker(reminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		cfg.createDueReminders(ctx)
+		for cfg.sendReminderEmail(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// createDueReminders sends the reminders whose lead time has come.
+func (cfg *apiCfg) createDueReminders(ctx context.Context) {
+	now := time.Now()
+
+	tasks, err := cfg.DB.ListDueTaskReminders(ctx, reminder.DefaultLeadMinutes)
+	if err != nil {
+		cfg.logger.Error("Failed to list task reminders", "error", err)
+	}
+	for _, due := range tasks {
+		err := cfg.createReminder(ctx, database.CreateReminderParams{
+			UserID:      due.UserID,
+			TaskID:      uuid.NullUUID{UUID: due.ID, Valid: true},
+			DueAt:       due.DueAt.Time,
+			LeadMinutes: due.LeadMinutes,
+			SendEmail:   due.SendEmail,
+		}, database.CreateNotificationParams{
+			UserID:    due.UserID,
+			Type:      "task_reminder",
+			Message:   reminder.TaskMessage(due.Title, due.DueAt.Time, now),
+			ContactID: due.ContactID,
+			TaskID:    uuid.NullUUID{UUID: due.ID, Valid: true},
+		})
+		if err != nil {
+			cfg.logger.Error("Failed to send task reminder", "task_id", due.ID, "error", err)
+		}
+	}
+
+	appointments, err := cfg.DB.ListDueAppointmentReminders(ctx, reminder.DefaultLeadMinutes)
+	if err != nil {
+		cfg.logger.Error("Failed to list appointment reminders", "error", err)
+	}
+	for _, due := range appointments {
+		err := cfg.createReminder(ctx, database.CreateReminderParams{
+			UserID:        due.UserID,
+			AppointmentID: uuid.NullUUID{UUID: due.ID, Valid: true},
+			DueAt:         due.DueAt,
+			LeadMinutes:   due.LeadMinutes,
+			SendEmail:     due.SendEmail,
+		}, database.CreateNotificationParams{
+			UserID:        due.UserID,
+			Type:          "appointment_reminder",
+			Message:       reminder.AppointmentMessage(due.Title, due.DueAt, now),
+			ContactID:     due.ContactID,
+			AppointmentID: uuid.NullUUID{UUID: due.ID, Valid: true},
+		})
+		if err != nil {
+			cfg.logger.Error("Failed to send appointment reminder", "appointment_id", due.ID, "error", err)
+		}
+	}
+}
+
+// createReminder records a reminder along with its notification. Nothing
+// is created when the reminder was already sent.
+func (cfg *apiCfg) createReminder(ctx context.Context, params database.CreateReminderParams, notification database.CreateNotificationParams) error {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	sent, err := qtx.CreateReminder(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	created, err := qtx.CreateNotification(ctx, notification)
+	if err != nil {
+		return err
+	}
+	err = qtx.SetReminderNotification(ctx, database.SetReminderNotificationParams{
+		ID:             sent.ID,
+		NotificationID: uuid.NullUUID{UUID: created.ID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sendReminderEmail emails one reminder still waiting for its email and
+// reports whether there may be more. A failed email is retried on the next
+// run, up to maxReminderEmailAttempts times.
+func (cfg *apiCfg) sendReminderEmail(ctx context.Context) bool {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		cfg.logger.Error("Failed to start reminder email", "error", err)
+		return false
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	claimed, err := qtx.ClaimReminderEmail(ctx, maxReminderEmailAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		cfg.logger.Error("Failed to claim reminder email", "error", err)
+		return false
+	}
+	logger := cfg.logger.With("reminder_id", claimed.ID)
+
+	message := reminder.AppointmentMessage(claimed.Title, claimed.DueAt, time.Now())
+	if claimed.TaskID.Valid {
+		message = reminder.TaskMessage(claimed.Title, claimed.DueAt, time.Now())
+	}
+
+	_, sendErr := cfg.postmarkClient.SendEmail(postmark.Email{
+		From:     cfg.FromEmail,
+		To:       claimed.Email,
+		Subject:  "Reminder: " + claimed.Title,
+		HtmlBody: "<p>" + html.EscapeString(message) + "</p>",
+		TextBody: message,
+	})
+	if sendErr != nil {
+		logger.Error("Failed to send reminder email", "error", sendErr)
+		err = qtx.RecordReminderEmailFailure(ctx, claimed.ID)
+	} else {
+		err = qtx.MarkReminderEmailed(ctx, claimed.ID)
+	}
+	if err != nil {
+		logger.Error("Failed to record reminder email", "error", err)
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit reminder email", "error", err)
+		return false
+	}
+	return sendErr == nil
+}
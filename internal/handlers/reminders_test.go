@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestSetReminderPreferencesAuditsTheFirstSaveAsACreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		before json.RawMessage
+		want   string
+	}{
+		{"first save", nil, "create"},
+		{"later save", json.RawMessage(`{"enabled": true, "email": true}`), "update"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			user := uuid.New()
+
+			saved := false
+			db.stub("SetReminderPreferences", func(args []any) (any, error) {
+				saved = true
+				return database.ReminderPreference{UserID: user, Enabled: true, TaskLeadMinutes: []int32{15}, AppointmentLeadMinutes: []int32{15}}, nil
+			})
+			db.stub("GetAuditSnapshot", func(args []any) (any, error) {
+				if saved {
+					return json.RawMessage(`{"enabled": true, "email": false}`), nil
+				}
+				if tt.before == nil {
+					return nil, nil
+				}
+				return tt.before, nil
+			})
+
+			r := asUser(httptest.NewRequest(http.MethodPut, "/api/reminder-preferences", strings.NewReader(`{"email": false}`)), user)
+			w := httptest.NewRecorder()
+			cfg.SetReminderPreferences(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+
+			events := db.callsTo("CreateAuditEvent")
+			if len(events) != 1 || events[0][2] != "reminder_preferences" || events[0][3] != user || events[0][4] != tt.want {
+				t.Errorf("recorded audit events %v, want a %s of the user's preferences", events, tt.want)
+			}
+		})
+	}
+}
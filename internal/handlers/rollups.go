@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"cmp"
+	"database/sql"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRollupPageSize = 50
+	maxRollupPageSize     = 500
+)
+
+// rollupMetrics are the dashboard numbers of one agent or a whole
+// organization. Deal volume and commission are in the deal's currency;
+// closed deals are those closed this year.
+type rollupMetrics struct {
+	TotalContacts        int64         `json:"total_contacts"`
+	NewContacts          int64         `json:"new_contacts"`
+	AppointmentsThisWeek int64         `json:"appointments_this_week"`
+	TasksDueToday        int64         `json:"tasks_due_today"`
+	OpenDeals            int64         `json:"open_deals"`
+	OpenVolume           int64         `json:"open_volume"`
+	ClosedDeals          int64         `json:"closed_deals"`
+	ClosedVolume         int64         `json:"closed_volume"`
+	Commission           float64       `json:"commission"`
+	ContactsBySource     []sourceCount `json:"contacts_by_source"`
+}
+
+// sourceCount is how many contacts came from a source. Contacts without a
+// source have an empty one.
+type sourceCount struct {
+	Source       string `json:"source"`
+	ContactCount int64  `json:"contact_count"`
+}
+
+type agentRollup struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	Role   string    `json:"role"`
+	rollupMetrics
+}
+
+func (m *rollupMetrics) add(o rollupMetrics) {
+	m.TotalContacts += o.TotalContacts
+	m.NewContacts += o.NewContacts
+	m.AppointmentsThisWeek += o.AppointmentsThisWeek
+	m.TasksDueToday += o.TasksDueToday
+	m.OpenDeals += o.OpenDeals
+	m.OpenVolume += o.OpenVolume
+	m.ClosedDeals += o.ClosedDeals
+	m.ClosedVolume += o.ClosedVolume
+	m.Commission = math.Round((m.Commission+o.Commission)*100) / 100
+}
+
+// GetOrganizationRollup reports the dashboard metrics, deal volume and
+// commission of every member of an organization and of the organization as
+// a whole. Only the organization's admins may see it. Every metric can be
+// drilled into with GetOrganizationRollupRecords. "Today", "this week",
+// "this month" and "this year" are those of the viewer's time zone.
+func (cfg *apiCfg) GetOrganizationRollup(w http.ResponseWriter, r *http.Request) {
+	orgUUID, err := GetUUIDFromUrl("organizationID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid organization ID", err)
+		return
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+	loc, err := cfg.userLocation(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	now := time.Now()
+	week, today := timezone.Week(now, loc), timezone.Day(now, loc)
+	rows, err := cfg.DB.GetOrganizationRollup(r.Context(), database.GetOrganizationRollupParams{
+		MonthStart:     timezone.Month(now, loc).Start,
+		WeekStart:      week.Start,
+		WeekEnd:        week.End,
+		DayStart:       today.Start,
+		DayEnd:         today.End,
+		YearStart:      timezone.Year(now, loc).Start,
+		OrganizationID: orgUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve rollup", err)
+		return
+	}
+	sources, err := cfg.DB.GetOrganizationContactsBySource(r.Context(), orgUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve contacts by source", err)
+		return
+	}
+
+	// Rows are sorted by count, so each agent's sources stay sorted too
+	agentSources := map[uuid.UUID][]sourceCount{}
+	totalSources := map[string]int64{}
+	for _, s := range sources {
+		agentSources[s.UserID.UUID] = append(agentSources[s.UserID.UUID], sourceCount{
+			Source:       s.Source.String,
+			ContactCount: s.ContactCount,
+		})
+		totalSources[s.Source.String] += s.ContactCount
+	}
+
+	type response struct {
+		OrganizationID uuid.UUID     `json:"organization_id"`
+		Totals         rollupMetrics `json:"totals"`
+		Agents         []agentRollup `json:"agents"`
+	}
+	resp := response{
+		OrganizationID: orgUUID,
+		Agents:         make([]agentRollup, 0, len(rows)),
+	}
+	for _, row := range rows {
+		agent := agentRollup{
+			UserID: row.UserID,
+			Name:   row.Name,
+			Email:  row.Email,
+			Role:   row.Role,
+			rollupMetrics: rollupMetrics{
+				TotalContacts:        row.TotalContacts,
+				NewContacts:          row.NewContacts,
+				AppointmentsThisWeek: row.AppointmentsThisWeek,
+				TasksDueToday:        row.TasksDueToday,
+				OpenDeals:            row.OpenDeals,
+				OpenVolume:           row.OpenVolume,
+				ClosedDeals:          row.ClosedDeals,
+				ClosedVolume:         row.ClosedVolume,
+				Commission:           row.Commission,
+				ContactsBySource:     agentSources[row.UserID],
+			},
+		}
+		if agent.ContactsBySource == nil {
+			agent.ContactsBySource = []sourceCount{}
+		}
+		resp.Totals.add(agent.rollupMetrics)
+		resp.Agents = append(resp.Agents, agent)
+	}
+
+	resp.Totals.ContactsBySource = make([]sourceCount, 0, len(totalSources))
+	for source, count := range totalSources {
+		resp.Totals.ContactsBySource = append(resp.Totals.ContactsBySource, sourceCount{Source: source, ContactCount: count})
+	}
+	slices.SortFunc(resp.Totals.ContactsBySource, func(a, b sourceCount) int {
+		return cmp.Or(cmp.Compare(b.ContactCount, a.ContactCount), cmp.Compare(a.Source, b.Source))
+	})
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// GetOrganizationRollupRecords lists the records behind one rollup metric,
+// named as in the rollup response. agent_id limits them to one member and
+// source picks the contacts_by_source entry, an empty source being contacts
+// without one. Results are paged with limit and offset.
+func (cfg *apiCfg) GetOrganizationRollupRecords(w http.ResponseWriter, r *http.Request) {
+	orgUUID, err := GetUUIDFromUrl("organizationID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid organization ID", err)
+		return
+	}
+
+	query := r.URL.Query()
+	var agent uuid.NullUUID
+	if id := query.Get("agent_id"); id != "" {
+		agent.UUID, err = uuid.Parse(id)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid agent ID", err)
+			return
+		}
+		agent.Valid = true
+	}
+
+	limit, offset := defaultRollupPageSize, 0
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRollupPageSize {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit parameter", err)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid offset parameter", err)
+			return
+		}
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+	loc, err := cfg.userLocation(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+	now := time.Now()
+
+	var records any
+	switch metric := r.PathValue("metric"); metric {
+	case "total_contacts", "new_contacts", "contacts_by_source":
+		source := query.Get("source")
+		records, err = cfg.DB.ListRollupContacts(r.Context(), database.ListRollupContactsParams{
+			OrganizationID: orgUUID,
+			UserID:         agent,
+			NewOnly:        metric == "new_contacts",
+			MonthStart:     timezone.Month(now, loc).Start,
+			BySource:       metric == "contacts_by_source",
+			Source:         sql.NullString{String: source, Valid: source != ""},
+			RowLimit:       int32(limit),
+			RowOffset:      int32(offset),
+		})
+	case "appointments_this_week":
+		week := timezone.Week(now, loc)
+		records, err = cfg.DB.ListRollupAppointments(r.Context(), database.ListRollupAppointmentsParams{
+			OrganizationID: orgUUID,
+			UserID:         agent,
+			WeekStart:      week.Start,
+			WeekEnd:        week.End,
+			RowLimit:       int32(limit),
+			RowOffset:      int32(offset),
+		})
+	case "tasks_due_today":
+		today := timezone.Day(now, loc)
+		records, err = cfg.DB.ListRollupTasks(r.Context(), database.ListRollupTasksParams{
+			OrganizationID: orgUUID,
+			UserID:         agent,
+			DayStart:       today.Start,
+			DayEnd:         today.End,
+			RowLimit:       int32(limit),
+			RowOffset:      int32(offset),
+		})
+	case "open_deals", "open_volume", "closed_deals", "closed_volume", "commission":
+		records, err = cfg.DB.ListRollupDeals(r.Context(), database.ListRollupDealsParams{
+			OrganizationID: orgUUID,
+			UserID:         agent,
+			Closed:         metric != "open_deals" && metric != "open_volume",
+			YearStart:      timezone.Year(now, loc).Start,
+			RowLimit:       int32(limit),
+			RowOffset:      int32(offset),
+		})
+	default:
+		respondWithError(w, http.StatusNotFound, "Unknown metric "+strconv.Quote(metric), nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve records", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, records)
+}
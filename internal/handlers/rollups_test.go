@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func rollupRequest(orgID uuid.UUID, metric, query string) *http.Request {
+	path := "/api/organizations/" + orgID.String() + "/rollup"
+	if metric != "" {
+		path += "/" + metric
+	}
+	r := httptest.NewRequest(http.MethodGet, path+query, nil)
+	r.SetPathValue("organizationID", orgID.String())
+	r.SetPathValue("metric", metric)
+	return r
+}
+
+func TestOrganizationRollupIsForAdmins(t *testing.T) {
+	org, owner, admin, member, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		user   uuid.UUID
+		status int
+	}{
+		{"owner", owner, http.StatusOK},
+		{"admin", admin, http.StatusOK},
+		{"member", member, http.StatusForbidden},
+		{"outsider", outsider, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		for _, route := range []string{
+			"GET /api/organizations/{organizationID}/rollup",
+			"GET /api/organizations/{organizationID}/rollup/{metric}",
+		} {
+			t.Run(tt.name+" "+route, func(t *testing.T) {
+				cfg, db := newTestConfig(t)
+				db.stub("GetMemberRole", func(args []any) (any, error) {
+					switch args[0] {
+					case owner:
+						return "owner", nil
+					case admin:
+						return "admin", nil
+					case member:
+						return "member", nil
+					}
+					return nil, nil
+				})
+				db.stub("GetUserTimeZone", func(args []any) (any, error) {
+					return "America/Chicago", nil
+				})
+
+				handler := cfg.Authorize(route, map[string]http.HandlerFunc{
+					"GET /api/organizations/{organizationID}/rollup":          cfg.GetOrganizationRollup,
+					"GET /api/organizations/{organizationID}/rollup/{metric}": cfg.GetOrganizationRollupRecords,
+				}[route])
+				w := httptest.NewRecorder()
+				handler(w, asUser(rollupRequest(org, "total_contacts", ""), tt.user))
+
+				if w.Code != tt.status {
+					t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestGetOrganizationRollupAddsUpAgents(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	org, ann, bo := uuid.New(), uuid.New(), uuid.New()
+	db.stub("GetUserTimeZone", func(args []any) (any, error) {
+		return "America/Chicago", nil
+	})
+	db.stub("GetOrganizationRollup", func(args []any) (any, error) {
+		return []database.GetOrganizationRollupRow{
+			{UserID: ann, Name: "Ann", Role: "owner", TotalContacts: 10, OpenDeals: 2, OpenVolume: 500000, Commission: 1234.56},
+			{UserID: bo, Name: "Bo", Role: "member", TotalContacts: 5, ClosedDeals: 1, ClosedVolume: 300000, Commission: 0.01},
+		}, nil
+	})
+	source := func(user uuid.UUID, name string, count int64) database.GetOrganizationContactsBySourceRow {
+		return database.GetOrganizationContactsBySourceRow{
+			UserID:       uuid.NullUUID{UUID: user, Valid: true},
+			Source:       sql.NullString{String: name, Valid: name != ""},
+			ContactCount: count,
+		}
+	}
+	db.stub("GetOrganizationContactsBySource", func(args []any) (any, error) {
+		return []database.GetOrganizationContactsBySourceRow{
+			source(ann, "Zillow", 6),
+			source(ann, "", 4),
+			source(bo, "Referral", 3),
+			source(bo, "Zillow", 2),
+		}, nil
+	})
+
+	w := httptest.NewRecorder()
+	cfg.GetOrganizationRollup(w, asUser(rollupRequest(org, "", ""), ann))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Totals rollupMetrics `json:"totals"`
+		Agents []agentRollup `json:"agents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	totals := resp.Totals
+	if totals.TotalContacts != 15 || totals.OpenDeals != 2 || totals.ClosedVolume != 300000 {
+		t.Errorf("totals = %+v, want the sum of both agents", totals)
+	}
+	if totals.Commission != 1234.57 {
+		t.Errorf("total commission = %v, want 1234.57", totals.Commission)
+	}
+	want := []sourceCount{{"Zillow", 8}, {"", 4}, {"Referral", 3}}
+	if len(totals.ContactsBySource) != len(want) {
+		t.Fatalf("total sources = %+v, want %+v", totals.ContactsBySource, want)
+	}
+	for i := range want {
+		if totals.ContactsBySource[i] != want[i] {
+			t.Errorf("total sources = %+v, want %+v", totals.ContactsBySource, want)
+			break
+		}
+	}
+
+	if len(resp.Agents) != 2 {
+		t.Fatalf("got %d agents, want 2", len(resp.Agents))
+	}
+	if got := resp.Agents[1].ContactsBySource; len(got) != 2 || got[0] != (sourceCount{"Referral", 3}) {
+		t.Errorf("Bo's sources = %+v, want only his own", got)
+	}
+}
+
+func TestGetOrganizationRollupRecordsDrillsIntoMetric(t *testing.T) {
+	org, agent := uuid.New(), uuid.New()
+
+	tests := []struct {
+		metric string
+		query  string
+		check  func(t *testing.T, db *fakeDB)
+	}{
+		{"new_contacts", "", func(t *testing.T, db *fakeDB) {
+			args := db.callsTo("ListRollupContacts")[0]
+			if args[1] != (uuid.NullUUID{}) || args[2] != true || args[4] != false {
+				t.Errorf("listed contacts with %v, want every agent's new contacts", args)
+			}
+		}},
+		{"contacts_by_source", "?source=Zillow&agent_id=" + agent.String(), func(t *testing.T, db *fakeDB) {
+			args := db.callsTo("ListRollupContacts")[0]
+			if args[1] != (uuid.NullUUID{UUID: agent, Valid: true}) || args[4] != true ||
+				args[5] != (sql.NullString{String: "Zillow", Valid: true}) {
+				t.Errorf("listed contacts with %v, want the agent's Zillow contacts", args)
+			}
+		}},
+		{"open_volume", "?limit=10&offset=20", func(t *testing.T, db *fakeDB) {
+			args := db.callsTo("ListRollupDeals")[0]
+			if args[2] != false || args[4] != int32(10) || args[5] != int32(20) {
+				t.Errorf("listed deals with %v, want open deals 20 to 30", args)
+			}
+		}},
+		{"commission", "", func(t *testing.T, db *fakeDB) {
+			if args := db.callsTo("ListRollupDeals")[0]; args[2] != true {
+				t.Errorf("listed deals with %v, want closed deals", args)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			db.stub("GetUserTimeZone", func(args []any) (any, error) {
+				return "America/Chicago", nil
+			})
+
+			w := httptest.NewRecorder()
+			cfg.GetOrganizationRollupRecords(w, asUser(rollupRequest(org, tt.metric, tt.query), uuid.New()))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			tt.check(t, db)
+		})
+	}
+}
+
+func TestGetOrganizationRollupRecordsRejectsUnknownMetric(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	db.stub("GetUserTimeZone", func(args []any) (any, error) {
+		return "America/Chicago", nil
+	})
+
+	w := httptest.NewRecorder()
+	cfg.GetOrganizationRollupRecords(w, asUser(rollupRequest(uuid.New(), "deleted_contacts", ""), uuid.New()))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	for _, query := range []string{"ListRollupContacts", "ListRollupAppointments", "ListRollupTasks", "ListRollupDeals"} {
+		if got := len(db.callsTo(query)); got != 0 {
+			t.Errorf("ran %s %d times for an unknown metric", query, got)
+		}
+	}
+}
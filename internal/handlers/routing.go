@@ -0,0 +1,761 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/routing"
+	"github.com/google/uuid"
+)
+
+const leadRoutingPollInterval = 30 * time.Second
+
+// --------------------------------------------------------------
+// Routing
+// --------------------------------------------------------------
+
+// leadRouter routes the leads of one organization. It is loaded once per
+// request or import chunk and keeps track of what it assigned, so leads
+// routed together are spread like leads routed one at a time.
+type leadRouter struct {
+	cfg            *apiCfg
+	organizationID uuid.UUID
+	// rules are the enabled rules, in priority order
+	rules    []routing.Rule
+	timeouts map[uuid.UUID]sql.NullInt32
+}
+
+func (cfg *apiCfg) loadLeadRouter(ctx context.Context, q *database.Queries, organizationID uuid.UUID) (*leadRouter, error) {
+	rules, err := q.ListRoutingRules(ctx, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("list routing rules: %w", err)
+	}
+	agents, err := q.ListRoutingRuleAgents(ctx, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("list routing rule agents: %w", err)
+	}
+
+	byRule := map[uuid.UUID][]routing.Agent{}
+	for _, a := range agents {
+		byRule[a.RuleID] = append(byRule[a.RuleID], routing.Agent{
+			UserID:       a.UserID,
+			Capacity:     int(a.Capacity),
+			Assigned:     a.AssignedCount,
+			LastAssigned: a.LastAssignedAt.Time,
+		})
+	}
+
+	lr := &leadRouter{cfg: cfg, organizationID: organizationID, timeouts: map[uuid.UUID]sql.NullInt32{}}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		lr.rules = append(lr.rules, routing.Rule{
+			ID:       rule.ID,
+			Strategy: routing.Strategy(rule.Strategy),
+			ZipCodes: rule.ZipCodes,
+			Cities:   rule.Cities,
+			Sources:  rule.Sources,
+			Agents:   byRule[rule.ID],
+		})
+		lr.timeouts[rule.ID] = rule.ClaimTimeoutMinutes
+	}
+	return lr, nil
+}
+
+// route assigns a new lead with the first matching rule. Leads no rule
+// matches keep the owner they were created with.
+func (lr *leadRouter) route(ctx context.Context, q *database.Queries, contact database.Contact) error {
+	lead := routing.Lead{
+		ZipCode: contact.ZipCode.String,
+		City:    contact.City.String,
+		Source:  contact.Source.String,
+	}
+	for i := range lr.rules {
+		rule := &lr.rules[i]
+		if len(rule.Agents) == 0 || !rule.Matches(lead) {
+			continue
+		}
+		if rule.Strategy == routing.FirstToClaim {
+			return lr.offer(ctx, q, rule, contact.ID)
+		}
+		userID, _ := rule.Pick(nil)
+		return lr.assign(ctx, q, rule, contact.ID, userID, "A new lead has been assigned to you.")
+	}
+	return nil
+}
+
+// assign gives the lead to an agent of rule and notifies them.
+func (lr *leadRouter) assign(ctx context.Context, q *database.Queries, rule *routing.Rule, contactID, userID uuid.UUID, message string) error {
+	if err := lr.setOwner(ctx, q, contactID, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		return err
+	}
+
+	err := q.RecordRoutingRuleAssignment(ctx, database.RecordRoutingRuleAssignmentParams{
+		RuleID: rule.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("record assignment: %w", err)
+	}
+	rule.Assigned(userID, time.Now())
+
+	if err := lr.createAssignment(ctx, q, rule.ID, contactID, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		return err
+	}
+
+	_, err = q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:    userID,
+		Type:      "lead_assigned",
+		Message:   message,
+		ContactID: uuid.NullUUID{UUID: contactID, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("create notification: %w", err)
+	}
+	return nil
+}
+
+// offer leaves the lead without an owner and lets every agent of rule know
+// they can claim it.
+func (lr *leadRouter) offer(ctx context.Context, q *database.Queries, rule *routing.Rule, contactID uuid.UUID) error {
+	if err := lr.setOwner(ctx, q, contactID, uuid.NullUUID{}); err != nil {
+		return err
+	}
+
+	if err := lr.createAssignment(ctx, q, rule.ID, contactID, uuid.NullUUID{}); err != nil {
+		return err
+	}
+
+	for _, agent := range rule.Agents {
+		_, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:    agent.UserID,
+			Type:      "lead_offered",
+			Message:   "A new lead is available. Claim it before another agent does.",
+			ContactID: uuid.NullUUID{UUID: contactID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("create notification: %w", err)
+		}
+	}
+	return nil
+}
+
+// setOwner changes the lead's owner and records the change in the contact's
+// history and the audit log, like any other change of owner.
+func (lr *leadRouter) setOwner(ctx context.Context, q *database.Queries, contactID uuid.UUID, ownerID uuid.NullUUID) error {
+	before, err := lr.cfg.auditSnapshot(ctx, q, authz.KindContact, contactID)
+	if err != nil {
+		return err
+	}
+	err = q.SetLeadOwner(ctx, database.SetLeadOwnerParams{
+		ID:      contactID,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		return fmt.Errorf("set lead owner: %w", err)
+	}
+	if err := lr.cfg.recordFieldHistory(ctx, q, authz.KindContact, contactID, before); err != nil {
+		return fmt.Errorf("record contact history: %w", err)
+	}
+	return lr.cfg.recordAudit(ctx, q, authz.KindContact, contactID, audit.Update, before)
+}
+
+func (lr *leadRouter) createAssignment(ctx context.Context, q *database.Queries, ruleID, contactID uuid.UUID, userID uuid.NullUUID) error {
+	var expiresAt sql.NullTime
+	if timeout := lr.timeouts[ruleID]; timeout.Valid {
+		expiresAt = sql.NullTime{Time: time.Now().Add(time.Duration(timeout.Int32) * time.Minute), Valid: true}
+	}
+
+	_, err := q.CreateLeadAssignment(ctx, database.CreateLeadAssignmentParams{
+		ContactID:      contactID,
+		OrganizationID: lr.organizationID,
+		RuleID:         uuid.NullUUID{UUID: ruleID, Valid: true},
+		UserID:         userID,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("create lead assignment: %w", err)
+	}
+	return nil
+}
+
+// rule returns the enabled rule with the ID.
+func (lr *leadRouter) rule(id uuid.UUID) (*routing.Rule, bool) {
+	for i := range lr.rules {
+		if lr.rules[i].ID == id {
+			return &lr.rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// routeLeadsOption reads whether an import asked for its contacts to be
+// routed with the active organization's rules. It writes the error response
+// and returns false when the option can't be used.
+func routeLeadsOption(w http.ResponseWriter, value string, orgID uuid.NullUUID) (bool, bool) {
+	if value == "" {
+		return false, true
+	}
+	route, err := strconv.ParseBool(value)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid route_leads value", err)
+		return false, false
+	}
+	if route && !orgID.Valid {
+		respondWithError(w, http.StatusBadRequest, "No active organization to route leads with", nil)
+		return false, false
+	}
+	return route, true
+}
+
+// --------------------------------------------------------------
+// Reassignment worker
+// --------------------------------------------------------------
+
+// StartLeadRoutingWorker reassigns leads nobody claimed within their rule's
+// claim timeout until ctx is cancelled. A lead moves to the rule's agent who
+// is next in line among those it hasn't been assigned to yet; once every
+// agent has had it, it stays with the last one.
+func (cfg *apiCfg) StartLeadRoutingWorker(ctx context.Context) {
+	ticker := time.NewTicker(leadRoutingPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for cfg.reassignExpiredLead(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reassignExpiredLead expires one overdue assignment and reports whether
+// there may be more.
+func (cfg *apiCfg) reassignExpiredLead(ctx context.Context) bool {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		cfg.logger.Error("Failed to start lead reassignment", "error", err)
+		return false
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	expired, err := qtx.ExpireLeadAssignment(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		cfg.logger.Error("Failed to expire lead assignment", "error", err)
+		return false
+	}
+	logger := cfg.logger.With("assignment_id", expired.ID, "contact_id", expired.ContactID)
+
+	if err := cfg.reassignLead(ctx, qtx, expired); err != nil {
+		logger.Error("Failed to reassign lead", "error", err)
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit lead reassignment", "error", err)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiCfg) reassignLead(ctx context.Context, qtx *database.Queries, expired database.LeadAssignment) error {
+	lr, err := cfg.loadLeadRouter(ctx, qtx, expired.OrganizationID)
+	if err != nil {
+		return err
+	}
+	// The rule was deleted or disabled since, so the lead stays where it is
+	rule, ok := lr.rule(expired.RuleID.UUID)
+	if !expired.RuleID.Valid || !ok {
+		return nil
+	}
+
+	assignees, err := qtx.ListLeadAssignees(ctx, expired.ContactID)
+	if err != nil {
+		return fmt.Errorf("list lead assignees: %w", err)
+	}
+	exclude := map[uuid.UUID]bool{}
+	for _, a := range assignees {
+		exclude[a.UUID] = true
+	}
+
+	userID, ok := rule.Pick(exclude)
+	if !ok {
+		return nil
+	}
+	return lr.assign(ctx, qtx, rule, expired.ContactID, userID, "A lead nobody claimed in time has been assigned to you.")
+}
+
+// --------------------------------------------------------------
+// Rules
+// --------------------------------------------------------------
+
+type routingRuleAgentRequest struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Capacity int       `json:"capacity"`
+}
+
+type routingRuleRequest struct {
+	Name                string                    `json:"name"`
+	Priority            int                       `json:"priority"`
+	Strategy            string                    `json:"strategy"`
+	ZipCodes            []string                  `json:"zip_codes"`
+	Cities              []string                  `json:"cities"`
+	Sources             []string                  `json:"sources"`
+	ClaimTimeoutMinutes int                       `json:"claim_timeout_minutes"`
+	Enabled             *bool                     `json:"enabled"`
+	Agents              []routingRuleAgentRequest `json:"agents"`
+}
+
+type routingRuleResponse struct {
+	database.RoutingRule
+	Agents []database.RoutingRuleAgent
+}
+
+// validate checks the request and fills in defaults. It writes the error
+// response and returns false when the rule can't be saved.
+func (req *routingRuleRequest) validate(w http.ResponseWriter) bool {
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required", nil)
+		return false
+	}
+	if !routing.ValidStrategy(req.Strategy) {
+		respondWithError(w, http.StatusBadRequest, `Invalid strategy. Use "round_robin", "weighted" or "first_to_claim"`, nil)
+		return false
+	}
+	if req.ClaimTimeoutMinutes < 0 {
+		respondWithError(w, http.StatusBadRequest, "Claim timeout can't be negative", nil)
+		return false
+	}
+	seen := map[uuid.UUID]bool{}
+	for i, agent := range req.Agents {
+		if seen[agent.UserID] {
+			respondWithError(w, http.StatusBadRequest, "Agents can only be listed once", nil)
+			return false
+		}
+		seen[agent.UserID] = true
+		if agent.Capacity < 0 {
+			respondWithError(w, http.StatusBadRequest, "Agent capacity can't be negative", nil)
+			return false
+		}
+		if agent.Capacity == 0 {
+			req.Agents[i].Capacity = 1
+		}
+	}
+	if req.ZipCodes == nil {
+		req.ZipCodes = []string{}
+	}
+	if req.Cities == nil {
+		req.Cities = []string{}
+	}
+	if req.Sources == nil {
+		req.Sources = []string{}
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return true
+}
+
+func (req *routingRuleRequest) claimTimeout() sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(req.ClaimTimeoutMinutes), Valid: req.ClaimTimeoutMinutes > 0}
+}
+
+// setRoutingRuleAgents replaces the agents of a rule, keeping what the rule
+// assigned to the agents that stay. It reports false when an agent isn't a
+// member of the organization.
+func setRoutingRuleAgents(ctx context.Context, qtx *database.Queries, rule database.RoutingRule, agents []routingRuleAgentRequest) (bool, error) {
+	userIDs := make([]uuid.UUID, 0, len(agents))
+	for _, agent := range agents {
+		userIDs = append(userIDs, agent.UserID)
+	}
+
+	members, err := qtx.CountOrganizationMembers(ctx, database.CountOrganizationMembersParams{
+		OrganizationID: rule.OrganizationID,
+		UserIds:        userIDs,
+	})
+	if err != nil {
+		return false, err
+	}
+	if members != int64(len(userIDs)) {
+		return false, nil
+	}
+
+	err = qtx.RemoveOtherRoutingRuleAgents(ctx, database.RemoveOtherRoutingRuleAgentsParams{
+		RuleID:  rule.ID,
+		UserIds: userIDs,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, agent := range agents {
+		err = qtx.SetRoutingRuleAgent(ctx, database.SetRoutingRuleAgentParams{
+			RuleID:   rule.ID,
+			UserID:   agent.UserID,
+			Capacity: int32(agent.Capacity),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// respondWithRoutingRule responds with the rule and its agents as saved.
+func (cfg *apiCfg) respondWithRoutingRule(w http.ResponseWriter, r *http.Request, code int, rule database.RoutingRule) {
+	agents, err := cfg.DB.ListRoutingRuleAgents(r.Context(), rule.OrganizationID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve routing rule agents", err)
+		return
+	}
+
+	response := routingRuleResponse{RoutingRule: rule, Agents: []database.RoutingRuleAgent{}}
+	for _, agent := range agents {
+		if agent.RuleID == rule.ID {
+			response.Agents = append(response.Agents, agent)
+		}
+	}
+	respondWithJSON(w, code, response)
+}
+
+func (cfg *apiCfg) GetRoutingRules(w http.ResponseWriter, r *http.Request) {
+	orgUUID, err := GetUUIDFromUrl("organizationID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid organization ID", err)
+		return
+	}
+
+	rules, err := cfg.DB.ListRoutingRules(r.Context(), orgUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve routing rules", err)
+		return
+	}
+	agents, err := cfg.DB.ListRoutingRuleAgents(r.Context(), orgUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve routing rule agents", err)
+		return
+	}
+
+	byRule := map[uuid.UUID][]database.RoutingRuleAgent{}
+	for _, agent := range agents {
+		byRule[agent.RuleID] = append(byRule[agent.RuleID], agent)
+	}
+
+	response := make([]routingRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		ruleAgents := byRule[rule.ID]
+		if ruleAgents == nil {
+			ruleAgents = []database.RoutingRuleAgent{}
+		}
+		response = append(response, routingRuleResponse{RoutingRule: rule, Agents: ruleAgents})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateRoutingRule adds a rule to an organization. Rules are tried in
+// ascending priority; every condition left empty matches all leads.
+func (cfg *apiCfg) CreateRoutingRule(w http.ResponseWriter, r *http.Request) {
+	orgUUID, err := GetUUIDFromUrl("organizationID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid organization ID", err)
+		return
+	}
+
+	var req routingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	rule, err := qtx.CreateRoutingRule(r.Context(), database.CreateRoutingRuleParams{
+		OrganizationID:      orgUUID,
+		Name:                req.Name,
+		Priority:            int32(req.Priority),
+		Strategy:            req.Strategy,
+		ZipCodes:            req.ZipCodes,
+		Cities:              req.Cities,
+		Sources:             req.Sources,
+		ClaimTimeoutMinutes: req.claimTimeout(),
+		Enabled:             *req.Enabled,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create routing rule", err)
+		return
+	}
+
+	ok, err := setRoutingRuleAgents(r.Context(), qtx, rule, req.Agents)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to set routing rule agents", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Every agent must be a member of the organization", nil)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindRoutingRule, rule.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithRoutingRule(w, r, http.StatusCreated, rule)
+}
+
+// UpdateRoutingRule replaces a rule and its agents. Agents who stay on the
+// rule keep their place in its rotation.
+func (cfg *apiCfg) UpdateRoutingRule(w http.ResponseWriter, r *http.Request) {
+	ruleUUID, err := GetUUIDFromUrl("ruleID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid routing rule ID", err)
+		return
+	}
+
+	var req routingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindRoutingRule, ruleUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	rule, err := qtx.UpdateRoutingRule(r.Context(), database.UpdateRoutingRuleParams{
+		ID:                  ruleUUID,
+		Name:                req.Name,
+		Priority:            int32(req.Priority),
+		Strategy:            req.Strategy,
+		ZipCodes:            req.ZipCodes,
+		Cities:              req.Cities,
+		Sources:             req.Sources,
+		ClaimTimeoutMinutes: req.claimTimeout(),
+		Enabled:             *req.Enabled,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Routing rule not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update routing rule", err)
+		return
+	}
+
+	ok, err := setRoutingRuleAgents(r.Context(), qtx, rule, req.Agents)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to set routing rule agents", err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Every agent must be a member of the organization", nil)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindRoutingRule, ruleUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	cfg.respondWithRoutingRule(w, r, http.StatusOK, rule)
+}
+
+func (cfg *apiCfg) DeleteRoutingRule(w http.ResponseWriter, r *http.Request) {
+	ruleUUID, err := GetUUIDFromUrl("ruleID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid routing rule ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindRoutingRule, ruleUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	if err := qtx.DeleteRoutingRule(r.Context(), ruleUUID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete routing rule", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindRoutingRule, ruleUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// --------------------------------------------------------------
+// Leads
+// --------------------------------------------------------------
+
+// GetPendingLeads lists the leads assigned or offered to the caller that
+// they haven't claimed yet.
+func (cfg *apiCfg) GetPendingLeads(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	leads, err := cfg.DB.ListPendingLeadAssignments(r.Context(), uuid.NullUUID{UUID: userUUID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve pending leads", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, leads)
+}
+
+// ClaimLead accepts a lead assigned to the caller, which stops it from
+// being reassigned, or takes a lead offered to every agent of a first to
+// claim rule, which makes the caller its owner.
+func (cfg *apiCfg) ClaimLead(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	assignmentUUID, err := GetUUIDFromUrl("assignmentID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid assignment ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindLeadAssignment, assignmentUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	assignment, err := qtx.ClaimLeadAssignment(r.Context(), database.ClaimLeadAssignmentParams{
+		UserID: uuid.NullUUID{UUID: userUUID, Valid: true},
+		ID:     assignmentUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Lead is no longer available to claim", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to claim lead", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindLeadAssignment, assignmentUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if !assignment.UserID.Valid {
+		contactBefore, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, assignment.ContactID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		err = qtx.SetLeadOwner(r.Context(), database.SetLeadOwnerParams{
+			ID:      assignment.ContactID,
+			OwnerID: uuid.NullUUID{UUID: userUUID, Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to set lead owner", err)
+			return
+		}
+		err = cfg.recordFieldHistory(r.Context(), qtx, authz.KindContact, assignment.ContactID, contactBefore)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record contact history", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, assignment.ContactID, audit.Update, contactBefore); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		if assignment.RuleID.Valid {
+			err = qtx.RecordRoutingRuleAssignment(r.Context(), database.RecordRoutingRuleAssignmentParams{
+				RuleID: assignment.RuleID.UUID,
+				UserID: userUUID,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to record assignment", err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, assignment)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestReassignExpiredLeadRecordsTheOwnerChange(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	org, rule, contact := uuid.New(), uuid.New(), uuid.New()
+	first, next := uuid.New(), uuid.New()
+	db.stub("ExpireLeadAssignment", func(args []any) (any, error) {
+		return database.LeadAssignment{
+			ID:             uuid.New(),
+			ContactID:      contact,
+			OrganizationID: org,
+			RuleID:         uuid.NullUUID{UUID: rule, Valid: true},
+			UserID:         uuid.NullUUID{UUID: first, Valid: true},
+			Status:         "expired",
+		}, nil
+	})
+	db.stub("ListRoutingRules", func(args []any) (any, error) {
+		return database.RoutingRule{ID: rule, OrganizationID: org, Strategy: "round_robin", Enabled: true}, nil
+	})
+	db.stub("ListRoutingRuleAgents", func(args []any) (any, error) {
+		return []database.RoutingRuleAgent{{RuleID: rule, UserID: first}, {RuleID: rule, UserID: next}}, nil
+	})
+	db.stub("ListLeadAssignees", func(args []any) (any, error) {
+		return uuid.NullUUID{UUID: first, Valid: true}, nil
+	})
+	db.stub("CreateLeadAssignment", func(args []any) (any, error) {
+		return database.LeadAssignment{ID: uuid.New(), ContactID: contact, OrganizationID: org}, nil
+	})
+	db.stub("CreateNotification", func(args []any) (any, error) {
+		return database.Notification{ID: uuid.New(), UserID: args[0].(uuid.UUID)}, nil
+	})
+	// The contact before and after SetLeadOwner
+	owner := first
+	db.stub("SetLeadOwner", func(args []any) (any, error) {
+		owner = args[1].(uuid.NullUUID).UUID
+		return nil, nil
+	})
+	db.stub("GetAuditSnapshot", func(args []any) (any, error) {
+		return json.RawMessage(`{"first_name": "Ann", "owner_id": "` + owner.String() + `"}`), nil
+	})
+
+	if more := cfg.reassignExpiredLead(context.Background()); !more {
+		t.Fatal("reassignExpiredLead() = false, want true after reassigning a lead")
+	}
+
+	if owner != next {
+		t.Fatalf("lead owned by %v, want the next agent %v", owner, next)
+	}
+	history := db.callsTo("CreateFieldHistory")
+	if len(history) != 1 || history[0][0] != (uuid.NullUUID{UUID: contact, Valid: true}) || history[0][2] != "owner_id" {
+		t.Errorf("recorded history %v, want the contact's owner change", history)
+	}
+	events := db.callsTo("CreateAuditEvent")
+	if len(events) != 1 || events[0][2] != "contact" || events[0][3] != contact || events[0][4] != "update" {
+		t.Errorf("recorded audit events %v, want the contact's update", events)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
@@ -3,9 +3,13 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/smartlist"
 	"github.com/google/uuid"
 	"github.com/sqlc-dev/pqtype"
 )
@@ -18,7 +22,11 @@ func (cfg *apiCfg) GetAllSmartLists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	smartList, err := cfg.DB.GetAllSmartLists(r.Context(), uuid.NullUUID{UUID: userUUID, Valid: true})
+	orgID, _ := GetActiveOrganization(r.Context())
+	smartList, err := cfg.DB.GetAllSmartLists(r.Context(), database.GetAllSmartListsParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		OrganizationID: orgID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get smart lists", err)
 		return
@@ -38,6 +46,7 @@ func (cfg *apiCfg) CreateSmartList(w http.ResponseWriter, r *http.Request) {
 	type SmartListRequest struct {
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		Scope       string `json:"scope"`
 	}
 
 	var smartListReq SmartListRequest
@@ -46,15 +55,46 @@ func (cfg *apiCfg) CreateSmartList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	smartList, err := cfg.DB.CreateSmartList(r.Context(), database.CreateSmartListParams{
-		UserID:      uuid.NullUUID{UUID: userUUID, Valid: true},
-		Name:        smartListReq.Name,
-		Description: sql.NullString{String: smartListReq.Description, Valid: smartListReq.Description != ""},
+	orgID, ok := definitionScope(w, r, smartListReq.Scope)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	smartList, err := qtx.CreateSmartList(r.Context(), database.CreateSmartListParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		Name:           smartListReq.Name,
+		Description:    sql.NullString{String: smartListReq.Description, Valid: smartListReq.Description != ""},
+		OrganizationID: orgID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create smart list", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindSmartList, smartList.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Rebuild membership right away so counts match the new filter. Contacts
+	// matched by a changed filter don't trigger notifications.
+	if err := cfg.refreshSmartListMembers(r.Context(), tx, smartList, nil, false); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh smart list members", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, smartList)
 }
@@ -77,17 +117,55 @@ func (cfg *apiCfg) SetSmartListFilterCriteria(w http.ResponseWriter, r *http.Req
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
-	cfg.logger.Info("Received Filter Criteria: ", "Criteria:", string(filterCriteriaReq.FilterCriteria))
-	cfg.logger.Info("Filter Criteria Length: ", "RawMessage", filterCriteriaReq.FilterCriteria)
 
-	smartList, err := cfg.DB.SetSmartListFilterCriteria(r.Context(), database.SetSmartListFilterCriteriaParams{
+	filterCriteria, err := normalizeFilterCriteria(filterCriteriaReq.FilterCriteria)
+	if err != nil {
+		respondWithFilterError(w, err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindSmartList, smartListUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	smartList, err := qtx.SetSmartListFilterCriteria(r.Context(), database.SetSmartListFilterCriteriaParams{
 		ID:             smartListUUID,
-		FilterCriteria: pqtype.NullRawMessage{RawMessage: filterCriteriaReq.FilterCriteria, Valid: len(filterCriteriaReq.FilterCriteria) > 0},
+		FilterCriteria: filterCriteria,
 	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Smart list not found", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to set filter criteria", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindSmartList, smartListUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Rebuild membership right away so counts match the new filter. Contacts
+	// matched by a changed filter don't trigger notifications.
+	if err := cfg.refreshSmartListMembers(r.Context(), tx, smartList, nil, false); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh smart list members", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	cfg.logger.Info("Updated Smart List Criteria: ", "Criteria:", string(smartList.FilterCriteria.RawMessage))
 	respondWithJSON(w, http.StatusOK, smartList)
@@ -113,16 +191,88 @@ func (cfg *apiCfg) UpdateSmartList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	smartList, err := cfg.DB.UpdateSmartList(r.Context(), database.UpdateSmartListParams{
+	filterCriteria, err := normalizeFilterCriteria(updateSmartListReq.FilterCriteria)
+	if err != nil {
+		respondWithFilterError(w, err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindSmartList, smartListUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	smartList, err := qtx.UpdateSmartList(r.Context(), database.UpdateSmartListParams{
 		ID:             smartListUUID,
 		Name:           updateSmartListReq.Name,
 		Description:    sql.NullString{String: updateSmartListReq.Description, Valid: updateSmartListReq.Description != ""},
-		FilterCriteria: pqtype.NullRawMessage{RawMessage: updateSmartListReq.FilterCriteria, Valid: len(updateSmartListReq.FilterCriteria) > 0},
+		FilterCriteria: filterCriteria,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update smart list", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindSmartList, smartListUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Rebuild membership right away so counts match the new filter. Contacts
+	// matched by a changed filter don't trigger notifications.
+	if err := cfg.refreshSmartListMembers(r.Context(), tx, smartList, nil, false); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh smart list members", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, smartList)
 }
+
+// normalizeFilterCriteria validates a submitted filter and re-encodes it so
+// only the canonical form is stored. An absent filter is stored as NULL,
+// which matches every contact.
+func normalizeFilterCriteria(raw json.RawMessage) (pqtype.NullRawMessage, error) {
+	if len(raw) == 0 {
+		return pqtype.NullRawMessage{}, nil
+	}
+	filter, err := smartlist.Parse(raw)
+	if err != nil {
+		return pqtype.NullRawMessage{}, err
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return pqtype.NullRawMessage{}, err
+	}
+	return pqtype.NullRawMessage{RawMessage: data, Valid: true}, nil
+}
+
+// respondWithFilterError reports every problem in a rejected filter along
+// with the path of the rule it belongs to.
+func respondWithFilterError(w http.ResponseWriter, err error) {
+	var verr smartlist.ValidationError
+	if !errors.As(err, &verr) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to encode filter criteria", err)
+		return
+	}
+	type filterErrorResponse struct {
+		Error  string                 `json:"error"`
+		Fields []smartlist.FieldError `json:"fields"`
+	}
+	respondWithJSON(w, http.StatusBadRequest, filterErrorResponse{
+		Error:  "Invalid filter criteria",
+		Fields: verr,
+	})
+}
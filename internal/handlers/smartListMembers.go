@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/smartlist"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	smartListPollInterval = 5 * time.Second
+	// Lists are fully re-evaluated this often so rules relative to the
+	// current time pick up contacts that haven't changed
+	smartListRefreshInterval = 5 * time.Minute
+	smartListDirtyBatchSize  = 500
+)
+
+// Smart list filters are compiled at runtime, so the membership refresh can't
+// be generated by sqlc. $1 is the list owner, $2 the list, $3 an optional set
+// of contacts to limit the refresh to and $4 the list's organization; %s is
+// replaced by the compiled filter, whose parameters start at $5. Personal
+// lists match the owner's contacts and shared lists the organization's;
+// deleted contacts match neither.
+const refreshSmartListMembersQuery = `WITH matched AS (
+    SELECT
+        c.id
+    FROM
+        contacts c
+    WHERE
+        (
+            (
+                $4::uuid IS NULL
+                AND (
+                    c.owner_id = $1
+                    OR EXISTS (
+                        SELECT
+                            1
+                        FROM
+                            collaborators col
+                        WHERE
+                            col.contact_id = c.id
+                            AND col.user_id = $1
+                    )
+                )
+            )
+            OR c.organization_id = $4::uuid
+        )
+        AND c.deleted_at IS NULL
+        AND (
+            $3::uuid[] IS NULL
+            OR c.id = ANY($3::uuid[])
+        )
+        AND %s
+),
+removed AS (
+    DELETE FROM
+        smart_list_members m
+    WHERE
+        m.smart_list_id = $2
+        AND (
+            $3::uuid[] IS NULL
+            OR m.contact_id = ANY($3::uuid[])
+        )
+        AND m.contact_id NOT IN (
+            SELECT
+                id
+            FROM
+                matched
+        )
+)
+INSERT INTO
+    smart_list_members (smart_list_id, contact_id)
+SELECT
+    $2,
+    id
+FROM
+    matched
+ON CONFLICT (smart_list_id, contact_id) DO NOTHING
+RETURNING
+    contact_id`
+
+// refreshSmartListMembers re-evaluates a list's filter and syncs
+// smart_list_members. With contactIDs set only those contacts are checked,
+// otherwise the whole list is rebuilt. Subscribers are notified about
+// contacts that entered the list when notify is set.
+func (cfg *apiCfg) refreshSmartListMembers(ctx context.Context, tx *sql.Tx, list database.SmartList, contactIDs []uuid.UUID, notify bool) error {
+	filter, err := smartlist.Parse(list.FilterCriteria.RawMessage)
+	if err != nil {
+		return err
+	}
+	where, filterArgs, err := filter.Compile(4)
+	if err != nil {
+		return err
+	}
+
+	var scope any
+	if contactIDs != nil {
+		scope = pq.Array(contactIDs)
+	}
+	args := append([]any{list.UserID, list.ID, scope, list.OrganizationID}, filterArgs...)
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(refreshSmartListMembersQuery, where), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var added []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		added = append(added, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	qtx := cfg.DB.WithTx(tx)
+	if contactIDs == nil {
+		if err := qtx.SetSmartListMembersRefreshed(ctx, list.ID); err != nil {
+			return err
+		}
+	}
+	if notify && len(added) > 0 {
+		if _, err := qtx.NotifySmartListSubscribers(ctx, database.NotifySmartListSubscribersParams{
+			ContactIds:  added,
+			SmartListID: list.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartSmartListWorker keeps smart list membership current. Changes to
+// contacts, tags, deals, logs and collaborators are queued by database
+// triggers; the worker re-evaluates the affected contacts and periodically
+// rebuilds every list. Rows are claimed with SKIP LOCKED so several instances
+// can run the worker side by side.
+func (cfg *apiCfg) StartSmartListWorker(ctx context.Context) {
+	ticker := time.NewTicker(smartListPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for cfg.refreshDirtyContacts(ctx) {
+		}
+		for cfg.refreshStaleSmartList(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDirtyContacts processes one batch of queued contacts and reports
+// whether there may be more.
+func (cfg *apiCfg) refreshDirtyContacts(ctx context.Context) bool {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		cfg.logger.Error("Failed to start smart list refresh", "error", err)
+		return false
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	contactIDs, err := qtx.ClaimDirtySmartListContacts(ctx, smartListDirtyBatchSize)
+	if err != nil {
+		cfg.logger.Error("Failed to claim changed contacts", "error", err)
+		return false
+	}
+	if len(contactIDs) == 0 {
+		return false
+	}
+
+	lists, err := qtx.ListSmartListsForContacts(ctx, contactIDs)
+	if err != nil {
+		cfg.logger.Error("Failed to get smart lists for changed contacts", "error", err)
+		return false
+	}
+
+	for _, list := range lists {
+		// Lists that were never built are left to the full refresh, which
+		// doesn't notify about the initial members
+		if !list.MembersRefreshedAt.Valid {
+			continue
+		}
+		err := cfg.refreshSmartListMembers(ctx, tx, list, contactIDs, true)
+		var verr smartlist.ValidationError
+		if errors.As(err, &verr) {
+			cfg.logger.Warn("Skipping smart list with invalid filter", "smart_list_id", list.ID, "error", err)
+			continue
+		}
+		if err != nil {
+			// Rolling back puts the contacts back in the queue
+			cfg.logger.Error("Failed to refresh smart list", "smart_list_id", list.ID, "error", err)
+			return false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		cfg.logger.Error("Failed to commit smart list refresh", "error", err)
+		return false
+	}
+	return len(contactIDs) == smartListDirtyBatchSize
+}
+
+// refreshStaleSmartList rebuilds the list that has gone longest without a
+// full refresh and reports whether one was found.
+func (cfg *apiCfg) refreshStaleSmartList(ctx context.Context) bool {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		cfg.logger.Error("Failed to start smart list refresh", "error", err)
+		return false
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	list, err := qtx.ClaimStaleSmartList(ctx, time.Now().Add(-smartListRefreshInterval))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		cfg.logger.Error("Failed to claim smart list for refresh", "error", err)
+		return false
+	}
+
+	err = cfg.refreshSmartListMembers(ctx, tx, list, nil, list.MembersRefreshedAt.Valid)
+	var verr smartlist.ValidationError
+	if errors.As(err, &verr) {
+		// Keep the old members and try again next interval
+		cfg.logger.Warn("Skipping smart list with invalid filter", "smart_list_id", list.ID, "error", err)
+		err = qtx.SetSmartListMembersRefreshed(ctx, list.ID)
+	}
+	if err != nil {
+		cfg.logger.Error("Failed to refresh smart list", "smart_list_id", list.ID, "error", err)
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		cfg.logger.Error("Failed to commit smart list refresh", "error", err)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiCfg) SubscribeToSmartList(w http.ResponseWriter, r *http.Request) {
+	smartListUUID, err := GetUUIDFromUrl("smartListID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid smart list ID", err)
+		return
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	_, err = cfg.DB.GetSmartListByID(r.Context(), database.GetSmartListByIDParams{
+		ID:     smartListUUID,
+		UserID: uuid.NullUUID{UUID: userUUID, Valid: true},
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Smart list not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get smart list", err)
+		return
+	}
+
+	err = cfg.DB.SubscribeToSmartList(r.Context(), database.SubscribeToSmartListParams{
+		SmartListID: smartListUUID,
+		UserID:      userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to subscribe to smart list", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiCfg) UnsubscribeFromSmartList(w http.ResponseWriter, r *http.Request) {
+	smartListUUID, err := GetUUIDFromUrl("smartListID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid smart list ID", err)
+		return
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	removed, err := cfg.DB.UnsubscribeFromSmartList(r.Context(), database.UnsubscribeFromSmartListParams{
+		SmartListID: smartListUUID,
+		UserID:      userUUID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unsubscribe from smart list", err)
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "Subscription not found", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sqlc-dev/pqtype"
+)
+
+const refreshMembersStatement = "WITH matched AS ("
+
+func testSmartList(filter string, built bool) database.SmartList {
+	list := database.SmartList{
+		ID:             uuid.New(),
+		Name:           "Zillow leads",
+		UserID:         uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		FilterCriteria: pqtype.NullRawMessage{RawMessage: []byte(filter), Valid: true},
+	}
+	if built {
+		list.MembersRefreshedAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	}
+	return list
+}
+
+const zillowFilter = `{"match": "and", "rules": [{"field": "source", "op": "in", "value": ["Zillow"]}]}`
+
+func TestRefreshDirtyContactsNotifiesAboutContactsThatJoin(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	changed := []uuid.UUID{uuid.New(), uuid.New()}
+	built := testSmartList(zillowFilter, true)
+	unbuilt := testSmartList(zillowFilter, false)
+	db.stub("ClaimDirtySmartListContacts", func(args []any) (any, error) {
+		return changed, nil
+	})
+	db.stub("ListSmartListsForContacts", func(args []any) (any, error) {
+		return []database.SmartList{built, unbuilt}, nil
+	})
+	// Only the first changed contact is new to the list
+	db.stub(refreshMembersStatement, func(args []any) (any, error) {
+		return []uuid.UUID{changed[0]}, nil
+	})
+
+	if more := cfg.refreshDirtyContacts(context.Background()); more {
+		t.Error("refreshDirtyContacts() = true for a partial batch, want false")
+	}
+
+	refreshes := db.callsTo(refreshMembersStatement)
+	if len(refreshes) != 1 {
+		t.Fatalf("refreshed %d lists, want only the built one", len(refreshes))
+	}
+	if list := refreshes[0][1]; list != built.ID {
+		t.Errorf("refreshed list %v, want %v", list, built.ID)
+	}
+	if scope := refreshes[0][2].(pq.GenericArray).A.([]uuid.UUID); !slices.Equal(scope, changed) {
+		t.Errorf("refresh limited to %v, want the changed contacts %v", scope, changed)
+	}
+	if args := refreshes[0][4:]; len(args) != 1 {
+		t.Errorf("refresh got filter args %v, want the one source list", args)
+	}
+
+	notified := db.callsTo("NotifySmartListSubscribers")
+	if len(notified) != 1 {
+		t.Fatalf("notified subscribers %d times, want 1", len(notified))
+	}
+	if added := notified[0][0].(pq.GenericArray).A.([]uuid.UUID); !slices.Equal(added, changed[:1]) {
+		t.Errorf("notified about %v, want only %v", added, changed[:1])
+	}
+	if got := len(db.callsTo("SetSmartListMembersRefreshed")); got != 0 {
+		t.Errorf("a partial refresh marked the list refreshed %d times", got)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
+
+func TestRefreshStaleSmartListDoesntNotifyOnFirstBuild(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	list := testSmartList(zillowFilter, false)
+	db.stub("ClaimStaleSmartList", func(args []any) (any, error) {
+		return list, nil
+	})
+	db.stub(refreshMembersStatement, func(args []any) (any, error) {
+		return []uuid.UUID{uuid.New(), uuid.New()}, nil
+	})
+
+	if found := cfg.refreshStaleSmartList(context.Background()); !found {
+		t.Fatal("refreshStaleSmartList() = false, want true")
+	}
+
+	refreshes := db.callsTo(refreshMembersStatement)
+	if len(refreshes) != 1 {
+		t.Fatalf("refreshed the list %d times, want 1", len(refreshes))
+	}
+	if refreshes[0][2] != nil {
+		t.Errorf("full refresh limited to %v, want every contact", refreshes[0][2])
+	}
+	if got := len(db.callsTo("NotifySmartListSubscribers")); got != 0 {
+		t.Errorf("notified subscribers %d times about the initial members", got)
+	}
+	if got := len(db.callsTo("SetSmartListMembersRefreshed")); got != 1 {
+		t.Errorf("marked the list refreshed %d times, want 1", got)
+	}
+}
+
+func TestRefreshStaleSmartListKeepsMembersOfInvalidFilter(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	list := testSmartList(`{"match": "and", "rules": [{"field": "favorite_color", "op": "eq", "value": "blue"}]}`, true)
+	db.stub("ClaimStaleSmartList", func(args []any) (any, error) {
+		return list, nil
+	})
+
+	if found := cfg.refreshStaleSmartList(context.Background()); !found {
+		t.Fatal("refreshStaleSmartList() = false, want true")
+	}
+	if got := len(db.callsTo(refreshMembersStatement)); got != 0 {
+		t.Errorf("rebuilt the members of an invalid filter %d times", got)
+	}
+	if got := len(db.callsTo("SetSmartListMembersRefreshed")); got != 1 {
+		t.Errorf("marked the list refreshed %d times, want 1 so it waits for the next interval", got)
+	}
+	if got := len(db.callsTo("COMMIT")); got != 1 {
+		t.Errorf("committed %d times, want 1", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -15,6 +17,7 @@ func (cfg *apiCfg) CreateStage(w http.ResponseWriter, r *http.Request) {
 		Description string `json:"description"`
 		ClientType  string `json:"client_type"`
 		OrderIndex  int    `json:"order_index"`
+		Scope       string `json:"scope"`
 	}
 
 	// Get ownerID from Context
@@ -30,17 +33,41 @@ func (cfg *apiCfg) CreateStage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stage, err := cfg.DB.CreateStage(r.Context(), database.CreateStageParams{
-		Name:        req.Name,
-		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
-		ClientType:  database.ClientType(req.ClientType),
-		OrderIndex:  int32(req.OrderIndex),
-		OwnerID:     uuid.NullUUID{UUID: ownerUUID, Valid: true},
+	orgID, ok := definitionScope(w, r, req.Scope)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	stage, err := qtx.CreateStage(r.Context(), database.CreateStageParams{
+		Name:           req.Name,
+		Description:    sql.NullString{String: req.Description, Valid: req.Description != ""},
+		ClientType:     database.ClientType(req.ClientType),
+		OrderIndex:     int32(req.OrderIndex),
+		OwnerID:        uuid.NullUUID{UUID: ownerUUID, Valid: true},
+		OrganizationID: orgID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create stage", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindStage, stage.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, stage)
 }
@@ -53,7 +80,11 @@ func (cfg *apiCfg) GetStages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stages, err := cfg.DB.GetAllStages(r.Context(), uuid.NullUUID{UUID: ownerUUID, Valid: true})
+	orgID, _ := GetActiveOrganization(r.Context())
+	stages, err := cfg.DB.GetAllStages(r.Context(), database.GetAllStagesParams{
+		OwnerID:        uuid.NullUUID{UUID: ownerUUID, Valid: true},
+		OrganizationID: orgID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve stages", err)
 		return
@@ -77,9 +108,11 @@ func (cfg *apiCfg) GetStagesByClientType(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	orgID, _ := GetActiveOrganization(r.Context())
 	stages, err := cfg.DB.GetStagesByClientType(r.Context(), database.GetStagesByClientTypeParams{
-		OwnerID:    uuid.NullUUID{UUID: ownerUUID, Valid: true},
-		ClientType: database.ClientType(clientTypeStr),
+		OwnerID:        uuid.NullUUID{UUID: ownerUUID, Valid: true},
+		ClientType:     database.ClientType(clientTypeStr),
+		OrganizationID: orgID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve stages", err)
@@ -110,7 +143,21 @@ func (cfg *apiCfg) UpdateStage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stage, err := cfg.DB.UpdateStage(r.Context(), database.UpdateStageParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindStage, stageUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	stage, err := qtx.UpdateStage(r.Context(), database.UpdateStageParams{
 		ID:          stageUUID,
 		Name:        req.Name,
 		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
@@ -121,6 +168,15 @@ func (cfg *apiCfg) UpdateStage(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update stage", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindStage, stageUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, stage)
 }
@@ -133,11 +189,34 @@ func (cfg *apiCfg) DeleteStage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeleteStage(r.Context(), stageUUID)
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindStage, stageUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteStage(r.Context(), stageUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete stage", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindStage, stageUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
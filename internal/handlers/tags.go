@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/google/uuid"
 )
@@ -20,6 +22,7 @@ func (cfg *apiCfg) CreateTag(w http.ResponseWriter, r *http.Request) {
 	type request struct {
 		TagName        string `json:"tag_name"`
 		TagDescription string `json:"tag_description"`
+		Scope          string `json:"scope"`
 	}
 
 	var req request
@@ -28,15 +31,39 @@ func (cfg *apiCfg) CreateTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, err := cfg.DB.CreateTag(r.Context(), database.CreateTagParams{
-		UserID:      uuid.NullUUID{UUID: userUUID, Valid: true},
-		Name:        req.TagName,
-		Description: sql.NullString{String: req.TagDescription, Valid: req.TagDescription != ""},
+	orgID, ok := definitionScope(w, r, req.Scope)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	tag, err := qtx.CreateTag(r.Context(), database.CreateTagParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		Name:           req.TagName,
+		Description:    sql.NullString{String: req.TagDescription, Valid: req.TagDescription != ""},
+		OrganizationID: orgID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create tag", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindTag, tag.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, tag)
 }
@@ -49,7 +76,11 @@ func (cfg *apiCfg) GetAllTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tags, err := cfg.DB.GetAllTags(r.Context(), uuid.NullUUID{UUID: userUUID, Valid: true})
+	orgID, _ := GetActiveOrganization(r.Context())
+	tags, err := cfg.DB.GetAllTags(r.Context(), database.GetAllTagsParams{
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		OrganizationID: orgID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve tags", err)
 		return
@@ -66,11 +97,34 @@ func (cfg *apiCfg) DeleteTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeleteTag(r.Context(), tagUUID)
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindTag, tagUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.DeleteTag(r.Context(), tagUUID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete tag", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindTag, tagUUID, audit.Delete, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Tag deleted successfully"})
 }
@@ -90,7 +144,27 @@ func (cfg *apiCfg) AssignTagToContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tag, err := cfg.DB.AssignTagToContact(r.Context(), database.AssignTagToContactParams{
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	tag, err := qtx.AssignTagToContact(r.Context(), database.AssignTagToContactParams{
 		TagID:     tagUUID,
 		ContactID: contactUUID,
 	})
@@ -98,6 +172,31 @@ func (cfg *apiCfg) AssignTagToContact(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to assign tag to contact", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contactUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Start the caller's action plans triggered by the tag
+	orgID, _ := GetActiveOrganization(r.Context())
+	plans, err := qtx.ListTagActionPlans(r.Context(), database.ListTagActionPlansParams{
+		TagID:          uuid.NullUUID{UUID: tagUUID, Valid: true},
+		UserID:         uuid.NullUUID{UUID: userUUID, Valid: true},
+		OrganizationID: orgID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load action plans", err)
+		return
+	}
+	if err := cfg.applyTriggeredActionPlans(r.Context(), qtx, plans, contactUUID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to apply action plans", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, tag)
 }
@@ -117,7 +216,21 @@ func (cfg *apiCfg) RemoveTagFromContact(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.DB.RemoveTagFromContact(r.Context(), database.RemoveTagFromContactParams{
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, contactUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	err = qtx.RemoveTagFromContact(r.Context(), database.RemoveTagFromContactParams{
 		TagID:     tagUUID,
 		ContactID: contactUUID,
 	})
@@ -125,6 +238,15 @@ func (cfg *apiCfg) RemoveTagFromContact(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "Failed to remove tag from contact", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contactUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
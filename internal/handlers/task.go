@@ -3,10 +3,14 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +23,7 @@ func (cfg *apiCfg) CreateTask(w http.ResponseWriter, r *http.Request) {
 		Status    string `json:"status"`
 		Priority  string `json:"priority"`
 		Note      string `json:"note"`
+		RRule     string `json:"rrule"`
 	}
 
 	var req request
@@ -40,18 +45,72 @@ func (cfg *apiCfg) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse date string to time.Time
+	loc, err := cfg.userLocation(r.Context(), AssignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	// Parse date string to time.Time, in the user's time zone
 	var parsedDate time.Time
 	if req.Date != "" {
-		parsedDate, err = time.Parse("2006-01-02T15:04", req.Date)
+		parsedDate, err = time.ParseInLocation("2006-01-02T15:04", req.Date, loc)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid date format", err)
 			return
 		}
 	}
 
+	// A recurring task starts a series, with the first occurrence as its task
+	if req.RRule != "" {
+		rule, err := parseTaskRule(req.RRule, parsedDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rrule: "+err.Error(), err)
+			return
+		}
+
+		tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+			return
+		}
+		defer tx.Rollback()
+
+		task, err := cfg.createTaskSeries(r.Context(), cfg.DB.WithTx(tx), rule, database.CreateTaskSeriesParams{
+			Rrule:        rule.String(),
+			Dtstart:      parsedDate,
+			ContactID:    uuid.NullUUID{UUID: ContactUUID, Valid: true},
+			AssignedToID: uuid.NullUUID{UUID: AssignedToUUID, Valid: true},
+			Title:        req.Title,
+			Type:         database.NullTaskType{TaskType: database.TaskType(req.Type), Valid: req.Type != ""},
+			Priority:     database.NullTaskPriority{TaskPriority: database.TaskPriority(req.Priority), Valid: req.Priority != ""},
+			Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create task", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, task)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
 	// Call the CreateTask method from the queries
-	task, err := cfg.DB.CreateTask(r.Context(), database.CreateTaskParams{
+	task, err := qtx.CreateTask(r.Context(), database.CreateTaskParams{
 		ContactID:    uuid.NullUUID{UUID: ContactUUID, Valid: true},
 		AssignedToID: uuid.NullUUID{UUID: AssignedToUUID, Valid: true},
 		Title:        req.Title,
@@ -65,6 +124,15 @@ func (cfg *apiCfg) CreateTask(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create task", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindTask, task.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusCreated, task)
 }
@@ -114,7 +182,17 @@ func (cfg *apiCfg) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, task)
+	response := taskResponse{Task: task}
+	if task.SeriesID.Valid {
+		series, err := cfg.DB.GetTaskSeriesByID(r.Context(), task.SeriesID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task series", err)
+			return
+		}
+		response.Series = &series
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
 }
 
 func (cfg *apiCfg) DeleteTask(w http.ResponseWriter, r *http.Request) {
@@ -124,9 +202,68 @@ func (cfg *apiCfg) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DB.DeleteTask(r.Context(), taskUUID)
+	wholeSeries, err := seriesScope(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete task", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid scope", err)
+		return
+	}
+
+	task, err := cfg.DB.GetTaskByID(r.Context(), taskUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Task not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get task", err)
+		return
+	}
+	if wholeSeries && !task.SeriesID.Valid {
+		respondWithError(w, http.StatusBadRequest, "Task isn't recurring", nil)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Deleting the series trashes its pending occurrence and keeps past ones
+	if wholeSeries {
+		err = cfg.cancelTaskSeries(r.Context(), qtx, task.SeriesID.UUID, true)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete task series", err)
+			return
+		}
+	} else {
+		before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindTask, taskUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		err = qtx.DeleteTask(r.Context(), taskUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete task", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindTask, taskUUID, audit.Delete, before); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+
+		// A deleted occurrence is replaced by the next one
+		err = cfg.advanceTaskSeries(r.Context(), qtx, task)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to add next occurrence", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
 		return
 	}
 
@@ -140,7 +277,16 @@ func (cfg *apiCfg) GetOverdueTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := cfg.DB.GetOverdueTasks(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	tasks, err := cfg.DB.GetOverdueTasks(r.Context(), database.GetOverdueTasksParams{
+		DayStart:     timezone.Day(time.Now(), loc).Start,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get overdue tasks", err)
 		return
@@ -166,12 +312,80 @@ func (cfg *apiCfg) UpdateTaskStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedTask, err := cfg.DB.UpdateTaskStatus(r.Context(), database.UpdateTaskStatusParams{
-		ID:     taskUUID,
-		Status: database.NullTaskStatus{TaskStatus: database.TaskStatus(req.Status), Valid: req.Status != ""},
-	})
+	wholeSeries, err := seriesScope(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scope", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update task status", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// A series can only be cancelled as a whole
+	if wholeSeries {
+		if req.Status != string(database.TaskStatusCancelled) {
+			respondWithError(w, http.StatusBadRequest, "A task series can only be cancelled", nil)
+			return
+		}
+		task, err := qtx.GetTaskByID(r.Context(), taskUUID)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Task not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task", err)
+			return
+		}
+		if !task.SeriesID.Valid {
+			respondWithError(w, http.StatusBadRequest, "Task isn't recurring", nil)
+			return
+		}
+		err = cfg.cancelTaskSeries(r.Context(), qtx, task.SeriesID.UUID, false)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to cancel task series", err)
+			return
+		}
+	} else {
+		before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindTask, taskUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+		updatedTask, err := qtx.UpdateTaskStatus(r.Context(), database.UpdateTaskStatusParams{
+			ID:     taskUUID,
+			Status: database.NullTaskStatus{TaskStatus: database.TaskStatus(req.Status), Valid: req.Status != ""},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update task status", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindTask, taskUUID, audit.Update, before); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+
+		// Completing or cancelling an occurrence brings up the next one
+		err = cfg.advanceTaskSeries(r.Context(), qtx, updatedTask)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to add next occurrence", err)
+			return
+		}
+	}
+
+	updatedTask, err := qtx.GetTaskByID(r.Context(), taskUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get task", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
 		return
 	}
 
@@ -188,6 +402,7 @@ func (cfg *apiCfg) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		Status       string `json:"status"`
 		Priority     string `json:"priority"`
 		Note         string `json:"note"`
+		RRule        string `json:"rrule"`
 	}
 
 	taskUUID, err := GetUUIDFromUrl("taskID", r)
@@ -224,7 +439,101 @@ func (cfg *apiCfg) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	updatedTask, err := cfg.DB.UpdateTask(r.Context(), database.UpdateTaskParams{
+	wholeSeries, err := seriesScope(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scope", err)
+		return
+	}
+
+	task, err := cfg.DB.GetTaskByID(r.Context(), taskUUID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Task not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get task", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Editing the series changes its rule and fields, and its pending
+	// occurrence with them. The rule and start are kept when left out.
+	if wholeSeries {
+		if !task.SeriesID.Valid {
+			respondWithError(w, http.StatusBadRequest, "Task isn't recurring", nil)
+			return
+		}
+		series, err := qtx.GetTaskSeriesByID(r.Context(), task.SeriesID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task series", err)
+			return
+		}
+		if req.RRule == "" {
+			req.RRule = series.Rrule
+		}
+		if req.Date == "" {
+			parsedDate = series.Dtstart
+		}
+		loc, err := cfg.userLocation(r.Context(), AssignedToUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+			return
+		}
+
+		rule, err := parseTaskRule(req.RRule, parsedDate.In(loc))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rrule: "+err.Error(), err)
+			return
+		}
+
+		err = cfg.updateTaskSeries(r.Context(), qtx, series.ID, rule, database.UpdateTaskSeriesParams{
+			Rrule:        rule.String(),
+			Dtstart:      parsedDate,
+			ContactID:    uuid.NullUUID{UUID: ContactUUID, Valid: true},
+			AssignedToID: uuid.NullUUID{UUID: AssignedToUUID, Valid: true},
+			Title:        req.Title,
+			Type:         database.NullTaskType{TaskType: database.TaskType(req.Type), Valid: req.Type != ""},
+			Priority:     database.NullTaskPriority{TaskPriority: database.TaskPriority(req.Priority), Valid: req.Priority != ""},
+			Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+		})
+		if errors.Is(err, errSeriesCancelled) {
+			respondWithError(w, http.StatusConflict, "Task series is cancelled", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update task series", err)
+			return
+		}
+
+		updatedTask, err := qtx.GetTaskByID(r.Context(), taskUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task", err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, updatedTask)
+		return
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindTask, taskUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	updatedTask, err := qtx.UpdateTask(r.Context(), database.UpdateTaskParams{
 		ID:           taskUUID,
 		ContactID:    uuid.NullUUID{UUID: ContactUUID, Valid: true},
 		AssignedToID: uuid.NullUUID{UUID: AssignedToUUID, Valid: true},
@@ -239,6 +548,23 @@ func (cfg *apiCfg) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update task", err)
 		return
 	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindTask, taskUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Editing one occurrence leaves the series as is, but closing it
+	// brings up the next one
+	err = cfg.advanceTaskSeries(r.Context(), qtx, updatedTask)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to add next occurrence", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, updatedTask)
 }
@@ -250,7 +576,18 @@ func (cfg *apiCfg) GetTasksDueToday(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := cfg.DB.GetTaskDueToday(r.Context(), uuid.NullUUID{UUID: assignedToUUID, Valid: true})
+	loc, err := cfg.userLocation(r.Context(), assignedToUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	today := timezone.Day(time.Now(), loc)
+	tasks, err := cfg.DB.GetTaskDueToday(r.Context(), database.GetTaskDueTodayParams{
+		DayStart:     today.Start,
+		DayEnd:       today.End,
+		AssignedToID: uuid.NullUUID{UUID: assignedToUUID, Valid: true},
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get tasks due today", err)
 		return
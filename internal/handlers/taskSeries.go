@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/recurrence"
+	"github.com/google/uuid"
+)
+
+var errSeriesCancelled = errors.New("task series is cancelled")
+
+// taskResponse is a task along with the series it is an occurrence of, if
+// any.
+type taskResponse struct {
+	database.Task
+	Series *database.TaskSeries
+}
+
+// seriesScope reports whether a request on a recurring task applies to its
+// whole series. The scope query parameter is "occurrence", the default, or
+// "series".
+func seriesScope(r *http.Request) (bool, error) {
+	switch scope := r.URL.Query().Get("scope"); scope {
+	case "", "occurrence":
+		return false, nil
+	case "series":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown scope %q", scope)
+	}
+}
+
+// createTaskSeries starts a recurring task and adds its first occurrence.
+func (cfg *apiCfg) createTaskSeries(ctx context.Context, q *database.Queries, rule recurrence.Rule, params database.CreateTaskSeriesParams) (database.Task, error) {
+	series, err := q.CreateTaskSeries(ctx, params)
+	if err != nil {
+		return database.Task{}, err
+	}
+
+	task, err := q.CreateTaskOccurrence(ctx, database.CreateTaskOccurrenceParams{
+		OccurrenceDate: rule.First(),
+		SeriesID:       series.ID,
+	})
+	if err != nil {
+		return database.Task{}, err
+	}
+	if err := cfg.recordAudit(ctx, q, authz.KindTask, task.ID, audit.Create, nil); err != nil {
+		return database.Task{}, err
+	}
+	return task, nil
+}
+
+// advanceTaskSeries adds the occurrence that follows task in its series
+// once task is no longer pending. Nothing is added while an occurrence is
+// pending, after the series is cancelled or once its rule has ended, so it's
+// safe to call after any change to an occurrence.
+func (cfg *apiCfg) advanceTaskSeries(ctx context.Context, q *database.Queries, task database.Task) error {
+	if !task.SeriesID.Valid {
+		return nil
+	}
+
+	series, err := q.LockTaskSeries(ctx, task.SeriesID.UUID)
+	if err != nil {
+		return err
+	}
+	if series.CancelledAt.Valid {
+		return nil
+	}
+
+	pending, err := q.ListPendingSeriesTasks(ctx, task.SeriesID)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return nil
+	}
+
+	// Occurrences keep their time of day in the assignee's time zone
+	loc, err := cfg.assigneeLocation(ctx, series.AssignedToID)
+	if err != nil {
+		return err
+	}
+	rule, err := recurrence.Parse(series.Rrule, series.Dtstart.In(loc))
+	if err != nil {
+		return err
+	}
+
+	// Occurrences generated before, and since deleted, are skipped
+	for next := rule.After(task.OccurrenceDate.Time); !next.IsZero(); next = rule.After(next) {
+		occurrence, err := q.CreateTaskOccurrence(ctx, database.CreateTaskOccurrenceParams{
+			OccurrenceDate: next,
+			SeriesID:       series.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return cfg.recordAudit(ctx, q, authz.KindTask, occurrence.ID, audit.Create, nil)
+	}
+	return nil
+}
+
+// cancelTaskSeries stops a series from adding occurrences. Its pending
+// occurrence is cancelled, or moved to the trash when trash is set, while
+// past occurrences are kept.
+func (cfg *apiCfg) cancelTaskSeries(ctx context.Context, q *database.Queries, seriesID uuid.UUID, trash bool) error {
+	if _, err := q.LockTaskSeries(ctx, seriesID); err != nil {
+		return err
+	}
+	if err := q.CancelTaskSeries(ctx, seriesID); err != nil {
+		return err
+	}
+
+	pending, err := q.ListPendingSeriesTasks(ctx, uuid.NullUUID{UUID: seriesID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, task := range pending {
+		before, err := cfg.auditSnapshot(ctx, q, authz.KindTask, task.ID)
+		if err != nil {
+			return err
+		}
+		if trash {
+			err = q.DeleteTask(ctx, task.ID)
+		} else {
+			_, err = q.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
+				ID:     task.ID,
+				Status: database.NullTaskStatus{TaskStatus: database.TaskStatusCancelled, Valid: true},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		action := audit.Update
+		if trash {
+			action = audit.Delete
+		}
+		if err := cfg.recordAudit(ctx, q, authz.KindTask, task.ID, action, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateTaskSeries changes the rule and fields of a series and applies them
+// to its pending occurrence, which is moved to the first date of the new
+// rule from its own date or the new start, whichever is later. It is
+// cancelled when the new rule has no such date.
+func (cfg *apiCfg) updateTaskSeries(ctx context.Context, q *database.Queries, seriesID uuid.UUID, rule recurrence.Rule, params database.UpdateTaskSeriesParams) error {
+	series, err := q.LockTaskSeries(ctx, seriesID)
+	if err != nil {
+		return err
+	}
+	if series.CancelledAt.Valid {
+		return errSeriesCancelled
+	}
+
+	params.ID = seriesID
+	if _, err := q.UpdateTaskSeries(ctx, params); err != nil {
+		return err
+	}
+
+	pending, err := q.ListPendingSeriesTasks(ctx, uuid.NullUUID{UUID: seriesID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, task := range pending {
+		before, err := cfg.auditSnapshot(ctx, q, authz.KindTask, task.ID)
+		if err != nil {
+			return err
+		}
+
+		from := task.OccurrenceDate.Time
+		if params.Dtstart.After(from) {
+			from = params.Dtstart
+		}
+		if next := rule.From(from); next.IsZero() {
+			_, err = q.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
+				ID:     task.ID,
+				Status: database.NullTaskStatus{TaskStatus: database.TaskStatusCancelled, Valid: true},
+			})
+		} else {
+			err = q.ApplyTaskSeries(ctx, database.ApplyTaskSeriesParams{
+				OccurrenceDate: next,
+				ID:             task.ID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if err := cfg.recordAudit(ctx, q, authz.KindTask, task.ID, audit.Update, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTaskRule parses a task's recurrence rule, anchored at its date.
+func parseTaskRule(rule string, start time.Time) (recurrence.Rule, error) {
+	parsed, err := recurrence.Parse(rule, start)
+	if err != nil {
+		return recurrence.Rule{}, err
+	}
+	if parsed.First().IsZero() {
+		return recurrence.Rule{}, errors.New("recurrence rule has no occurrences")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/timezone"
+	"github.com/google/uuid"
+)
+
+// GetTimeZone returns the caller's time zone.
+func (cfg *apiCfg) GetTimeZone(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	timeZone, err := cfg.DB.GetUserTimeZone(r.Context(), userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get time zone", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"time_zone": timeZone})
+}
+
+// SetTimeZone sets the caller's time zone, an IANA name such as
+// "America/Los_Angeles". Their "today", "this week" and "this month" and
+// their daily digest follow it.
+func (cfg *apiCfg) SetTimeZone(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		TimeZone string `json:"time_zone"`
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No user ID in context", err)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	loc, err := timezone.Load(strings.TrimSpace(req.TimeZone))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid time_zone", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.KindUser, userUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	timeZone, err := qtx.SetUserTimeZone(r.Context(), database.SetUserTimeZoneParams{
+		ID:       userUUID,
+		TimeZone: loc.String(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save time zone", err)
+		return
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindUser, userUUID, audit.Update, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"time_zone": timeZone})
+}
+
+// userLocation returns the user's time zone. Zones saved before they were
+// validated, or that the server's time zone database lacks, fall back to
+// the default.
+func (cfg *apiCfg) userLocation(ctx context.Context, userID uuid.UUID) (*time.Location, error) {
+	name, err := cfg.DB.GetUserTimeZone(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := timezone.Load(name)
+	if err != nil {
+		cfg.logger.Warn("Unknown time zone, using the default", "user_id", userID, "time_zone", name)
+		return timezone.Load(timezone.Default)
+	}
+	return loc, nil
+}
+
+// assigneeLocation returns the time zone of a record's assignee, or the
+// default for unassigned records.
+func (cfg *apiCfg) assigneeLocation(ctx context.Context, assignee uuid.NullUUID) (*time.Location, error) {
+	if !assignee.Valid {
+		return timezone.Load(timezone.Default)
+	}
+	return cfg.userLocation(ctx, assignee.UUID)
+}
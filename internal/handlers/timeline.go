@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// timelineEventTypes are the event types GetContactTimeline can return.
+var timelineEventTypes = map[string]bool{
+	"note":         true,
+	"contact_log":  true,
+	"task":         true,
+	"appointment":  true,
+	"deal":         true,
+	"email":        true,
+	"stage_change": true,
+	"tag_added":    true,
+	"tag_removed":  true,
+}
+
+// Bounds used when the request leaves the date range or cursor open.
+var (
+	timelineStart = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	timelineEnd   = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+func (cfg *apiCfg) GetContactTimeline(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No User ID in Context", err)
+		return
+	}
+
+	contactUUID, err := GetUUIDFromUrl("contactID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	// Get query parameters from url for pagination (limit and cursor)
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	cursorAt, cursorID := timelineEnd, uuid.Max
+	if c := query.Get("cursor"); c != "" {
+		cursorAt, cursorID, err = decodeTimelineCursor(c)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+	}
+
+	// Filters
+	types := []string{}
+	if t := query.Get("types"); t != "" {
+		for _, eventType := range strings.Split(t, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !timelineEventTypes[eventType] {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown event type %q", eventType), nil)
+				return
+			}
+			types = append(types, eventType)
+		}
+	}
+
+	from, err := parseTimelineDate(query.Get("from"), false)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid from date. Use YYYY-MM-DD or RFC 3339.", err)
+		return
+	}
+	until, err := parseTimelineDate(query.Get("to"), true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid to date. Use YYYY-MM-DD or RFC 3339.", err)
+		return
+	}
+
+	canView, err := cfg.DB.CanViewContact(r.Context(), database.CanViewContactParams{
+		ContactID: contactUUID,
+		UserID:    uuid.NullUUID{UUID: userUUID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get contact", err)
+		return
+	}
+	if !canView {
+		respondWithError(w, http.StatusNotFound, "Contact not found", nil)
+		return
+	}
+
+	// Fetch one extra event to know whether there is another page
+	events, err := cfg.DB.GetContactTimeline(r.Context(), database.GetContactTimelineParams{
+		ContactID:  uuid.NullUUID{UUID: contactUUID, Valid: true},
+		EventTypes: types,
+		FromAt:     from,
+		UntilAt:    until,
+		CursorAt:   cursorAt,
+		CursorID:   cursorID,
+		PageSize:   int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get timeline", err)
+		return
+	}
+
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		nextCursor = encodeTimelineCursor(last.OccurredAt, last.ID)
+	}
+	if events == nil {
+		events = []database.GetContactTimelineRow{}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"events":      events,
+		"next_cursor": nextCursor,
+	})
+}
+
+// encodeTimelineCursor returns an opaque cursor pointing just past the event
+// with the given time and ID.
+func encodeTimelineCursor(at time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeTimelineCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return t, u, nil
+}
+
+// parseTimelineDate parses a from or to filter. A bare date used as the end
+// of the range includes that whole day.
+func parseTimelineDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		if end {
+			return timelineEnd, nil
+		}
+		return timelineStart, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
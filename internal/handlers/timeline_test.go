@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+// stubTimeline serves events, newest first, with the keyset pagination of
+// the GetContactTimeline query.
+func stubTimeline(db *fakeDB, events []database.GetContactTimelineRow) {
+	slices.SortFunc(events, func(a, b database.GetContactTimelineRow) int {
+		if c := b.OccurredAt.Compare(a.OccurredAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+
+	db.stub("CanViewContact", func(args []any) (any, error) {
+		return true, nil
+	})
+	db.stub("GetContactTimeline", func(args []any) (any, error) {
+		cursorAt, cursorID, pageSize := args[4].(time.Time), args[5].(uuid.UUID), int(args[6].(int32))
+		var page []database.GetContactTimelineRow
+		for _, e := range events {
+			before := e.OccurredAt.Before(cursorAt) ||
+				(e.OccurredAt.Equal(cursorAt) && bytes.Compare(e.ID[:], cursorID[:]) < 0)
+			if before && len(page) < pageSize {
+				page = append(page, e)
+			}
+		}
+		return page, nil
+	})
+}
+
+func getTimelinePage(t *testing.T, cfg *apiCfg, contactID uuid.UUID, query url.Values) (events []database.GetContactTimelineRow, next string) {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/contacts/contact/"+contactID.String()+"/timeline?"+query.Encode(), nil)
+	r.SetPathValue("contactID", contactID.String())
+	w := httptest.NewRecorder()
+	cfg.GetContactTimeline(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Events     []database.GetContactTimelineRow `json:"events"`
+		NextCursor string                           `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Events, resp.NextCursor
+}
+
+func TestGetContactTimelinePagesThroughEveryEvent(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	// Two events share a timestamp, so the cursor has to break the tie
+	now := time.Now().UTC().Truncate(time.Second)
+	events := []database.GetContactTimelineRow{
+		{ID: uuid.New(), EventType: "note", OccurredAt: now},
+		{ID: uuid.New(), EventType: "task", OccurredAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), EventType: "deal", OccurredAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), EventType: "email", OccurredAt: now.Add(-2 * time.Hour)},
+		{ID: uuid.New(), EventType: "contact_log", OccurredAt: now.Add(-3 * time.Hour)},
+	}
+	stubTimeline(db, events)
+
+	contactID := uuid.New()
+	var seen []uuid.UUID
+	cursor := ""
+	for pages := 1; ; pages++ {
+		query := url.Values{"limit": {"2"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		page, next := getTimelinePage(t, cfg, contactID, query)
+		if len(page) > 2 {
+			t.Fatalf("page %d has %d events, want at most 2", pages, len(page))
+		}
+		for _, e := range page {
+			seen = append(seen, e.ID)
+		}
+		if next == "" {
+			if pages != 3 {
+				t.Errorf("got %d pages, want 3", pages)
+			}
+			break
+		}
+		if pages == 3 {
+			t.Fatal("last page has a next cursor")
+		}
+		cursor = next
+	}
+
+	var want []uuid.UUID
+	for _, e := range events {
+		want = append(want, e.ID)
+	}
+	if !slices.Equal(seen, want) {
+		t.Errorf("paged through %v, want every event once, newest first: %v", seen, want)
+	}
+
+	// The query is asked for one more event than the page holds
+	if size := db.callsTo("GetContactTimeline")[0][6]; size != int32(3) {
+		t.Errorf("page size = %v, want 3", size)
+	}
+}
+
+func TestGetContactTimelineRejectsInvalidCursor(t *testing.T) {
+	cfg, db := newTestConfig(t)
+	stubTimeline(db, nil)
+
+	contactID := uuid.New()
+	r := httptest.NewRequest(http.MethodGet, "/api/contacts/contact/"+contactID.String()+"/timeline?cursor=not-a-cursor", nil)
+	r.SetPathValue("contactID", contactID.String())
+	w := httptest.NewRecorder()
+	cfg.GetContactTimeline(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if got := len(db.callsTo("GetContactTimeline")); got != 0 {
+		t.Errorf("queried the timeline %d times with an invalid cursor", got)
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+type transferResult struct {
+	Contacts     int   `json:"contacts"`
+	Tasks        int64 `json:"tasks"`
+	Appointments int64 `json:"appointments"`
+	Deals        int64 `json:"deals"`
+}
+
+// TransferContacts moves a set of contacts to another user in one
+// transaction: the contacts listed in contact_ids, the members of the smart
+// list smart_list_id or every contact of from_user_id. The pending tasks,
+// upcoming appointments and open deals the previous owner was assigned move
+// with them. With keep_as_collaborator the previous owner stays on each
+// contact as a collaborator with collaborator_role (viewer by default).
+//
+// Only contacts the caller owns or administers as an organization admin are
+// moved. The new owner must share an organization with the caller and be a
+// member of every organization the contacts belong to.
+func (cfg *apiCfg) TransferContacts(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		ToUserID           uuid.UUID     `json:"to_user_id"`
+		ContactIDs         []uuid.UUID   `json:"contact_ids"`
+		SmartListID        uuid.NullUUID `json:"smart_list_id"`
+		FromUserID         uuid.NullUUID `json:"from_user_id"`
+		KeepAsCollaborator bool          `json:"keep_as_collaborator"`
+		CollaboratorRole   string        `json:"collaborator_role"`
+	}
+
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	if req.ToUserID == uuid.Nil {
+		respondWithError(w, http.StatusBadRequest, "to_user_id is required", nil)
+		return
+	}
+	selectors := 0
+	for _, set := range []bool{len(req.ContactIDs) > 0, req.SmartListID.Valid, req.FromUserID.Valid} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		respondWithError(w, http.StatusBadRequest, "Provide exactly one of contact_ids, smart_list_id or from_user_id", nil)
+		return
+	}
+	if req.CollaboratorRole == "" {
+		req.CollaboratorRole = string(authz.RoleViewer)
+	}
+	if req.KeepAsCollaborator && !authz.ValidRole(req.CollaboratorRole) {
+		respondWithError(w, http.StatusBadRequest, "Invalid collaborator role. Use viewer, editor or manager", nil)
+		return
+	}
+
+	if req.ToUserID != userUUID {
+		shared, err := cfg.DB.SharesOrganization(r.Context(), database.SharesOrganizationParams{
+			UserID:      userUUID,
+			OtherUserID: req.ToUserID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check the new owner", err)
+			return
+		}
+		if !shared {
+			respondWithError(w, http.StatusBadRequest, "The new owner must be a member of one of your organizations", nil)
+			return
+		}
+	}
+
+	// Start DB transaction
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	rows, err := qtx.ListTransferableContacts(r.Context(), database.ListTransferableContactsParams{
+		ContactIds:  req.ContactIDs,
+		SmartListID: req.SmartListID,
+		FromUserID:  req.FromUserID,
+		UserID:      uuid.NullUUID{UUID: userUUID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve contacts", err)
+		return
+	}
+
+	// Listed contacts are moved all together or not at all
+	if len(req.ContactIDs) > 0 {
+		requested := map[uuid.UUID]bool{}
+		for _, id := range req.ContactIDs {
+			requested[id] = true
+		}
+		if len(rows) != len(requested) {
+			respondWithError(w, http.StatusNotFound, "Contact not found", nil)
+			return
+		}
+	}
+
+	var contactIDs []uuid.UUID
+	previousOwners := map[uuid.UUID]int{}
+	checkedOrgs := map[uuid.UUID]bool{}
+	for _, row := range rows {
+		if row.OwnerID.Valid && row.OwnerID.UUID == req.ToUserID {
+			continue
+		}
+		if row.OrganizationID.Valid && !checkedOrgs[row.OrganizationID.UUID] {
+			_, err := qtx.GetMemberRole(r.Context(), database.GetMemberRoleParams{
+				UserID:         req.ToUserID,
+				OrganizationID: row.OrganizationID.UUID,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusBadRequest, "The new owner must be a member of the contacts' organization", err)
+				return
+			}
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to check the new owner", err)
+				return
+			}
+			checkedOrgs[row.OrganizationID.UUID] = true
+		}
+		contactIDs = append(contactIDs, row.ID)
+		if row.OwnerID.Valid {
+			previousOwners[row.OwnerID.UUID]++
+		}
+	}
+
+	result := transferResult{Contacts: len(contactIDs)}
+	if len(contactIDs) == 0 {
+		respondWithJSON(w, http.StatusOK, result)
+		return
+	}
+	toUser := uuid.NullUUID{UUID: req.ToUserID, Valid: true}
+
+	before := make(map[uuid.UUID]json.RawMessage, len(contactIDs))
+	for _, id := range contactIDs {
+		if before[id], err = cfg.auditSnapshot(r.Context(), qtx, authz.KindContact, id); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+	}
+
+	// Records assigned to the previous owner move first, while the contacts
+	// still say who that was
+	if req.KeepAsCollaborator {
+		err = qtx.AddPreviousOwnerCollaborators(r.Context(), database.AddPreviousOwnerCollaboratorsParams{
+			Role:       req.CollaboratorRole,
+			ContactIds: contactIDs,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to keep the previous owner as a collaborator", err)
+			return
+		}
+	}
+
+	result.Tasks, err = qtx.TransferOpenTasks(r.Context(), database.TransferOpenTasksParams{ToUserID: toUser, ContactIds: contactIDs})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer tasks", err)
+		return
+	}
+	result.Appointments, err = qtx.TransferFutureAppointments(r.Context(), database.TransferFutureAppointmentsParams{ToUserID: toUser, ContactIds: contactIDs})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer appointments", err)
+		return
+	}
+	result.Deals, err = qtx.TransferOpenDeals(r.Context(), database.TransferOpenDealsParams{ToUserID: toUser, ContactIds: contactIDs})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer deals", err)
+		return
+	}
+
+	err = qtx.TransferContacts(r.Context(), database.TransferContactsParams{ToUserID: toUser, ContactIds: contactIDs})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to transfer contacts", err)
+		return
+	}
+
+	err = qtx.RemoveOwnerCollaborator(r.Context(), database.RemoveOwnerCollaboratorParams{UserID: req.ToUserID, ContactIds: contactIDs})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update collaborators", err)
+		return
+	}
+	for _, id := range contactIDs {
+		if err := cfg.recordFieldHistory(r.Context(), qtx, authz.KindContact, id, before[id]); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record contact history", err)
+			return
+		}
+		if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, id, audit.Update, before[id]); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+			return
+		}
+	}
+
+	if err := qtx.CloseTransferredLeadAssignments(r.Context(), contactIDs); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to close lead assignments", err)
+		return
+	}
+
+	// Notify both sides, unless they made the transfer themselves
+	var single uuid.NullUUID
+	if len(contactIDs) == 1 {
+		single = uuid.NullUUID{UUID: contactIDs[0], Valid: true}
+	}
+	notify := map[uuid.UUID]string{}
+	if req.ToUserID != userUUID {
+		notify[req.ToUserID] = fmt.Sprintf("%s transferred to you.", countContacts(len(contactIDs)))
+	}
+	for owner, count := range previousOwners {
+		if owner != userUUID {
+			notify[owner] = fmt.Sprintf("%s transferred to another agent.", countContacts(count))
+		}
+	}
+	for user, message := range notify {
+		_, err = qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
+			UserID:    user,
+			Type:      "contacts_transferred",
+			Message:   message,
+			ContactID: single,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create notification", err)
+			return
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func countContacts(n int) string {
+	if n == 1 {
+		return "1 contact was"
+	}
+	return fmt.Sprintf("%d contacts were", n)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// newTransferConfig returns a test config whose notifications are created.
+func newTransferConfig(t *testing.T) (*apiCfg, *fakeDB) {
+	t.Helper()
+
+	cfg, db := newTestConfig(t)
+	db.stub("CreateNotification", func(args []any) (any, error) {
+		return database.Notification{ID: uuid.New(), UserID: args[0].(uuid.UUID), Type: args[1].(string), Message: args[2].(string)}, nil
+	})
+	return cfg, db
+}
+
+func transferRequest(t *testing.T, userID uuid.UUID, body any) *http.Request {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asUser(httptest.NewRequest(http.MethodPost, "/api/contacts/transfer", strings.NewReader(string(data))), userID)
+}
+
+func TestTransferContactsChecksTheNewOwner(t *testing.T) {
+	caller, newOwner := uuid.New(), uuid.New()
+	contact := database.ListTransferableContactsRow{
+		ID:             uuid.New(),
+		OwnerID:        uuid.NullUUID{UUID: caller, Valid: true},
+		OrganizationID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+
+	tests := []struct {
+		name     string
+		shared   bool
+		inOrg    bool
+		status   int
+		checkOrg bool
+	}{
+		{"shares no organization with the caller", false, true, http.StatusBadRequest, false},
+		{"isn't in the contact's organization", true, false, http.StatusBadRequest, true},
+		{"is in both", true, true, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, db := newTransferConfig(t)
+			db.stub("SharesOrganization", func(args []any) (any, error) {
+				return tt.shared, nil
+			})
+			db.stub("ListTransferableContacts", func(args []any) (any, error) {
+				return contact, nil
+			})
+			db.stub("GetMemberRole", func(args []any) (any, error) {
+				if tt.inOrg {
+					return "member", nil
+				}
+				return nil, nil
+			})
+
+			w := httptest.NewRecorder()
+			cfg.TransferContacts(w, transferRequest(t, caller, map[string]any{
+				"to_user_id":  newOwner,
+				"contact_ids": []uuid.UUID{contact.ID},
+			}))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			shares := db.callsTo("SharesOrganization")
+			if len(shares) != 1 || shares[0][0] != caller || shares[0][1] != newOwner {
+				t.Errorf("checked shared organizations with %v, want the caller and the new owner", shares)
+			}
+			roles := db.callsTo("GetMemberRole")
+			if !tt.checkOrg {
+				if len(roles) != 0 {
+					t.Errorf("checked the contact's organization %d times after the shared organization check failed", len(roles))
+				}
+			} else if len(roles) != 1 || roles[0][0] != newOwner || roles[0][1] != contact.OrganizationID.UUID {
+				t.Errorf("checked membership with %v, want the new owner in the contact's organization", roles)
+			}
+
+			transferred := len(db.callsTo("TransferContacts"))
+			committed := len(db.callsTo("COMMIT"))
+			if tt.status != http.StatusOK {
+				if transferred != 0 || committed != 0 {
+					t.Errorf("transferred %d times and committed %d times on a %d response", transferred, committed, w.Code)
+				}
+				return
+			}
+			if transferred != 1 || committed != 1 {
+				t.Errorf("transferred %d times and committed %d times, want 1 each", transferred, committed)
+			}
+		})
+	}
+}
+
+func TestTransferContactsToSelfSkipsSharedOrganizationCheck(t *testing.T) {
+	cfg, db := newTransferConfig(t)
+
+	admin := uuid.New()
+	db.stub("ListTransferableContacts", func(args []any) (any, error) {
+		return []database.ListTransferableContactsRow{{ID: uuid.New(), OwnerID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}}, nil
+	})
+
+	w := httptest.NewRecorder()
+	cfg.TransferContacts(w, transferRequest(t, admin, map[string]any{
+		"to_user_id":   admin,
+		"from_user_id": uuid.New(),
+	}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got := len(db.callsTo("SharesOrganization")); got != 0 {
+		t.Errorf("checked shared organizations %d times for a transfer to the caller", got)
+	}
+}
+
+func TestTransferContactsSkipsTheNewOwnersContacts(t *testing.T) {
+	cfg, db := newTransferConfig(t)
+
+	caller, newOwner, org := uuid.New(), uuid.New(), uuid.New()
+	inOrg := uuid.NullUUID{UUID: org, Valid: true}
+	rows := []database.ListTransferableContactsRow{
+		{ID: uuid.New(), OwnerID: uuid.NullUUID{UUID: caller, Valid: true}, OrganizationID: inOrg},
+		{ID: uuid.New(), OwnerID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, OrganizationID: inOrg},
+		{ID: uuid.New(), OwnerID: uuid.NullUUID{UUID: newOwner, Valid: true}, OrganizationID: inOrg},
+	}
+	db.stub("SharesOrganization", func(args []any) (any, error) {
+		return true, nil
+	})
+	db.stub("ListTransferableContacts", func(args []any) (any, error) {
+		return rows, nil
+	})
+	db.stub("GetMemberRole", func(args []any) (any, error) {
+		return "member", nil
+	})
+
+	w := httptest.NewRecorder()
+	cfg.TransferContacts(w, transferRequest(t, caller, map[string]any{
+		"to_user_id":   newOwner,
+		"from_user_id": caller,
+	}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got := len(db.callsTo("GetMemberRole")); got != 1 {
+		t.Errorf("checked the new owner's membership %d times, want once for the one organization", got)
+	}
+
+	transfers := db.callsTo("TransferContacts")
+	if len(transfers) != 1 {
+		t.Fatalf("transferred %d times, want 1", len(transfers))
+	}
+	moved := transfers[0][1].(pq.GenericArray).A.([]uuid.UUID)
+	if want := []uuid.UUID{rows[0].ID, rows[1].ID}; !slices.Equal(moved, want) {
+		t.Errorf("moved %v, want %v without the new owner's own contact", moved, want)
+	}
+
+	// The caller is told nothing about their own transfer
+	for _, n := range db.callsTo("CreateNotification") {
+		if n[0] == caller {
+			t.Errorf("notified the caller about their own transfer")
+		}
+	}
+	if got := len(db.callsTo("CreateNotification")); got != 2 {
+		t.Errorf("sent %d notifications, want the new owner and the other previous owner", got)
+	}
+}
+
+func TestTransferContactsMovesListedContactsAllOrNothing(t *testing.T) {
+	cfg, db := newTransferConfig(t)
+
+	caller := uuid.New()
+	listed := []uuid.UUID{uuid.New(), uuid.New()}
+	db.stub("ListTransferableContacts", func(args []any) (any, error) {
+		return []database.ListTransferableContactsRow{{ID: listed[0], OwnerID: uuid.NullUUID{UUID: caller, Valid: true}}}, nil
+	})
+
+	w := httptest.NewRecorder()
+	cfg.TransferContacts(w, transferRequest(t, caller, map[string]any{
+		"to_user_id":  caller,
+		"contact_ids": listed,
+	}))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := len(db.callsTo("TransferContacts")); got != 0 {
+		t.Errorf("transferred %d times when one listed contact can't be moved", got)
+	}
+}
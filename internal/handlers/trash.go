@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	trashPurgeInterval   = time.Hour
+	defaultTrashPageSize = 50
+	maxTrashPageSize     = 200
+)
+
+// trashKinds maps the record types that can be trashed to the queries that
+// restore and permanently delete one of them.
+var trashKinds = map[string]struct {
+	restore func(*database.Queries, context.Context, uuid.UUID) error
+	purge   func(*database.Queries, context.Context, uuid.UUID) error
+}{
+	"contact":     {(*database.Queries).RestoreContact, (*database.Queries).PurgeContact},
+	"deal":        {(*database.Queries).RestoreDeal, (*database.Queries).PurgeDeal},
+	"task":        {(*database.Queries).RestoreTask, (*database.Queries).PurgeTask},
+	"appointment": {(*database.Queries).RestoreAppointment, (*database.Queries).PurgeAppointment},
+	"stage":       {(*database.Queries).RestoreStage, (*database.Queries).PurgeStage},
+}
+
+type trashItem struct {
+	Type      string        `json:"type"`
+	ID        uuid.UUID     `json:"id"`
+	Title     string        `json:"title"`
+	ContactID uuid.NullUUID `json:"contact_id"`
+	DeletedAt time.Time     `json:"deleted_at"`
+	PurgeAt   time.Time     `json:"purge_at"`
+}
+
+// GetTrash lists the trashed records the caller may restore, optionally of
+// one type, with when the retention job will purge each of them.
+func (cfg *apiCfg) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	query := r.URL.Query()
+	kind := query.Get("type")
+	if _, ok := trashKinds[kind]; kind != "" && !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid type. Use contact, deal, task, appointment or stage", nil)
+		return
+	}
+
+	limit, offset := defaultTrashPageSize, 0
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTrashPageSize {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit parameter", err)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid offset parameter", err)
+			return
+		}
+	}
+
+	rows, err := cfg.DB.ListTrash(r.Context(), database.ListTrashParams{
+		UserID:    uuid.NullUUID{UUID: userUUID, Valid: true},
+		Kind:      sql.NullString{String: kind, Valid: kind != ""},
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list trash", err)
+		return
+	}
+
+	items := make([]trashItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, trashItem{
+			Type:      row.Kind,
+			ID:        row.ID,
+			Title:     row.Title,
+			ContactID: row.ContactID,
+			DeletedAt: row.DeletedAt.Time,
+			PurgeAt:   row.DeletedAt.Time.Add(cfg.trashRetention),
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, items)
+}
+
+func (cfg *apiCfg) RestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	cfg.handleTrashItem(w, r, true)
+}
+
+func (cfg *apiCfg) PurgeTrashItem(w http.ResponseWriter, r *http.Request) {
+	cfg.handleTrashItem(w, r, false)
+}
+
+// handleTrashItem restores or permanently deletes the trashed record in the
+// path. Records that aren't in the trash, or that the caller may not manage,
+// are reported as not found.
+func (cfg *apiCfg) handleTrashItem(w http.ResponseWriter, r *http.Request, restore bool) {
+	userUUID, err := GetUserUUID(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized: unable to get user ID", err)
+		return
+	}
+
+	kind := r.PathValue("kind")
+	queries, ok := trashKinds[kind]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid type. Use contact, deal, task, appointment or stage", nil)
+		return
+	}
+	recordUUID, err := GetUUIDFromUrl("recordID", r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid record ID", err)
+		return
+	}
+
+	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start database transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	item, err := qtx.GetTrashItem(r.Context(), database.GetTrashItemParams{
+		Kind: kind,
+		ID:   recordUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Record not found in trash", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get trashed record", err)
+		return
+	}
+	allowed, err := mayManageTrashItem(r.Context(), qtx, userUUID, item)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check permissions", err)
+		return
+	}
+	if !allowed {
+		respondWithError(w, http.StatusNotFound, "Record not found in trash", nil)
+		return
+	}
+
+	before, err := cfg.auditSnapshot(r.Context(), qtx, authz.Kind(kind), recordUUID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+	action := audit.Purge
+	if restore {
+		action = audit.Restore
+		err = queries.restore(qtx, r.Context(), recordUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to restore record", err)
+			return
+		}
+	} else {
+		err = queries.purge(qtx, r.Context(), recordUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete record", err)
+			return
+		}
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.Kind(kind), recordUUID, action, before); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// mayManageTrashItem reports whether the user may restore or purge a
+// trashed record: they own or are assigned it, own its contact or administer
+// the organization it belongs to. These are the records ListTrash lists.
+func mayManageTrashItem(ctx context.Context, q *database.Queries, userID uuid.UUID, item database.TrashItem) (bool, error) {
+	for _, owner := range []uuid.NullUUID{item.OwnerID, item.ContactOwnerID} {
+		if owner.Valid && owner.UUID == userID {
+			return true, nil
+		}
+	}
+	if !item.OrganizationID.Valid {
+		return false, nil
+	}
+
+	role, err := q.GetMemberRole(ctx, database.GetMemberRoleParams{
+		UserID:         userID,
+		OrganizationID: item.OrganizationID.UUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return authz.IsOrgAdmin(role), nil
+}
+
+// StartTrashPurgeWorker permanently deletes records that have been in the
+// trash longer than the retention period, checking every trashPurgeInterval.
+func (cfg *apiCfg) StartTrashPurgeWorker(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		cfg.purgeExpiredTrash(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpiredTrash deletes the expired records of every type in one
+// transaction.
+func (cfg *apiCfg) purgeExpiredTrash(ctx context.Context) {
+	tx, err := cfg.RawDB.BeginTx(ctx, nil)
+	if err != nil {
+		cfg.logger.Error("Failed to start trash purge", "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+	before := sql.NullTime{Time: time.Now().Add(-cfg.trashRetention), Valid: true}
+
+	purges := []struct {
+		kind  string
+		purge func(*database.Queries, context.Context, sql.NullTime) (int64, error)
+	}{
+		{"task", (*database.Queries).PurgeExpiredTasks},
+		{"appointment", (*database.Queries).PurgeExpiredAppointments},
+		{"deal", (*database.Queries).PurgeExpiredDeals},
+		{"stage", (*database.Queries).PurgeExpiredStages},
+		{"contact", (*database.Queries).PurgeExpiredContacts},
+	}
+	purged := map[string]int64{}
+	for _, p := range purges {
+		n, err := p.purge(qtx, ctx, before)
+		if err != nil {
+			cfg.logger.Error("Failed to purge trash", "type", p.kind, "error", err)
+			return
+		}
+		if n > 0 {
+			purged[p.kind] = n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		cfg.logger.Error("Failed to commit trash purge", "error", err)
+		return
+	}
+	for kind, n := range purged {
+		cfg.logger.Info("Purged trash", "type", kind, "count", n)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestMayManageTrashItem(t *testing.T) {
+	owner, contactOwner, user := uuid.New(), uuid.New(), uuid.New()
+	org := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	item := func(org uuid.NullUUID) database.TrashItem {
+		return database.TrashItem{
+			Kind:           "deal",
+			ID:             uuid.New(),
+			OwnerID:        uuid.NullUUID{UUID: owner, Valid: true},
+			ContactOwnerID: uuid.NullUUID{UUID: contactOwner, Valid: true},
+			OrganizationID: org,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		user    uuid.UUID
+		item    database.TrashItem
+		role    string // empty when the user isn't a member
+		allowed bool
+		lookup  bool
+	}{
+		{"owner", owner, item(org), "", true, false},
+		{"contact owner", contactOwner, item(org), "", true, false},
+		{"organization owner", user, item(org), "owner", true, true},
+		{"organization admin", user, item(org), "admin", true, true},
+		{"organization member", user, item(org), "member", false, true},
+		{"outside the organization", user, item(org), "", false, true},
+		{"stranger to a personal record", user, item(uuid.NullUUID{}), "admin", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, db := newTestConfig(t)
+			db.stub("GetMemberRole", func(args []any) (any, error) {
+				if tt.role == "" {
+					return nil, nil
+				}
+				return tt.role, nil
+			})
+
+			allowed, err := mayManageTrashItem(context.Background(), cfg.DB, tt.user, tt.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.allowed {
+				t.Errorf("mayManageTrashItem() = %v, want %v", allowed, tt.allowed)
+			}
+
+			lookups := db.callsTo("GetMemberRole")
+			if !tt.lookup {
+				if len(lookups) != 0 {
+					t.Errorf("looked up the user's role %d times, want 0", len(lookups))
+				}
+				return
+			}
+			if len(lookups) != 1 || lookups[0][0] != tt.user || lookups[0][1] != tt.item.OrganizationID.UUID {
+				t.Errorf("looked up roles with %v, want the user in the record's organization", lookups)
+			}
+		})
+	}
+}
+
+func TestTrashItemIsRestoredOrPurgedOnlyWhenAllowed(t *testing.T) {
+	owner := uuid.New()
+	deal := database.TrashItem{
+		Kind:    "deal",
+		ID:      uuid.New(),
+		OwnerID: uuid.NullUUID{UUID: owner, Valid: true},
+	}
+
+	tests := []struct {
+		name    string
+		user    uuid.UUID
+		trashed bool
+		status  int
+	}{
+		{"by the owner", owner, true, http.StatusNoContent},
+		{"by someone else", uuid.New(), true, http.StatusNotFound},
+		{"when it isn't in the trash", owner, false, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		for _, restore := range []bool{true, false} {
+			name, query := "purge "+tt.name, "PurgeDeal"
+			method, path := http.MethodDelete, "/api/trash/deal/"+deal.ID.String()
+			if restore {
+				name, query = "restore "+tt.name, "RestoreDeal"
+				method, path = http.MethodPost, path+"/restore"
+			}
+			t.Run(name, func(t *testing.T) {
+				cfg, db := newTestConfig(t)
+				db.stub("GetTrashItem", func(args []any) (any, error) {
+					if !tt.trashed {
+						return nil, nil
+					}
+					return deal, nil
+				})
+
+				r := httptest.NewRequest(method, path, nil)
+				r.SetPathValue("kind", "deal")
+				r.SetPathValue("recordID", deal.ID.String())
+				w := httptest.NewRecorder()
+				cfg.handleTrashItem(w, asUser(r, tt.user), restore)
+
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				if lookup := db.callsTo("GetTrashItem"); len(lookup) != 1 || lookup[0][0] != "deal" || lookup[0][1] != deal.ID {
+					t.Errorf("looked up the trash with %v, want the deal in the path", lookup)
+				}
+				want := 0
+				if tt.status == http.StatusNoContent {
+					want = 1
+				}
+				if got := len(db.callsTo(query)); got != want {
+					t.Errorf("ran %s %d times, want %d", query, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestTrashItemRejectsUnknownKind(t *testing.T) {
+	cfg, db := newTestConfig(t)
+
+	id := uuid.New()
+	r := httptest.NewRequest(http.MethodDelete, "/api/trash/note/"+id.String(), nil)
+	r.SetPathValue("kind", "note")
+	r.SetPathValue("recordID", id.String())
+	w := httptest.NewRecorder()
+	cfg.PurgeTrashItem(w, asUser(r, uuid.New()))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if got := len(db.callsTo("GetTrashItem")); got != 0 {
+		t.Errorf("looked up the trash %d times for an unknown kind", got)
+	}
+}
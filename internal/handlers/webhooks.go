@@ -3,12 +3,19 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/DiegoGarciaCo/CRM/internal/audit"
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
 	"github.com/DiegoGarciaCo/CRM/internal/database"
+	"github.com/DiegoGarciaCo/CRM/internal/phone"
 	"github.com/google/uuid"
 )
 
+// CollectLandingPageForm saves a lead from a landing page. With an
+// organization_id query parameter the lead belongs to that organization,
+// which the caller must be a member of, and is routed with its rules.
 func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request) {
 	// Get User ID from context
 	userID, err := GetUserUUID(r.Context())
@@ -23,11 +30,36 @@ func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	var orgID uuid.NullUUID
+	if id := r.URL.Query().Get("organization_id"); id != "" {
+		orgID.UUID, err = uuid.Parse(id)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid organization ID", err)
+			return
+		}
+		orgID.Valid = true
+
+		_, err = cfg.DB.GetMemberRole(r.Context(), database.GetMemberRoleParams{
+			UserID:         userID,
+			OrganizationID: orgID.UUID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusForbidden, "Not a member of the organization", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check organization membership", err)
+			return
+		}
+	}
+
 	type LandingPageForm struct {
 		FirstName   string `json:"first_name"`
 		LastName    string `json:"last_name"`
 		Email       string `json:"email"`
 		PhoneNumber string `json:"phone_number"`
+		City        string `json:"city"`
+		ZipCode     string `json:"zip_code"`
 	}
 
 	var form LandingPageForm
@@ -37,6 +69,16 @@ func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Landing pages may leave the phone number optional
+	var number phone.Number
+	if form.PhoneNumber != "" {
+		number, err = cfg.parsePhone(form.PhoneNumber)
+		if err != nil {
+			respondWithPhoneError(w, form.PhoneNumber, err)
+			return
+		}
+	}
+
 	// Start DB transaction
 	tx, err := cfg.RawDB.BeginTx(r.Context(), nil)
 	if err != nil {
@@ -49,10 +91,13 @@ func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request
 
 	// Insert form data into the database
 	contact, err := qtx.LandingPageEmails(r.Context(), database.LandingPageEmailsParams{
-		FirstName: form.FirstName,
-		LastName:  form.LastName,
-		Source:    sql.NullString{String: source, Valid: true},
-		OwnerID:   uuid.NullUUID{UUID: userID, Valid: true},
+		FirstName:      form.FirstName,
+		LastName:       form.LastName,
+		Source:         sql.NullString{String: source, Valid: true},
+		OwnerID:        uuid.NullUUID{UUID: userID, Valid: true},
+		OrganizationID: orgID,
+		City:           sql.NullString{String: form.City, Valid: form.City != ""},
+		ZipCode:        sql.NullString{String: form.ZipCode, Valid: form.ZipCode != ""},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to save form data", err)
@@ -60,9 +105,10 @@ func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request
 	}
 
 	// Insert email into the database
-	err = qtx.EnterEmail(r.Context(), database.EnterEmailParams{
+	_, err = qtx.EnterEmail(r.Context(), database.EnterEmailParams{
 		ContactID:    uuid.NullUUID{UUID: contact.ID, Valid: true},
 		EmailAddress: form.Email,
+		IsPrimary:    sql.NullBool{Bool: true, Valid: true},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to save email", err)
@@ -70,12 +116,49 @@ func (cfg *apiCfg) CollectLandingPageForm(w http.ResponseWriter, r *http.Request
 	}
 
 	// Insert phone number into the database
-	err = qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
-		ContactID:   uuid.NullUUID{UUID: contact.ID, Valid: true},
-		PhoneNumber: form.PhoneNumber,
+	if number.E164 != "" {
+		_, err = qtx.EnterPhoneNumber(r.Context(), database.EnterPhoneNumberParams{
+			ContactID:   uuid.NullUUID{UUID: contact.ID, Valid: true},
+			PhoneNumber: number.Display,
+			E164:        sql.NullString{String: number.E164, Valid: true},
+			IsPrimary:   sql.NullBool{Bool: true, Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save phone number", err)
+			return
+		}
+	}
+
+	// Hand the lead to an agent of the organization
+	if orgID.Valid {
+		lr, err := cfg.loadLeadRouter(r.Context(), qtx, orgID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to load routing rules", err)
+			return
+		}
+		if err := lr.route(r.Context(), qtx, contact); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route lead", err)
+			return
+		}
+	}
+	if err := cfg.recordAudit(r.Context(), qtx, authz.KindContact, contact.ID, audit.Create, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to record audit event", err)
+		return
+	}
+
+	// Start the action plans for leads from this source, once the lead has
+	// its owner so the tasks go to them
+	plans, err := qtx.ListLeadActionPlans(r.Context(), database.ListLeadActionPlansParams{
+		Source:         source,
+		UserID:         uuid.NullUUID{UUID: userID, Valid: true},
+		OrganizationID: orgID,
 	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to save phone number", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to load action plans", err)
+		return
+	}
+	if err := cfg.applyTriggeredActionPlans(r.Context(), qtx, plans, contact.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to apply action plans", err)
 		return
 	}
 
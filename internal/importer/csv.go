@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// contactFields are the contact columns a CSV header can be mapped to.
+var contactFields = []string{
+	"first_name",
+	"last_name",
+	"birthdate",
+	"source",
+	"status",
+	"address",
+	"city",
+	"state",
+	"zip_code",
+	"lender",
+	"price_range",
+	"timeframe",
+}
+
+// Mapping maps a CSV header to a contact field. Phones and emails accept an
+// optional type suffix ("phone:mobile", "email:work"), and any number of
+// columns may map to phone, email or tags. An empty target or "ignore" drops
+// the column.
+type Mapping map[string]string
+
+// Validate returns an error listing every header mapped to an unknown field.
+func (m Mapping) Validate() error {
+	var bad []string
+	for header, target := range m {
+		if !validTarget(target) {
+			bad = append(bad, fmt.Sprintf("%s -> %s", header, target))
+		}
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return fmt.Errorf("unknown mapping targets: %s", strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+func validTarget(target string) bool {
+	field, _, _ := strings.Cut(target, ":")
+	switch field {
+	case "", "ignore", "phone", "email", "tags":
+		return true
+	}
+	for _, f := range contactFields {
+		if f == target {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultMapping guesses a mapping from the header row by normalizing header
+// names ("First Name", "first-name", "FirstName") against the field names.
+func DefaultMapping(headers []string) Mapping {
+	aliases := map[string]string{
+		"firstname":    "first_name",
+		"first":        "first_name",
+		"lastname":     "last_name",
+		"last":         "last_name",
+		"birthdate":    "birthdate",
+		"birthday":     "birthdate",
+		"source":       "source",
+		"leadsource":   "source",
+		"status":       "status",
+		"address":      "address",
+		"street":       "address",
+		"city":         "city",
+		"state":        "state",
+		"zip":          "zip_code",
+		"zipcode":      "zip_code",
+		"postalcode":   "zip_code",
+		"lender":       "lender",
+		"pricerange":   "price_range",
+		"timeframe":    "timeframe",
+		"phone":        "phone",
+		"phonenumber":  "phone",
+		"mobile":       "phone:mobile",
+		"cell":         "phone:mobile",
+		"email":        "email",
+		"emailaddress": "email",
+		"tags":         "tags",
+	}
+
+	m := Mapping{}
+	for _, h := range headers {
+		key := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(h))
+		key = strings.TrimRight(key, "0123456789")
+		if target, ok := aliases[key]; ok {
+			m[h] = target
+		}
+	}
+	return m
+}
+
+// ReadHeaders returns the header row of a CSV file with any UTF-8 BOM removed.
+func ReadHeaders(r io.Reader) ([]string, error) {
+	reader := newCSVReader(r)
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	return cleanHeaders(headers), nil
+}
+
+// ParseCSV reads a CSV file whose first row is a header and applies mapping
+// to each following row. When mapping is nil the headers are auto-mapped.
+// Rows that fail to parse are returned with Err set rather than aborting the
+// whole file.
+func ParseCSV(r io.Reader, mapping Mapping) ([]Row, error) {
+	reader := newCSVReader(r)
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+	headers = cleanHeaders(headers)
+
+	if mapping == nil {
+		mapping = DefaultMapping(headers)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		contact, err := applyMapping(headers, record, mapping)
+		rows = append(rows, Row{Line: line, Contact: contact, Err: err})
+	}
+
+	return rows, nil
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+func cleanHeaders(headers []string) []string {
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSpace(headers[i])
+	}
+	return headers
+}
+
+func applyMapping(headers, record []string, mapping Mapping) (Contact, error) {
+	var c Contact
+	for i, value := range record {
+		if i >= len(headers) {
+			break
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		field, kind, _ := strings.Cut(mapping[headers[i]], ":")
+		switch field {
+		case "first_name":
+			c.FirstName = value
+		case "last_name":
+			c.LastName = value
+		case "birthdate":
+			date, err := parseBirthdate(value)
+			if err != nil {
+				return c, err
+			}
+			c.Birthdate = date
+		case "source":
+			c.Source = value
+		case "status":
+			c.Status = value
+		case "address":
+			c.Address = value
+		case "city":
+			c.City = value
+		case "state":
+			c.State = value
+		case "zip_code":
+			c.ZipCode = value
+		case "lender":
+			c.Lender = value
+		case "price_range":
+			c.PriceRange = value
+		case "timeframe":
+			c.Timeframe = value
+		case "phone":
+			c.Phones = append(c.Phones, Phone{Number: value, Type: kind})
+		case "email":
+			c.Emails = append(c.Emails, Email{Address: strings.ToLower(value), Type: kind})
+		case "tags":
+			c.Tags = append(c.Tags, splitTags(value)...)
+		}
+	}
+
+	c.setPrimaries()
+	return c, c.Validate()
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DiegoGarciaCo/CRM/internal/phone"
+)
+
+// Row is a single contact parsed from an uploaded file. Line is the 1-based
+// line in the source file the row started on, so errors can be reported back
+// against the user's spreadsheet.
+type Row struct {
+	Line    int
+	Contact Contact
+	Err     error
+}
+
+type Phone struct {
+	Number    string
+	E164      string
+	Type      string
+	IsPrimary bool
+}
+
+type Email struct {
+	Address   string
+	Type      string
+	IsPrimary bool
+}
+
+type Contact struct {
+	FirstName  string
+	LastName   string
+	Birthdate  time.Time
+	Source     string
+	Status     string
+	Address    string
+	City       string
+	State      string
+	ZipCode    string
+	Lender     string
+	PriceRange string
+	Timeframe  string
+	Phones     []Phone
+	Emails     []Email
+	Tags       []string
+}
+
+// IsEmpty reports whether the row carries nothing that identifies a person.
+// Empty rows are skipped instead of failed.
+func (c Contact) IsEmpty() bool {
+	return c.FirstName == "" && c.LastName == "" && len(c.Emails) == 0 && len(c.Phones) == 0
+}
+
+// Column limits mirror the VARCHAR sizes in sql/schema so bad rows are caught
+// before they reach the database.
+var fieldLimits = map[string]int{
+	"first_name":  100,
+	"last_name":   100,
+	"source":      100,
+	"status":      50,
+	"address":     255,
+	"city":        100,
+	"state":       100,
+	"zip_code":    20,
+	"lender":      100,
+	"price_range": 50,
+	"timeframe":   50,
+}
+
+// Validate checks the contact against the database column limits.
+func (c Contact) Validate() error {
+	values := map[string]string{
+		"first_name":  c.FirstName,
+		"last_name":   c.LastName,
+		"source":      c.Source,
+		"status":      c.Status,
+		"address":     c.Address,
+		"city":        c.City,
+		"state":       c.State,
+		"zip_code":    c.ZipCode,
+		"lender":      c.Lender,
+		"price_range": c.PriceRange,
+		"timeframe":   c.Timeframe,
+	}
+	for _, field := range contactFields {
+		if len(values[field]) > fieldLimits[field] {
+			return fmt.Errorf("%s exceeds %d characters", field, fieldLimits[field])
+		}
+	}
+	for _, e := range c.Emails {
+		if len(e.Address) > 255 {
+			return fmt.Errorf("email %q exceeds 255 characters", e.Address)
+		}
+		if !strings.Contains(e.Address, "@") {
+			return fmt.Errorf("invalid email %q", e.Address)
+		}
+	}
+	for _, t := range c.Tags {
+		if len(t) > 100 {
+			return fmt.Errorf("tag %q exceeds 100 characters", t)
+		}
+	}
+	return nil
+}
+
+// NormalizePhones parses every phone number, reading national numbers as
+// numbers of region, and replaces it with its display form. Parsing needs the
+// importing user's region, so it happens after the file has been read rather
+// than in Validate.
+func (c *Contact) NormalizePhones(region string) error {
+	for i, p := range c.Phones {
+		n, err := phone.Parse(p.Number, region)
+		if err != nil {
+			return fmt.Errorf("invalid phone number %q: %w", p.Number, err)
+		}
+		c.Phones[i].Number = n.Display
+		c.Phones[i].E164 = n.E164
+	}
+	return nil
+}
+
+// setPrimaries leaves exactly one primary phone and email: the first one the
+// source file flagged, or the first one when it flagged none.
+func (c *Contact) setPrimaries() {
+	hasPrimary := false
+	for i := range c.Phones {
+		c.Phones[i].IsPrimary = c.Phones[i].IsPrimary && !hasPrimary
+		hasPrimary = hasPrimary || c.Phones[i].IsPrimary
+	}
+	if !hasPrimary && len(c.Phones) > 0 {
+		c.Phones[0].IsPrimary = true
+	}
+
+	hasPrimary = false
+	for i := range c.Emails {
+		c.Emails[i].IsPrimary = c.Emails[i].IsPrimary && !hasPrimary
+		hasPrimary = hasPrimary || c.Emails[i].IsPrimary
+	}
+	if !hasPrimary && len(c.Emails) > 0 {
+		c.Emails[0].IsPrimary = true
+	}
+}
+
+var birthdateLayouts = []string{
+	"2006-01-02",
+	"01/02/2006",
+	"1/2/2006",
+	"01-02-2006",
+	"2006/01/02",
+	"20060102",
+}
+
+func parseBirthdate(s string) (time.Time, error) {
+	for _, layout := range birthdateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid birthdate %q, use YYYY-MM-DD or MM/DD/YYYY", s)
+}
+
+// splitTags splits a tag cell on semicolons or commas and drops blanks.
+func splitTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' })
+	tags := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			tags = append(tags, f)
+		}
+	}
+	return tags
+}
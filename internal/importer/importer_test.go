@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffFirst Name,Last Name,Email,Mobile,Birthday,Tags\n" +
+		"Jane,Doe,JANE@example.com,555-123-4567,1985-04-15,buyer;hot\n" +
+		",,,,,\n" +
+		"John,Smith,john@example.com,,04/15/85x,\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("ParseCSV() returned %d rows, want 3", len(rows))
+	}
+
+	jane := rows[0]
+	if jane.Err != nil {
+		t.Fatalf("row 1 error = %v", jane.Err)
+	}
+	if jane.Line != 2 {
+		t.Errorf("row 1 line = %d, want 2", jane.Line)
+	}
+	if jane.Contact.FirstName != "Jane" || jane.Contact.LastName != "Doe" {
+		t.Errorf("row 1 name = %q %q", jane.Contact.FirstName, jane.Contact.LastName)
+	}
+	if len(jane.Contact.Emails) != 1 || jane.Contact.Emails[0].Address != "jane@example.com" || !jane.Contact.Emails[0].IsPrimary {
+		t.Errorf("row 1 emails = %+v", jane.Contact.Emails)
+	}
+	if len(jane.Contact.Phones) != 1 || jane.Contact.Phones[0].Type != "mobile" {
+		t.Errorf("row 1 phones = %+v", jane.Contact.Phones)
+	}
+	if got := strings.Join(jane.Contact.Tags, ","); got != "buyer,hot" {
+		t.Errorf("row 1 tags = %q", got)
+	}
+
+	if !rows[1].Contact.IsEmpty() {
+		t.Errorf("row 2 should be empty, got %+v", rows[1].Contact)
+	}
+
+	if rows[2].Err == nil {
+		t.Errorf("row 3 should fail on its birthdate")
+	}
+}
+
+func TestParseCSVMapping(t *testing.T) {
+	input := "Col A,Col B,Col C\nJane,Doe,jane@work.com\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), Mapping{
+		"Col A": "first_name",
+		"Col B": "last_name",
+		"Col C": "email:work",
+	})
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("ParseCSV() rows = %+v", rows)
+	}
+	if rows[0].Contact.Emails[0].Type != "work" {
+		t.Errorf("email type = %q, want work", rows[0].Contact.Emails[0].Type)
+	}
+
+	_, err = ParseCSV(strings.NewReader(input), Mapping{"Col A": "nickname"})
+	if err == nil {
+		t.Errorf("ParseCSV() with unknown target should fail")
+	}
+}
+
+func TestParseVCard(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:Doe;Jane;;;\r\n" +
+		"FN:Jane Doe\r\n" +
+		"EMAIL;TYPE=INTERNET,HOME:jane@example.com\r\n" +
+		"TEL;TYPE=CELL:+1 555 123 4567\r\n" +
+		"TEL;TYPE=WORK,PREF:555-765-4321\r\n" +
+		"ADR;TYPE=HOME:;;123 Main St;Denver;CO;80202;USA\r\n" +
+		"BDAY:1985-04-15\r\n" +
+		"CATEGORIES:Buyer,Zillow\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:John Smith\r\n" +
+		"BDAY:not-a-date\r\n" +
+		"END:VCARD\r\n"
+
+	rows, err := ParseVCard(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseVCard() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("ParseVCard() returned %d rows, want 2", len(rows))
+	}
+
+	jane := rows[0]
+	if jane.Err != nil {
+		t.Fatalf("card 1 error = %v", jane.Err)
+	}
+	c := jane.Contact
+	if c.FirstName != "Jane" || c.LastName != "Doe" || c.City != "Denver" || c.ZipCode != "80202" {
+		t.Errorf("card 1 contact = %+v", c)
+	}
+	if len(c.Phones) != 2 || c.Phones[0].Type != "mobile" || c.Phones[0].IsPrimary || !c.Phones[1].IsPrimary {
+		t.Errorf("card 1 phones = %+v", c.Phones)
+	}
+	if len(c.Tags) != 2 {
+		t.Errorf("card 1 tags = %v", c.Tags)
+	}
+
+	if rows[1].Line != 12 || rows[1].Err == nil {
+		t.Errorf("card 2 = line %d err %v, want line 12 with an error", rows[1].Line, rows[1].Err)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[
+		{"first_name": "Jane", "last_name": "Doe", "birthdate": "1985-04-15", "zipCode": "80202",
+		 "emails": [{"email": "Jane@Example.com", "type": "work"}], "tags": ["buyer", " "]},
+		{"first_name": "John", "birthdate": "15/04/1985"}
+	]`
+
+	rows, err := ParseJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("ParseJSON() returned %d rows, want 2", len(rows))
+	}
+
+	jane := rows[0]
+	if jane.Err != nil || jane.Line != 1 {
+		t.Fatalf("row 1 = line %d err %v", jane.Line, jane.Err)
+	}
+	if jane.Contact.ZipCode != "80202" || len(jane.Contact.Tags) != 1 {
+		t.Errorf("row 1 contact = %+v", jane.Contact)
+	}
+	if len(jane.Contact.Emails) != 1 || jane.Contact.Emails[0].Address != "jane@example.com" || !jane.Contact.Emails[0].IsPrimary {
+		t.Errorf("row 1 emails = %+v", jane.Contact.Emails)
+	}
+
+	if rows[1].Err == nil {
+		t.Errorf("row 2 should fail on its birthdate")
+	}
+
+	if _, err := ParseJSON(strings.NewReader(`{"first_name": "Jane"}`)); err == nil {
+		t.Errorf("ParseJSON() with an object should fail")
+	}
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonContact is the payload shape accepted by POST /api/contacts/import.
+type jsonContact struct {
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Birthdate    string `json:"birthdate"`
+	PhoneNumbers []struct {
+		Number    string `json:"number"`
+		Type      string `json:"type"`
+		IsPrimary bool   `json:"is_primary"`
+	} `json:"phone_numbers"`
+	Emails []struct {
+		Email     string `json:"email"`
+		Type      string `json:"type"`
+		IsPrimary bool   `json:"is_primary"`
+	} `json:"emails"`
+	Source     string   `json:"source"`
+	Status     string   `json:"status"`
+	Address    string   `json:"address"`
+	City       string   `json:"city"`
+	State      string   `json:"state"`
+	Zipcode    string   `json:"zipCode"`
+	Lender     string   `json:"lender"`
+	PriceRange string   `json:"price_range"`
+	Timeframe  string   `json:"timeframe"`
+	Tags       []string `json:"tags"`
+}
+
+// ParseJSON reads a JSON array of contacts. Line is the 1-based position of
+// the contact in the array.
+func ParseJSON(r io.Reader) ([]Row, error) {
+	var contacts []jsonContact
+	if err := json.NewDecoder(r).Decode(&contacts); err != nil {
+		return nil, fmt.Errorf("decoding contacts: %w", err)
+	}
+
+	rows := make([]Row, 0, len(contacts))
+	for i, jc := range contacts {
+		c := Contact{
+			FirstName:  strings.TrimSpace(jc.FirstName),
+			LastName:   strings.TrimSpace(jc.LastName),
+			Source:     strings.TrimSpace(jc.Source),
+			Status:     strings.TrimSpace(jc.Status),
+			Address:    strings.TrimSpace(jc.Address),
+			City:       strings.TrimSpace(jc.City),
+			State:      strings.TrimSpace(jc.State),
+			ZipCode:    strings.TrimSpace(jc.Zipcode),
+			Lender:     strings.TrimSpace(jc.Lender),
+			PriceRange: strings.TrimSpace(jc.PriceRange),
+			Timeframe:  strings.TrimSpace(jc.Timeframe),
+		}
+		for _, p := range jc.PhoneNumbers {
+			if p.Number = strings.TrimSpace(p.Number); p.Number != "" {
+				c.Phones = append(c.Phones, Phone{Number: p.Number, Type: p.Type, IsPrimary: p.IsPrimary})
+			}
+		}
+		for _, e := range jc.Emails {
+			if e.Email = strings.ToLower(strings.TrimSpace(e.Email)); e.Email != "" {
+				c.Emails = append(c.Emails, Email{Address: e.Email, Type: e.Type, IsPrimary: e.IsPrimary})
+			}
+		}
+		for _, t := range jc.Tags {
+			if t = strings.TrimSpace(t); t != "" {
+				c.Tags = append(c.Tags, t)
+			}
+		}
+
+		var err error
+		if jc.Birthdate != "" {
+			c.Birthdate, err = parseBirthdate(jc.Birthdate)
+		}
+		if err == nil {
+			c.setPrimaries()
+			err = c.Validate()
+		}
+		rows = append(rows, Row{Line: i + 1, Contact: c, Err: err})
+	}
+
+	return rows, nil
+}
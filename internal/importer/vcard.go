@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseVCard reads every card in a .vcf file (vCard 2.1, 3.0 or 4.0). Cards
+// that cannot be parsed are returned with Err set.
+func ParseVCard(r io.Reader) ([]Row, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	var card []vcardLine
+	start := 0
+	inCard := false
+
+	for _, l := range lines {
+		name, params, value := splitProperty(l.text)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			inCard = true
+			start = l.number
+			card = card[:0]
+		case name == "END" && strings.EqualFold(value, "VCARD"):
+			if inCard {
+				contact, err := parseCard(card)
+				rows = append(rows, Row{Line: start, Contact: contact, Err: err})
+			}
+			inCard = false
+		case inCard:
+			card = append(card, vcardLine{name: name, params: params, value: value})
+		}
+	}
+
+	if inCard {
+		rows = append(rows, Row{Line: start, Err: fmt.Errorf("card is missing END:VCARD")})
+	}
+
+	return rows, nil
+}
+
+type numberedLine struct {
+	number int
+	text   string
+}
+
+type vcardLine struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// unfoldLines joins continuation lines (those starting with a space or tab)
+// onto the line before them, as required by RFC 6350.
+func unfoldLines(r io.Reader) ([]numberedLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []numberedLine
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if n == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, numberedLine{number: n, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// splitProperty splits "item1.TEL;TYPE=CELL,PREF:+1 555" into its upper-cased
+// name, parameters and raw value. Bare 2.1 style parameters ("TEL;CELL:")
+// are treated as TYPE values.
+func splitProperty(line string) (string, map[string][]string, string) {
+	head, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", nil, ""
+	}
+
+	parts := strings.Split(head, ";")
+	name := strings.ToUpper(parts[0])
+	if _, after, ok := strings.Cut(name, "."); ok {
+		name = after
+	}
+
+	params := map[string][]string{}
+	for _, p := range parts[1:] {
+		key, val, ok := strings.Cut(p, "=")
+		if !ok {
+			key, val = "TYPE", p
+		}
+		key = strings.ToUpper(key)
+		for _, v := range strings.Split(val, ",") {
+			params[key] = append(params[key], strings.ToLower(strings.Trim(v, `"`)))
+		}
+	}
+
+	return name, params, value
+}
+
+func parseCard(lines []vcardLine) (Contact, error) {
+	var c Contact
+	var fullName string
+
+	for _, l := range lines {
+		switch l.name {
+		case "N":
+			parts := splitStructured(l.value)
+			if len(parts) > 0 {
+				c.LastName = parts[0]
+			}
+			if len(parts) > 1 {
+				c.FirstName = parts[1]
+			}
+		case "FN":
+			fullName = unescape(l.value)
+		case "EMAIL":
+			address := strings.ToLower(strings.TrimPrefix(unescape(l.value), "mailto:"))
+			if address == "" {
+				continue
+			}
+			c.Emails = append(c.Emails, Email{
+				Address:   address,
+				Type:      vcardType(l.params, "home", "work"),
+				IsPrimary: isPreferred(l.params),
+			})
+		case "TEL":
+			number := strings.TrimPrefix(unescape(l.value), "tel:")
+			if number == "" {
+				continue
+			}
+			phoneType := vcardType(l.params, "cell", "home", "work")
+			if phoneType == "cell" {
+				phoneType = "mobile"
+			}
+			c.Phones = append(c.Phones, Phone{
+				Number:    number,
+				Type:      phoneType,
+				IsPrimary: isPreferred(l.params),
+			})
+		case "ADR":
+			if c.Address != "" {
+				continue
+			}
+			// PO box; extended; street; locality; region; postal code; country
+			parts := splitStructured(l.value)
+			get := func(i int) string {
+				if i < len(parts) {
+					return parts[i]
+				}
+				return ""
+			}
+			c.Address = get(2)
+			c.City = get(3)
+			c.State = get(4)
+			c.ZipCode = get(5)
+		case "BDAY":
+			value := unescape(l.value)
+			// Dates without a year ("--0415") can't be stored in a DATE column.
+			if value == "" || strings.HasPrefix(value, "--") {
+				continue
+			}
+			if len(value) > 10 {
+				value = value[:10]
+			}
+			date, err := parseBirthdate(value)
+			if err != nil {
+				return c, err
+			}
+			c.Birthdate = date
+		case "CATEGORIES":
+			for _, tag := range splitEscaped(l.value, ',') {
+				if tag != "" {
+					c.Tags = append(c.Tags, tag)
+				}
+			}
+		}
+	}
+
+	if c.FirstName == "" && c.LastName == "" && fullName != "" {
+		first, last, _ := strings.Cut(fullName, " ")
+		c.FirstName, c.LastName = first, strings.TrimSpace(last)
+	}
+
+	c.setPrimaries()
+	return c, c.Validate()
+}
+
+func splitStructured(value string) []string {
+	return splitEscaped(value, ';')
+}
+
+// splitEscaped splits on sep, ignoring separators escaped with a backslash,
+// and unescapes each part.
+func splitEscaped(value string, sep rune) []string {
+	var parts []string
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			b.WriteRune(r)
+			escaped = true
+		case r == sep:
+			parts = append(parts, strings.TrimSpace(unescape(b.String())))
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(unescape(b.String())))
+}
+
+var vcardUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(s string) string {
+	return strings.TrimSpace(vcardUnescaper.Replace(s))
+}
+
+func vcardType(params map[string][]string, known ...string) string {
+	for _, t := range params["TYPE"] {
+		for _, k := range known {
+			if t == k {
+				return k
+			}
+		}
+	}
+	return ""
+}
+
+func isPreferred(params map[string][]string) bool {
+	for _, t := range params["TYPE"] {
+		if t == "pref" {
+			return true
+		}
+	}
+	_, ok := params["PREF"]
+	return ok
+}
@@ -0,0 +1,225 @@
+// Package phone parses phone numbers written in national or international
+// format and normalizes them to E.164 ("+15551234567") plus a display form.
+//
+// It intentionally knows only the numbering rules the CRM needs: full
+// validation for the North American Numbering Plan and length checks based on
+// the country calling code for everything else.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultRegion is used when no region is configured.
+const DefaultRegion = "US"
+
+var (
+	ErrEmpty         = errors.New("phone number is empty")
+	ErrInvalidChars  = errors.New("phone number contains letters or symbols")
+	ErrExtension     = errors.New("phone number extensions are not supported")
+	ErrTooShort      = errors.New("phone number is too short")
+	ErrTooLong       = errors.New("phone number is too long")
+	ErrCountryCode   = errors.New("phone number has an unknown country code")
+	ErrInvalidNANP   = errors.New("phone number is not a valid North American number")
+	ErrUnknownRegion = errors.New("unknown phone region")
+	ErrNeedsCountry  = errors.New("phone number needs a country code, start it with +")
+)
+
+// Number is a parsed phone number.
+type Number struct {
+	// E164 is the canonical form, e.g. "+15551234567"
+	E164 string
+	// Display is the human friendly form, e.g. "(555) 123-4567"
+	Display string
+	// CountryCode is the calling code without "+", e.g. "1"
+	CountryCode string
+	// National is the national significant number, e.g. "5551234567"
+	National string
+}
+
+type region struct {
+	code  string
+	trunk string
+}
+
+// regions maps ISO 3166 region codes that can be used as the default region
+// to their calling code and national trunk prefix.
+var regions = map[string]region{
+	"US": {"1", "1"}, "CA": {"1", "1"}, "PR": {"1", "1"},
+	"GB": {"44", "0"}, "IE": {"353", "0"}, "FR": {"33", "0"}, "DE": {"49", "0"},
+	"NL": {"31", "0"}, "BE": {"32", "0"}, "CH": {"41", "0"}, "AT": {"43", "0"},
+	"ES": {"34", ""}, "PT": {"351", ""}, "IT": {"39", ""}, "DK": {"45", ""},
+	"NO": {"47", ""}, "SE": {"46", "0"}, "FI": {"358", "0"}, "PL": {"48", ""},
+	"MX": {"52", ""}, "BR": {"55", "0"}, "AR": {"54", "0"}, "CO": {"57", ""},
+	"AU": {"61", "0"}, "NZ": {"64", "0"}, "JP": {"81", "0"}, "KR": {"82", "0"},
+	"CN": {"86", "0"}, "IN": {"91", "0"}, "PH": {"63", "0"}, "SG": {"65", ""},
+	"HK": {"852", ""}, "IL": {"972", "0"}, "AE": {"971", "0"}, "ZA": {"27", "0"},
+}
+
+// shortCodes are the one and two digit country calling codes. Calling codes
+// are prefix free, so any other number has a three digit code.
+var shortCodes = map[string]bool{
+	"1": true, "7": true,
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true,
+	"36": true, "39": true, "40": true, "41": true, "43": true, "44": true, "45": true,
+	"46": true, "47": true, "48": true, "49": true, "51": true, "52": true, "53": true,
+	"54": true, "55": true, "56": true, "57": true, "58": true, "60": true, "61": true,
+	"62": true, "63": true, "64": true, "65": true, "66": true, "81": true, "82": true,
+	"84": true, "86": true, "90": true, "91": true, "92": true, "93": true, "94": true,
+	"95": true, "98": true,
+}
+
+// unassigned lists leading digits that no calling code starts with.
+var unassigned = []string{"0", "80", "83", "87", "89", "99"}
+
+// ValidRegion reports whether region can be used as a default region.
+func ValidRegion(r string) bool {
+	_, ok := regions[strings.ToUpper(r)]
+	return ok
+}
+
+// Parse normalizes raw. Numbers without a leading "+" or international "00"
+// prefix are read as national numbers of defaultRegion.
+func Parse(raw, defaultRegion string) (Number, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return Number{}, ErrEmpty
+	}
+
+	// "+44 (0)20 ..." shows the trunk prefix that is dropped when dialing
+	// from abroad
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "00") {
+		s = strings.Replace(s, "(0)", "", 1)
+	}
+
+	international := false
+	var digits strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case strings.ContainsRune(" -.()/ ", r):
+			// formatting
+		case r == 'x' || r == 'X' || r == '#' || strings.HasPrefix(strings.ToLower(s[i:]), "ext"):
+			return Number{}, ErrExtension
+		default:
+			return Number{}, ErrInvalidChars
+		}
+	}
+	d := digits.String()
+	if !international && strings.HasPrefix(d, "00") {
+		international = true
+		d = d[2:]
+	}
+
+	if international {
+		return parseInternational(d)
+	}
+
+	reg, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		if defaultRegion == "" {
+			return Number{}, ErrNeedsCountry
+		}
+		return Number{}, fmt.Errorf("%w %q", ErrUnknownRegion, defaultRegion)
+	}
+
+	if reg.code == "1" {
+		// Accept "1 555 123 4567" as well as the bare ten digits
+		if len(d) == 11 && strings.HasPrefix(d, "1") {
+			d = d[1:]
+		}
+		return nanp(d)
+	}
+	if reg.trunk != "" {
+		d = strings.TrimPrefix(d, reg.trunk)
+	}
+	return build(reg.code, d)
+}
+
+func parseInternational(d string) (Number, error) {
+	if len(d) < 2 {
+		return Number{}, ErrTooShort
+	}
+	for _, p := range unassigned {
+		if strings.HasPrefix(d, p) {
+			return Number{}, ErrCountryCode
+		}
+	}
+
+	var code string
+	switch {
+	case shortCodes[d[:1]]:
+		code = d[:1]
+	case shortCodes[d[:2]]:
+		code = d[:2]
+	case len(d) >= 3:
+		code = d[:3]
+	default:
+		return Number{}, ErrTooShort
+	}
+
+	if code == "1" {
+		return nanp(d[1:])
+	}
+	return build(code, d[len(code):])
+}
+
+// nanp validates a ten digit North American number. Exchange codes aren't
+// checked so the 555 numbers used in examples and tests stay valid.
+func nanp(d string) (Number, error) {
+	switch {
+	case len(d) < 10:
+		return Number{}, ErrTooShort
+	case len(d) > 10:
+		return Number{}, ErrTooLong
+	case d[0] < '2':
+		return Number{}, ErrInvalidNANP
+	}
+	return Number{
+		E164:        "+1" + d,
+		Display:     "(" + d[:3] + ") " + d[3:6] + "-" + d[6:],
+		CountryCode: "1",
+		National:    d,
+	}, nil
+}
+
+// build checks the length of a national number and formats it. E.164 allows
+// at most 15 digits including the country code.
+func build(code, national string) (Number, error) {
+	switch {
+	case len(national) < 4:
+		return Number{}, ErrTooShort
+	case len(code)+len(national) > 15:
+		return Number{}, ErrTooLong
+	}
+	return Number{
+		E164:        "+" + code + national,
+		Display:     "+" + code + " " + group(national),
+		CountryCode: code,
+		National:    national,
+	}, nil
+}
+
+// group splits a national number into blocks of up to three digits, keeping
+// the last four together: "2079460958" -> "207 946 0958".
+func group(d string) string {
+	if len(d) <= 4 {
+		return d
+	}
+	head, tail := d[:len(d)-4], d[len(d)-4:]
+	// The first block takes the remainder so "1234" becomes "1 234"
+	first := len(head) % 3
+	if first == 0 {
+		first = 3
+	}
+	parts := []string{head[:first]}
+	for head = head[first:]; head != ""; head = head[3:] {
+		parts = append(parts, head[:3])
+	}
+	return strings.Join(append(parts, tail), " ")
+}
@@ -0,0 +1,62 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw, region, e164, display string
+	}{
+		{"(555) 123-4567", "US", "+15551234567", "(555) 123-4567"},
+		{"5551234567", "US", "+15551234567", "(555) 123-4567"},
+		{"1-555-123-4567", "US", "+15551234567", "(555) 123-4567"},
+		{"+1 555.123.4567", "GB", "+15551234567", "(555) 123-4567"},
+		{"020 7946 0958", "GB", "+442079460958", "+44 207 946 0958"},
+		{"+44 (0)20 7946 0958", "US", "+442079460958", "+44 207 946 0958"},
+		{"0044 20 7946 0958", "US", "+442079460958", "+44 207 946 0958"},
+		{"+353 1 234 5678", "US", "+35312345678", "+353 1 234 5678"},
+		{"06 12 34 56 78", "fr", "+33612345678", "+33 61 234 5678"},
+		{"+39 06 1234 5678", "US", "+390612345678", "+39 061 234 5678"},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.raw, tt.region)
+		if err != nil {
+			t.Errorf("Parse(%q, %q) error = %v", tt.raw, tt.region, err)
+			continue
+		}
+		if n.E164 != tt.e164 {
+			t.Errorf("Parse(%q, %q).E164 = %q, want %q", tt.raw, tt.region, n.E164, tt.e164)
+		}
+		if n.Display != tt.display {
+			t.Errorf("Parse(%q, %q).Display = %q, want %q", tt.raw, tt.region, n.Display, tt.display)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		raw, region string
+		want        error
+	}{
+		{"", "US", ErrEmpty},
+		{"   ", "US", ErrEmpty},
+		{"555-1234", "US", ErrTooShort},
+		{"555 123 45678", "US", ErrTooLong},
+		{"(155) 123-4567", "US", ErrInvalidNANP},
+		{"call me", "US", ErrInvalidChars},
+		{"555-123-4567 x12", "US", ErrExtension},
+		{"555-123-4567 ext. 12", "US", ErrExtension},
+		{"+999 1234 5678", "US", ErrCountryCode},
+		{"+44 123", "US", ErrTooShort},
+		{"+49 1234 5678 9012 345", "US", ErrTooLong},
+		{"5551234567", "XX", ErrUnknownRegion},
+		{"5551234567", "", ErrNeedsCountry},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.raw, tt.region); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.raw, tt.region, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package recurrence schedules recurring tasks from iCalendar (RFC 5545)
+// RRULEs such as "FREQ=DAILY;INTERVAL=90" or "FREQ=WEEKLY;BYDAY=MO".
+//
+// A rule is anchored at the task's first date, its DTSTART, and occurrences
+// keep that time of day in the anchor's location.
+package recurrence
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+var (
+	ErrEmpty     = errors.New("recurrence rule is empty")
+	ErrDtstart   = errors.New("recurrence rule can't set DTSTART, it starts at the task's date")
+	ErrFrequency = errors.New("tasks can repeat at most daily")
+	ErrNoStart   = errors.New("recurring tasks need a date")
+)
+
+// Rule is a parsed recurrence rule anchored at its start.
+type Rule struct {
+	rrule *rrule.RRule
+}
+
+// Parse parses an RRULE, with or without the "RRULE:" prefix, starting at
+// start. Rules repeating more often than daily are rejected.
+func Parse(rule string, start time.Time) (Rule, error) {
+	rule = strings.TrimSpace(rule)
+	rule = strings.TrimPrefix(strings.TrimPrefix(rule, "RRULE:"), "rrule:")
+	if rule == "" {
+		return Rule{}, ErrEmpty
+	}
+	if start.IsZero() {
+		return Rule{}, ErrNoStart
+	}
+	if strings.ContainsAny(rule, "\r\n") || strings.Contains(strings.ToUpper(rule), "DTSTART") {
+		return Rule{}, ErrDtstart
+	}
+
+	opt, err := rrule.StrToROptionInLocation(strings.ToUpper(rule), start.Location())
+	if err != nil {
+		return Rule{}, err
+	}
+	if opt.Freq > rrule.DAILY {
+		return Rule{}, ErrFrequency
+	}
+	opt.Dtstart = start
+
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{rrule: r}, nil
+}
+
+// String returns the rule in its canonical form, without DTSTART.
+func (r Rule) String() string {
+	return r.rrule.OrigOptions.RRuleString()
+}
+
+// First returns the first occurrence, which is the start itself when it
+// matches the rule. It is zero when the rule has no occurrences.
+func (r Rule) First() time.Time {
+	return r.rrule.After(r.rrule.GetDTStart(), true)
+}
+
+// From returns the first occurrence at or after t, or zero once the rule
+// has ended.
+func (r Rule) From(t time.Time) time.Time {
+	return r.rrule.After(t, true)
+}
+
+// After returns the first occurrence strictly after t, or zero once the
+// rule has ended.
+func (r Rule) After(t time.Time) time.Time {
+	return r.rrule.After(t, false)
+}
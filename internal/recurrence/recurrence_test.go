@@ -0,0 +1,98 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02T15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestWeeklyStartsOnFirstMatchingDay(t *testing.T) {
+	// Wednesday, so the first occurrence is the following Monday
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO", date("2024-05-01T09:30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.First(); !got.Equal(date("2024-05-06T09:30")) {
+		t.Errorf("First() = %v", got)
+	}
+	if got := rule.After(date("2024-05-06T09:30")); !got.Equal(date("2024-05-13T09:30")) {
+		t.Errorf("After() = %v", got)
+	}
+}
+
+func TestEveryNinetyDays(t *testing.T) {
+	rule, err := Parse("RRULE:FREQ=DAILY;INTERVAL=90", date("2024-01-10T10:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := rule.First()
+	if !first.Equal(date("2024-01-10T10:00")) {
+		t.Errorf("First() = %v", first)
+	}
+	if got := rule.After(first); !got.Equal(date("2024-04-09T10:00")) {
+		t.Errorf("After() = %v", got)
+	}
+	// Moving an occurrence doesn't move the schedule
+	if got := rule.From(date("2024-03-01T00:00")); !got.Equal(date("2024-04-09T10:00")) {
+		t.Errorf("From() = %v", got)
+	}
+	if rule.String() != "FREQ=DAILY;INTERVAL=90" {
+		t.Errorf("String() = %q", rule.String())
+	}
+}
+
+func TestRuleEnds(t *testing.T) {
+	rule, err := Parse("FREQ=MONTHLY;COUNT=2", date("2024-01-31T08:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := rule.After(rule.First())
+	if !second.Equal(date("2024-03-31T08:00")) {
+		t.Errorf("second occurrence = %v, months without a 31st are skipped", second)
+	}
+	if got := rule.After(second); !got.IsZero() {
+		t.Errorf("After() the last occurrence = %v, want zero", got)
+	}
+
+	rule, err = Parse("FREQ=WEEKLY;UNTIL=20240115T000000Z", date("2024-01-01T08:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rule.After(date("2024-01-08T08:00")); !got.IsZero() {
+		t.Errorf("After() UNTIL = %v, want zero", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	start := date("2024-01-01T08:00")
+	tests := []struct {
+		rule  string
+		start time.Time
+		want  error
+	}{
+		{"", start, ErrEmpty},
+		{"FREQ=DAILY", time.Time{}, ErrNoStart},
+		{"FREQ=HOURLY", start, ErrFrequency},
+		{"DTSTART:20240101T000000Z\nRRULE:FREQ=DAILY", start, ErrDtstart},
+		{"FREQ=DAILY;DTSTART=20240101T000000Z", start, ErrDtstart},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.rule, tt.start); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.rule, err, tt.want)
+		}
+	}
+
+	for _, rule := range []string{"INTERVAL=2", "FREQ=SOMETIMES", "FREQ=WEEKLY;BYDAY=XX"} {
+		if _, err := Parse(rule, start); err == nil {
+			t.Errorf("Parse(%q) should fail", rule)
+		}
+	}
+}
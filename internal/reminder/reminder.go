@@ -0,0 +1,84 @@
+// Package reminder decides how agents are reminded of upcoming tasks and
+// appointments.
+//
+// A reminder is sent a lead time ahead of the record's due time, once per
+// lead time a user has chosen, such as a day and an hour before.
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// MaxLeadTimes caps how many reminders a user gets for one record.
+const MaxLeadTimes = 5
+
+// MaxLeadMinutes is the longest lead time, 30 days.
+const MaxLeadMinutes = 30 * 24 * 60
+
+// DefaultLeadMinutes are the lead times of users who haven't chosen any: a
+// day and an hour ahead.
+var DefaultLeadMinutes = []int32{24 * 60, 60}
+
+var (
+	ErrTooManyLeadTimes = fmt.Errorf("at most %d lead times are allowed", MaxLeadTimes)
+	ErrLeadTime         = errors.New("lead times must be between 1 minute and 30 days")
+)
+
+// NormalizeLeadMinutes validates lead times in minutes and returns them
+// longest first, without duplicates.
+func NormalizeLeadMinutes(minutes []int) ([]int32, error) {
+	leads := make([]int32, 0, len(minutes))
+	for _, m := range minutes {
+		if m < 1 || m > MaxLeadMinutes {
+			return nil, ErrLeadTime
+		}
+		if !slices.Contains(leads, int32(m)) {
+			leads = append(leads, int32(m))
+		}
+	}
+	if len(leads) > MaxLeadTimes {
+		return nil, ErrTooManyLeadTimes
+	}
+	slices.Sort(leads)
+	slices.Reverse(leads)
+	return leads, nil
+}
+
+// Until describes how long until due, rounded for a reminder message: "in
+// 45 minutes", "in 1 hour", "in 3 days". Reminders are sent on a polling
+// interval, so a reminder a day ahead that goes out a minute late still
+// reads "in 1 day".
+func Until(due, now time.Time) string {
+	minutes := int(math.Round(due.Sub(now).Minutes()))
+	switch {
+	case minutes < 1:
+		return "now"
+	case minutes < 55:
+		return "in " + plural(minutes, "minute")
+	case minutes < 24*60-30:
+		return "in " + plural((minutes+30)/60, "hour")
+	default:
+		return "in " + plural((minutes+12*60)/(24*60), "day")
+	}
+}
+
+// TaskMessage is the reminder for a task.
+func TaskMessage(title string, due, now time.Time) string {
+	return fmt.Sprintf("Task %q is due %s.", title, Until(due, now))
+}
+
+// AppointmentMessage is the reminder for an appointment.
+func AppointmentMessage(title string, due, now time.Time) string {
+	return fmt.Sprintf("Appointment %q starts %s.", title, Until(due, now))
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,62 @@
+package reminder
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizeLeadMinutes(t *testing.T) {
+	got, err := NormalizeLeadMinutes([]int{60, 1440, 60, 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int32{1440, 60, 15}; !slices.Equal(got, want) {
+		t.Errorf("NormalizeLeadMinutes() = %v, want %v", got, want)
+	}
+
+	if got, err := NormalizeLeadMinutes(nil); err != nil || len(got) != 0 {
+		t.Errorf("NormalizeLeadMinutes(nil) = %v, %v", got, err)
+	}
+
+	for _, leads := range [][]int{{0}, {-5}, {MaxLeadMinutes + 1}} {
+		if _, err := NormalizeLeadMinutes(leads); !errors.Is(err, ErrLeadTime) {
+			t.Errorf("NormalizeLeadMinutes(%v) error = %v, want ErrLeadTime", leads, err)
+		}
+	}
+	if _, err := NormalizeLeadMinutes([]int{1, 2, 3, 4, 5, 6}); !errors.Is(err, ErrTooManyLeadTimes) {
+		t.Errorf("NormalizeLeadMinutes() error = %v, want ErrTooManyLeadTimes", err)
+	}
+}
+
+func TestUntil(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{30 * time.Second, "in 1 minute"},
+		{10 * time.Second, "now"},
+		{45 * time.Minute, "in 45 minutes"},
+		{59 * time.Minute, "in 1 hour"},
+		{150 * time.Minute, "in 3 hours"},
+		{24*time.Hour - time.Minute, "in 1 day"},
+		{72 * time.Hour, "in 3 days"},
+	}
+	for _, tt := range tests {
+		if got := Until(now.Add(tt.in), now); got != tt.want {
+			t.Errorf("Until(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := TaskMessage("Call Ana", now.Add(time.Hour), now); got != `Task "Call Ana" is due in 1 hour.` {
+		t.Errorf("TaskMessage() = %q", got)
+	}
+	if got := AppointmentMessage("Showing", now.Add(24*time.Hour), now); got != `Appointment "Showing" starts in 1 day.` {
+		t.Errorf("AppointmentMessage() = %q", got)
+	}
+}
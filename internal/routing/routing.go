@@ -0,0 +1,170 @@
+// Package routing decides which agent of an organization an incoming lead is
+// assigned to.
+//
+// An organization has rules in priority order. The first rule whose
+// conditions match the lead routes it; a rule with no conditions matches
+// every lead. The rule's strategy then picks one of its agents, or, for
+// FirstToClaim, offers the lead to all of them.
+package routing
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategy is how a rule distributes leads among its agents.
+type Strategy string
+
+const (
+	// RoundRobin gives each lead to the agent who has gone longest without
+	// one from the rule.
+	RoundRobin Strategy = "round_robin"
+	// Weighted gives each agent leads in proportion to their capacity.
+	Weighted Strategy = "weighted"
+	// FirstToClaim offers the lead to every agent and the first to claim it
+	// gets it.
+	FirstToClaim Strategy = "first_to_claim"
+)
+
+// Strategies lists the valid strategies.
+var Strategies = []Strategy{RoundRobin, Weighted, FirstToClaim}
+
+// ValidStrategy reports whether s is a known strategy.
+func ValidStrategy(s string) bool {
+	for _, strategy := range Strategies {
+		if string(strategy) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Lead is the subset of a contact rules match on.
+type Lead struct {
+	ZipCode string
+	City    string
+	Source  string
+}
+
+// Agent is an agent a rule routes to, with what the rule has given them so
+// far.
+type Agent struct {
+	UserID uuid.UUID
+	// Capacity is the agent's share of leads under Weighted.
+	Capacity int
+	// Assigned is how many leads the rule has assigned the agent.
+	Assigned int64
+	// LastAssigned is when the rule last assigned the agent a lead, zero if
+	// never.
+	LastAssigned time.Time
+}
+
+// Rule routes the leads matching all of its non-empty conditions. Zip codes
+// match by prefix, so "981" covers every zip code starting with it; cities
+// and sources match case-insensitively.
+type Rule struct {
+	ID       uuid.UUID
+	Strategy Strategy
+	ZipCodes []string
+	Cities   []string
+	Sources  []string
+	Agents   []Agent
+}
+
+// Matches reports whether the rule applies to lead.
+func (r Rule) Matches(lead Lead) bool {
+	zip := strings.TrimSpace(lead.ZipCode)
+	if len(r.ZipCodes) > 0 && !anyOf(r.ZipCodes, func(z string) bool {
+		z = strings.TrimSpace(z)
+		return z != "" && strings.HasPrefix(zip, z)
+	}) {
+		return false
+	}
+	if len(r.Cities) > 0 && !anyOf(r.Cities, equalFold(lead.City)) {
+		return false
+	}
+	if len(r.Sources) > 0 && !anyOf(r.Sources, equalFold(lead.Source)) {
+		return false
+	}
+	return true
+}
+
+// Match returns the first of rules, which are in priority order, that
+// applies to lead and has agents to route to.
+func Match(rules []Rule, lead Lead) (Rule, bool) {
+	for _, r := range rules {
+		if len(r.Agents) > 0 && r.Matches(lead) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Pick chooses the agent the next lead goes to, skipping those in exclude.
+// FirstToClaim rules pick like RoundRobin, which is how a lead nobody
+// claimed in time is handed out. Ties go to the agent listed first.
+func (r Rule) Pick(exclude map[uuid.UUID]bool) (uuid.UUID, bool) {
+	best := -1
+	for i, a := range r.Agents {
+		if exclude[a.UserID] {
+			continue
+		}
+		if best < 0 || r.before(a, r.Agents[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return uuid.Nil, false
+	}
+	return r.Agents[best].UserID, true
+}
+
+// Assigned records that the agent was given a lead at, so later picks from
+// the rule take it into account.
+func (r *Rule) Assigned(userID uuid.UUID, at time.Time) {
+	for i := range r.Agents {
+		if r.Agents[i].UserID == userID {
+			r.Agents[i].Assigned++
+			r.Agents[i].LastAssigned = at
+		}
+	}
+}
+
+// before reports whether a should get the next lead ahead of b.
+func (r Rule) before(a, b Agent) bool {
+	if r.Strategy == Weighted {
+		// Compare Assigned/Capacity without dividing
+		load := a.Assigned * int64(capacity(b))
+		other := b.Assigned * int64(capacity(a))
+		if load != other {
+			return load < other
+		}
+	}
+	return a.LastAssigned.Before(b.LastAssigned)
+}
+
+// capacity treats missing capacities as 1.
+func capacity(a Agent) int {
+	if a.Capacity < 1 {
+		return 1
+	}
+	return a.Capacity
+}
+
+func anyOf(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalFold(s string) func(string) bool {
+	s = strings.TrimSpace(s)
+	return func(v string) bool {
+		return s != "" && strings.EqualFold(strings.TrimSpace(v), s)
+	}
+}
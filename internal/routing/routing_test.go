@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		lead Lead
+		want bool
+	}{
+		{"no conditions", Rule{}, Lead{}, true},
+		{"zip prefix", Rule{ZipCodes: []string{"981"}}, Lead{ZipCode: "98101"}, true},
+		{"zip exact", Rule{ZipCodes: []string{"98101"}}, Lead{ZipCode: " 98101 "}, true},
+		{"other zip", Rule{ZipCodes: []string{"981"}}, Lead{ZipCode: "97201"}, false},
+		{"missing zip", Rule{ZipCodes: []string{"981"}}, Lead{}, false},
+		{"city any case", Rule{Cities: []string{"Seattle", "Tacoma"}}, Lead{City: "tacoma"}, true},
+		{"other city", Rule{Cities: []string{"Seattle"}}, Lead{City: "Portland"}, false},
+		{"source", Rule{Sources: []string{"Zillow"}}, Lead{Source: "zillow"}, true},
+		{"all conditions", Rule{Cities: []string{"Seattle"}, Sources: []string{"Zillow"}}, Lead{City: "Seattle", Source: "Zillow"}, true},
+		{"one condition fails", Rule{Cities: []string{"Seattle"}, Sources: []string{"Zillow"}}, Lead{City: "Seattle", Source: "Redfin"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Matches(tt.lead); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSkipsRulesWithoutAgents(t *testing.T) {
+	agent := Agent{UserID: uuid.New()}
+	empty := Rule{ID: uuid.New(), Sources: []string{"Zillow"}}
+	zillow := Rule{ID: uuid.New(), Sources: []string{"Zillow"}, Agents: []Agent{agent}}
+	fallback := Rule{ID: uuid.New(), Agents: []Agent{agent}}
+	rules := []Rule{empty, zillow, fallback}
+
+	if got, ok := Match(rules, Lead{Source: "Zillow"}); !ok || got.ID != zillow.ID {
+		t.Errorf("Match(Zillow) = %v, %v, want the Zillow rule", got.ID, ok)
+	}
+	if got, ok := Match(rules, Lead{Source: "Redfin"}); !ok || got.ID != fallback.ID {
+		t.Errorf("Match(Redfin) = %v, %v, want the fallback rule", got.ID, ok)
+	}
+	if _, ok := Match([]Rule{empty}, Lead{Source: "Zillow"}); ok {
+		t.Error("Match() found a rule without agents")
+	}
+}
+
+// distribute routes n leads through rule and counts what each agent got.
+func distribute(rule Rule, n int) map[uuid.UUID]int {
+	got := map[uuid.UUID]int{}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for range n {
+		id, ok := rule.Pick(nil)
+		if !ok {
+			break
+		}
+		got[id]++
+		at = at.Add(time.Minute)
+		rule.Assigned(id, at)
+	}
+	return got
+}
+
+func TestPickRoundRobin(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	rule := Rule{Strategy: RoundRobin, Agents: []Agent{
+		{UserID: a, Capacity: 5},
+		{UserID: b},
+		// c had a lead most recently, so goes last
+		{UserID: c, LastAssigned: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	var order []uuid.UUID
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for range 6 {
+		id, _ := rule.Pick(nil)
+		order = append(order, id)
+		at = at.Add(time.Minute)
+		rule.Assigned(id, at)
+	}
+	want := []uuid.UUID{a, b, c, a, b, c}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("round robin order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	rule := Rule{Strategy: Weighted, Agents: []Agent{
+		{UserID: a, Capacity: 3},
+		{UserID: b, Capacity: 1},
+		{UserID: c, Capacity: 0},
+	}}
+
+	got := distribute(rule, 50)
+	if got[a] != 30 || got[b] != 10 || got[c] != 10 {
+		t.Errorf("weighted distribution = %d/%d/%d, want 30/10/10", got[a], got[b], got[c])
+	}
+}
+
+func TestPickWeightedCatchesUpNewAgents(t *testing.T) {
+	veteran, rookie := uuid.New(), uuid.New()
+	rule := Rule{Strategy: Weighted, Agents: []Agent{
+		{UserID: veteran, Capacity: 1, Assigned: 10},
+		{UserID: rookie, Capacity: 1},
+	}}
+
+	got := distribute(rule, 12)
+	if got[rookie] != 11 || got[veteran] != 1 {
+		t.Errorf("got veteran %d, rookie %d, want 1 and 11", got[veteran], got[rookie])
+	}
+}
+
+func TestPickExcludes(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	rule := Rule{Strategy: FirstToClaim, Agents: []Agent{{UserID: a}, {UserID: b}}}
+
+	if got, ok := rule.Pick(map[uuid.UUID]bool{a: true}); !ok || got != b {
+		t.Errorf("Pick(exclude a) = %v, %v, want b", got, ok)
+	}
+	if _, ok := rule.Pick(map[uuid.UUID]bool{a: true, b: true}); ok {
+		t.Error("Pick() found an agent with everyone excluded")
+	}
+}
+
+func TestValidStrategy(t *testing.T) {
+	for _, s := range []string{"round_robin", "weighted", "first_to_claim"} {
+		if !ValidStrategy(s) {
+			t.Errorf("ValidStrategy(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "zip_code", "Round_Robin"} {
+		if ValidStrategy(s) {
+			t.Errorf("ValidStrategy(%q) = true", s)
+		}
+	}
+}
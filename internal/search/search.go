@@ -0,0 +1,157 @@
+// Package search prepares contact search input for the SearchContacts query
+// and builds the highlighted snippets shown next to each result.
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MinPhoneDigits is the shortest digit sequence that is matched against phone
+// numbers; shorter ones would match nearly every contact.
+const MinPhoneDigits = 3
+
+// snippetLength is the maximum number of characters of context in a snippet.
+const snippetLength = 160
+
+// Match fields reported by the SearchContacts query.
+const (
+	FieldName    = "name"
+	FieldEmail   = "email"
+	FieldPhone   = "phone"
+	FieldAddress = "address"
+	FieldNote    = "note"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Pattern returns an ILIKE pattern matching the query anywhere in a value.
+func Pattern(query string) string {
+	return "%" + likeEscaper.Replace(strings.TrimSpace(query)) + "%"
+}
+
+// Digits returns the digits of the query, or "" when there are too few of
+// them to search phone numbers with.
+func Digits(query string) string {
+	var b strings.Builder
+	for _, r := range query {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() < MinPhoneDigits {
+		return ""
+	}
+	return b.String()
+}
+
+// DigitsPattern returns a LIKE pattern matching the query's digits anywhere
+// in a digits-only phone number, or "" when phones shouldn't be searched.
+func DigitsPattern(query string) string {
+	digits := Digits(query)
+	if digits == "" {
+		return ""
+	}
+	return "%" + digits + "%"
+}
+
+// Snippet returns an HTML-escaped excerpt of text with the parts matching
+// query wrapped in <mark>. Long text is cut down to a window around the first
+// match. Phone numbers are matched on their digits so "5551234" highlights
+// "555) 123-4".
+func Snippet(field, text, query string) string {
+	re := matcher(field, query)
+
+	var matches [][]int
+	if re != nil {
+		matches = re.FindAllStringIndex(text, -1)
+	}
+
+	start, end := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetLength {
+		first := 0
+		if len(matches) > 0 {
+			first = matches[0][0]
+		}
+		start, end = window(text, first)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[1] <= pos || m[0] >= end {
+			continue
+		}
+		from, to := max(m[0], pos), min(m[1], end)
+		b.WriteString(html.EscapeString(text[pos:from]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[from:to]))
+		b.WriteString("</mark>")
+		pos = to
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// matcher builds a case-insensitive regexp for the query's terms.
+func matcher(field, query string) *regexp.Regexp {
+	if field == FieldPhone {
+		digits := Digits(query)
+		if digits == "" {
+			return nil
+		}
+		// Allow formatting characters between the digits
+		parts := strings.Split(digits, "")
+		return regexp.MustCompile(strings.Join(parts, `\D*`))
+	}
+
+	var terms []string
+	for _, t := range strings.Fields(query) {
+		terms = append(terms, regexp.QuoteMeta(t))
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	// Prefer the whole query over its individual words
+	whole := regexp.QuoteMeta(strings.TrimSpace(query))
+	return regexp.MustCompile("(?i)" + whole + "|" + strings.Join(terms, "|"))
+}
+
+// window picks snippetLength characters of text around the match at byte
+// offset at, snapped to word boundaries where possible.
+func window(text string, at int) (int, int) {
+	const before = snippetLength / 3
+
+	start := at
+	for n := 0; n < before && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	// Don't start in the middle of a word
+	if start > 0 {
+		if i := strings.IndexFunc(text[start:at], unicode.IsSpace); i >= 0 {
+			start += i + 1
+		}
+	}
+
+	end := start
+	for n := 0; n < snippetLength && end < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	if end < len(text) {
+		if i := strings.LastIndexFunc(text[at:end], unicode.IsSpace); i > 0 {
+			end = at + i
+		}
+	}
+	return start, end
+}
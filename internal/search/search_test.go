@@ -0,0 +1,50 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	if got := Pattern(" 100%_off "); got != `%100\%\_off%` {
+		t.Errorf("Pattern() = %q", got)
+	}
+	if got := DigitsPattern("(555) 12"); got != "%55512%" {
+		t.Errorf("DigitsPattern() = %q", got)
+	}
+	if got := DigitsPattern("Apt 4"); got != "" {
+		t.Errorf("DigitsPattern() with one digit = %q, want empty", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		field, text, query, want string
+	}{
+		{FieldName, "Jane Doe", "jan", "<mark>Jan</mark>e Doe"},
+		{FieldName, "Jane Doe", "jane doe", "<mark>Jane Doe</mark>"},
+		{FieldEmail, "jane.doe@example.com", "doe@ex", "jane.<mark>doe@ex</mark>ample.com"},
+		{FieldPhone, "(555) 123-4567", "5551234", "(<mark>555) 123-4</mark>567"},
+		{FieldAddress, "12 <Main> St", "main", "12 &lt;<mark>Main</mark>&gt; St"},
+		{FieldNote, "no match here", "zzz", "no match here"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.field, tt.text, tt.query); got != tt.want {
+			t.Errorf("Snippet(%q, %q, %q) = %q, want %q", tt.field, tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSnippetWindow(t *testing.T) {
+	note := strings.Repeat("filler words here ", 20) + "wants a pool in the backyard " + strings.Repeat("more filler text ", 20)
+	got := Snippet(FieldNote, note, "pool")
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Snippet() = %q, want ellipses on both ends", got)
+	}
+	if !strings.Contains(got, "wants a <mark>pool</mark> in the backyard") {
+		t.Errorf("Snippet() = %q, missing highlighted match", got)
+	}
+	if n := len([]rune(got)); n > snippetLength+len("<mark></mark>")+2 {
+		t.Errorf("Snippet() is %d characters long", n)
+	}
+}
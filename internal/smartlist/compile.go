@@ -0,0 +1,190 @@
+package smartlist
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Compile renders the filter as a boolean SQL expression over the contacts
+// table aliased as "c". Values are never inlined; they are returned as args
+// numbered from $(offset+1) so the expression can be embedded in a query
+// that already uses offset parameters.
+func (f Filter) Compile(offset int) (string, []any, error) {
+	if err := f.Validate(); err != nil {
+		return "", nil, err
+	}
+	c := compiler{offset: offset}
+	return c.node(f), c.args, nil
+}
+
+type compiler struct {
+	offset int
+	args   []any
+}
+
+// arg adds a parameter and returns its placeholder.
+func (c *compiler) arg(v any) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$%d", c.offset+len(c.args))
+}
+
+func (c *compiler) node(f Filter) string {
+	if !f.IsGroup() {
+		return c.rule(f)
+	}
+	if len(f.Rules) == 0 {
+		return "TRUE"
+	}
+	sep := " AND "
+	if f.Match == MatchAny {
+		sep = " OR "
+	}
+	parts := make([]string, len(f.Rules))
+	for i, r := range f.Rules {
+		parts[i] = c.node(r)
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+func (c *compiler) rule(f Filter) string {
+	fd := fields[f.Field]
+	// The filter was validated, so the value always decodes
+	value, _ := decodeValue(fd.kind, f.Op, f.Value)
+
+	switch fd.kind {
+	case kindText:
+		return c.text(fd, f.Op, value)
+	case kindDate, kindTimestamp:
+		return c.date(fd, f.Op, value)
+	case kindUUID:
+		return c.uuid(fd, f.Op, value)
+	case kindTag:
+		cond := "EXISTS (SELECT 1 FROM contact_tags ct WHERE ct.contact_id = c.id AND ct.tag_id = ANY(" + c.arg(uuidArray(value)) + "::uuid[]))"
+		if f.Op == OpNotHasTag {
+			return "NOT " + cond
+		}
+		return cond
+	case kindDealStage:
+		cond := "EXISTS (SELECT 1 FROM deals d WHERE d.contact_id = c.id AND d.deleted_at IS NULL AND d.stage_id = ANY(" + c.arg(uuidArray(value)) + "::uuid[]))"
+		if f.Op == OpNotHasDealInStage {
+			return "NOT " + cond
+		}
+		return cond
+	}
+	return "FALSE"
+}
+
+// negate wraps a condition so that rows where the column is NULL also match,
+// which is what users expect from "is not", "does not contain" and "not in".
+func negate(fd field, cond string) string {
+	if fd.nullable {
+		return "(" + fd.column + " IS NULL OR NOT " + cond + ")"
+	}
+	return "NOT " + cond
+}
+
+func (c *compiler) text(fd field, op string, value any) string {
+	col := fd.column
+	switch op {
+	case OpEq:
+		return "lower(" + col + ") = lower(" + c.arg(value) + ")"
+	case OpNeq:
+		return negate(fd, "lower("+col+") = lower("+c.arg(value)+")")
+	case OpContains:
+		return col + " ILIKE '%' || " + c.arg(escapeLike(value.(string))) + " || '%'"
+	case OpNotContains:
+		return negate(fd, "("+col+" ILIKE '%' || "+c.arg(escapeLike(value.(string)))+" || '%')")
+	case OpStartsWith:
+		return col + " ILIKE " + c.arg(escapeLike(value.(string))) + " || '%'"
+	case OpIn:
+		return "lower(" + col + ") = ANY(" + c.arg(pq.Array(lowerAll(value.([]string)))) + "::text[])"
+	case OpNotIn:
+		return negate(fd, "lower("+col+") = ANY("+c.arg(pq.Array(lowerAll(value.([]string))))+"::text[])")
+	case OpIsEmpty:
+		return "coalesce(" + col + ", '') = ''"
+	case OpIsNotEmpty:
+		return "coalesce(" + col + ", '') <> ''"
+	}
+	return "FALSE"
+}
+
+func (c *compiler) date(fd field, op string, value any) string {
+	col := fd.column
+	switch op {
+	case OpIsEmpty:
+		return col + " IS NULL"
+	case OpIsNotEmpty:
+		return col + " IS NOT NULL"
+	case OpEq:
+		return col + " = " + c.arg(value.(time.Time).Format("2006-01-02")) + "::date"
+	}
+
+	var bound string
+	relative := false
+	switch v := value.(type) {
+	case RelativeDate:
+		bound = "NOW() - " + c.arg(v.interval()) + "::interval"
+		relative = true
+	case time.Time:
+		if fd.kind == kindDate {
+			bound = c.arg(v.Format("2006-01-02")) + "::date"
+		} else {
+			bound = c.arg(v) + "::timestamptz"
+		}
+	}
+	if op == OpBefore {
+		// A contact never contacted is also "not contacted in 30 days"
+		if relative && fd.nullable {
+			return "(" + col + " IS NULL OR " + col + " < " + bound + ")"
+		}
+		return col + " < " + bound
+	}
+	return col + " > " + bound
+}
+
+func (c *compiler) uuid(fd field, op string, value any) string {
+	col := fd.column
+	switch op {
+	case OpEq:
+		return col + " = " + c.arg(value) + "::uuid"
+	case OpNeq:
+		return negate(fd, "("+col+" = "+c.arg(value)+"::uuid)")
+	case OpIn:
+		return col + " = ANY(" + c.arg(uuidArray(value)) + "::uuid[])"
+	case OpNotIn:
+		return negate(fd, "("+col+" = ANY("+c.arg(uuidArray(value))+"::uuid[]))")
+	case OpIsEmpty:
+		return col + " IS NULL"
+	case OpIsNotEmpty:
+		return col + " IS NOT NULL"
+	}
+	return "FALSE"
+}
+
+func uuidArray(value any) any {
+	ids := value.([]uuid.UUID)
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return pq.Array(strs)
+}
+
+func lowerAll(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes user input match literally inside an ILIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,372 @@
+// Package smartlist defines the filter language stored in
+// smart_lists.filter_criteria and compiles it to parameterized SQL.
+//
+// A filter is a tree of groups and rules:
+//
+//	{"match": "or", "rules": [
+//	    {"match": "and", "rules": [
+//	        {"field": "tag", "op": "has_tag", "value": "<tag id>"},
+//	        {"field": "last_contacted_at", "op": "before", "value": {"ago": 30, "unit": "days"}}
+//	    ]},
+//	    {"field": "source", "op": "in", "value": ["Zillow", "Referral"]}
+//	]}
+//
+// A node with a field is a rule, anything else is a group. An empty top-level
+// group matches every contact.
+package smartlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxDepth limits how deeply groups can be nested
+	maxDepth = 5
+	// maxRules limits the total number of rules in a filter
+	maxRules = 100
+)
+
+// Filter is a node of the filter tree. Groups set Match and Rules, rules set
+// Field, Op and Value.
+type Filter struct {
+	Match string          `json:"match,omitempty"`
+	Rules []Filter        `json:"rules,omitempty"`
+	Field string          `json:"field,omitempty"`
+	Op    string          `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Group match modes.
+const (
+	MatchAll = "and"
+	MatchAny = "or"
+)
+
+// Operators.
+const (
+	OpEq                = "eq"
+	OpNeq               = "neq"
+	OpContains          = "contains"
+	OpNotContains       = "not_contains"
+	OpStartsWith        = "starts_with"
+	OpIn                = "in"
+	OpNotIn             = "not_in"
+	OpBefore            = "before"
+	OpAfter             = "after"
+	OpIsEmpty           = "is_empty"
+	OpIsNotEmpty        = "is_not_empty"
+	OpHasTag            = "has_tag"
+	OpNotHasTag         = "not_has_tag"
+	OpHasDealInStage    = "has_deal_in_stage"
+	OpNotHasDealInStage = "not_has_deal_in_stage"
+)
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindDate
+	kindTimestamp
+	kindUUID
+	kindTag
+	kindDealStage
+)
+
+var kindOps = map[fieldKind][]string{
+	kindText:      {OpEq, OpNeq, OpContains, OpNotContains, OpStartsWith, OpIn, OpNotIn, OpIsEmpty, OpIsNotEmpty},
+	kindDate:      {OpEq, OpBefore, OpAfter, OpIsEmpty, OpIsNotEmpty},
+	kindTimestamp: {OpBefore, OpAfter, OpIsEmpty, OpIsNotEmpty},
+	kindUUID:      {OpEq, OpNeq, OpIn, OpNotIn, OpIsEmpty, OpIsNotEmpty},
+	kindTag:       {OpHasTag, OpNotHasTag},
+	kindDealStage: {OpHasDealInStage, OpNotHasDealInStage},
+}
+
+type field struct {
+	column   string
+	kind     fieldKind
+	nullable bool
+}
+
+// fields maps filterable field names to contact columns. Columns are always
+// qualified with the "c" alias used by the queries the filter is embedded in.
+var fields = map[string]field{
+	"first_name":        {"c.first_name", kindText, false},
+	"last_name":         {"c.last_name", kindText, false},
+	"source":            {"c.source", kindText, true},
+	"status":            {"c.status", kindText, true},
+	"address":           {"c.address", kindText, true},
+	"city":              {"c.city", kindText, true},
+	"state":             {"c.state", kindText, true},
+	"zip_code":          {"c.zip_code", kindText, true},
+	"lender":            {"c.lender", kindText, true},
+	"price_range":       {"c.price_range", kindText, true},
+	"timeframe":         {"c.timeframe", kindText, true},
+	"birthdate":         {"c.birthdate", kindDate, true},
+	"created_at":        {"c.created_at", kindTimestamp, true},
+	"updated_at":        {"c.updated_at", kindTimestamp, true},
+	"last_contacted_at": {"c.last_contacted_at", kindTimestamp, true},
+	"owner_id":          {"c.owner_id", kindUUID, true},
+	"tag":               {"", kindTag, false},
+	"deal_stage":        {"", kindDealStage, false},
+}
+
+// RelativeDate is a point in time relative to now, e.g. 30 days ago.
+type RelativeDate struct {
+	Ago  int    `json:"ago"`
+	Unit string `json:"unit"`
+}
+
+var relativeUnits = map[string]bool{"days": true, "weeks": true, "months": true, "years": true}
+
+// interval renders the relative date as a Postgres interval literal.
+func (d RelativeDate) interval() string {
+	return fmt.Sprintf("%d %s", d.Ago, d.Unit)
+}
+
+// FieldError describes a problem with one node of a filter. Path locates the
+// node, e.g. "rules[1].rules[0].value".
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a filter.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		if fe.Path == "" {
+			msgs[i] = fe.Message
+		} else {
+			msgs[i] = fe.Path + ": " + fe.Message
+		}
+	}
+	return "invalid filter: " + strings.Join(msgs, "; ")
+}
+
+// Parse decodes and validates a stored or submitted filter. Empty input and
+// JSON null are an empty filter that matches every contact.
+func Parse(data []byte) (Filter, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return Filter{Match: MatchAll}, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f Filter
+	if err := dec.Decode(&f); err != nil {
+		return Filter{}, ValidationError{{Message: "filter is not valid JSON: " + err.Error()}}
+	}
+	if err := f.Validate(); err != nil {
+		return Filter{}, err
+	}
+	if f.Match == "" {
+		f.Match = MatchAll
+	}
+	return f, nil
+}
+
+// Validate checks the whole tree and returns a ValidationError listing every
+// problem, or nil.
+func (f Filter) Validate() error {
+	v := validator{}
+	v.node(f, "", 1)
+	if v.rules > maxRules {
+		v.add("", fmt.Sprintf("filter has %d rules, the maximum is %d", v.rules, maxRules))
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// IsGroup reports whether the node is a group rather than a rule.
+func (f Filter) IsGroup() bool {
+	return f.Field == ""
+}
+
+type validator struct {
+	errs  ValidationError
+	rules int
+}
+
+func (v *validator) add(path, msg string) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: msg})
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func (v *validator) node(f Filter, path string, depth int) {
+	if f.IsGroup() {
+		v.group(f, path, depth)
+		return
+	}
+	v.rules++
+	v.rule(f, path)
+}
+
+func (v *validator) group(f Filter, path string, depth int) {
+	if f.Op != "" || len(f.Value) > 0 {
+		v.add(path, "a group cannot have an op or value, set field to make it a rule")
+	}
+	switch f.Match {
+	case MatchAll, MatchAny:
+	case "":
+		// Only the top level may omit match, which defaults to "and"
+		if path != "" {
+			v.add(join(path, "match"), `match is required, use "and" or "or"`)
+		}
+	default:
+		v.add(join(path, "match"), fmt.Sprintf(`unknown match %q, use "and" or "or"`, f.Match))
+	}
+	if path != "" && len(f.Rules) == 0 {
+		v.add(join(path, "rules"), "a group needs at least one rule")
+	}
+	if depth > maxDepth {
+		v.add(path, fmt.Sprintf("groups cannot be nested more than %d levels deep", maxDepth))
+		return
+	}
+	for i, r := range f.Rules {
+		v.node(r, join(path, fmt.Sprintf("rules[%d]", i)), depth+1)
+	}
+}
+
+func (v *validator) rule(f Filter, path string) {
+	if f.Match != "" || len(f.Rules) > 0 {
+		v.add(path, "a rule cannot have match or rules")
+	}
+	fd, ok := fields[f.Field]
+	if !ok {
+		v.add(join(path, "field"), fmt.Sprintf("unknown field %q", f.Field))
+		return
+	}
+	if !contains(kindOps[fd.kind], f.Op) {
+		v.add(join(path, "op"), fmt.Sprintf("op %q is not supported for %s, use one of: %s", f.Op, f.Field, strings.Join(kindOps[fd.kind], ", ")))
+		return
+	}
+	if _, err := decodeValue(fd.kind, f.Op, f.Value); err != nil {
+		v.add(join(path, "value"), err.Error())
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeValue parses a rule's value into the Go type its operator expects:
+// string, []string, time.Time, RelativeDate, uuid.UUID, []uuid.UUID or nil
+// for operators that take no value.
+func decodeValue(kind fieldKind, op string, raw json.RawMessage) (any, error) {
+	hasValue := len(raw) > 0 && !bytes.Equal(raw, []byte("null"))
+
+	if op == OpIsEmpty || op == OpIsNotEmpty {
+		if hasValue {
+			return nil, fmt.Errorf("%s does not take a value", op)
+		}
+		return nil, nil
+	}
+	if !hasValue {
+		return nil, fmt.Errorf("a value is required")
+	}
+
+	switch kind {
+	case kindText:
+		if op == OpIn || op == OpNotIn {
+			var list []string
+			if err := json.Unmarshal(raw, &list); err != nil || len(list) == 0 {
+				return nil, fmt.Errorf("must be a non-empty list of strings")
+			}
+			return list, nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if s == "" {
+			return nil, fmt.Errorf("must not be empty, use is_empty to match missing values")
+		}
+		return s, nil
+
+	case kindDate, kindTimestamp:
+		var rel RelativeDate
+		if raw[0] == '{' {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&rel); err != nil {
+				return nil, fmt.Errorf(`relative dates look like {"ago": 30, "unit": "days"}`)
+			}
+			if op == OpEq {
+				return nil, fmt.Errorf("eq needs an exact date, use before or after for relative dates")
+			}
+			if rel.Ago < 0 {
+				return nil, fmt.Errorf("ago must not be negative")
+			}
+			if !relativeUnits[rel.Unit] {
+				return nil, fmt.Errorf("unknown unit %q, use days, weeks, months or years", rel.Unit)
+			}
+			return rel, nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("must be a date (YYYY-MM-DD), a timestamp (RFC 3339) or a relative date")
+		}
+		return parseTime(s)
+
+	case kindUUID, kindTag, kindDealStage:
+		single := kind == kindUUID && (op == OpEq || op == OpNeq)
+		var ids []string
+		if raw[0] == '[' && !single {
+			if err := json.Unmarshal(raw, &ids); err != nil || len(ids) == 0 {
+				return nil, fmt.Errorf("must be an ID or a non-empty list of IDs")
+			}
+		} else {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, fmt.Errorf("must be an ID")
+			}
+			ids = []string{s}
+		}
+		parsed := make([]uuid.UUID, len(ids))
+		for i, id := range ids {
+			u, err := uuid.Parse(id)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a valid ID", id)
+			}
+			parsed[i] = u
+		}
+		if single {
+			return parsed[0], nil
+		}
+		return parsed, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field")
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD) or timestamp (RFC 3339)", s)
+}
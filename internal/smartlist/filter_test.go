@@ -0,0 +1,117 @@
+package smartlist
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseEmpty(t *testing.T) {
+	for _, in := range []string{"", "null", "{}", `{"match": "and", "rules": []}`} {
+		f, err := Parse([]byte(in))
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", in, err)
+		}
+		where, args, err := f.Compile(0)
+		if err != nil || where != "TRUE" || len(args) != 0 {
+			t.Errorf("Parse(%q).Compile() = %q, %v, %v", in, where, args, err)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	f, err := Parse([]byte(`{"match": "or", "rules": [
+		{"match": "and", "rules": [
+			{"field": "tag", "op": "has_tag", "value": "6f1c1a52-6d5b-4c55-9a1b-9b6f3f0c2a11"},
+			{"field": "last_contacted_at", "op": "before", "value": {"ago": 30, "unit": "days"}}
+		]},
+		{"field": "source", "op": "in", "value": ["Zillow", "Referral"]},
+		{"field": "city", "op": "not_contains", "value": "50%"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	where, args, err := f.Compile(2)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	want := "((EXISTS (SELECT 1 FROM contact_tags ct WHERE ct.contact_id = c.id AND ct.tag_id = ANY($3::uuid[])) AND (c.last_contacted_at IS NULL OR c.last_contacted_at < NOW() - $4::interval))" +
+		" OR lower(c.source) = ANY($5::text[])" +
+		" OR (c.city IS NULL OR NOT (c.city ILIKE '%' || $6 || '%')))"
+	if where != want {
+		t.Errorf("Compile() =\n%s\nwant\n%s", where, want)
+	}
+	if len(args) != 4 || args[1] != "30 days" || args[3] != `50\%` {
+		t.Errorf("Compile() args = %#v", args)
+	}
+}
+
+func TestCompileDateBefore(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"relative on a nullable column matches NULL", `{"field": "last_contacted_at", "op": "before", "value": {"ago": 30, "unit": "days"}}`, "(c.last_contacted_at IS NULL OR c.last_contacted_at < NOW() - $1::interval)"},
+		{"relative after doesn't", `{"field": "last_contacted_at", "op": "after", "value": {"ago": 30, "unit": "days"}}`, "c.last_contacted_at > NOW() - $1::interval"},
+		{"absolute date doesn't", `{"field": "birthdate", "op": "before", "value": "1990-01-01"}`, "c.birthdate < $1::date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(`{"rules": [` + tt.rule + `]}`))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			where, _, err := f.Compile(0)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if where != "("+tt.want+")" {
+				t.Errorf("Compile() = %s, want (%s)", where, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	_, err := Parse([]byte(`{"match": "and", "rules": [
+		{"field": "favorite_color", "op": "eq", "value": "blue"},
+		{"field": "first_name", "op": "before", "value": "2024-01-01"},
+		{"match": "or", "rules": [
+			{"field": "created_at", "op": "after", "value": {"ago": 3, "unit": "fortnights"}},
+			{"field": "owner_id", "op": "eq", "value": "not-a-uuid"},
+			{"field": "city", "op": "is_empty", "value": "Denver"}
+		]},
+		{"match": "xor", "rules": []}
+	]}`))
+
+	var verr ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Parse() error = %v, want ValidationError", err)
+	}
+	wantPaths := []string{
+		"rules[0].field",
+		"rules[1].op",
+		"rules[2].rules[0].value",
+		"rules[2].rules[1].value",
+		"rules[2].rules[2].value",
+		"rules[3].match",
+		"rules[3].rules",
+	}
+	if len(verr) != len(wantPaths) {
+		t.Fatalf("got %d errors, want %d: %v", len(verr), len(wantPaths), verr)
+	}
+	for i, p := range wantPaths {
+		if verr[i].Path != p {
+			t.Errorf("error %d path = %q, want %q (%s)", i, verr[i].Path, p, verr[i].Message)
+		}
+	}
+}
+
+func TestParseRejectsUnknownKeys(t *testing.T) {
+	_, err := Parse([]byte(`{"first_name": "Jane"}`))
+	if err == nil || !strings.Contains(err.Error(), "first_name") {
+		t.Errorf("Parse() error = %v, want unknown field error", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package timezone computes calendar periods, such as today or this week,
+// in a user's time zone.
+//
+// Periods are half-open ranges of instants, so queries compare timestamps
+// against them directly instead of casting to dates in the database
+// server's time zone. Periods start at local midnight and may be an hour
+// shorter or longer when they contain a daylight saving change.
+package timezone
+
+import (
+	"errors"
+	"time"
+)
+
+// Default is the time zone of users who haven't chosen one.
+const Default = "UTC"
+
+var ErrUnknown = errors.New("unknown time zone")
+
+// Period is the range of instants from Start up to but not including End.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the period.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// Load loads an IANA time zone such as "America/Los_Angeles". Unlike
+// time.LoadLocation it rejects "" and "Local", which mean the server's zone.
+func Load(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrUnknown
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrUnknown
+	}
+	return loc, nil
+}
+
+// Day returns the day t falls on in loc.
+func Day(t time.Time, loc *time.Location) Period {
+	y, m, d := t.In(loc).Date()
+	return Period{Start: startOfDay(y, m, d, loc), End: startOfDay(y, m, d+1, loc)}
+}
+
+// Week returns the week t falls in in loc. Weeks start on Monday, as in
+// ISO 8601.
+func Week(t time.Time, loc *time.Location) Period {
+	t = t.In(loc)
+	y, m, d := t.Date()
+	d -= (int(t.Weekday()) + 6) % 7
+	return Period{Start: startOfDay(y, m, d, loc), End: startOfDay(y, m, d+7, loc)}
+}
+
+// Month returns the calendar month t falls in in loc.
+func Month(t time.Time, loc *time.Location) Period {
+	y, m, _ := t.In(loc).Date()
+	return Period{Start: startOfDay(y, m, 1, loc), End: startOfDay(y, m+1, 1, loc)}
+}
+
+// Year returns the calendar year t falls in in loc.
+func Year(t time.Time, loc *time.Location) Period {
+	y := t.In(loc).Year()
+	return Period{Start: startOfDay(y, time.January, 1, loc), End: startOfDay(y+1, time.January, 1, loc)}
+}
+
+// startOfDay returns the first instant of a day in loc, normalizing the
+// date like time.Date. Where a daylight saving change skips midnight, the
+// day starts at the change.
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	m := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if m.Hour() == 0 {
+		return m
+	}
+	// time.Date resolves the missing midnight into the previous evening or
+	// the early morning depending on the zone, so step to the change
+	zoneStart, zoneEnd := m.ZoneBounds()
+	if m.Hour() >= 12 {
+		return zoneEnd
+	}
+	return zoneStart
+}
@@ -0,0 +1,105 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := Load(name)
+	if err != nil {
+		t.Skip(err)
+	}
+	return loc
+}
+
+func TestDayAcrossDST(t *testing.T) {
+	la := load(t, "America/Los_Angeles")
+	tests := []struct {
+		name  string
+		t     time.Time
+		start time.Time
+		hours float64
+	}{
+		// Daylight saving time starts on March 10, 2024 and ends on November 3
+		{"spring forward", time.Date(2024, 3, 10, 12, 0, 0, 0, la), time.Date(2024, 3, 10, 0, 0, 0, 0, la), 23},
+		{"fall back", time.Date(2024, 11, 3, 23, 30, 0, 0, la), time.Date(2024, 11, 3, 0, 0, 0, 0, la), 25},
+		{"summer", time.Date(2024, 6, 1, 0, 0, 0, 0, la), time.Date(2024, 6, 1, 0, 0, 0, 0, la), 24},
+		// 5pm in Los Angeles is already the next day in UTC
+		{"evening", time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, la), 24},
+	}
+	for _, tt := range tests {
+		day := Day(tt.t, la)
+		if !day.Start.Equal(tt.start) {
+			t.Errorf("%s: Day() starts at %v, want %v", tt.name, day.Start, tt.start)
+		}
+		if got := day.End.Sub(day.Start).Hours(); got != tt.hours {
+			t.Errorf("%s: Day() is %v hours, want %v", tt.name, got, tt.hours)
+		}
+		if !day.Contains(tt.t) || day.Contains(day.End) {
+			t.Errorf("%s: Day() = %v, should contain %v but not its end", tt.name, day, tt.t)
+		}
+	}
+}
+
+func TestDayWithoutMidnight(t *testing.T) {
+	// Clocks in São Paulo went from midnight to 1am on November 4, 2018
+	sp := load(t, "America/Sao_Paulo")
+	day := Day(time.Date(2018, 11, 4, 12, 0, 0, 0, sp), sp)
+	if want := time.Date(2018, 11, 4, 3, 0, 0, 0, time.UTC); !day.Start.Equal(want) {
+		t.Errorf("Day() starts at %v, want %v", day.Start, want)
+	}
+	if got := day.End.Sub(day.Start).Hours(); got != 23 {
+		t.Errorf("Day() is %v hours, want 23", got)
+	}
+}
+
+func TestWeek(t *testing.T) {
+	la := load(t, "America/Los_Angeles")
+	// Sunday March 10, 2024 belongs to the week starting Monday March 4
+	week := Week(time.Date(2024, 3, 10, 12, 0, 0, 0, la), la)
+	if want := time.Date(2024, 3, 4, 0, 0, 0, 0, la); !week.Start.Equal(want) {
+		t.Errorf("Week() starts at %v, want %v", week.Start, want)
+	}
+	if got := week.End.Sub(week.Start).Hours(); got != 7*24-1 {
+		t.Errorf("Week() is %v hours, want %v", got, 7*24-1)
+	}
+
+	monday := Week(time.Date(2024, 3, 11, 0, 0, 0, 0, la), la)
+	if !monday.Start.Equal(week.End) {
+		t.Errorf("Week() on Monday starts at %v, want %v", monday.Start, week.End)
+	}
+}
+
+func TestMonthAndYear(t *testing.T) {
+	la := load(t, "America/Los_Angeles")
+	now := time.Date(2024, 11, 15, 9, 0, 0, 0, la)
+
+	month := Month(now, la)
+	if want := time.Date(2024, 11, 1, 0, 0, 0, 0, la); !month.Start.Equal(want) {
+		t.Errorf("Month() starts at %v, want %v", month.Start, want)
+	}
+	if got := month.End.Sub(month.Start).Hours(); got != 30*24+1 {
+		t.Errorf("Month() is %v hours, want %v", got, 30*24+1)
+	}
+
+	year := Year(now, la)
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, la); !year.Start.Equal(want) {
+		t.Errorf("Year() starts at %v, want %v", year.Start, want)
+	}
+	if want := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC); !year.End.Equal(want) {
+		t.Errorf("Year() ends at %v, want %v", year.End, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		if _, err := Load(name); err == nil {
+			t.Errorf("Load(%q) should fail", name)
+		}
+	}
+	if _, err := Load(Default); err != nil {
+		t.Errorf("Load(%q) = %v", Default, err)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/DiegoGarciaCo/CRM/internal/database"
 	"github.com/DiegoGarciaCo/CRM/internal/handlers"
+	"github.com/DiegoGarciaCo/CRM/internal/phone"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 
@@ -70,6 +72,20 @@ func main() {
 	if fromEmail == "" {
 		log.Fatal("FROM_EMAIL_ADDRESS is not set")
 	}
+	phoneRegion := os.Getenv("DEFAULT_PHONE_REGION")
+	if phoneRegion == "" {
+		phoneRegion = phone.DefaultRegion
+	}
+	if !phone.ValidRegion(phoneRegion) {
+		log.Fatalf("DEFAULT_PHONE_REGION %q is not a supported region", phoneRegion)
+	}
+	trashRetentionDays := 30
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		trashRetentionDays, err = strconv.Atoi(v)
+		if err != nil || trashRetentionDays < 1 {
+			log.Fatalf("TRASH_RETENTION_DAYS %q is not a positive number of days", v)
+		}
+	}
 
 	// -----------------------------------------------
 	// Initialize Logger
@@ -129,7 +145,7 @@ func main() {
 	// ------------------------------------------------
 	// Initialize config, server and cors
 	// ------------------------------------------------
-	cfg := handlers.New(port, JWTSecret, dbQueries, db, dev, logger, s3Client, s3Bucket, s3Region, &postmarkClient, EmailSecret, betterAuthSecret, serverURL, fromEmail)
+	cfg := handlers.New(port, JWTSecret, dbQueries, db, dev, logger, s3Client, s3Bucket, s3Region, &postmarkClient, EmailSecret, betterAuthSecret, serverURL, fromEmail, phoneRegion, time.Duration(trashRetentionDays)*24*time.Hour)
 
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"https://access.soldbyghost.com", "https://app.soldbyghost.com", "http://localhost:3000"},
@@ -138,125 +154,200 @@ func main() {
 		AllowCredentials: true,
 	})
 
+	// ------------------------------------------------
+	// Start background workers
+	// ------------------------------------------------
+	go cfg.StartImportWorker(context.Background())
+	go cfg.StartSmartListWorker(context.Background())
+	go cfg.StartLeadRoutingWorker(context.Background())
+	go cfg.StartTrashPurgeWorker(context.Background())
+	go cfg.StartReminderWorker(context.Background())
+	go cfg.StartDigestWorker(context.Background())
+
 	// Create a new HTTP server mux
 	mux := http.NewServeMux()
 
+	// Every route goes through its record authorization rules
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, cfg.Authorize(pattern, h))
+	}
+
 	// ------------------------------------------------
 	// Define routes and handlers
 	// ------------------------------------------------
 
 	// Dashboard Routes
-	mux.HandleFunc("GET /api/dashboard/new-contacts", cfg.GetNewContactsCount)
-	mux.HandleFunc("GET /api/dashboard/appointments", cfg.GetAppointmentsCount)
-	mux.HandleFunc("GET /api/dashboard/tasks-today", cfg.GetTasksDueTodayCount)
-	mux.HandleFunc("GET /api/dashboard/5-newest-contacts", cfg.Get5NewestContacts)
-	mux.HandleFunc("GET /api/dashboard/5-upcoming-appointments", cfg.Get5UpcomingAppointments)
-	mux.HandleFunc("GET /api/dashboard/contacts-count", cfg.GetContactsCount)
-	mux.HandleFunc("GET /api/dashboard/contacts-by-source", cfg.ContactCountBySource)
+	handle("GET /api/dashboard/new-contacts", cfg.GetNewContactsCount)
+	handle("GET /api/dashboard/appointments", cfg.GetAppointmentsCount)
+	handle("GET /api/dashboard/tasks-today", cfg.GetTasksDueTodayCount)
+	handle("GET /api/dashboard/5-newest-contacts", cfg.Get5NewestContacts)
+	handle("GET /api/dashboard/5-upcoming-appointments", cfg.Get5UpcomingAppointments)
+	handle("GET /api/dashboard/contacts-count", cfg.GetContactsCount)
+	handle("GET /api/dashboard/contacts-by-source", cfg.ContactCountBySource)
 
 	// Contact Routes
-	mux.HandleFunc("POST /api/contacts", cfg.CreateContact)
-	mux.HandleFunc("POST /api/contacts/import", cfg.ImportContacts)
-	mux.HandleFunc("GET /api/contacts/contact/{contactID}", cfg.GetContactByID)
-	mux.HandleFunc("GET /api/contacts", cfg.GetAllContacts)
-	mux.HandleFunc("GET /api/contacts/search", cfg.SearchContacts)
-	mux.HandleFunc("GET /api/contacts/smart-list/{smartListID}", cfg.GetContactsBySmartList)
-	mux.HandleFunc("PUT /api/contacts/{contactID}", cfg.UpdateContact)
+	handle("POST /api/contacts", cfg.CreateContact)
+	handle("POST /api/contacts/import", cfg.ImportContacts)
+	handle("POST /api/contacts/transfer", cfg.TransferContacts)
+	handle("POST /api/contacts/bulk", cfg.BulkUpdateContacts)
+	handle("POST /api/contacts/import/upload", cfg.UploadContactsFile)
+	handle("POST /api/contacts/import/preview", cfg.PreviewContactsFile)
+	handle("GET /api/contacts/import/mappings", cfg.ListImportMappings)
+	handle("POST /api/contacts/import/mappings", cfg.SaveImportMapping)
+	handle("DELETE /api/contacts/import/mappings/{mappingID}", cfg.DeleteImportMapping)
+	handle("GET /api/contacts/contact/{contactID}", cfg.GetContactByID)
+	handle("GET /api/contacts/contact/{contactID}/timeline", cfg.GetContactTimeline)
+	handle("GET /api/contacts/contact/{contactID}/history", cfg.GetContactHistory)
+	handle("GET /api/contacts/contact/{contactID}/action-plans", cfg.GetContactActionPlans)
+	handle("GET /api/contacts", cfg.GetAllContacts)
+	handle("GET /api/contacts/search", cfg.SearchContacts)
+	handle("GET /api/contacts/smart-list/{smartListID}", cfg.GetContactsBySmartList)
+	handle("PUT /api/contacts/{contactID}", cfg.UpdateContact)
+	handle("GET /api/contacts/duplicates", cfg.FindDuplicateContacts)
+	handle("GET /api/contacts/export", cfg.ExportContacts)
+	handle("POST /api/contacts/{contactID}/merge", cfg.MergeContacts)
+	handle("POST /api/contacts/{contactID}/action-plans", cfg.ApplyActionPlan)
+	handle("GET /api/contact-merges/{contactID}", cfg.ListContactMerges)
+
+	// Import Routes
+	handle("GET /api/imports/{jobID}", cfg.GetImportJob)
+	handle("POST /api/imports/{jobID}/cancel", cfg.CancelImportJob)
+	handle("POST /api/imports/{jobID}/retry", cfg.RetryImportJob)
 
 	// Notes Routes
-	mux.HandleFunc("POST /api/notes", cfg.CreateNote)
-	mux.HandleFunc("GET /api/notes/{contactID}", cfg.GetNotesByContactID)
+	handle("POST /api/notes", cfg.CreateNote)
+	handle("GET /api/notes/{contactID}", cfg.GetNotesByContactID)
 
 	// Contact Logs Routes
-	mux.HandleFunc("POST /api/contact-logs", cfg.LogContact)
-	mux.HandleFunc("GET /api/contact-logs/{contactID}", cfg.GetContactLogsByContactID)
+	handle("POST /api/contact-logs", cfg.LogContact)
+	handle("GET /api/contact-logs/{contactID}", cfg.GetContactLogsByContactID)
 
 	// Tasks Routes
-	mux.HandleFunc("POST /api/tasks", cfg.CreateTask)
-	mux.HandleFunc("GET /api/tasks/contact/{contactID}", cfg.GetTasksByContactID)
-	mux.HandleFunc("GET /api/tasks/assigned", cfg.GetTaskByAssignedToID)
-	mux.HandleFunc("GET /api/tasks/{taskID}", cfg.GetTaskByID)
-	mux.HandleFunc("DELETE /api/tasks/{taskID}", cfg.DeleteTask)
-	mux.HandleFunc("PUT /api/tasks/{taskID}", cfg.UpdateTask)
-	mux.HandleFunc("GET /api/tasks/late", cfg.GetOverdueTasks)
-	mux.HandleFunc("PUT /api/tasks/status/{taskID}", cfg.UpdateTaskStatus)
-	mux.HandleFunc("GET /api/tasks/today", cfg.GetTasksDueToday)
+	handle("POST /api/tasks", cfg.CreateTask)
+	handle("GET /api/tasks/contact/{contactID}", cfg.GetTasksByContactID)
+	handle("GET /api/tasks/assigned", cfg.GetTaskByAssignedToID)
+	handle("GET /api/tasks/{taskID}", cfg.GetTaskByID)
+	handle("DELETE /api/tasks/{taskID}", cfg.DeleteTask)
+	handle("PUT /api/tasks/{taskID}", cfg.UpdateTask)
+	handle("GET /api/tasks/late", cfg.GetOverdueTasks)
+	handle("PUT /api/tasks/status/{taskID}", cfg.UpdateTaskStatus)
+	handle("GET /api/tasks/today", cfg.GetTasksDueToday)
 
 	// Appointments Routes
-	mux.HandleFunc("POST /api/appointments", cfg.CreateAppointment)
-	mux.HandleFunc("GET /api/appointments/{AppointmentID}", cfg.GetAppointmentByID)
-	mux.HandleFunc("PUT /api/appointments/{AppointmentID}", cfg.UpdateAppointment)
-	mux.HandleFunc("DELETE /api/appointments/{AppointmentID}", cfg.DeleteAppointment)
-	mux.HandleFunc("GET /api/appointments/contact/{ContactID}", cfg.ListAppointmentsByContactID)
-	mux.HandleFunc("GET /api/appointments/upcoming", cfg.ListUpcomingAppointments)
-	mux.HandleFunc("GET /api/appointments/today", cfg.ListAppointmentsToday)
-	mux.HandleFunc("GET /api/appointments", cfg.ListAppointments)
+	handle("POST /api/appointments", cfg.CreateAppointment)
+	handle("GET /api/appointments/{AppointmentID}", cfg.GetAppointmentByID)
+	handle("PUT /api/appointments/{AppointmentID}", cfg.UpdateAppointment)
+	handle("DELETE /api/appointments/{AppointmentID}", cfg.DeleteAppointment)
+	handle("GET /api/appointments/contact/{ContactID}", cfg.ListAppointmentsByContactID)
+	handle("GET /api/appointments/upcoming", cfg.ListUpcomingAppointments)
+	handle("GET /api/appointments/today", cfg.ListAppointmentsToday)
+	handle("GET /api/appointments", cfg.ListAppointments)
 
 	// Deals Routes
-	mux.HandleFunc("POST /api/deals", cfg.CreateDeal)
-	mux.HandleFunc("GET /api/deals/{dealID}", cfg.GetDealByID)
-	mux.HandleFunc("PUT /api/deals/{dealID}", cfg.UpdateDeal)
-	mux.HandleFunc("DELETE /api/deals/{dealID}", cfg.DeleteDeal)
-	mux.HandleFunc("GET /api/deals", cfg.ListDeals)
-	mux.HandleFunc("GET /api/deals/contact/{contactID}", cfg.ListDealsByContactID)
-	mux.HandleFunc("GET /api/deals/stage/{stageID}", cfg.ListDealsByStageID)
+	handle("POST /api/deals", cfg.CreateDeal)
+	handle("GET /api/deals/{dealID}", cfg.GetDealByID)
+	handle("PUT /api/deals/{dealID}", cfg.UpdateDeal)
+	handle("DELETE /api/deals/{dealID}", cfg.DeleteDeal)
+	handle("GET /api/deals/{dealID}/history", cfg.GetDealHistory)
+	handle("GET /api/deals", cfg.ListDeals)
+	// A contact's and a stage's deals live under them, since
+	// /api/deals/contact/{contactID} would overlap /api/deals/{dealID}/history
+	handle("GET /api/contacts/contact/{contactID}/deals", cfg.ListDealsByContactID)
+	handle("GET /api/stages/{stageID}/deals", cfg.ListDealsByStageID)
 
 	// Goals Routes
-	mux.HandleFunc("POST /api/goals", cfg.SetGoal)
-	mux.HandleFunc("GET /api/goals", cfg.GetGoalByUserAndYear)
-	mux.HandleFunc("PUT /api/goals/{GoalID}", cfg.UpdateGoal)
+	handle("POST /api/goals", cfg.SetGoal)
+	handle("GET /api/goals", cfg.GetGoalByUserAndYear)
+	handle("PUT /api/goals/{GoalID}", cfg.UpdateGoal)
 
 	// Smart Lists Routes
-	mux.HandleFunc("GET /api/smart-lists", cfg.GetAllSmartLists)
-	mux.HandleFunc("POST /api/smart-lists", cfg.CreateSmartList)
-	mux.HandleFunc("PUT /api/smart-lists/{smartListID}/filter", cfg.SetSmartListFilterCriteria)
-	mux.HandleFunc("PUT /api/smart-lists/{smartListID}/name", cfg.UpdateSmartList)
+	handle("GET /api/smart-lists", cfg.GetAllSmartLists)
+	handle("POST /api/smart-lists", cfg.CreateSmartList)
+	handle("PUT /api/smart-lists/{smartListID}/filter", cfg.SetSmartListFilterCriteria)
+	handle("PUT /api/smart-lists/{smartListID}/name", cfg.UpdateSmartList)
+	handle("POST /api/smart-lists/{smartListID}/subscription", cfg.SubscribeToSmartList)
+	handle("DELETE /api/smart-lists/{smartListID}/subscription", cfg.UnsubscribeFromSmartList)
 
 	// Stages Routes
-	mux.HandleFunc("POST /api/stages", cfg.CreateStage)
-	mux.HandleFunc("GET /api/stages", cfg.GetStages)
-	mux.HandleFunc("GET /api/stages/client-type", cfg.GetStagesByClientType)
-	mux.HandleFunc("PUT /api/stages/{stageID}", cfg.UpdateStage)
-	mux.HandleFunc("DELETE /api/stages/{stageID}", cfg.DeleteStage)
+	handle("POST /api/stages", cfg.CreateStage)
+	handle("GET /api/stages", cfg.GetStages)
+	handle("GET /api/stages/client-type", cfg.GetStagesByClientType)
+	handle("PUT /api/stages/{stageID}", cfg.UpdateStage)
+	handle("DELETE /api/stages/{stageID}", cfg.DeleteStage)
 
 	// Tags Routes
-	mux.HandleFunc("POST /api/tags", cfg.CreateTag)
-	mux.HandleFunc("GET /api/tags", cfg.GetAllTags)
-	mux.HandleFunc("DELETE /api/tags/{tagID}", cfg.DeleteTag)
-	mux.HandleFunc("POST /api/tags/{tagID}/contact/{contactID}", cfg.AssignTagToContact)
-	mux.HandleFunc("DELETE /api/tags/{tagID}/contact/{contactID}", cfg.RemoveTagFromContact)
+	handle("POST /api/tags", cfg.CreateTag)
+	handle("GET /api/tags", cfg.GetAllTags)
+	handle("DELETE /api/tags/{tagID}", cfg.DeleteTag)
+	handle("POST /api/tags/{tagID}/contact/{contactID}", cfg.AssignTagToContact)
+	handle("DELETE /api/tags/{tagID}/contact/{contactID}", cfg.RemoveTagFromContact)
+
+	// Action Plans Routes
+	handle("GET /api/action-plans", cfg.GetActionPlans)
+	handle("POST /api/action-plans", cfg.CreateActionPlan)
+	handle("GET /api/action-plans/{actionPlanID}", cfg.GetActionPlan)
+	handle("PUT /api/action-plans/{actionPlanID}", cfg.UpdateActionPlan)
+	handle("DELETE /api/action-plans/{actionPlanID}", cfg.DeleteActionPlan)
 
 	// Webhooks Routes
-	mux.HandleFunc("POST /webhooks/landing-page-form", cfg.CollectLandingPageForm)
+	handle("POST /webhooks/landing-page-form", cfg.CollectLandingPageForm)
 
 	// Email Routes
-	mux.HandleFunc("GET /api/verify", cfg.VerifyEmail)
-	mux.HandleFunc("POST /api/resend-verification", cfg.ResendVerificationEmail)
-	mux.HandleFunc("POST /api/emails/contact/{contactID}", cfg.CreateEmailAddress)
-	mux.HandleFunc("PUT /api/emails/{emailID}", cfg.UpdateEmailAddress)
-	mux.HandleFunc("DELETE /api/emails/{emailID}", cfg.DeleteEmailAddress)
+	handle("GET /api/verify", cfg.VerifyEmail)
+	handle("POST /api/resend-verification", cfg.ResendVerificationEmail)
+	handle("POST /api/emails/contact/{contactID}", cfg.CreateEmailAddress)
+	handle("PUT /api/emails/{emailID}", cfg.UpdateEmailAddress)
+	handle("DELETE /api/emails/{emailID}", cfg.DeleteEmailAddress)
 
 	// Phone Routes
-	mux.HandleFunc("POST /api/phone-numbers/contact/{contactID}", cfg.CreatePhoneNumber)
-	mux.HandleFunc("PUT /api/phone-numbers/{phoneNumberID}", cfg.UpdatePhoneNumber)
-	mux.HandleFunc("DELETE /api/phone-numbers/{phoneNumberID}", cfg.DeletePhoneNumber)
+	handle("POST /api/phone-numbers/contact/{contactID}", cfg.CreatePhoneNumber)
+	handle("PUT /api/phone-numbers/{phoneNumberID}", cfg.UpdatePhoneNumber)
+	handle("DELETE /api/phone-numbers/{phoneNumberID}", cfg.DeletePhoneNumber)
 
 	// S3 Routes
-	mux.HandleFunc("PUT /api/upload-profile-picture", cfg.UploadProfilePicture)
+	handle("PUT /api/upload-profile-picture", cfg.UploadProfilePicture)
 
 	// Collaborators Routes
-	mux.HandleFunc("POST /api/collaborators", cfg.AddCollaborator)
-	mux.HandleFunc("DELETE /api/collaborators/{collaboratorID}/contact/{contactID}", cfg.RemoveCollaborator)
+	handle("POST /api/collaborators", cfg.AddCollaborator)
+	handle("GET /api/collaborators/contact/{contactID}", cfg.ListCollaborators)
+	handle("DELETE /api/collaborators/{collaboratorID}/contact/{contactID}", cfg.RemoveCollaborator)
 
 	// Member Routes
-	mux.HandleFunc("POST /api/members/organizations", cfg.GetCollaborators)
+	handle("POST /api/members/organizations", cfg.GetCollaborators)
+
+	// Organization Routes
+	handle("GET /api/organizations/{organizationID}/rollup", cfg.GetOrganizationRollup)
+	handle("GET /api/organizations/{organizationID}/rollup/{metric}", cfg.GetOrganizationRollupRecords)
+
+	// Lead Routing Routes
+	handle("GET /api/organizations/{organizationID}/routing-rules", cfg.GetRoutingRules)
+	handle("POST /api/organizations/{organizationID}/routing-rules", cfg.CreateRoutingRule)
+	handle("PUT /api/routing-rules/{ruleID}", cfg.UpdateRoutingRule)
+	handle("DELETE /api/routing-rules/{ruleID}", cfg.DeleteRoutingRule)
+	handle("GET /api/leads/pending", cfg.GetPendingLeads)
+	handle("POST /api/leads/{assignmentID}/claim", cfg.ClaimLead)
+
+	// Trash Routes
+	handle("GET /api/trash", cfg.GetTrash)
+	handle("POST /api/trash/{kind}/{recordID}/restore", cfg.RestoreTrashItem)
+	handle("DELETE /api/trash/{kind}/{recordID}", cfg.PurgeTrashItem)
+
+	// Audit Routes
+	handle("GET /api/audit", cfg.GetAuditEvents)
 
 	// Notifications Routes
-	mux.HandleFunc("GET /api/notifications", cfg.GetNotifications)
-	mux.HandleFunc("POST /api/notifications", cfg.CreateNotification)
-	mux.HandleFunc("PUT /api/notifications/mark-as-read/{notificationID}", cfg.MarkNotificationAsRead)
-	mux.HandleFunc("PUT /api/notifications/read-all", cfg.MarkAllNotificationsAsRead)
-	mux.HandleFunc("DELETE /api/notifications/{notificationID}", cfg.DeleteNotification)
+	handle("GET /api/notifications", cfg.GetNotifications)
+	handle("POST /api/notifications", cfg.CreateNotification)
+	handle("PUT /api/notifications/mark-as-read/{notificationID}", cfg.MarkNotificationAsRead)
+	handle("PUT /api/notifications/read-all", cfg.MarkAllNotificationsAsRead)
+	handle("DELETE /api/notifications/{notificationID}", cfg.DeleteNotification)
+	handle("GET /api/reminder-preferences", cfg.GetReminderPreferences)
+	handle("PUT /api/reminder-preferences", cfg.SetReminderPreferences)
+	handle("GET /api/digest-preferences", cfg.GetDigestPreferences)
+	handle("PUT /api/digest-preferences", cfg.SetDigestPreferences)
+	handle("GET /api/digest/preview", cfg.PreviewDigest)
+	handle("GET /api/time-zone", cfg.GetTimeZone)
+	handle("PUT /api/time-zone", cfg.SetTimeZone)
 
 	// ------------------------------------------------------------
 	// Wrap mux with CORS handler, middleware and start server
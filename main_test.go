@@ -0,0 +1,56 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/DiegoGarciaCo/CRM/internal/authz"
+)
+
+// TestRoutesHaveAuthorizationRules checks that the routes registered in main
+// and the routes authz knows about are the same set.
+func TestRoutesHaveAuthorizationRules(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "handle" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			t.Errorf("route pattern %v isn't a string literal", call.Args[0])
+			return true
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		if registered[pattern] {
+			t.Errorf("route %q registered twice", pattern)
+		}
+		registered[pattern] = true
+		return true
+	})
+
+	if len(registered) == 0 {
+		t.Fatal("no routes found in main.go")
+	}
+	for pattern := range registered {
+		if _, ok := authz.Routes[pattern]; !ok {
+			t.Errorf("route %q has no entry in authz.Routes", pattern)
+		}
+	}
+	for pattern := range authz.Routes {
+		if !registered[pattern] {
+			t.Errorf("authz.Routes has %q, which main.go doesn't register", pattern)
+		}
+	}
+}
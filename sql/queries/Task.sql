@@ -22,6 +22,7 @@ FROM
     tasks
 WHERE
     contact_id = $1
+    AND deleted_at IS NULL
 ORDER BY
     date DESC;
 
@@ -32,30 +33,63 @@ FROM
     tasks
 WHERE
     assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC;
 
 -- name: GetTaskDueToday :many
+-- Tasks due between day_start and day_end, the bounds of the user's day
 SELECT
     *
 FROM
     tasks
 WHERE
-    date::date = current_date
-    AND assigned_to_id = $1
-    AND STATUS != 'completed'
+    date >= @day_start::timestamptz
+    AND date < @day_end::timestamptz
+    AND assigned_to_id = @assigned_to_id
+    AND STATUS NOT IN ('completed', 'cancelled')
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC;
 
 -- name: GetOverdueTasks :many
+-- Tasks due before day_start, the start of the user's day
 SELECT
     *
 FROM
     tasks
 WHERE
-    date::date < current_date
-    AND STATUS != 'completed'
-    AND assigned_to_id = $1
+    date < @day_start::timestamptz
+    AND STATUS NOT IN ('completed', 'cancelled')
+    AND assigned_to_id = @assigned_to_id
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     date DESC;
 
@@ -71,10 +105,14 @@ RETURNING
     *;
 
 -- name: DeleteTask :exec
-DELETE FROM
+-- Moves the task to the trash
+UPDATE
     tasks
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: GetTaskByID :one
 SELECT
@@ -82,7 +120,8 @@ SELECT
 FROM
     tasks
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: UpdateTask :one
 UPDATE
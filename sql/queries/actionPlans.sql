@@ -0,0 +1,213 @@
+-- name: CreateActionPlan :one
+INSERT INTO
+    action_plans (
+        user_id,
+        organization_id,
+        name,
+        description,
+        trigger_tag_id,
+        apply_to_leads,
+        lead_sources
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7)
+RETURNING
+    *;
+
+-- name: UpdateActionPlan :one
+UPDATE
+    action_plans
+SET
+    name = $2,
+    description = $3,
+    trigger_tag_id = $4,
+    apply_to_leads = $5,
+    lead_sources = $6,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    *;
+
+-- name: DeleteActionPlan :exec
+DELETE FROM
+    action_plans
+WHERE
+    id = $1;
+
+-- name: GetActionPlanByID :one
+SELECT
+    *
+FROM
+    action_plans
+WHERE
+    id = $1;
+
+-- name: ListActionPlans :many
+-- The user's personal plans and those of their active organization
+SELECT
+    *
+FROM
+    action_plans
+WHERE
+    (
+        organization_id IS NULL
+        AND user_id = $1
+    )
+    OR organization_id = $2
+ORDER BY
+    name;
+
+-- name: ListActionPlanSteps :many
+SELECT
+    *
+FROM
+    action_plan_steps
+WHERE
+    action_plan_id = ANY(@action_plan_ids::uuid[])
+ORDER BY
+    action_plan_id,
+    position;
+
+-- name: CreateActionPlanStep :exec
+INSERT INTO
+    action_plan_steps (
+        action_plan_id,
+        position,
+        title,
+        task_type,
+        offset_days,
+        priority,
+        note_template
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: DeleteActionPlanSteps :exec
+DELETE FROM
+    action_plan_steps
+WHERE
+    action_plan_id = $1;
+
+-- name: ListTagActionPlans :many
+-- The plans the user can use that are applied when the tag is assigned
+SELECT
+    *
+FROM
+    action_plans
+WHERE
+    trigger_tag_id = @tag_id
+    AND (
+        (
+            organization_id IS NULL
+            AND user_id = @user_id
+        )
+        OR organization_id = @organization_id
+    )
+ORDER BY
+    name;
+
+-- name: ListLeadActionPlans :many
+-- The plans the user can use that are applied to webhook leads from source
+SELECT
+    *
+FROM
+    action_plans
+WHERE
+    apply_to_leads
+    AND (
+        cardinality(lead_sources) = 0
+        OR @source::text = ANY(lead_sources)
+    )
+    AND (
+        (
+            organization_id IS NULL
+            AND user_id = @user_id
+        )
+        OR organization_id = @organization_id
+    )
+ORDER BY
+    name;
+
+-- name: GetActionPlanContact :one
+-- The contact fields step notes can use, and who the plan's tasks go to
+SELECT
+    id,
+    first_name,
+    last_name,
+    source,
+    city,
+    zip_code,
+    price_range,
+    timeframe,
+    owner_id
+FROM
+    contacts
+WHERE
+    id = $1
+    AND deleted_at IS NULL;
+
+-- name: CreateContactActionPlan :one
+-- No row is returned while the plan is already active for the contact
+INSERT INTO
+    contact_action_plans (contact_id, action_plan_id, started_at, applied_by)
+VALUES
+    ($1, $2, $3, $4)
+ON CONFLICT (contact_id, action_plan_id)
+WHERE
+    status = 'active' DO NOTHING
+RETURNING
+    *;
+
+-- name: ListContactActionPlans :many
+SELECT
+    cap.*,
+    ap.name
+FROM
+    contact_action_plans cap
+    JOIN action_plans ap ON ap.id = cap.action_plan_id
+WHERE
+    cap.contact_id = $1
+ORDER BY
+    cap.created_at DESC;
+
+-- name: CreateActionPlanTask :one
+INSERT INTO
+    tasks (
+        contact_id,
+        assigned_to_id,
+        title,
+        TYPE,
+        date,
+        priority,
+        note,
+        contact_action_plan_id
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+    *;
+
+-- name: ListActiveActionPlanTasks :many
+-- The pending tasks of the plans running for a contact
+SELECT
+    t.*
+FROM
+    tasks t
+    JOIN contact_action_plans cap ON cap.id = t.contact_action_plan_id
+WHERE
+    cap.contact_id = $1
+    AND cap.status = 'active'
+    AND t.status = 'pending'
+    AND t.deleted_at IS NULL;
+
+-- name: PauseContactActionPlans :exec
+UPDATE
+    contact_action_plans
+SET
+    status = 'paused',
+    paused_at = CURRENT_TIMESTAMP,
+    pause_reason = $2
+WHERE
+    contact_id = $1
+    AND status = 'active';
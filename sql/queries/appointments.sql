@@ -30,7 +30,8 @@ SELECT
 FROM
     appointments
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: UpdateAppointment :one
 UPDATE
@@ -49,19 +50,35 @@ RETURNING
     *;
 
 -- name: DeleteAppointment :exec
-DELETE FROM
+-- Moves the appointment to the trash
+UPDATE
     appointments
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: ListTodaysAppointments :many
+-- Appointments between day_start and day_end, the bounds of the user's day
 SELECT
     *
 FROM
     appointments
 WHERE
-    scheduled_at::date = current_date
-    AND assigned_to_id = $1
+    scheduled_at >= @day_start::timestamptz
+    AND scheduled_at < @day_end::timestamptz
+    AND assigned_to_id = @assigned_to_id
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC;
 
@@ -72,6 +89,16 @@ FROM
     appointments
 WHERE
     assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC;
 
@@ -83,6 +110,16 @@ FROM
 WHERE
     scheduled_at > NOW()
     AND assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at ASC;
 
@@ -94,6 +131,16 @@ FROM
 WHERE
     scheduled_at < NOW()
     AND assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     scheduled_at DESC;
 
@@ -104,5 +151,6 @@ FROM
     appointments
 WHERE
     contact_id = $1
+    AND deleted_at IS NULL
 ORDER BY
     scheduled_at ASC;
@@ -0,0 +1,296 @@
+-- name: GetAuditSnapshot :one
+-- The record of kind as JSON, the way the audit log compares it. Contacts
+-- include their tag names and collaborators, routing rules their agents and
+-- action plans their steps, so changes to those show up on the record. Import
+-- jobs leave out their file and row results, and digest preferences when
+-- the digest was last sent. A user's preferences are keyed by their user ID,
+-- and users show only the settings they change through the API, leaving out
+-- their account and sign-in details. Only the branch matching kind is
+-- evaluated.
+SELECT
+    s.snapshot
+FROM
+    (
+        SELECT
+            'contact' AS kind,
+            c.id,
+            to_jsonb(c) || jsonb_build_object(
+                'tags',
+                coalesce(
+                    (
+                        SELECT
+                            jsonb_agg(
+                                t.name
+                                ORDER BY
+                                    t.name
+                            )
+                        FROM
+                            contact_tags ct
+                            JOIN tags t ON t.id = ct.tag_id
+                        WHERE
+                            ct.contact_id = c.id
+                    ),
+                    '[]'::jsonb
+                ),
+                'collaborators',
+                coalesce(
+                    (
+                        SELECT
+                            jsonb_agg(
+                                jsonb_build_object('user_id', col.user_id, 'role', col.role)
+                                ORDER BY
+                                    col.user_id
+                            )
+                        FROM
+                            collaborators col
+                        WHERE
+                            col.contact_id = c.id
+                    ),
+                    '[]'::jsonb
+                )
+            ) AS snapshot
+        FROM
+            contacts c
+        UNION ALL
+        SELECT
+            'email',
+            e.id,
+            to_jsonb(e)
+        FROM
+            emails e
+        UNION ALL
+        SELECT
+            'phone_number',
+            p.id,
+            to_jsonb(p)
+        FROM
+            phone_numbers p
+        UNION ALL
+        SELECT
+            'note',
+            n.id,
+            to_jsonb(n)
+        FROM
+            contact_notes n
+        UNION ALL
+        SELECT
+            'contact_log',
+            l.id,
+            to_jsonb(l)
+        FROM
+            contact_logs l
+        UNION ALL
+        SELECT
+            'task',
+            t.id,
+            to_jsonb(t)
+        FROM
+            tasks t
+        UNION ALL
+        SELECT
+            'appointment',
+            a.id,
+            to_jsonb(a)
+        FROM
+            appointments a
+        UNION ALL
+        SELECT
+            'deal',
+            d.id,
+            to_jsonb(d)
+        FROM
+            deals d
+        UNION ALL
+        SELECT
+            'stage',
+            st.id,
+            to_jsonb(st)
+        FROM
+            stages st
+        UNION ALL
+        SELECT
+            'tag',
+            tg.id,
+            to_jsonb(tg)
+        FROM
+            tags tg
+        UNION ALL
+        SELECT
+            'smart_list',
+            sl.id,
+            to_jsonb(sl)
+        FROM
+            smart_lists sl
+        UNION ALL
+        SELECT
+            'goal',
+            g.id,
+            to_jsonb(g)
+        FROM
+            goals g
+        UNION ALL
+        SELECT
+            'import_mapping',
+            im.id,
+            to_jsonb(im)
+        FROM
+            import_mappings im
+        UNION ALL
+        SELECT
+            'action_plan',
+            ap.id,
+            to_jsonb(ap) || jsonb_build_object(
+                'steps',
+                coalesce(
+                    (
+                        SELECT
+                            jsonb_agg(
+                                to_jsonb(s) - 'id' - 'action_plan_id'
+                                ORDER BY
+                                    s.position
+                            )
+                        FROM
+                            action_plan_steps s
+                        WHERE
+                            s.action_plan_id = ap.id
+                    ),
+                    '[]'::jsonb
+                )
+            )
+        FROM
+            action_plans ap
+        UNION ALL
+        SELECT
+            'routing_rule',
+            rr.id,
+            to_jsonb(rr) || jsonb_build_object(
+                'agents',
+                coalesce(
+                    (
+                        SELECT
+                            jsonb_agg(
+                                jsonb_build_object('user_id', ra.user_id, 'capacity', ra.capacity)
+                                ORDER BY
+                                    ra.user_id
+                            )
+                        FROM
+                            routing_rule_agents ra
+                        WHERE
+                            ra.rule_id = rr.id
+                    ),
+                    '[]'::jsonb
+                )
+            )
+        FROM
+            routing_rules rr
+        UNION ALL
+        SELECT
+            'lead_assignment',
+            la.id,
+            to_jsonb(la)
+        FROM
+            lead_assignments la
+        UNION ALL
+        SELECT
+            'import_job',
+            ij.id,
+            to_jsonb(ij) - 'payload' - 'errors' - 'lease_id' - 'locked_until'
+        FROM
+            import_jobs ij
+        UNION ALL
+        SELECT
+            'notification',
+            nt.id,
+            to_jsonb(nt)
+        FROM
+            notifications nt
+        UNION ALL
+        SELECT
+            'reminder_preferences',
+            rp.user_id,
+            to_jsonb(rp)
+        FROM
+            reminder_preferences rp
+        UNION ALL
+        SELECT
+            'digest_preferences',
+            dp.user_id,
+            to_jsonb(dp) - 'last_sent_on'
+        FROM
+            digest_preferences dp
+        UNION ALL
+        SELECT
+            'user',
+            u.id,
+            jsonb_build_object('time_zone', u."timeZone")
+        FROM
+            users u
+    ) s
+WHERE
+    s.kind = @kind::text
+    AND s.id = @id;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO
+    audit_events (
+        actor_id,
+        auth_method,
+        entity_type,
+        entity_id,
+        action,
+        changes,
+        request_id
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAuditEvents :many
+-- Audit events, newest first. Without entity_visible, which the caller sets
+-- once it has checked the user may view the filtered entity, only the
+-- user's own changes and those of members of organizations they administer
+-- are listed.
+SELECT
+    e.*
+FROM
+    audit_events e
+WHERE
+    (
+        @entity_visible::bool
+        OR e.actor_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+                JOIN member actor ON actor."organizationId" = admin."organizationId"
+            WHERE
+                admin."userId" = @user_id
+                AND admin.role IN ('owner', 'admin')
+                AND actor."userId" = e.actor_id
+        )
+    )
+    AND (
+        sqlc.narg(entity_type)::text IS NULL
+        OR e.entity_type = sqlc.narg(entity_type)
+    )
+    AND (
+        sqlc.narg(entity_id)::uuid IS NULL
+        OR e.entity_id = sqlc.narg(entity_id)
+    )
+    AND (
+        sqlc.narg(actor_id)::uuid IS NULL
+        OR e.actor_id = sqlc.narg(actor_id)
+    )
+    AND (
+        sqlc.narg(from_at)::timestamptz IS NULL
+        OR e.created_at >= sqlc.narg(from_at)
+    )
+    AND (
+        sqlc.narg(until_at)::timestamptz IS NULL
+        OR e.created_at < sqlc.narg(until_at)
+    )
+ORDER BY
+    e.created_at DESC,
+    e.id DESC
+LIMIT
+    @row_limit OFFSET @row_offset;
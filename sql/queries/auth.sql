@@ -1,8 +1,14 @@
 -- name: CheckSessionByID :one
+-- The session's active organization is only returned while the user is
+-- still a member of it.
 SELECT
-    "userId",
-    "expiresAt"
+    s."userId",
+    s."expiresAt",
+    m."organizationId" AS active_organization_id,
+    m.role AS organization_role
 FROM
-    SESSION
+    SESSION s
+    LEFT JOIN member m ON m."organizationId" = s."activeOrganizationId"
+    AND m."userId" = s."userId"
 WHERE
-    token = $1;
+    s.token = $1;
@@ -0,0 +1,227 @@
+-- name: GetContactAccess :one
+SELECT
+    c.owner_id,
+    (
+        SELECT
+            col.role
+        FROM
+            collaborators col
+        WHERE
+            col.contact_id = c.id
+            AND col.user_id = @user_id
+        LIMIT
+            1
+    ) AS collaborator_role,
+    EXISTS (
+        SELECT
+            1
+        FROM
+            member admin
+        WHERE
+            admin."userId" = @user_id
+            AND admin.role IN ('owner', 'admin')
+            AND (
+                admin."organizationId" = c.organization_id
+                OR (
+                    c.organization_id IS NULL
+                    AND EXISTS (
+                        SELECT
+                            1
+                        FROM
+                            member owner
+                        WHERE
+                            owner."organizationId" = admin."organizationId"
+                            AND owner."userId" = c.owner_id
+                    )
+                )
+            )
+    ) AS org_admin
+FROM
+    contacts c
+WHERE
+    c.id = @contact_id
+    AND c.deleted_at IS NULL;
+
+-- name: GetRecordOwnership :one
+-- Returns the contact a child record belongs to, the user it belongs to or
+-- is assigned to, and the organization it is shared with. Only the branch
+-- matching kind is evaluated. Trashed records aren't found.
+SELECT
+    r.contact_id,
+    r.owner_id,
+    r.organization_id
+FROM
+    (
+        SELECT
+            'note' AS kind,
+            id,
+            contact_id,
+            NULL::uuid AS owner_id,
+            NULL::uuid AS organization_id
+        FROM
+            contact_notes
+        UNION ALL
+        SELECT
+            'contact_log',
+            id,
+            contact_id,
+            NULL,
+            NULL
+        FROM
+            contact_logs
+        UNION ALL
+        SELECT
+            'task',
+            id,
+            contact_id,
+            assigned_to_id,
+            NULL
+        FROM
+            tasks
+        WHERE
+            deleted_at IS NULL
+        UNION ALL
+        SELECT
+            'appointment',
+            id,
+            contact_id,
+            assigned_to_id,
+            NULL
+        FROM
+            appointments
+        WHERE
+            deleted_at IS NULL
+        UNION ALL
+        SELECT
+            'deal',
+            id,
+            contact_id,
+            assigned_to_id,
+            NULL
+        FROM
+            deals
+        WHERE
+            deleted_at IS NULL
+        UNION ALL
+        SELECT
+            'email',
+            id,
+            contact_id,
+            NULL,
+            NULL
+        FROM
+            emails
+        UNION ALL
+        SELECT
+            'phone_number',
+            id,
+            contact_id,
+            NULL,
+            NULL
+        FROM
+            phone_numbers
+        UNION ALL
+        SELECT
+            'smart_list',
+            id,
+            NULL,
+            user_id,
+            organization_id
+        FROM
+            smart_lists
+        UNION ALL
+        SELECT
+            'tag',
+            id,
+            NULL,
+            user_id,
+            organization_id
+        FROM
+            tags
+        UNION ALL
+        SELECT
+            'stage',
+            id,
+            NULL,
+            owner_id,
+            organization_id
+        FROM
+            stages
+        WHERE
+            deleted_at IS NULL
+        UNION ALL
+        SELECT
+            'notification',
+            id,
+            NULL,
+            user_id,
+            NULL
+        FROM
+            notifications
+        UNION ALL
+        SELECT
+            'goal',
+            id,
+            NULL,
+            user_id,
+            NULL
+        FROM
+            goals
+        UNION ALL
+        SELECT
+            'import_job',
+            id,
+            NULL,
+            user_id,
+            NULL
+        FROM
+            import_jobs
+        UNION ALL
+        SELECT
+            'import_mapping',
+            id,
+            NULL,
+            user_id,
+            NULL
+        FROM
+            import_mappings
+        UNION ALL
+        SELECT
+            'action_plan',
+            id,
+            NULL,
+            user_id,
+            organization_id
+        FROM
+            action_plans
+        UNION ALL
+        SELECT
+            'routing_rule',
+            id,
+            NULL,
+            NULL,
+            organization_id
+        FROM
+            routing_rules
+        UNION ALL
+        SELECT
+            'lead_assignment',
+            id,
+            NULL,
+            NULL,
+            organization_id
+        FROM
+            lead_assignments
+    ) r
+WHERE
+    r.kind = @kind::text
+    AND r.id = @id;
+
+-- name: GetMemberRole :one
+SELECT
+    role
+FROM
+    member
+WHERE
+    "userId" = @user_id
+    AND "organizationId" = @organization_id;
@@ -2,7 +2,10 @@
 INSERT INTO
     collaborators (contact_id, user_id, role)
 VALUES
-    ($1, $2, $3);
+    ($1, $2, $3) ON CONFLICT (contact_id, user_id) DO
+UPDATE
+SET
+    role = EXCLUDED.role;
 
 -- name: RemoveCollaborator :exec
 DELETE FROM
@@ -20,4 +23,6 @@ FROM
     collaborators c
     JOIN users u ON c.user_id = u.id
 WHERE
-    c.contact_id = $1;
+    c.contact_id = $1
+ORDER BY
+    u.name;
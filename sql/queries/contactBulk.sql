@@ -0,0 +1,42 @@
+-- name: ListSmartListMemberIDs :many
+SELECT
+    contact_id
+FROM
+    smart_list_members
+WHERE
+    smart_list_id = $1
+ORDER BY
+    contact_id;
+
+-- name: RemoveTagsFromContact :exec
+-- Removes every tag with one of the names from the contact, whoever the tag
+-- belongs to
+DELETE FROM
+    contact_tags ct
+USING
+    tags t
+WHERE
+    ct.tag_id = t.id
+    AND ct.contact_id = @contact_id
+    AND t.name = ANY(@tag_names::text[]);
+
+-- name: SetContactFields :exec
+-- Fields left NULL keep their value
+UPDATE
+    contacts
+SET
+    status = coalesce(sqlc.narg(status), status),
+    source = coalesce(sqlc.narg(source), source),
+    timeframe = coalesce(sqlc.narg(timeframe), timeframe),
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = @id;
+
+-- name: SoftDeleteContact :exec
+UPDATE
+    contacts
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND deleted_at IS NULL;
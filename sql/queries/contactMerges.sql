@@ -0,0 +1,244 @@
+-- name: ListDuplicateCandidates :many
+SELECT
+    c.id,
+    c.first_name,
+    c.last_name,
+    c.created_at,
+    coalesce(
+        (
+            SELECT
+                array_agg(e.email_address)
+            FROM
+                emails e
+            WHERE
+                e.contact_id = c.id
+        ),
+        '{}'
+    )::text [] AS emails,
+    coalesce(
+        (
+            SELECT
+                array_agg(coalesce(p.e164, p.phone_number))
+            FROM
+                phone_numbers p
+            WHERE
+                p.contact_id = c.id
+        ),
+        '{}'
+    )::text [] AS phone_numbers
+FROM
+    contacts c
+WHERE
+    c.owner_id = $1
+    AND c.deleted_at IS NULL
+ORDER BY
+    c.created_at ASC;
+
+-- name: GetContactForMerge :one
+SELECT
+    *
+FROM
+    contacts
+WHERE
+    id = $1
+    AND owner_id = $2
+    AND deleted_at IS NULL FOR
+UPDATE;
+
+-- name: GetMergedContactSnapshot :one
+SELECT
+    json_build_object(
+        'contact',
+        row_to_json(c.*),
+        'emails',
+        coalesce(
+            (
+                SELECT
+                    json_agg(e.*)
+                FROM
+                    emails e
+                WHERE
+                    e.contact_id = c.id
+            ),
+            '[]'
+        ),
+        'phone_numbers',
+        coalesce(
+            (
+                SELECT
+                    json_agg(p.*)
+                FROM
+                    phone_numbers p
+                WHERE
+                    p.contact_id = c.id
+            ),
+            '[]'
+        )
+    )::jsonb AS snapshot
+FROM
+    contacts c
+WHERE
+    c.id = $1;
+
+-- name: FillContactFromDuplicate :exec
+UPDATE
+    contacts s
+SET
+    birthdate = coalesce(s.birthdate, d.birthdate),
+    source = coalesce(s.source, d.source),
+    address = coalesce(s.address, d.address),
+    city = coalesce(s.city, d.city),
+    state = coalesce(s.state, d.state),
+    zip_code = coalesce(s.zip_code, d.zip_code),
+    lender = coalesce(s.lender, d.lender),
+    price_range = coalesce(s.price_range, d.price_range),
+    timeframe = coalesce(s.timeframe, d.timeframe),
+    last_contacted_at = greatest(s.last_contacted_at, d.last_contacted_at),
+    updated_at = CURRENT_TIMESTAMP
+FROM
+    contacts d
+WHERE
+    s.id = @survivor_id::uuid
+    AND d.id = @duplicate_id::uuid;
+
+-- name: MoveContactEmails :execrows
+UPDATE
+    emails
+SET
+    contact_id = @survivor_id::uuid,
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = @duplicate_id::uuid
+    AND lower(email_address) NOT IN (
+        SELECT
+            lower(email_address)
+        FROM
+            emails
+        WHERE
+            contact_id = @survivor_id::uuid
+    );
+
+-- name: MoveContactPhoneNumbers :execrows
+UPDATE
+    phone_numbers
+SET
+    contact_id = @survivor_id::uuid,
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = @duplicate_id::uuid
+    AND regexp_replace(phone_number, '\D', '', 'g') NOT IN (
+        SELECT
+            regexp_replace(phone_number, '\D', '', 'g')
+        FROM
+            phone_numbers
+        WHERE
+            contact_id = @survivor_id::uuid
+    );
+
+-- name: MoveContactTags :execrows
+INSERT INTO
+    contact_tags (contact_id, tag_id)
+SELECT
+    @survivor_id::uuid,
+    tag_id
+FROM
+    contact_tags
+WHERE
+    contact_id = @duplicate_id::uuid ON CONFLICT DO NOTHING;
+
+-- name: MoveContactNotes :execrows
+UPDATE
+    contact_notes
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: MoveContactLogs :execrows
+UPDATE
+    contact_logs
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: MoveContactTasks :execrows
+UPDATE
+    tasks
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: MoveContactAppointments :execrows
+UPDATE
+    appointments
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: MoveContactDeals :execrows
+UPDATE
+    deals
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: MoveContactCollaborators :execrows
+UPDATE
+    collaborators
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid
+    AND user_id NOT IN (
+        SELECT
+            user_id
+        FROM
+            collaborators
+        WHERE
+            contact_id = @survivor_id::uuid
+    );
+
+-- name: MoveContactEvents :execrows
+UPDATE
+    contact_events
+SET
+    contact_id = @survivor_id::uuid
+WHERE
+    contact_id = @duplicate_id::uuid;
+
+-- name: DeleteMergedContact :exec
+DELETE FROM
+    contacts
+WHERE
+    id = $1
+    AND owner_id = $2;
+
+-- name: CreateContactMerge :one
+INSERT INTO
+    contact_merges (
+        surviving_contact_id,
+        merged_contact_id,
+        merged_contact,
+        moved_records,
+        merged_by
+    )
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    *;
+
+-- name: ListContactMerges :many
+SELECT
+    *
+FROM
+    contact_merges
+WHERE
+    surviving_contact_id = $1
+ORDER BY
+    created_at DESC;
@@ -1,36 +1,90 @@
 -- name: BulkInsertContacts :many
-INSERT INTO
-    contacts (
+-- INSERT ... RETURNING doesn't keep the order of the input arrays, so each
+-- contact is returned with the position of its row in them
+WITH input AS (
+    SELECT
+        gen_random_uuid() AS id,
+        u.*
+    FROM
+        unnest(
+            @first_names::text [],
+            @last_names::text [],
+            @birthdates::text [],
+            @sources::text [],
+            @statuses::text [],
+            @addresses::text [],
+            @cities::text [],
+            @states::text [],
+            @zip_codes::text [],
+            @lenders::text [],
+            @price_ranges::text [],
+            @timeframes::text [],
+            @owner_ids::uuid []
+        ) WITH ORDINALITY AS u (
+            first_name,
+            last_name,
+            birthdate,
+            source,
+            STATUS,
+            address,
+            city,
+            state,
+            zip_code,
+            lender,
+            price_range,
+            timeframe,
+            owner_id,
+            ordinal
+        )
+),
+inserted AS (
+    INSERT INTO
+        contacts (
+            id,
+            first_name,
+            last_name,
+            birthdate,
+            source,
+            STATUS,
+            address,
+            city,
+            state,
+            zip_code,
+            lender,
+            price_range,
+            timeframe,
+            owner_id,
+            organization_id
+        )
+    SELECT
+        id,
         first_name,
         last_name,
-        birthdate,
-        source,
-        STATUS,
-        address,
-        city,
-        state,
-        zip_code,
-        lender,
-        price_range,
-        timeframe,
-        owner_id
-    )
+        NULLIF(birthdate, '')::date,
+        NULLIF(source, ''),
+        NULLIF(STATUS, ''),
+        NULLIF(address, ''),
+        NULLIF(city, ''),
+        NULLIF(state, ''),
+        NULLIF(zip_code, ''),
+        NULLIF(lender, ''),
+        NULLIF(price_range, ''),
+        NULLIF(timeframe, ''),
+        owner_id,
+        sqlc.narg(organization_id)::uuid
+    FROM
+        input
+    RETURNING
+        *
+)
 SELECT
-    unnest(@first_names::text []),
-    unnest(@last_names::text []),
-    unnest(@birthdates::date []),
-    unnest(@sources::text []),
-    unnest(@statuses::text []),
-    unnest(@addresses::text []),
-    unnest(@cities::text []),
-    unnest(@states::text []),
-    unnest(@zip_codes::text []),
-    unnest(@lenders::text []),
-    unnest(@price_ranges::text []),
-    unnest(@timeframes::text []),
-    unnest(@owner_ids::uuid [])
-RETURNING
-    *;
+    inserted.*,
+    input.ordinal
+FROM
+    inserted
+    JOIN input ON input.id = inserted.id
+ORDER BY
+    input.ordinal;
 
 -- name: CreateContact :one
 INSERT INTO
@@ -47,7 +101,8 @@ INSERT INTO
         lender,
         price_range,
         timeframe,
-        owner_id
+        owner_id,
+        organization_id
     )
 VALUES
     (
@@ -63,7 +118,8 @@ VALUES
         $10,
         $11,
         $12,
-        $13
+        $13,
+        $14
     )
 RETURNING
     *;
@@ -118,12 +174,82 @@ SELECT
                 cb.contact_id = c.id
         ),
         '[]'
-    ) AS collaborators
+    ) AS collaborators,
+    (
+        SELECT
+            e.email_address
+        FROM
+            emails e
+        WHERE
+            e.contact_id = c.id
+            AND e.is_primary
+    ) AS primary_email,
+    (
+        SELECT
+            p.phone_number
+        FROM
+            phone_numbers p
+        WHERE
+            p.contact_id = c.id
+            AND p.is_primary
+    ) AS primary_phone
 FROM
     contacts c
 WHERE
     c.id = $1;
 
+-- name: CanViewContact :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = @contact_id
+            AND c.deleted_at IS NULL
+            AND (
+                c.owner_id = @user_id
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        collaborators col
+                    WHERE
+                        col.contact_id = c.id
+                        AND col.user_id = @user_id
+                )
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        member admin
+                    WHERE
+                        admin."userId" = @user_id
+                        AND admin."organizationId" = c.organization_id
+                        AND admin.role IN ('owner', 'admin')
+                )
+            )
+    );
+
+-- name: GetContactOrganization :one
+SELECT
+    organization_id
+FROM
+    contacts
+WHERE
+    id = $1;
+
+-- name: LockContact :exec
+-- Serializes changes to a contact's primary email and phone number.
+SELECT
+    id
+FROM
+    contacts
+WHERE
+    id = $1
+FOR UPDATE;
+
 -- name: GetAllContacts :many
 SELECT
     c.*,
@@ -142,17 +268,30 @@ SELECT
 FROM
     contacts c
 WHERE
-    -- user owns the contact
-    c.owner_id = $3
-    -- OR user is a collaborator on the contact
-    OR EXISTS (
-        SELECT
-            1
-        FROM
-            collaborators col
-        WHERE
-            col.contact_id = c.id
-            AND col.user_id = $3
+    c.deleted_at IS NULL
+    AND (
+        -- user owns the contact
+        c.owner_id = $3
+        -- OR user is a collaborator on the contact
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = $3
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = $3
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
     )
 ORDER BY
     c.created_at DESC
@@ -234,27 +373,161 @@ FROM
     new_contact;
 
 -- name: SearchContacts :many
+WITH visible AS MATERIALIZED (
+    SELECT
+        c.id
+    FROM
+        contacts c
+    WHERE
+        c.owner_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = @user_id
+        )
+),
+matches AS (
+    SELECT
+        c.id AS contact_id,
+        'name' AS field,
+        c.first_name || ' ' || c.last_name AS matched_text,
+        (
+            2 * ts_rank(
+                to_tsvector('simple', c.first_name || ' ' || c.last_name),
+                websearch_to_tsquery('simple', @query::text)
+            ) + 2 * word_similarity(@query::text, c.first_name || ' ' || c.last_name)
+        )::float8 AS score
+    FROM
+        contacts c
+    WHERE
+        c.id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            to_tsvector('simple', c.first_name || ' ' || c.last_name) @@ websearch_to_tsquery('simple', @query::text)
+            OR (c.first_name || ' ' || c.last_name) ILIKE @pattern::text
+            OR @query::text <% (c.first_name || ' ' || c.last_name)
+        )
+    UNION ALL
+    SELECT
+        e.contact_id,
+        'email',
+        e.email_address,
+        (0.5 + 1.5 * similarity(e.email_address, @query::text))::float8
+    FROM
+        emails e
+    WHERE
+        e.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND e.email_address ILIKE @pattern::text
+    UNION ALL
+    SELECT
+        p.contact_id,
+        'phone',
+        p.phone_number,
+        1.5::float8
+    FROM
+        phone_numbers p
+    WHERE
+        @digits_pattern::text <> ''
+        AND p.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND regexp_replace(p.phone_number, '\D', '', 'g') LIKE @digits_pattern::text
+    UNION ALL
+    SELECT
+        c.id,
+        'address',
+        concat_ws(', ', c.address, c.city, c.state, c.zip_code),
+        word_similarity(
+            @query::text,
+            coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+        )::float8
+    FROM
+        contacts c
+    WHERE
+        c.id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            (
+                coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+            ) ILIKE @pattern::text
+            OR @query::text <% (
+                coalesce(c.address, '') || ' ' || coalesce(c.city, '') || ' ' || coalesce(c.state, '') || ' ' || coalesce(c.zip_code, '')
+            )
+        )
+    UNION ALL
+    SELECT
+        n.contact_id,
+        'note',
+        n.note,
+        (
+            0.2 + ts_rank(
+                to_tsvector('english', n.note),
+                websearch_to_tsquery('english', @query::text)
+            )
+        )::float8
+    FROM
+        contact_notes n
+    WHERE
+        n.contact_id IN (
+            SELECT
+                id
+            FROM
+                visible
+        )
+        AND (
+            to_tsvector('english', n.note) @@ websearch_to_tsquery('english', @query::text)
+            OR n.note ILIKE @pattern::text
+        )
+),
+ranked AS (
+    SELECT
+        m.contact_id,
+        sum(m.score)::float8 AS rank,
+        (array_agg(m.field ORDER BY m.score DESC))[1]::text AS matched_field,
+        (array_agg(m.matched_text ORDER BY m.score DESC))[1]::text AS matched_text
+    FROM
+        matches m
+    GROUP BY
+        m.contact_id
+)
 SELECT
-    *
+    c.*,
+    r.rank,
+    r.matched_field,
+    r.matched_text,
+    count(*) over () AS total_count
 FROM
-    contacts
+    ranked r
+    JOIN contacts c ON c.id = r.contact_id
 WHERE
-    owner_id = $1
-    AND (
-        first_name ilike $2
-        OR last_name ilike $2
-        OR concat(first_name, ' ', last_name) ilike $2
-        OR address ilike $2
-        OR city ilike $2
-        OR state ilike $2
-        OR lender ilike $2
-        OR source ilike $2
-    )
+    c.deleted_at IS NULL
 ORDER BY
-    last_name,
-    first_name
+    r.rank DESC,
+    c.last_name,
+    c.first_name,
+    c.id
 LIMIT
-    50;
+    @page_size OFFSET @page_offset;
 
 -- name: GetContactsBySmartList :many
 SELECT
@@ -277,12 +550,12 @@ SELECT
     c.updated_at,
     count(*) over () AS total_count
 FROM
-    contacts c
-    LEFT JOIN contact_tags ct ON ct.contact_id = c.id
-    LEFT JOIN tags t ON t.id = ct.tag_id
-    JOIN smart_lists s ON s.id = $1
+    smart_list_members m
+    JOIN contacts c ON c.id = m.contact_id
 WHERE
-    (
+    m.smart_list_id = $1
+    AND c.deleted_at IS NULL
+    AND (
         c.owner_id = $4
         OR EXISTS (
             SELECT
@@ -293,100 +566,17 @@ WHERE
                 col.contact_id = c.id
                 AND col.user_id = $4
         )
-    )
-    -- 🔽 ALL YOUR EXISTING FILTERS 🔽
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'first_name' IS NULL
-        OR c.first_name ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'first_name'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_name' IS NULL
-        OR c.last_name ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_name'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'birthdate' IS NULL
-        OR c.birthdate = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'birthdate'
-        )::date
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'source' IS NULL
-        OR c.source ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'source'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'status' IS NULL
-        OR c.status = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'status'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'address' IS NULL
-        OR c.address ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'address'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'city' IS NULL
-        OR c.city ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'city'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'state' IS NULL
-        OR c.state ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'state'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'zip_code' IS NULL
-        OR c.zip_code = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'zip_code'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'lender' IS NULL
-        OR c.lender ilike '%' || (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'lender'
-        ) || '%'
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'price_range' IS NULL
-        OR c.price_range = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'price_range'
-        )
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'timeframe' IS NULL
-        OR c.timeframe = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'timeframe'
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = $4
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
         )
     )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'owner_id' IS NULL
-        OR c.owner_id = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'owner_id'
-        )::uuid
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'tag_id' IS NULL
-        OR t.id = (
-            coalesce(s.filter_criteria, '{}'::jsonb) ->> 'tag_id'
-        )::uuid
-    )
-    AND (
-        coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_contacted_days' IS NULL
-        OR c.last_contacted_at <= NOW() - (
-            (
-                coalesce(s.filter_criteria, '{}'::jsonb) ->> 'last_contacted_days'
-            ) || ' days'
-        )::INTERVAL
-    )
 ORDER BY
     c.last_contacted_at ASC nulls FIRST,
     c.created_at DESC
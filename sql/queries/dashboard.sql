@@ -4,8 +4,9 @@ SELECT
 FROM
     contacts
 WHERE
-    created_at >= date_trunc('month', current_date)
-    AND owner_id = $1;
+    created_at >= @month_start::timestamptz
+    AND owner_id = @owner_id
+    AND deleted_at IS NULL;
 
 -- name: AppointmentsThisWeek :one
 SELECT
@@ -13,10 +14,20 @@ SELECT
 FROM
     appointments
 WHERE
-    scheduled_at >= date_trunc('week', current_date)
-    AND scheduled_at < date_trunc('week', current_date) + INTERVAL '7 days'
+    scheduled_at >= @week_start::timestamptz
+    AND scheduled_at < @week_end::timestamptz
     AND outcome = 'no-outcome'
-    AND assigned_to_id = $1;
+    AND assigned_to_id = @assigned_to_id
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = appointments.contact_id
+            AND c.deleted_at IS NOT NULL
+    );
 
 -- name: TasksDueTodayCount :one
 SELECT
@@ -24,9 +35,20 @@ SELECT
 FROM
     tasks
 WHERE
-    date = current_date
+    date >= @day_start::timestamptz
+    AND date < @day_end::timestamptz
     AND STATUS = 'pending'
-    AND assigned_to_id = $1;
+    AND assigned_to_id = @assigned_to_id
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = tasks.contact_id
+            AND c.deleted_at IS NOT NULL
+    );
 
 -- name: Get5NewestContacts :many
 SELECT
@@ -91,6 +113,7 @@ FROM
     LEFT JOIN phone_numbers p ON p.contact_id = c.id
 WHERE
     c.owner_id = $1
+    AND c.deleted_at IS NULL
 GROUP BY
     c.id
 ORDER BY
@@ -104,9 +127,19 @@ SELECT
 FROM
     appointments a
 WHERE
-    a.scheduled_at >= current_date
+    a.scheduled_at >= @day_start::timestamptz
     AND a.status = 'scheduled'
-    AND a.assigned_to_id = $1
+    AND a.assigned_to_id = @assigned_to_id
+    AND a.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = a.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     a.scheduled_at ASC
 LIMIT
@@ -118,7 +151,8 @@ SELECT
 FROM
     contacts
 WHERE
-    owner_id = $1;
+    owner_id = $1
+    AND deleted_at IS NULL;
 
 -- name: ContactsBySource :many
 SELECT
@@ -128,6 +162,7 @@ FROM
     contacts
 WHERE
     owner_id = $1
+    AND deleted_at IS NULL
 GROUP BY
     source
 ORDER BY
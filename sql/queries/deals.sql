@@ -54,7 +54,8 @@ SELECT
 FROM
     deals
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: UpdateDeal :one
 UPDATE
@@ -86,12 +87,14 @@ RETURNING
     *;
 
 -- name: DeleteDeal :exec
-DELETE FROM
+-- Moves the deal to the trash
+UPDATE
     deals
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
     id = $1
-RETURNING
-    *;
+    AND deleted_at IS NULL;
 
 -- name: ListDeals :many
 SELECT
@@ -100,6 +103,16 @@ FROM
     deals
 WHERE
     assigned_to_id = $3
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     created_at DESC
 LIMIT
@@ -111,7 +124,17 @@ SELECT
 FROM
     deals
 WHERE
-    assigned_to_id = $1;
+    assigned_to_id = $1
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    );
 
 -- name: ListDealsByStage :many
 SELECT
@@ -121,6 +144,16 @@ FROM
 WHERE
     stage_id = $1
     AND assigned_to_id = $4
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
 ORDER BY
     created_at DESC
 LIMIT
@@ -133,7 +166,17 @@ FROM
     deals
 WHERE
     stage_id = $1
-    AND assigned_to_id = $2;
+    AND assigned_to_id = $2
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    );
 
 -- name: ListDealsByContactID :many
 SELECT
@@ -142,4 +185,24 @@ FROM
     deals
 WHERE
     contact_id = $1
-    AND assigned_to_id = $2;
+    AND assigned_to_id = $2
+    AND deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = deals.contact_id
+            AND c.deleted_at IS NOT NULL
+    );
+
+-- name: LockDeal :exec
+-- Serializes updates to a deal so its field history stays in order.
+SELECT
+    id
+FROM
+    deals
+WHERE
+    id = $1
+FOR UPDATE;
@@ -0,0 +1,135 @@
+-- name: GetDigestPreferences :one
+SELECT
+    *
+FROM
+    digest_preferences
+WHERE
+    user_id = $1;
+
+-- name: SetDigestPreferences :one
+INSERT INTO
+    digest_preferences (user_id, enabled, send_hour)
+VALUES
+    ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    enabled = EXCLUDED.enabled,
+    send_hour = EXCLUDED.send_hour,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING
+    *;
+
+-- name: ListDueDigests :many
+-- Users whose send hour has come in their time zone and who haven't had
+-- today's digest yet.
+SELECT
+    u.id AS user_id,
+    u.name,
+    u.email,
+    u."timeZone" AS time_zone
+FROM
+    users u
+    LEFT JOIN digest_preferences p ON p.user_id = u.id
+WHERE
+    coalesce(p.enabled, TRUE)
+    AND u."emailVerified"
+    AND NOT coalesce(u.banned, FALSE)
+    AND extract(
+        HOUR
+        FROM
+            CURRENT_TIMESTAMP AT TIME ZONE u."timeZone"
+    ) >= coalesce(p.send_hour, 7)
+    AND (
+        p.last_sent_on IS NULL
+        OR p.last_sent_on < (
+            CURRENT_TIMESTAMP AT TIME ZONE u."timeZone"
+        )::date
+    );
+
+-- name: GetDigestRecipient :one
+SELECT
+    u.id AS user_id,
+    u.name,
+    u.email
+FROM
+    users u
+WHERE
+    u.id = $1;
+
+-- name: ClaimDigest :one
+-- Records the user's digest for sent_on, returning no rows when it was
+-- already sent. The row stays locked until the transaction ends.
+INSERT INTO
+    digest_preferences (user_id, last_sent_on)
+VALUES
+    (@user_id, @sent_on::date)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    last_sent_on = EXCLUDED.last_sent_on
+WHERE
+    digest_preferences.last_sent_on IS NULL
+    OR digest_preferences.last_sent_on < EXCLUDED.last_sent_on
+RETURNING
+    user_id;
+
+-- name: ListDigestBirthdays :many
+-- The user's contacts whose birthday is on the given day. Birthdays on
+-- February 29 fall on February 28 outside leap years.
+SELECT
+    id,
+    first_name,
+    last_name,
+    birthdate
+FROM
+    contacts
+WHERE
+    owner_id = @user_id
+    AND deleted_at IS NULL
+    AND birthdate IS NOT NULL
+    AND (
+        to_char(birthdate, 'MM-DD') = to_char(@day::date, 'MM-DD')
+        OR (
+            to_char(birthdate, 'MM-DD') = '02-29'
+            AND to_char(@day::date, 'MM-DD') = '02-28'
+            AND to_char(@day::date + 1, 'MM-DD') = '03-01'
+        )
+    )
+ORDER BY
+    first_name,
+    last_name;
+
+-- name: ListDigestDealMilestones :many
+-- Key dates of the user's deals between from_time and to_time.
+SELECT
+    d.id,
+    d.title,
+    m.milestone::text AS milestone,
+    m.due_at::timestamptz AS due_at
+FROM
+    deals d
+    CROSS JOIN LATERAL (
+        VALUES
+            ('closing', d.closing_date),
+            ('earnest_money', d.earnest_money_due_date),
+            ('inspection', d.inspection_date),
+            ('appraisal', d.appraisal_date),
+            ('final_walkthrough', d.final_walkthrough_date),
+            ('possession', d.possession_date)
+    ) AS m(milestone, due_at)
+WHERE
+    d.assigned_to_id = @user_id
+    AND d.deleted_at IS NULL
+    AND m.due_at >= @from_time::timestamptz
+    AND m.due_at < @to_time::timestamptz
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = d.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
+ORDER BY
+    m.due_at,
+    d.title;
@@ -1,8 +1,10 @@
--- name: EnterEmail :exec
+-- name: EnterEmail :one
 INSERT INTO
     emails (contact_id, email_address, TYPE, is_primary)
 VALUES
-    ($1, $2, $3, $4);
+    ($1, $2, $3, $4)
+RETURNING
+    id;
 
 -- name: UpdateEmail :exec
 UPDATE
@@ -24,11 +26,11 @@ WHERE
 
 -- name: BulkEnterEmails :exec
 INSERT INTO
-    emails (contact_id, email, TYPE, is_primary)
+    emails (contact_id, email_address, TYPE, is_primary)
 SELECT
     unnest(@contact_ids::uuid []),
     unnest(@emails::text []),
-    unnest(@types::text []),
+    NULLIF(unnest(@types::text []), ''),
     unnest(@is_primary::boolean []);
 
 -- name: VerifyEmail :exec
@@ -57,3 +59,52 @@ FROM
         TYPE text,
         is_primary boolean
     );
+
+-- name: GetEmailByID :one
+SELECT
+    *
+FROM
+    emails
+WHERE
+    id = $1;
+
+-- name: DemotePrimaryEmails :exec
+UPDATE
+    emails
+SET
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = $1
+    AND is_primary;
+
+-- name: PromotePrimaryEmail :exec
+-- Makes the contact's oldest email primary when it has none.
+UPDATE
+    emails
+SET
+    is_primary = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = (
+        SELECT
+            o.id
+        FROM
+            emails o
+        WHERE
+            o.contact_id = @contact_id
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            emails p
+        WHERE
+            p.contact_id = @contact_id
+            AND p.is_primary
+    );
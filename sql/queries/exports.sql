@@ -0,0 +1,195 @@
+-- name: ExportContacts :many
+SELECT
+    c.*,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    json_build_object(
+                        'email_address',
+                        e.email_address,
+                        'type',
+                        e.type,
+                        'is_primary',
+                        e.is_primary
+                    )
+                    ORDER BY
+                        e.is_primary DESC,
+                        e.created_at ASC
+                )
+            FROM
+                emails e
+            WHERE
+                e.contact_id = c.id
+        ),
+        '[]'
+    )::text AS emails,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    json_build_object(
+                        'phone_number',
+                        p.phone_number,
+                        'type',
+                        p.type,
+                        'is_primary',
+                        p.is_primary
+                    )
+                    ORDER BY
+                        p.is_primary DESC,
+                        p.created_at ASC
+                )
+            FROM
+                phone_numbers p
+            WHERE
+                p.contact_id = c.id
+        ),
+        '[]'
+    )::text AS phone_numbers,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    t.name
+                    ORDER BY
+                        t.name
+                )
+            FROM
+                tags t
+                JOIN contact_tags ct ON ct.tag_id = t.id
+            WHERE
+                ct.contact_id = c.id
+        ),
+        '[]'
+    )::text AS tags
+FROM
+    contacts c
+WHERE
+    (
+        c.owner_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = @user_id
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = @user_id
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
+    )
+    AND c.deleted_at IS NULL
+    AND c.id > @after_id
+ORDER BY
+    c.id ASC
+LIMIT
+    @page_size;
+
+-- name: ExportSmartListContacts :many
+SELECT
+    c.*,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    json_build_object(
+                        'email_address',
+                        e.email_address,
+                        'type',
+                        e.type,
+                        'is_primary',
+                        e.is_primary
+                    )
+                    ORDER BY
+                        e.is_primary DESC,
+                        e.created_at ASC
+                )
+            FROM
+                emails e
+            WHERE
+                e.contact_id = c.id
+        ),
+        '[]'
+    )::text AS emails,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    json_build_object(
+                        'phone_number',
+                        p.phone_number,
+                        'type',
+                        p.type,
+                        'is_primary',
+                        p.is_primary
+                    )
+                    ORDER BY
+                        p.is_primary DESC,
+                        p.created_at ASC
+                )
+            FROM
+                phone_numbers p
+            WHERE
+                p.contact_id = c.id
+        ),
+        '[]'
+    )::text AS phone_numbers,
+    coalesce(
+        (
+            SELECT
+                json_agg(
+                    t.name
+                    ORDER BY
+                        t.name
+                )
+            FROM
+                tags t
+                JOIN contact_tags ct ON ct.tag_id = t.id
+            WHERE
+                ct.contact_id = c.id
+        ),
+        '[]'
+    )::text AS tags
+FROM
+    smart_list_members m
+    JOIN contacts c ON c.id = m.contact_id
+WHERE
+    m.smart_list_id = @smart_list_id
+    AND (
+        c.owner_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = @user_id
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = @user_id
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
+    )
+    AND c.deleted_at IS NULL
+    AND c.id > @after_id
+ORDER BY
+    c.id ASC
+LIMIT
+    @page_size;
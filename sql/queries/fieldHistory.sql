@@ -0,0 +1,63 @@
+-- name: CreateFieldHistory :exec
+INSERT INTO
+    field_history (
+        contact_id,
+        deal_id,
+        field,
+        old_value,
+        new_value,
+        changed_by
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6);
+
+-- name: ListContactFieldHistory :many
+-- A contact's field changes, newest first. Owners are labeled with the
+-- user's name.
+SELECT
+    h.id,
+    h.field,
+    h.old_value,
+    h.new_value,
+    old_ref.name AS old_label,
+    new_ref.name AS new_label,
+    h.changed_by,
+    u.name AS changed_by_name,
+    h.changed_at
+FROM
+    field_history h
+    LEFT JOIN users old_ref ON h.field = 'owner_id'
+    AND old_ref.id = (h.old_value #>> '{}')::uuid
+    LEFT JOIN users new_ref ON h.field = 'owner_id'
+    AND new_ref.id = (h.new_value #>> '{}')::uuid
+    LEFT JOIN users u ON u.id = h.changed_by
+WHERE
+    h.contact_id = $1
+ORDER BY
+    h.changed_at DESC,
+    h.id DESC;
+
+-- name: ListDealFieldHistory :many
+-- A deal's field changes, newest first. Stages are labeled with their name.
+SELECT
+    h.id,
+    h.field,
+    h.old_value,
+    h.new_value,
+    old_ref.name AS old_label,
+    new_ref.name AS new_label,
+    h.changed_by,
+    u.name AS changed_by_name,
+    h.changed_at
+FROM
+    field_history h
+    LEFT JOIN stages old_ref ON h.field = 'stage_id'
+    AND old_ref.id = (h.old_value #>> '{}')::uuid
+    LEFT JOIN stages new_ref ON h.field = 'stage_id'
+    AND new_ref.id = (h.new_value #>> '{}')::uuid
+    LEFT JOIN users u ON u.id = h.changed_by
+WHERE
+    h.deal_id = $1
+ORDER BY
+    h.changed_at DESC,
+    h.id DESC;
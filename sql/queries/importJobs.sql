@@ -0,0 +1,140 @@
+-- name: CreateImportJob :one
+INSERT INTO
+    import_jobs (
+        user_id,
+        format,
+        file_name,
+        mapping,
+        default_source,
+        payload,
+        organization_id,
+        route_leads
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+    id;
+
+-- name: GetImportJob :one
+SELECT
+    id,
+    user_id,
+    status,
+    format,
+    file_name,
+    total_rows,
+    processed_rows,
+    imported_rows,
+    skipped_rows,
+    failed_rows,
+    errors,
+    error,
+    attempts,
+    created_at,
+    updated_at,
+    started_at,
+    finished_at
+FROM
+    import_jobs
+WHERE
+    id = $1
+    AND user_id = $2;
+
+-- name: ClaimImportJob :one
+UPDATE
+    import_jobs
+SET
+    status = 'running',
+    attempts = attempts + 1,
+    error = NULL,
+    lease_id = gen_random_uuid(),
+    locked_until = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::integer),
+    started_at = COALESCE(started_at, CURRENT_TIMESTAMP),
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = (
+        SELECT
+            id
+        FROM
+            import_jobs
+        WHERE
+            status = 'pending'
+            OR (
+                status = 'running'
+                AND locked_until < CURRENT_TIMESTAMP
+            )
+        ORDER BY
+            created_at ASC
+        LIMIT
+            1 FOR
+        UPDATE
+            SKIP LOCKED
+    )
+RETURNING
+    *;
+
+-- name: UpdateImportJobProgress :execrows
+UPDATE
+    import_jobs
+SET
+    total_rows = @total_rows,
+    processed_rows = @processed_rows,
+    imported_rows = imported_rows + @imported::integer,
+    skipped_rows = skipped_rows + @skipped::integer,
+    failed_rows = failed_rows + @failed::integer,
+    errors = errors || @errors::jsonb,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = @id
+    AND lease_id = @lease_id
+    AND status = 'running';
+
+-- name: ExtendImportJobLease :execrows
+UPDATE
+    import_jobs
+SET
+    locked_until = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::integer)
+WHERE
+    id = @id
+    AND lease_id = @lease_id
+    AND status = 'running';
+
+-- name: FinishImportJob :exec
+UPDATE
+    import_jobs
+SET
+    status = $2,
+    error = $3,
+    locked_until = NULL,
+    finished_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND lease_id = $4
+    AND status = 'running';
+
+-- name: CancelImportJob :execrows
+UPDATE
+    import_jobs
+SET
+    status = 'cancelled',
+    locked_until = NULL,
+    finished_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND user_id = $2
+    AND status IN ('pending', 'running');
+
+-- name: RetryImportJob :execrows
+UPDATE
+    import_jobs
+SET
+    status = 'pending',
+    error = NULL,
+    finished_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND user_id = $2
+    AND status IN ('failed', 'cancelled');
@@ -0,0 +1,46 @@
+-- name: SaveImportMapping :one
+INSERT INTO
+    import_mappings (user_id, name, mapping)
+VALUES
+    ($1, $2, $3) ON conflict ON CONSTRAINT unique_user_import_mapping DO
+UPDATE
+SET
+    mapping = excluded.mapping,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING
+    *;
+
+-- name: ListImportMappings :many
+SELECT
+    *
+FROM
+    import_mappings
+WHERE
+    user_id = $1
+ORDER BY
+    name ASC;
+
+-- name: GetImportMapping :one
+SELECT
+    *
+FROM
+    import_mappings
+WHERE
+    id = $1
+    AND user_id = $2;
+
+-- name: GetImportMappingByName :one
+SELECT
+    *
+FROM
+    import_mappings
+WHERE
+    user_id = $1
+    AND name = $2;
+
+-- name: DeleteImportMapping :exec
+DELETE FROM
+    import_mappings
+WHERE
+    id = $1
+    AND user_id = $2;
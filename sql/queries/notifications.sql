@@ -39,10 +39,15 @@ DELETE FROM
 WHERE
     id = $1;
 
--- name: MarkAllNotificationsAsRead :exec
-UPDATE
+-- name: ListUnreadNotificationIDs :many
+-- The user's unread notifications, locked so they can be marked read and
+-- audited one at a time.
+SELECT
+    id
+FROM
     notifications
-SET
-    READ = TRUE
 WHERE
-    user_id = $1;
+    user_id = $1
+    AND READ IS NOT TRUE
+ORDER BY
+    created_at FOR UPDATE;
@@ -1,8 +1,10 @@
--- name: EnterPhoneNumber :exec
+-- name: EnterPhoneNumber :one
 INSERT INTO
-    phone_numbers (contact_id, phone_number, TYPE, is_primary)
+    phone_numbers (contact_id, phone_number, TYPE, is_primary, e164)
 VALUES
-    ($1, $2, $3, $4);
+    ($1, $2, $3, $4, $5)
+RETURNING
+    id;
 
 -- name: DeletePhoneNumber :exec
 DELETE FROM
@@ -17,6 +19,7 @@ SET
     phone_number = $1,
     TYPE = $2,
     is_primary = $3,
+    e164 = $5,
     updated_at = CURRENT_TIMESTAMP
 WHERE
     id = $4;
@@ -42,12 +45,13 @@ WHERE
 
 -- name: BulkEnterPhoneNumbers :exec
 INSERT INTO
-    phone_numbers (contact_id, phone_number, TYPE, is_primary)
+    phone_numbers (contact_id, phone_number, TYPE, is_primary, e164)
 SELECT
     unnest(@contact_ids::uuid []),
     unnest(@phone_numbers::text []),
-    unnest(@types::text []),
-    unnest(@is_primary::boolean []);
+    NULLIF(unnest(@types::text []), ''),
+    unnest(@is_primary::boolean []),
+    NULLIF(unnest(@e164s::text []), '');
 
 -- name: TestBulkInsertPhoneNumbers :exec
 INSERT INTO
@@ -64,3 +68,44 @@ FROM
         TYPE text,
         is_primary boolean
     );
+
+-- name: DemotePrimaryPhoneNumbers :exec
+UPDATE
+    phone_numbers
+SET
+    is_primary = FALSE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    contact_id = $1
+    AND is_primary;
+
+-- name: PromotePrimaryPhoneNumber :exec
+-- Makes the contact's oldest phone number primary when it has none.
+UPDATE
+    phone_numbers
+SET
+    is_primary = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = (
+        SELECT
+            o.id
+        FROM
+            phone_numbers o
+        WHERE
+            o.contact_id = @contact_id
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            phone_numbers p
+        WHERE
+            p.contact_id = @contact_id
+            AND p.is_primary
+    );
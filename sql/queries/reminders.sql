@@ -0,0 +1,191 @@
+-- name: GetReminderPreferences :one
+SELECT
+    *
+FROM
+    reminder_preferences
+WHERE
+    user_id = $1;
+
+-- name: SetReminderPreferences :one
+INSERT INTO
+    reminder_preferences (
+        user_id,
+        enabled,
+        email,
+        task_lead_minutes,
+        appointment_lead_minutes
+    )
+VALUES
+    ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    enabled = EXCLUDED.enabled,
+    email = EXCLUDED.email,
+    task_lead_minutes = EXCLUDED.task_lead_minutes,
+    appointment_lead_minutes = EXCLUDED.appointment_lead_minutes,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING
+    *;
+
+-- name: ListDueTaskReminders :many
+-- The reminders whose lead time has come for pending tasks that aren't due
+-- yet, one per task: the one with the shortest lead time. Lead times longer
+-- than one already sent for the task's due time are left out, so reminders
+-- missed while the server was down aren't sent late.
+SELECT
+    DISTINCT ON (t.id) t.id,
+    t.title,
+    t.date AS due_at,
+    t.contact_id,
+    u.id AS user_id,
+    l.lead_minutes::integer AS lead_minutes,
+    coalesce(p.email, FALSE)::boolean AS send_email
+FROM
+    tasks t
+    JOIN users u ON u.id = t.assigned_to_id
+    LEFT JOIN reminder_preferences p ON p.user_id = u.id
+    CROSS JOIN LATERAL unnest(
+        coalesce(p.task_lead_minutes, @default_lead_minutes::integer [])
+    ) AS l(lead_minutes)
+WHERE
+    t.status = 'pending'
+    AND t.deleted_at IS NULL
+    AND t.date > CURRENT_TIMESTAMP
+    AND t.date - make_interval(mins => l.lead_minutes) <= CURRENT_TIMESTAMP
+    AND coalesce(p.enabled, TRUE)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = t.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            reminders r
+        WHERE
+            r.task_id = t.id
+            AND r.due_at = t.date
+            AND r.lead_minutes <= l.lead_minutes
+    )
+ORDER BY
+    t.id,
+    l.lead_minutes;
+
+-- name: ListDueAppointmentReminders :many
+-- The reminders whose lead time has come for appointments that haven't
+-- started, picked the way ListDueTaskReminders picks them for tasks.
+SELECT
+    DISTINCT ON (a.id) a.id,
+    a.title,
+    a.scheduled_at AS due_at,
+    a.contact_id,
+    u.id AS user_id,
+    l.lead_minutes::integer AS lead_minutes,
+    coalesce(p.email, FALSE)::boolean AS send_email
+FROM
+    appointments a
+    JOIN users u ON u.id = a.assigned_to_id
+    LEFT JOIN reminder_preferences p ON p.user_id = u.id
+    CROSS JOIN LATERAL unnest(
+        coalesce(p.appointment_lead_minutes, @default_lead_minutes::integer [])
+    ) AS l(lead_minutes)
+WHERE
+    a.outcome IS DISTINCT FROM 'cancelled'
+    AND a.deleted_at IS NULL
+    AND a.scheduled_at > CURRENT_TIMESTAMP
+    AND a.scheduled_at - make_interval(mins => l.lead_minutes) <= CURRENT_TIMESTAMP
+    AND coalesce(p.enabled, TRUE)
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = a.contact_id
+            AND c.deleted_at IS NOT NULL
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            reminders r
+        WHERE
+            r.appointment_id = a.id
+            AND r.due_at = a.scheduled_at
+            AND r.lead_minutes <= l.lead_minutes
+    )
+ORDER BY
+    a.id,
+    l.lead_minutes;
+
+-- name: CreateReminder :one
+-- No row is returned when the reminder was already sent
+INSERT INTO
+    reminders (
+        user_id,
+        task_id,
+        appointment_id,
+        due_at,
+        lead_minutes,
+        send_email
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING
+RETURNING
+    *;
+
+-- name: SetReminderNotification :exec
+UPDATE
+    reminders
+SET
+    notification_id = $2
+WHERE
+    id = $1;
+
+-- name: ClaimReminderEmail :one
+-- The oldest reminder whose email still needs sending, locked until the
+-- caller's transaction ends. Reminders other servers are sending are
+-- skipped.
+SELECT
+    r.id,
+    r.due_at,
+    r.lead_minutes,
+    r.task_id,
+    u.email,
+    coalesce(t.title, a.title)::text AS title
+FROM
+    reminders r
+    JOIN users u ON u.id = r.user_id
+    LEFT JOIN tasks t ON t.id = r.task_id
+    LEFT JOIN appointments a ON a.id = r.appointment_id
+WHERE
+    r.send_email
+    AND r.email_sent_at IS NULL
+    AND r.email_attempts < @max_attempts::integer
+    AND r.due_at > CURRENT_TIMESTAMP
+ORDER BY
+    r.created_at
+LIMIT
+    1 FOR UPDATE OF r SKIP LOCKED;
+
+-- name: MarkReminderEmailed :exec
+UPDATE
+    reminders
+SET
+    email_sent_at = CURRENT_TIMESTAMP,
+    email_attempts = email_attempts + 1
+WHERE
+    id = $1;
+
+-- name: RecordReminderEmailFailure :exec
+UPDATE
+    reminders
+SET
+    email_attempts = email_attempts + 1
+WHERE
+    id = $1;
@@ -0,0 +1,322 @@
+-- name: GetOrganizationRollup :many
+-- The dashboard metrics, deal volume and commission of every member of an
+-- organization. Contacts count when they belong to the organization or to
+-- none; tasks, appointments and deals when their contact isn't in another
+-- organization. Closed deals are those closed this year. The month, week,
+-- day and year bounds are those of the viewer's time zone.
+SELECT
+    m."userId" AS user_id,
+    u.name,
+    u.email,
+    m.role,
+    (
+        SELECT
+            count(*)
+        FROM
+            contacts c
+        WHERE
+            c.owner_id = m."userId"
+            AND coalesce(c.organization_id, m."organizationId") = m."organizationId"
+            AND c.deleted_at IS NULL
+    ) AS total_contacts,
+    (
+        SELECT
+            count(*)
+        FROM
+            contacts c
+        WHERE
+            c.owner_id = m."userId"
+            AND coalesce(c.organization_id, m."organizationId") = m."organizationId"
+            AND c.deleted_at IS NULL
+            AND c.created_at >= @month_start::timestamptz
+    ) AS new_contacts,
+    (
+        SELECT
+            count(*)
+        FROM
+            appointments a
+        WHERE
+            a.assigned_to_id = m."userId"
+            AND a.deleted_at IS NULL
+            AND a.scheduled_at >= @week_start::timestamptz
+            AND a.scheduled_at < @week_end::timestamptz
+            AND a.outcome = 'no-outcome'
+            AND NOT EXISTS (
+                SELECT
+                    1
+                FROM
+                    contacts c
+                WHERE
+                    c.id = a.contact_id
+                    AND (
+                        c.organization_id <> m."organizationId"
+                        OR c.deleted_at IS NOT NULL
+                    )
+            )
+    ) AS appointments_this_week,
+    (
+        SELECT
+            count(*)
+        FROM
+            tasks t
+        WHERE
+            t.assigned_to_id = m."userId"
+            AND t.deleted_at IS NULL
+            AND t.date >= @day_start::timestamptz
+            AND t.date < @day_end::timestamptz
+            AND t.status = 'pending'
+            AND NOT EXISTS (
+                SELECT
+                    1
+                FROM
+                    contacts c
+                WHERE
+                    c.id = t.contact_id
+                    AND (
+                        c.organization_id <> m."organizationId"
+                        OR c.deleted_at IS NOT NULL
+                    )
+            )
+    ) AS tasks_due_today,
+    d.open_deals,
+    d.open_volume,
+    d.closed_deals,
+    d.closed_volume,
+    d.commission
+FROM
+    member m
+    JOIN users u ON u.id = m."userId"
+    CROSS JOIN LATERAL (
+        SELECT
+            count(*) filter (
+                WHERE
+                    deals.closed_date IS NULL
+            ) AS open_deals,
+            coalesce(
+                sum(deals.price) filter (
+                    WHERE
+                        deals.closed_date IS NULL
+                ),
+                0
+            )::bigint AS open_volume,
+            count(*) filter (
+                WHERE
+                    deals.closed_date >= @year_start::timestamptz
+            ) AS closed_deals,
+            coalesce(
+                sum(deals.price) filter (
+                    WHERE
+                        deals.closed_date >= @year_start::timestamptz
+                ),
+                0
+            )::bigint AS closed_volume,
+            coalesce(
+                sum(deals.commission) filter (
+                    WHERE
+                        deals.closed_date >= @year_start::timestamptz
+                ),
+                0
+            )::float8 AS commission
+        FROM
+            deals
+        WHERE
+            deals.assigned_to_id = m."userId"
+            AND deals.deleted_at IS NULL
+            AND NOT EXISTS (
+                SELECT
+                    1
+                FROM
+                    contacts c
+                WHERE
+                    c.id = deals.contact_id
+                    AND (
+                        c.organization_id <> m."organizationId"
+                        OR c.deleted_at IS NOT NULL
+                    )
+            )
+    ) d
+WHERE
+    m."organizationId" = @organization_id
+ORDER BY
+    u.name;
+
+-- name: GetOrganizationContactsBySource :many
+SELECT
+    c.owner_id AS user_id,
+    c.source,
+    count(*) AS contact_count
+FROM
+    contacts c
+    JOIN member m ON m."userId" = c.owner_id
+WHERE
+    m."organizationId" = $1
+    AND coalesce(c.organization_id, m."organizationId") = m."organizationId"
+    AND c.deleted_at IS NULL
+GROUP BY
+    c.owner_id,
+    c.source
+ORDER BY
+    contact_count DESC;
+
+-- name: ListRollupContacts :many
+-- The contacts behind the contact metrics of GetOrganizationRollup, for one
+-- member or the whole organization
+SELECT
+    c.*
+FROM
+    contacts c
+WHERE
+    c.owner_id IN (
+        SELECT
+            "userId"
+        FROM
+            member
+        WHERE
+            "organizationId" = @organization_id
+    )
+    AND coalesce(c.organization_id, @organization_id) = @organization_id
+    AND c.deleted_at IS NULL
+    AND (
+        sqlc.narg(user_id)::uuid IS NULL
+        OR c.owner_id = sqlc.narg(user_id)
+    )
+    AND (
+        NOT @new_only::bool
+        OR c.created_at >= @month_start::timestamptz
+    )
+    AND (
+        NOT @by_source::bool
+        OR c.source IS NOT DISTINCT FROM sqlc.narg(source)
+    )
+ORDER BY
+    c.created_at DESC
+LIMIT
+    @row_limit OFFSET @row_offset;
+
+-- name: ListRollupAppointments :many
+-- The appointments behind appointments_this_week
+SELECT
+    a.*
+FROM
+    appointments a
+WHERE
+    a.deleted_at IS NULL
+    AND a.assigned_to_id IN (
+        SELECT
+            "userId"
+        FROM
+            member
+        WHERE
+            "organizationId" = @organization_id
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = a.contact_id
+            AND (
+                c.organization_id <> @organization_id
+                OR c.deleted_at IS NOT NULL
+            )
+    )
+    AND (
+        sqlc.narg(user_id)::uuid IS NULL
+        OR a.assigned_to_id = sqlc.narg(user_id)
+    )
+    AND a.scheduled_at >= @week_start::timestamptz
+    AND a.scheduled_at < @week_end::timestamptz
+    AND a.outcome = 'no-outcome'
+ORDER BY
+    a.scheduled_at ASC
+LIMIT
+    @row_limit OFFSET @row_offset;
+
+-- name: ListRollupTasks :many
+-- The tasks behind tasks_due_today
+SELECT
+    t.*
+FROM
+    tasks t
+WHERE
+    t.deleted_at IS NULL
+    AND t.assigned_to_id IN (
+        SELECT
+            "userId"
+        FROM
+            member
+        WHERE
+            "organizationId" = @organization_id
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = t.contact_id
+            AND (
+                c.organization_id <> @organization_id
+                OR c.deleted_at IS NOT NULL
+            )
+    )
+    AND (
+        sqlc.narg(user_id)::uuid IS NULL
+        OR t.assigned_to_id = sqlc.narg(user_id)
+    )
+    AND t.date >= @day_start::timestamptz
+    AND t.date < @day_end::timestamptz
+    AND t.status = 'pending'
+ORDER BY
+    t.created_at ASC
+LIMIT
+    @row_limit OFFSET @row_offset;
+
+-- name: ListRollupDeals :many
+-- The deals behind the open deal metrics, or with closed set the closed deal
+-- and commission metrics
+SELECT
+    d.*
+FROM
+    deals d
+WHERE
+    d.deleted_at IS NULL
+    AND d.assigned_to_id IN (
+        SELECT
+            "userId"
+        FROM
+            member
+        WHERE
+            "organizationId" = @organization_id
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            contacts c
+        WHERE
+            c.id = d.contact_id
+            AND (
+                c.organization_id <> @organization_id
+                OR c.deleted_at IS NOT NULL
+            )
+    )
+    AND (
+        sqlc.narg(user_id)::uuid IS NULL
+        OR d.assigned_to_id = sqlc.narg(user_id)
+    )
+    AND (
+        (
+            @closed::bool
+            AND d.closed_date >= @year_start::timestamptz
+        )
+        OR (
+            NOT @closed::bool
+            AND d.closed_date IS NULL
+        )
+    )
+ORDER BY
+    d.created_at DESC
+LIMIT
+    @row_limit OFFSET @row_offset;
@@ -0,0 +1,222 @@
+-- name: CreateRoutingRule :one
+INSERT INTO
+    routing_rules (
+        organization_id,
+        name,
+        priority,
+        strategy,
+        zip_codes,
+        cities,
+        sources,
+        claim_timeout_minutes,
+        enabled
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING
+    *;
+
+-- name: UpdateRoutingRule :one
+UPDATE
+    routing_rules
+SET
+    name = $2,
+    priority = $3,
+    strategy = $4,
+    zip_codes = $5,
+    cities = $6,
+    sources = $7,
+    claim_timeout_minutes = $8,
+    enabled = $9,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    *;
+
+-- name: DeleteRoutingRule :exec
+DELETE FROM
+    routing_rules
+WHERE
+    id = $1;
+
+-- name: ListRoutingRules :many
+SELECT
+    *
+FROM
+    routing_rules
+WHERE
+    organization_id = $1
+ORDER BY
+    priority ASC,
+    created_at ASC;
+
+-- name: ListRoutingRuleAgents :many
+-- The agents of every rule of an organization
+SELECT
+    a.*
+FROM
+    routing_rule_agents a
+    JOIN routing_rules r ON r.id = a.rule_id
+WHERE
+    r.organization_id = $1
+ORDER BY
+    a.rule_id,
+    a.user_id;
+
+-- name: SetRoutingRuleAgent :exec
+-- Adds an agent to a rule or changes their capacity, keeping what the rule
+-- has assigned them
+INSERT INTO
+    routing_rule_agents (rule_id, user_id, capacity)
+VALUES
+    ($1, $2, $3)
+ON CONFLICT (rule_id, user_id) DO UPDATE
+SET
+    capacity = EXCLUDED.capacity;
+
+-- name: RemoveOtherRoutingRuleAgents :exec
+DELETE FROM
+    routing_rule_agents
+WHERE
+    rule_id = @rule_id
+    AND NOT (user_id = ANY(@user_ids::uuid[]));
+
+-- name: CountOrganizationMembers :one
+-- How many of the users are members of the organization
+SELECT
+    count(*)
+FROM
+    member
+WHERE
+    "organizationId" = @organization_id
+    AND "userId" = ANY(@user_ids::uuid[]);
+
+-- name: RecordRoutingRuleAssignment :exec
+UPDATE
+    routing_rule_agents
+SET
+    assigned_count = assigned_count + 1,
+    last_assigned_at = CURRENT_TIMESTAMP
+WHERE
+    rule_id = $1
+    AND user_id = $2;
+
+-- name: SetLeadOwner :exec
+-- NULL leaves a first to claim lead without an owner until it is claimed
+UPDATE
+    contacts
+SET
+    owner_id = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1;
+
+-- name: CreateLeadAssignment :one
+INSERT INTO
+    lead_assignments (
+        contact_id,
+        organization_id,
+        rule_id,
+        user_id,
+        expires_at
+    )
+VALUES
+    ($1, $2, $3, $4, $5)
+RETURNING
+    *;
+
+-- name: ClaimLeadAssignment :one
+-- Claims a pending lead for the user: one assigned to them, or one offered
+-- to every agent of a first to claim rule they are an agent of
+UPDATE
+    lead_assignments la
+SET
+    status = 'claimed',
+    claimed_by = @user_id,
+    claimed_at = CURRENT_TIMESTAMP
+WHERE
+    la.id = @id
+    AND la.status = 'pending'
+    AND (
+        la.user_id = @user_id
+        OR (
+            la.user_id IS NULL
+            AND EXISTS (
+                SELECT
+                    1
+                FROM
+                    routing_rule_agents a
+                WHERE
+                    a.rule_id = la.rule_id
+                    AND a.user_id = @user_id
+            )
+        )
+    )
+RETURNING
+    la.*;
+
+-- name: ListPendingLeadAssignments :many
+-- Leads waiting for the user to claim them
+SELECT
+    la.*,
+    c.first_name,
+    c.last_name
+FROM
+    lead_assignments la
+    JOIN contacts c ON c.id = la.contact_id
+WHERE
+    la.status = 'pending'
+    AND c.deleted_at IS NULL
+    AND (
+        la.user_id = @user_id
+        OR (
+            la.user_id IS NULL
+            AND EXISTS (
+                SELECT
+                    1
+                FROM
+                    routing_rule_agents a
+                WHERE
+                    a.rule_id = la.rule_id
+                    AND a.user_id = @user_id
+            )
+        )
+    )
+ORDER BY
+    la.created_at ASC;
+
+-- name: ExpireLeadAssignment :one
+-- Expires the pending assignment whose claim timeout passed longest ago
+UPDATE
+    lead_assignments
+SET
+    status = 'expired'
+WHERE
+    id = (
+        SELECT
+            id
+        FROM
+            lead_assignments
+        WHERE
+            status = 'pending'
+            AND expires_at < CURRENT_TIMESTAMP
+        ORDER BY
+            expires_at ASC
+        LIMIT
+            1 FOR
+        UPDATE
+            SKIP LOCKED
+    )
+RETURNING
+    *;
+
+-- name: ListLeadAssignees :many
+-- The agents a lead has been assigned to so far
+SELECT DISTINCT
+    user_id
+FROM
+    lead_assignments
+WHERE
+    contact_id = $1
+    AND user_id IS NOT NULL;
@@ -3,10 +3,11 @@ INSERT INTO
     smart_lists (
         name,
         description,
-        user_id
+        user_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3)
+    ($1, $2, $3, $4)
 RETURNING
     *;
 
@@ -24,12 +25,59 @@ RETURNING
     *;
 
 -- name: GetAllSmartLists :many
+-- The user's personal lists and those of their active organization. A list
+-- only counts the members the user can see.
 SELECT
-    *
+    s.*,
+    (
+        SELECT
+            count(*)
+        FROM
+            smart_list_members m
+            JOIN contacts c ON c.id = m.contact_id
+        WHERE
+            m.smart_list_id = s.id
+            AND c.deleted_at IS NULL
+            AND (
+                c.owner_id = @user_id
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        collaborators col
+                    WHERE
+                        col.contact_id = c.id
+                        AND col.user_id = @user_id
+                )
+                OR EXISTS (
+                    SELECT
+                        1
+                    FROM
+                        member admin
+                    WHERE
+                        admin."userId" = @user_id
+                        AND admin."organizationId" = c.organization_id
+                        AND admin.role IN ('owner', 'admin')
+                )
+            )
+    ) AS member_count,
+    EXISTS (
+        SELECT
+            1
+        FROM
+            smart_list_subscriptions sub
+        WHERE
+            sub.smart_list_id = s.id
+            AND sub.user_id = @user_id
+    ) AS subscribed
 FROM
-    smart_lists
+    smart_lists s
 WHERE
-    user_id = $1;
+    (
+        s.organization_id IS NULL
+        AND s.user_id = @user_id
+    )
+    OR s.organization_id = @organization_id;
 
 -- name: SetSmartListFilterCriteria :one
 UPDATE
@@ -41,3 +89,169 @@ WHERE
     id = $1
 RETURNING
     *;
+
+-- name: GetSmartListByID :one
+-- Personal lists are visible to their owner and shared lists to every
+-- member of the organization
+SELECT
+    *
+FROM
+    smart_lists
+WHERE
+    id = $1
+    AND (
+        (
+            organization_id IS NULL
+            AND user_id = $2
+        )
+        OR organization_id IN (
+            SELECT
+                "organizationId"
+            FROM
+                member
+            WHERE
+                "userId" = $2
+        )
+    );
+
+-- name: ClaimStaleSmartList :one
+SELECT
+    *
+FROM
+    smart_lists
+WHERE
+    members_refreshed_at IS NULL
+    OR members_refreshed_at < @stale_before::timestamptz
+ORDER BY
+    members_refreshed_at ASC nulls FIRST
+LIMIT
+    1 FOR
+UPDATE
+    SKIP LOCKED;
+
+-- name: SetSmartListMembersRefreshed :exec
+UPDATE
+    smart_lists
+SET
+    members_refreshed_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1;
+
+-- name: ClaimDirtySmartListContacts :many
+DELETE FROM
+    smart_list_dirty_contacts
+WHERE
+    contact_id IN (
+        SELECT
+            d.contact_id
+        FROM
+            smart_list_dirty_contacts d
+        ORDER BY
+            d.queued_at ASC
+        LIMIT
+            $1 FOR
+        UPDATE
+            SKIP LOCKED
+    )
+RETURNING
+    contact_id;
+
+-- name: ListSmartListsForContacts :many
+-- Lists whose owner or organization can see one of the contacts, or that
+-- currently contain one of them
+SELECT
+    s.*
+FROM
+    smart_lists s
+WHERE
+    s.user_id IN (
+        SELECT
+            c.owner_id
+        FROM
+            contacts c
+        WHERE
+            c.id = ANY(@contact_ids::uuid[])
+        UNION
+        SELECT
+            col.user_id
+        FROM
+            collaborators col
+        WHERE
+            col.contact_id = ANY(@contact_ids::uuid[])
+    )
+    OR s.organization_id IN (
+        SELECT
+            c.organization_id
+        FROM
+            contacts c
+        WHERE
+            c.id = ANY(@contact_ids::uuid[])
+    )
+    OR EXISTS (
+        SELECT
+            1
+        FROM
+            smart_list_members m
+        WHERE
+            m.smart_list_id = s.id
+            AND m.contact_id = ANY(@contact_ids::uuid[])
+    );
+
+-- name: NotifySmartListSubscribers :execrows
+-- Subscribers are only told about contacts they can see: their own, ones they
+-- collaborate on and, for admins, their organization's.
+INSERT INTO
+    notifications (
+        user_id,
+        TYPE,
+        message,
+        contact_id
+    )
+SELECT
+    sub.user_id,
+    'smart_list_entered',
+    c.first_name || ' ' || c.last_name || ' entered smart list "' || s.name || '"',
+    c.id
+FROM
+    smart_list_subscriptions sub
+    JOIN smart_lists s ON s.id = sub.smart_list_id
+    JOIN contacts c ON c.id = ANY(@contact_ids::uuid[])
+WHERE
+    sub.smart_list_id = @smart_list_id
+    AND c.deleted_at IS NULL
+    AND (
+        c.owner_id = sub.user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                collaborators col
+            WHERE
+                col.contact_id = c.id
+                AND col.user_id = sub.user_id
+        )
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = sub.user_id
+                AND admin."organizationId" = c.organization_id
+                AND admin.role IN ('owner', 'admin')
+        )
+    );
+
+-- name: SubscribeToSmartList :exec
+INSERT INTO
+    smart_list_subscriptions (smart_list_id, user_id)
+VALUES
+    ($1, $2)
+ON CONFLICT (smart_list_id, user_id) DO NOTHING;
+
+-- name: UnsubscribeFromSmartList :execrows
+DELETE FROM
+    smart_list_subscriptions
+WHERE
+    smart_list_id = $1
+    AND user_id = $2;
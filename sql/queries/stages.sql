@@ -5,21 +5,30 @@ INSERT INTO
         description,
         client_type,
         order_index,
-        owner_id
+        owner_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3, $4, $5)
+    ($1, $2, $3, $4, $5, $6)
 RETURNING
     *;
 
 -- name: GetStagesByClientType :many
+-- The owner's personal stages and those of their active organization
 SELECT
     *
 FROM
     stages
 WHERE
     client_type = $1
-    AND owner_id = $2
+    AND deleted_at IS NULL
+    AND (
+        (
+            organization_id IS NULL
+            AND owner_id = $2
+        )
+        OR organization_id = $3
+    )
 ORDER BY
     order_index ASC;
 
@@ -37,18 +46,31 @@ RETURNING
     *;
 
 -- name: DeleteStage :exec
-DELETE FROM
+-- Moves the stage to the trash. Its deals keep pointing at it until it is
+-- purged.
+UPDATE
     stages
+SET
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
 
 -- name: GetAllStages :many
+-- The owner's personal stages and those of their active organization
 SELECT
     *
 FROM
     stages
 WHERE
-    owner_id = $1
+    deleted_at IS NULL
+    AND (
+        (
+            organization_id IS NULL
+            AND owner_id = $1
+        )
+        OR organization_id = $2
+    )
 ORDER BY
     client_type ASC,
     order_index ASC;
@@ -59,4 +81,5 @@ SELECT
 FROM
     stages
 WHERE
-    id = $1;
+    id = $1
+    AND deleted_at IS NULL;
@@ -3,10 +3,11 @@ INSERT INTO
     tags (
         name,
         description,
-        user_id
+        user_id,
+        organization_id
     )
 VALUES
-    ($1, $2, $3)
+    ($1, $2, $3, $4)
 RETURNING
     *;
 
@@ -19,12 +20,19 @@ RETURNING
     *;
 
 -- name: GetAllTags :many
+-- The user's personal tags and those of their active organization
 SELECT
     *
 FROM
     tags
 WHERE
-    user_id = $1;
+    (
+        organization_id IS NULL
+        AND user_id = $1
+    )
+    OR organization_id = $2
+ORDER BY
+    name;
 
 -- name: AssignTagToContact :one
 INSERT INTO
@@ -42,18 +50,44 @@ WHERE
     AND contact_id = $2;
 
 -- name: AssignTagsToContact :exec
+-- Tags are matched by name against the organization's tags first, then the
+-- user's personal tags. Names that match neither become personal tags.
 WITH input_tags AS (
     SELECT
-        unnest($2::text []) AS tag_name
+        DISTINCT unnest(@tag_names::text []) AS tag_name
+),
+organization_tags AS (
+    SELECT
+        t.id,
+        t.name
+    FROM
+        tags t
+    WHERE
+        t.organization_id = sqlc.narg(organization_id)::uuid
+        AND t.name IN (
+            SELECT
+                tag_name
+            FROM
+                input_tags
+        )
 ),
 inserted_tags AS (
     INSERT INTO
         tags(name, user_id)
     SELECT
         tag_name,
-        $1
+        @user_id
     FROM
-        input_tags ON conflict ON CONSTRAINT unique_user_tag DO nothing
+        input_tags
+    WHERE
+        tag_name NOT IN (
+            SELECT
+                name
+            FROM
+                organization_tags
+        ) ON conflict (user_id, name)
+    WHERE
+        organization_id IS NULL DO nothing
     RETURNING
         id,
         name
@@ -63,25 +97,39 @@ all_tags AS (
         id,
         name
     FROM
-        inserted_tags
+        organization_tags
     UNION
     SELECT
         id,
         name
+    FROM
+        inserted_tags
+    UNION
+    SELECT
+        t.id,
+        t.name
     FROM
         tags t
     WHERE
-        t.name IN (
+        t.organization_id IS NULL
+        AND t.user_id = @user_id
+        AND t.name IN (
             SELECT
                 tag_name
             FROM
                 input_tags
         )
+        AND t.name NOT IN (
+            SELECT
+                name
+            FROM
+                organization_tags
+        )
 )
 INSERT INTO
     contact_tags(contact_id, tag_id)
 SELECT
-    $3,
+    @contact_id,
     id
 FROM
     all_tags ON conflict DO nothing;
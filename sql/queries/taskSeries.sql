@@ -0,0 +1,131 @@
+-- name: CreateTaskSeries :one
+INSERT INTO
+    task_series (
+        rrule,
+        dtstart,
+        contact_id,
+        assigned_to_id,
+        title,
+        TYPE,
+        priority,
+        note
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+    *;
+
+-- name: GetTaskSeriesByID :one
+SELECT
+    *
+FROM
+    task_series
+WHERE
+    id = $1;
+
+-- name: LockTaskSeries :one
+-- Serializes changes to a series and the generation of its occurrences
+SELECT
+    *
+FROM
+    task_series
+WHERE
+    id = $1 FOR UPDATE;
+
+-- name: UpdateTaskSeries :one
+UPDATE
+    task_series
+SET
+    rrule = $2,
+    dtstart = $3,
+    contact_id = $4,
+    assigned_to_id = $5,
+    title = $6,
+    TYPE = $7,
+    priority = $8,
+    note = $9,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    *;
+
+-- name: CancelTaskSeries :exec
+UPDATE
+    task_series
+SET
+    cancelled_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND cancelled_at IS NULL;
+
+-- name: CreateTaskOccurrence :one
+-- Adds the occurrence of a series scheduled for occurrence_date, copying the
+-- series' fields. No row is returned when the series is cancelled or the
+-- occurrence was already generated.
+INSERT INTO
+    tasks (
+        series_id,
+        occurrence_date,
+        date,
+        contact_id,
+        assigned_to_id,
+        title,
+        TYPE,
+        priority,
+        note
+    )
+SELECT
+    s.id,
+    @occurrence_date::timestamptz,
+    @occurrence_date::timestamptz,
+    s.contact_id,
+    s.assigned_to_id,
+    s.title,
+    s.type,
+    s.priority,
+    s.note
+FROM
+    task_series s
+WHERE
+    s.id = @series_id
+    AND s.cancelled_at IS NULL
+ON CONFLICT (series_id, occurrence_date)
+WHERE
+    series_id IS NOT NULL DO NOTHING
+RETURNING
+    *;
+
+-- name: ListPendingSeriesTasks :many
+SELECT
+    *
+FROM
+    tasks
+WHERE
+    series_id = $1
+    AND STATUS = 'pending'
+    AND deleted_at IS NULL
+ORDER BY
+    occurrence_date;
+
+-- name: ApplyTaskSeries :exec
+-- Copies the series' fields onto one of its pending occurrences and moves
+-- it to occurrence_date
+UPDATE
+    tasks t
+SET
+    occurrence_date = @occurrence_date::timestamptz,
+    date = @occurrence_date::timestamptz,
+    contact_id = s.contact_id,
+    assigned_to_id = s.assigned_to_id,
+    title = s.title,
+    TYPE = s.type,
+    priority = s.priority,
+    note = s.note,
+    updated_at = CURRENT_TIMESTAMP
+FROM
+    task_series s
+WHERE
+    t.id = @id
+    AND s.id = t.series_id;
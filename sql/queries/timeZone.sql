@@ -0,0 +1,18 @@
+-- name: GetUserTimeZone :one
+SELECT
+    "timeZone"
+FROM
+    users
+WHERE
+    id = $1;
+
+-- name: SetUserTimeZone :one
+UPDATE
+    users
+SET
+    "timeZone" = $2,
+    "updatedAt" = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+RETURNING
+    "timeZone";
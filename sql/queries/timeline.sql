@@ -0,0 +1,146 @@
+-- name: GetContactTimeline :many
+-- Keyset paginated, newest first. Pass an empty event_types array for all
+-- types.
+SELECT
+    e.id,
+    e.event_type,
+    e.occurred_at,
+    e.title,
+    e.body,
+    e.details
+FROM
+    (
+        SELECT
+            n.id,
+            'note' AS event_type,
+            n.created_at AS occurred_at,
+            'Note' AS title,
+            n.note AS body,
+            jsonb_build_object('created_by', n.created_by) AS details
+        FROM
+            contact_notes n
+        WHERE
+            n.contact_id = @contact_id
+        UNION ALL
+        SELECT
+            l.id,
+            'contact_log',
+            l.created_at,
+            l.contact_method,
+            coalesce(l.note, ''),
+            jsonb_build_object('created_by', l.created_by, 'contact_method', l.contact_method)
+        FROM
+            contact_logs l
+        WHERE
+            l.contact_id = @contact_id
+        UNION ALL
+        SELECT
+            t.id,
+            'task',
+            t.created_at,
+            t.title,
+            coalesce(t.note, ''),
+            jsonb_build_object(
+                'type',
+                t.type,
+                'status',
+                t.status,
+                'priority',
+                t.priority,
+                'date',
+                t.date,
+                'assigned_to_id',
+                t.assigned_to_id
+            )
+        FROM
+            tasks t
+        WHERE
+            t.contact_id = @contact_id
+            AND t.deleted_at IS NULL
+        UNION ALL
+        SELECT
+            a.id,
+            'appointment',
+            a.scheduled_at,
+            a.title,
+            coalesce(a.note, ''),
+            jsonb_build_object(
+                'type',
+                a.type,
+                'outcome',
+                a.outcome,
+                'location',
+                a.location,
+                'assigned_to_id',
+                a.assigned_to_id
+            )
+        FROM
+            appointments a
+        WHERE
+            a.contact_id = @contact_id
+            AND a.deleted_at IS NULL
+        UNION ALL
+        SELECT
+            d.id,
+            'deal',
+            d.created_at,
+            d.title,
+            coalesce(d.description, ''),
+            jsonb_build_object(
+                'price',
+                d.price,
+                'stage_id',
+                d.stage_id,
+                'stage',
+                s.name,
+                'closing_date',
+                d.closing_date
+            )
+        FROM
+            deals d
+            LEFT JOIN stages s ON s.id = d.stage_id
+        WHERE
+            d.contact_id = @contact_id
+            AND d.deleted_at IS NULL
+        UNION ALL
+        SELECT
+            em.id,
+            'email',
+            em.created_at,
+            em.email_address,
+            '',
+            jsonb_build_object('type', em.type, 'is_primary', em.is_primary)
+        FROM
+            emails em
+        WHERE
+            em.contact_id = @contact_id
+        UNION ALL
+        SELECT
+            ce.id,
+            ce.event_type,
+            ce.created_at,
+            CASE
+                ce.event_type
+                WHEN 'stage_change' THEN coalesce(ce.details ->> 'to_stage', 'No stage')
+                ELSE coalesce(ce.details ->> 'tag', '')
+            END,
+            '',
+            ce.details || jsonb_build_object('deal_id', ce.deal_id, 'tag_id', ce.tag_id)
+        FROM
+            contact_events ce
+        WHERE
+            ce.contact_id = @contact_id
+    ) e
+WHERE
+    (
+        cardinality(@event_types::text []) = 0
+        OR e.event_type = ANY(@event_types::text [])
+    )
+    AND e.occurred_at >= @from_at::timestamptz
+    AND e.occurred_at < @until_at::timestamptz
+    AND (e.occurred_at, e.id) < (@cursor_at::timestamptz, @cursor_id::uuid)
+ORDER BY
+    e.occurred_at DESC,
+    e.id DESC
+LIMIT
+    @page_size;
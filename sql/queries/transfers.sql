@@ -0,0 +1,159 @@
+-- name: ListTransferableContacts :many
+-- The contacts a transfer moves, locked until it commits: those listed, the
+-- members of a smart list or every contact of a user. Only contacts the
+-- caller owns or administers as an organization admin are returned.
+SELECT
+    c.id,
+    c.owner_id,
+    c.organization_id
+FROM
+    contacts c
+WHERE
+    (
+        c.id = ANY(sqlc.narg(contact_ids)::uuid[])
+        OR c.id IN (
+            SELECT
+                m.contact_id
+            FROM
+                smart_list_members m
+            WHERE
+                m.smart_list_id = sqlc.narg(smart_list_id)
+        )
+        OR c.owner_id = sqlc.narg(from_user_id)
+    )
+    AND c.deleted_at IS NULL
+    AND (
+        c.owner_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member admin
+            WHERE
+                admin."userId" = @user_id
+                AND admin.role IN ('owner', 'admin')
+                AND (
+                    admin."organizationId" = c.organization_id
+                    OR (
+                        c.organization_id IS NULL
+                        AND EXISTS (
+                            SELECT
+                                1
+                            FROM
+                                member owner
+                            WHERE
+                                owner."organizationId" = admin."organizationId"
+                                AND owner."userId" = c.owner_id
+                        )
+                    )
+                )
+        )
+    )
+ORDER BY
+    c.id FOR
+UPDATE
+    OF c;
+
+-- name: SharesOrganization :one
+-- Whether two users are members of a common organization
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            member a
+            JOIN member b ON b."organizationId" = a."organizationId"
+        WHERE
+            a."userId" = @user_id
+            AND b."userId" = @other_user_id
+    );
+
+-- name: TransferOpenTasks :execrows
+-- Moves the pending tasks the contacts' current owners were assigned
+UPDATE
+    tasks t
+SET
+    assigned_to_id = @to_user_id,
+    updated_at = CURRENT_TIMESTAMP
+FROM
+    contacts c
+WHERE
+    t.contact_id = c.id
+    AND c.id = ANY(@contact_ids::uuid[])
+    AND t.assigned_to_id = c.owner_id
+    AND t.status = 'pending';
+
+-- name: TransferFutureAppointments :execrows
+-- Moves the upcoming appointments the contacts' current owners were assigned
+UPDATE
+    appointments a
+SET
+    assigned_to_id = @to_user_id,
+    updated_at = CURRENT_TIMESTAMP
+FROM
+    contacts c
+WHERE
+    a.contact_id = c.id
+    AND c.id = ANY(@contact_ids::uuid[])
+    AND a.assigned_to_id = c.owner_id
+    AND a.scheduled_at > CURRENT_TIMESTAMP;
+
+-- name: TransferOpenDeals :execrows
+-- Moves the deals the contacts' current owners were assigned that haven't
+-- closed
+UPDATE
+    deals d
+SET
+    assigned_to_id = @to_user_id,
+    updated_at = CURRENT_TIMESTAMP
+FROM
+    contacts c
+WHERE
+    d.contact_id = c.id
+    AND c.id = ANY(@contact_ids::uuid[])
+    AND d.assigned_to_id = c.owner_id
+    AND d.closed_date IS NULL;
+
+-- name: AddPreviousOwnerCollaborators :exec
+-- Keeps the contacts' current owners on them as collaborators
+INSERT INTO
+    collaborators (contact_id, user_id, role)
+SELECT
+    c.id,
+    c.owner_id,
+    @role::text
+FROM
+    contacts c
+WHERE
+    c.id = ANY(@contact_ids::uuid[])
+    AND c.owner_id IS NOT NULL ON CONFLICT (contact_id, user_id) DO
+UPDATE
+SET
+    role = EXCLUDED.role;
+
+-- name: RemoveOwnerCollaborator :exec
+-- The new owner no longer needs to collaborate on the contacts
+DELETE FROM
+    collaborators
+WHERE
+    user_id = @user_id
+    AND contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: CloseTransferredLeadAssignments :exec
+-- Transferred leads are no longer waiting to be claimed or reassigned
+UPDATE
+    lead_assignments
+SET
+    status = 'expired'
+WHERE
+    contact_id = ANY(@contact_ids::uuid[])
+    AND status = 'pending';
+
+-- name: TransferContacts :exec
+UPDATE
+    contacts
+SET
+    owner_id = @to_user_id,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = ANY(@contact_ids::uuid[]);
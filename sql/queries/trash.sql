@@ -0,0 +1,152 @@
+-- name: ListTrash :many
+-- The trashed records the user owns, is assigned or whose contact they own,
+-- and those of the organizations they administer, most recently deleted
+-- first
+SELECT
+    *
+FROM
+    trash_items t
+WHERE
+    (
+        t.owner_id = @user_id
+        OR t.contact_owner_id = @user_id
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                member m
+            WHERE
+                m."organizationId" = t.organization_id
+                AND m."userId" = @user_id
+                AND m.role IN ('owner', 'admin')
+        )
+    )
+    AND (
+        sqlc.narg(kind)::text IS NULL
+        OR t.kind = sqlc.narg(kind)
+    )
+ORDER BY
+    t.deleted_at DESC
+LIMIT
+    @row_limit OFFSET @row_offset;
+
+-- name: GetTrashItem :one
+-- A trashed record, with who it belongs to so the caller's permission to
+-- restore or purge it can be checked
+SELECT
+    *
+FROM
+    trash_items t
+WHERE
+    t.kind = @kind
+    AND t.id = @id;
+
+-- name: RestoreContact :exec
+UPDATE
+    contacts
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: RestoreDeal :exec
+UPDATE
+    deals
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: RestoreTask :exec
+UPDATE
+    tasks
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: RestoreAppointment :exec
+UPDATE
+    appointments
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: RestoreStage :exec
+UPDATE
+    stages
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeContact :exec
+DELETE FROM
+    contacts
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeal :exec
+DELETE FROM
+    deals
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeTask :exec
+DELETE FROM
+    tasks
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeAppointment :exec
+DELETE FROM
+    appointments
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeStage :exec
+DELETE FROM
+    stages
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL;
+
+-- name: PurgeExpiredTasks :execrows
+DELETE FROM
+    tasks
+WHERE
+    deleted_at < @trashed_before;
+
+-- name: PurgeExpiredAppointments :execrows
+DELETE FROM
+    appointments
+WHERE
+    deleted_at < @trashed_before;
+
+-- name: PurgeExpiredDeals :execrows
+DELETE FROM
+    deals
+WHERE
+    deleted_at < @trashed_before;
+
+-- name: PurgeExpiredStages :execrows
+DELETE FROM
+    stages
+WHERE
+    deleted_at < @trashed_before;
+
+-- name: PurgeExpiredContacts :execrows
+DELETE FROM
+    contacts
+WHERE
+    deleted_at < @trashed_before;
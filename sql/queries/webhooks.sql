@@ -1,7 +1,15 @@
 -- name: LandingPageEmails :one
 INSERT INTO
-    contacts (first_name, last_name, source, owner_id)
+    contacts (
+        first_name,
+        last_name,
+        source,
+        owner_id,
+        organization_id,
+        city,
+        zip_code
+    )
 VALUES
-    ($1, $2, $3, $4)
+    ($1, $2, $3, $4, $5, $6, $7)
 RETURNING
     *;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    format VARCHAR(10) NOT NULL,
+    file_name VARCHAR(255),
+    mapping JSONB,
+    default_source VARCHAR(100),
+    payload BYTEA NOT NULL,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    imported_rows INTEGER NOT NULL DEFAULT 0,
+    skipped_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    CONSTRAINT import_jobs_status_check CHECK (
+        status IN ('pending', 'running', 'completed', 'failed', 'cancelled')
+    )
+);
+
+CREATE INDEX idx_import_jobs_pending ON import_jobs (created_at)
+WHERE
+    status = 'pending';
+
+CREATE INDEX idx_import_jobs_user_id ON import_jobs (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE import_jobs;
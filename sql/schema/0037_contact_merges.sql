@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE contact_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    surviving_contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    -- The merged contact is deleted, so its row is kept as a snapshot instead of a foreign key
+    merged_contact_id UUID NOT NULL,
+    merged_contact JSONB NOT NULL,
+    moved_records JSONB NOT NULL DEFAULT '{}',
+    merged_by UUID REFERENCES users(id) ON DELETE
+    SET
+        NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_contact_merges_surviving_contact_id ON contact_merges (surviving_contact_id);
+
+-- +goose Down
+DROP TABLE contact_merges;
@@ -0,0 +1,95 @@
+-- +goose Up
+-- Convert the flat {"key": "value"} criteria into the filter tree understood
+-- by internal/smartlist. Every old key becomes one rule of a top-level "and"
+-- group, keeping the old matching behavior.
+UPDATE
+    smart_lists s
+SET
+    filter_criteria = jsonb_build_object(
+        'match',
+        'and',
+        'rules',
+        coalesce(
+            (
+                SELECT
+                    jsonb_agg(
+                        CASE
+                            WHEN kv.key IN (
+                                'first_name',
+                                'last_name',
+                                'source',
+                                'address',
+                                'city',
+                                'state',
+                                'lender'
+                            ) THEN jsonb_build_object('field', kv.key, 'op', 'contains', 'value', kv.value)
+                            WHEN kv.key IN (
+                                'status',
+                                'zip_code',
+                                'price_range',
+                                'timeframe',
+                                'birthdate',
+                                'owner_id'
+                            ) THEN jsonb_build_object('field', kv.key, 'op', 'eq', 'value', kv.value)
+                            WHEN kv.key = 'tag_id' THEN jsonb_build_object('field', 'tag', 'op', 'has_tag', 'value', kv.value)
+                            WHEN kv.key = 'last_contacted_days' THEN jsonb_build_object(
+                                'field',
+                                'last_contacted_at',
+                                'op',
+                                'before',
+                                'value',
+                                jsonb_build_object('ago', kv.value::int, 'unit', 'days')
+                            )
+                        END
+                    ) FILTER (
+                        WHERE
+                            kv.key IN (
+                                'first_name',
+                                'last_name',
+                                'source',
+                                'address',
+                                'city',
+                                'state',
+                                'lender',
+                                'status',
+                                'zip_code',
+                                'price_range',
+                                'timeframe',
+                                'birthdate',
+                                'owner_id',
+                                'tag_id',
+                                'last_contacted_days'
+                            )
+                            AND kv.value <> ''
+                            AND (
+                                kv.key <> 'last_contacted_days'
+                                OR kv.value ~ '^[0-9]+$'
+                            )
+                    )
+                FROM
+                    jsonb_each_text(s.filter_criteria) kv
+            ),
+            '[]'::jsonb
+        )
+    )
+WHERE
+    s.filter_criteria IS NULL
+    OR NOT (
+        s.filter_criteria ? 'match'
+        OR s.filter_criteria ? 'field'
+    );
+
+ALTER TABLE
+    smart_lists
+ALTER COLUMN
+    filter_criteria
+SET
+    DEFAULT '{"match": "and", "rules": []}'::jsonb;
+
+-- +goose Down
+ALTER TABLE
+    smart_lists
+ALTER COLUMN
+    filter_criteria
+SET
+    DEFAULT '{}'::jsonb;
@@ -0,0 +1,95 @@
+-- +goose Up
+CREATE TABLE smart_list_members (
+    "smart_list_id" UUID NOT NULL REFERENCES smart_lists(id) ON DELETE CASCADE,
+    "contact_id" UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    "added_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (smart_list_id, contact_id)
+);
+
+CREATE INDEX idx_smart_list_members_contact_id ON smart_list_members(contact_id);
+
+CREATE TABLE smart_list_subscriptions (
+    "smart_list_id" UUID NOT NULL REFERENCES smart_lists(id) ON DELETE CASCADE,
+    "user_id" UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (smart_list_id, user_id)
+);
+
+-- Contacts whose smart list membership has to be re-evaluated. Rows are
+-- queued by the triggers below and drained by the smart list worker.
+CREATE TABLE smart_list_dirty_contacts (
+    "contact_id" UUID PRIMARY KEY,
+    "queued_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lists are also re-evaluated periodically so rules relative to the current
+-- time ("not contacted in 7 days") pick up contacts that haven't changed.
+ALTER TABLE
+    smart_lists
+ADD
+    COLUMN members_refreshed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION queue_smart_list_refresh() RETURNS TRIGGER AS $$
+DECLARE
+    id_column TEXT := TG_ARGV[0];
+    old_id UUID;
+    new_id UUID;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_id := (to_jsonb(OLD) ->> id_column)::UUID;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_id := (to_jsonb(NEW) ->> id_column)::UUID;
+    END IF;
+
+    INSERT INTO
+        smart_list_dirty_contacts (contact_id)
+    SELECT
+        DISTINCT v.id
+    FROM
+        (
+            VALUES
+                (old_id),
+                (new_id)
+        ) v(id)
+    WHERE
+        v.id IS NOT NULL
+    ON CONFLICT (contact_id) DO NOTHING;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER contacts_smart_list_refresh
+AFTER INSERT OR UPDATE OR DELETE ON contacts
+FOR EACH ROW EXECUTE FUNCTION queue_smart_list_refresh('id');
+
+CREATE TRIGGER contact_tags_smart_list_refresh
+AFTER INSERT OR UPDATE OR DELETE ON contact_tags
+FOR EACH ROW EXECUTE FUNCTION queue_smart_list_refresh('contact_id');
+
+CREATE TRIGGER deals_smart_list_refresh
+AFTER INSERT OR UPDATE OR DELETE ON deals
+FOR EACH ROW EXECUTE FUNCTION queue_smart_list_refresh('contact_id');
+
+CREATE TRIGGER contact_logs_smart_list_refresh
+AFTER INSERT OR UPDATE OR DELETE ON contact_logs
+FOR EACH ROW EXECUTE FUNCTION queue_smart_list_refresh('contact_id');
+
+CREATE TRIGGER collaborators_smart_list_refresh
+AFTER INSERT OR UPDATE OR DELETE ON collaborators
+FOR EACH ROW EXECUTE FUNCTION queue_smart_list_refresh('contact_id');
+
+-- +goose Down
+DROP TRIGGER IF EXISTS collaborators_smart_list_refresh ON collaborators;
+DROP TRIGGER IF EXISTS contact_logs_smart_list_refresh ON contact_logs;
+DROP TRIGGER IF EXISTS deals_smart_list_refresh ON deals;
+DROP TRIGGER IF EXISTS contact_tags_smart_list_refresh ON contact_tags;
+DROP TRIGGER IF EXISTS contacts_smart_list_refresh ON contacts;
+DROP FUNCTION IF EXISTS queue_smart_list_refresh();
+ALTER TABLE smart_lists DROP COLUMN IF EXISTS members_refreshed_at;
+DROP TABLE IF EXISTS smart_list_dirty_contacts;
+DROP TABLE IF EXISTS smart_list_subscriptions;
+DROP TABLE IF EXISTS smart_list_members;
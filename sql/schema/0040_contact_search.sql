@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The expressions below must match the ones in the SearchContacts query for
+-- the indexes to be used.
+CREATE INDEX idx_contacts_name_fts ON contacts USING gin (
+    to_tsvector('simple', first_name || ' ' || last_name)
+);
+
+CREATE INDEX idx_contacts_name_trgm ON contacts USING gin (
+    (first_name || ' ' || last_name) gin_trgm_ops
+);
+
+CREATE INDEX idx_contacts_address_trgm ON contacts USING gin (
+    (
+        coalesce(address, '') || ' ' || coalesce(city, '') || ' ' || coalesce(state, '') || ' ' || coalesce(zip_code, '')
+    ) gin_trgm_ops
+);
+
+CREATE INDEX idx_emails_email_address_trgm ON emails USING gin (email_address gin_trgm_ops);
+
+CREATE INDEX idx_phone_numbers_digits_trgm ON phone_numbers USING gin (
+    (regexp_replace(phone_number, '\D', '', 'g')) gin_trgm_ops
+);
+
+CREATE INDEX idx_contact_notes_fts ON contact_notes USING gin (to_tsvector('english', note));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_contact_notes_fts;
+DROP INDEX IF EXISTS idx_phone_numbers_digits_trgm;
+DROP INDEX IF EXISTS idx_emails_email_address_trgm;
+DROP INDEX IF EXISTS idx_contacts_address_trgm;
+DROP INDEX IF EXISTS idx_contacts_name_trgm;
+DROP INDEX IF EXISTS idx_contacts_name_fts;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- International display forms ("+353 1 234 5678") can be longer than the
+-- 20 characters allowed so far.
+ALTER TABLE phone_numbers
+ALTER COLUMN phone_number TYPE VARCHAR(32);
+
+ALTER TABLE phone_numbers
+ADD COLUMN e164 VARCHAR(16);
+
+-- Backfill numbers that are unambiguously North American. Anything else is
+-- left for the next edit to normalize.
+WITH parsed AS (
+    SELECT
+        id,
+        right(regexp_replace(phone_number, '\D', '', 'g'), 10) AS national
+    FROM
+        phone_numbers
+    WHERE
+        regexp_replace(phone_number, '\D', '', 'g') ~ '^1?[2-9][0-9]{9}$'
+        AND phone_number !~ '[A-Za-z#]'
+        AND (
+            phone_number NOT LIKE '+%'
+            OR phone_number LIKE '+1%'
+        )
+)
+UPDATE
+    phone_numbers p
+SET
+    e164 = '+1' || parsed.national,
+    phone_number = '(' || substr(parsed.national, 1, 3) || ') ' || substr(parsed.national, 4, 3) || '-' || substr(parsed.national, 7, 4)
+FROM
+    parsed
+WHERE
+    p.id = parsed.id;
+
+CREATE INDEX idx_phone_numbers_e164 ON phone_numbers(e164);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_phone_numbers_e164;
+
+ALTER TABLE phone_numbers
+DROP COLUMN IF EXISTS e164;
+
+ALTER TABLE phone_numbers
+ALTER COLUMN phone_number TYPE VARCHAR(20) USING left(phone_number, 20);
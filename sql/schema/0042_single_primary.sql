@@ -0,0 +1,121 @@
+-- +goose Up
+-- Keep only the most recently updated primary per contact.
+WITH ranked AS (
+    SELECT
+        id,
+        row_number() OVER (
+            PARTITION BY contact_id
+            ORDER BY
+                updated_at DESC NULLS LAST,
+                created_at DESC NULLS LAST,
+                id
+        ) AS n
+    FROM
+        emails
+    WHERE
+        is_primary
+)
+UPDATE
+    emails e
+SET
+    is_primary = FALSE
+FROM
+    ranked
+WHERE
+    e.id = ranked.id
+    AND ranked.n > 1;
+
+WITH ranked AS (
+    SELECT
+        id,
+        row_number() OVER (
+            PARTITION BY contact_id
+            ORDER BY
+                updated_at DESC NULLS LAST,
+                created_at DESC NULLS LAST,
+                id
+        ) AS n
+    FROM
+        phone_numbers
+    WHERE
+        is_primary
+)
+UPDATE
+    phone_numbers p
+SET
+    is_primary = FALSE
+FROM
+    ranked
+WHERE
+    p.id = ranked.id
+    AND ranked.n > 1;
+
+-- Contacts that have emails or phone numbers but no primary get their oldest
+-- one promoted, matching what deleting a primary does from now on.
+UPDATE
+    emails e
+SET
+    is_primary = TRUE
+WHERE
+    e.id = (
+        SELECT
+            o.id
+        FROM
+            emails o
+        WHERE
+            o.contact_id = e.contact_id
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            emails p
+        WHERE
+            p.contact_id = e.contact_id
+            AND p.is_primary
+    );
+
+UPDATE
+    phone_numbers ph
+SET
+    is_primary = TRUE
+WHERE
+    ph.id = (
+        SELECT
+            o.id
+        FROM
+            phone_numbers o
+        WHERE
+            o.contact_id = ph.contact_id
+        ORDER BY
+            o.created_at,
+            o.id
+        LIMIT
+            1
+    )
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            phone_numbers p
+        WHERE
+            p.contact_id = ph.contact_id
+            AND p.is_primary
+    );
+
+CREATE UNIQUE INDEX idx_emails_one_primary ON emails(contact_id)
+WHERE
+    is_primary;
+
+CREATE UNIQUE INDEX idx_phone_numbers_one_primary ON phone_numbers(contact_id)
+WHERE
+    is_primary;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_phone_numbers_one_primary;
+DROP INDEX IF EXISTS idx_emails_one_primary;
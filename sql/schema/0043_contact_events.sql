@@ -0,0 +1,97 @@
+-- +goose Up
+-- Changes to a contact that leave no row of their own behind. Notes, logs,
+-- tasks, appointments, deals and emails are read from their own tables by the
+-- timeline; stage and tag changes are recorded here by the triggers below.
+CREATE TABLE contact_events (
+    "id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    "contact_id" UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    "event_type" VARCHAR(50) NOT NULL,
+    "deal_id" UUID REFERENCES deals(id) ON DELETE SET NULL,
+    "tag_id" UUID REFERENCES tags(id) ON DELETE SET NULL,
+    "details" JSONB NOT NULL DEFAULT '{}',
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_contact_events_contact_id_created_at ON contact_events(contact_id, created_at DESC, id DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION record_deal_stage_change() RETURNS TRIGGER AS $$
+DECLARE
+    old_stage UUID;
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        old_stage := OLD.stage_id;
+    END IF;
+
+    IF NEW.contact_id IS NULL OR NEW.stage_id IS NOT DISTINCT FROM old_stage THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO
+        contact_events (contact_id, event_type, deal_id, details)
+    SELECT
+        NEW.contact_id,
+        'stage_change',
+        NEW.id,
+        jsonb_build_object(
+            'deal_title', NEW.title,
+            'from_stage_id', old_stage,
+            'from_stage', (SELECT name FROM stages WHERE id = old_stage),
+            'to_stage_id', NEW.stage_id,
+            'to_stage', (SELECT name FROM stages WHERE id = NEW.stage_id)
+        );
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION record_contact_tag_change() RETURNS TRIGGER AS $$
+DECLARE
+    row_contact UUID;
+    row_tag UUID;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        row_contact := NEW.contact_id;
+        row_tag := NEW.tag_id;
+    ELSE
+        row_contact := OLD.contact_id;
+        row_tag := OLD.tag_id;
+    END IF;
+
+    -- Rows removed because the contact or the tag itself was deleted aren't
+    -- tag changes
+    INSERT INTO
+        contact_events (contact_id, event_type, tag_id, details)
+    SELECT
+        c.id,
+        CASE WHEN TG_OP = 'INSERT' THEN 'tag_added' ELSE 'tag_removed' END,
+        t.id,
+        jsonb_build_object('tag', t.name)
+    FROM
+        contacts c,
+        tags t
+    WHERE
+        c.id = row_contact
+        AND t.id = row_tag;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER deals_record_stage_change
+AFTER INSERT OR UPDATE OF stage_id ON deals
+FOR EACH ROW EXECUTE FUNCTION record_deal_stage_change();
+
+CREATE TRIGGER contact_tags_record_change
+AFTER INSERT OR DELETE ON contact_tags
+FOR EACH ROW EXECUTE FUNCTION record_contact_tag_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS contact_tags_record_change ON contact_tags;
+DROP TRIGGER IF EXISTS deals_record_stage_change ON deals;
+DROP FUNCTION IF EXISTS record_contact_tag_change();
+DROP FUNCTION IF EXISTS record_deal_stage_change();
+DROP TABLE IF EXISTS contact_events;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Roles used to be free text. Anything that isn't a known role becomes
+-- editor, which matches what collaborators could do before roles were
+-- enforced.
+UPDATE collaborators
+SET role = lower(trim(role));
+
+UPDATE collaborators
+SET role = 'editor'
+WHERE role NOT IN ('viewer', 'editor', 'manager');
+
+-- Keep one row per user and contact, the one with the highest role
+DELETE FROM collaborators c
+USING collaborators d
+WHERE c.contact_id = d.contact_id
+    AND c.user_id = d.user_id
+    AND c.id <> d.id
+    AND (
+        array_position(ARRAY['viewer', 'editor', 'manager'], c.role::text),
+        c.created_at,
+        c.id
+    ) < (
+        array_position(ARRAY['viewer', 'editor', 'manager'], d.role::text),
+        d.created_at,
+        d.id
+    );
+
+ALTER TABLE collaborators
+    ALTER COLUMN role TYPE VARCHAR(20),
+    ADD CONSTRAINT collaborators_role_check CHECK (role IN ('viewer', 'editor', 'manager')),
+    ADD CONSTRAINT collaborators_contact_user_key UNIQUE (contact_id, user_id);
+
+-- +goose Down
+ALTER TABLE collaborators
+    DROP CONSTRAINT collaborators_contact_user_key,
+    DROP CONSTRAINT collaborators_role_check,
+    ALTER COLUMN role TYPE VARCHAR(100);
@@ -0,0 +1,88 @@
+-- +goose Up
+-- Stages, tags and smart lists with an organization_id are shared by the
+-- whole organization and managed by its admins. Those without one stay
+-- personal to their owner.
+ALTER TABLE stages
+ADD COLUMN organization_id UUID REFERENCES organization(id) ON DELETE CASCADE;
+
+ALTER TABLE tags
+ADD COLUMN organization_id UUID REFERENCES organization(id) ON DELETE CASCADE;
+
+ALTER TABLE smart_lists
+ADD COLUMN organization_id UUID REFERENCES organization(id) ON DELETE CASCADE;
+
+-- Contacts remember the organization they were created in. Their
+-- organization's admins can manage them.
+ALTER TABLE contacts
+ADD COLUMN organization_id UUID REFERENCES organization(id) ON DELETE SET NULL;
+
+-- Imports run in the background, so the job carries the organization its
+-- contacts go into
+ALTER TABLE import_jobs
+ADD COLUMN organization_id UUID REFERENCES organization(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_stages_organization_id ON stages(organization_id);
+
+CREATE INDEX idx_tags_organization_id ON tags(organization_id);
+
+CREATE INDEX idx_smart_lists_organization_id ON smart_lists(organization_id);
+
+CREATE INDEX idx_contacts_organization_id ON contacts(organization_id);
+
+-- Tag names are unique per owner among personal tags and per organization
+-- among shared ones, so an agent can keep a personal tag with the same name
+-- as their brokerage's
+ALTER TABLE tags DROP CONSTRAINT unique_user_tag;
+
+CREATE UNIQUE INDEX unique_user_tag ON tags(user_id, name)
+WHERE
+    organization_id IS NULL;
+
+CREATE UNIQUE INDEX unique_organization_tag ON tags(organization_id, name)
+WHERE
+    organization_id IS NOT NULL;
+
+-- Existing contacts belong to their owner's organization when the owner is
+-- in exactly one
+UPDATE contacts c
+SET organization_id = m."organizationId"
+FROM member m
+WHERE m."userId" = c.owner_id
+    AND NOT EXISTS (
+        SELECT
+            1
+        FROM
+            member other
+        WHERE
+            other."userId" = m."userId"
+            AND other."organizationId" <> m."organizationId"
+    );
+
+-- +goose Down
+DROP INDEX IF EXISTS unique_organization_tag;
+
+DROP INDEX IF EXISTS unique_user_tag;
+
+DELETE FROM tags
+WHERE organization_id IS NOT NULL;
+
+ALTER TABLE tags
+ADD CONSTRAINT unique_user_tag UNIQUE (user_id, name);
+
+DROP INDEX IF EXISTS idx_contacts_organization_id;
+
+DROP INDEX IF EXISTS idx_smart_lists_organization_id;
+
+DROP INDEX IF EXISTS idx_tags_organization_id;
+
+DROP INDEX IF EXISTS idx_stages_organization_id;
+
+ALTER TABLE import_jobs DROP COLUMN organization_id;
+
+ALTER TABLE contacts DROP COLUMN organization_id;
+
+ALTER TABLE smart_lists DROP COLUMN organization_id;
+
+ALTER TABLE tags DROP COLUMN organization_id;
+
+ALTER TABLE stages DROP COLUMN organization_id;
@@ -0,0 +1,75 @@
+-- +goose Up
+-- Rules an organization routes incoming leads with, tried in priority order.
+-- Empty condition arrays match every lead.
+CREATE TABLE routing_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    strategy VARCHAR(20) NOT NULL CHECK (
+        strategy IN ('round_robin', 'weighted', 'first_to_claim')
+    ),
+    zip_codes TEXT [] NOT NULL DEFAULT '{}',
+    cities TEXT [] NOT NULL DEFAULT '{}',
+    sources TEXT [] NOT NULL DEFAULT '{}',
+    -- Minutes an agent has to claim a lead before it moves on, NULL to
+    -- never reassign
+    claim_timeout_minutes INTEGER CHECK (claim_timeout_minutes > 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_routing_rules_organization_id ON routing_rules(organization_id, priority);
+
+-- The agents a rule routes to and what it has given them so far
+CREATE TABLE routing_rule_agents (
+    rule_id UUID NOT NULL REFERENCES routing_rules(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    capacity INTEGER NOT NULL DEFAULT 1 CHECK (capacity > 0),
+    assigned_count BIGINT NOT NULL DEFAULT 0,
+    last_assigned_at TIMESTAMPTZ,
+    PRIMARY KEY (rule_id, user_id)
+);
+
+-- Every time a lead is routed. user_id is NULL while a first to claim lead
+-- is offered to all of the rule's agents.
+CREATE TABLE lead_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES routing_rules(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (
+        status IN ('pending', 'claimed', 'expired')
+    ),
+    expires_at TIMESTAMPTZ,
+    claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_lead_assignments_contact_id ON lead_assignments(contact_id);
+
+CREATE INDEX idx_lead_assignments_expires_at ON lead_assignments(expires_at)
+WHERE
+    status = 'pending';
+
+-- A lead waits on one assignment at a time
+CREATE UNIQUE INDEX one_pending_lead_assignment ON lead_assignments(contact_id)
+WHERE
+    status = 'pending';
+
+-- Imports only route their contacts when asked to, so agents can still
+-- import their own sphere
+ALTER TABLE import_jobs
+ADD COLUMN route_leads BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE import_jobs DROP COLUMN route_leads;
+
+DROP TABLE IF EXISTS lead_assignments;
+
+DROP TABLE IF EXISTS routing_rule_agents;
+
+DROP TABLE IF EXISTS routing_rules;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Deleting a user used to delete every contact they owned. Their contacts
+-- are now kept without an owner so an organization admin can transfer them.
+ALTER TABLE contacts
+    DROP CONSTRAINT contacts_owner_id_fkey,
+    ADD CONSTRAINT contacts_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE contacts
+    DROP CONSTRAINT contacts_owner_id_fkey,
+    ADD CONSTRAINT contacts_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE;
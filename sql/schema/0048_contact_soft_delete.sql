@@ -0,0 +1,14 @@
+-- +goose Up
+-- Deleted contacts are kept, with everything attached to them, until they
+-- are purged. Queries listing contacts skip those with deleted_at set.
+ALTER TABLE contacts
+ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+CREATE INDEX idx_contacts_deleted_at ON contacts(deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_contacts_deleted_at;
+
+ALTER TABLE contacts DROP COLUMN deleted_at;
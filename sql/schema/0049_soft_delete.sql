@@ -0,0 +1,127 @@
+-- +goose Up
+-- Deals, tasks, appointments and stages are moved to the trash like contacts
+-- instead of being deleted, so the records cascading from them survive until
+-- the trash is purged.
+ALTER TABLE deals
+ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+ALTER TABLE tasks
+ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+ALTER TABLE appointments
+ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+ALTER TABLE stages
+ADD COLUMN deleted_at TIMESTAMPTZ DEFAULT NULL;
+
+CREATE INDEX idx_deals_deleted_at ON deals(deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+CREATE INDEX idx_appointments_deleted_at ON appointments(deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+CREATE INDEX idx_stages_deleted_at ON stages(deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+-- Every trashed record with who may restore or purge it: owner_id is the
+-- record's owner or assignee, contact_owner_id the owner of its contact and
+-- organization_id the organization whose admins manage it.
+CREATE VIEW trash_items AS
+SELECT
+    'contact'::text AS kind,
+    c.id,
+    c.first_name || ' ' || c.last_name AS title,
+    c.id AS contact_id,
+    c.owner_id,
+    c.owner_id AS contact_owner_id,
+    c.organization_id,
+    c.deleted_at
+FROM
+    contacts c
+WHERE
+    c.deleted_at IS NOT NULL
+UNION ALL
+SELECT
+    'deal',
+    d.id,
+    d.title,
+    d.contact_id,
+    d.assigned_to_id,
+    c.owner_id,
+    c.organization_id,
+    d.deleted_at
+FROM
+    deals d
+    LEFT JOIN contacts c ON c.id = d.contact_id
+WHERE
+    d.deleted_at IS NOT NULL
+UNION ALL
+SELECT
+    'task',
+    t.id,
+    t.title,
+    t.contact_id,
+    t.assigned_to_id,
+    c.owner_id,
+    c.organization_id,
+    t.deleted_at
+FROM
+    tasks t
+    LEFT JOIN contacts c ON c.id = t.contact_id
+WHERE
+    t.deleted_at IS NOT NULL
+UNION ALL
+SELECT
+    'appointment',
+    a.id,
+    a.title,
+    a.contact_id,
+    a.assigned_to_id,
+    c.owner_id,
+    c.organization_id,
+    a.deleted_at
+FROM
+    appointments a
+    LEFT JOIN contacts c ON c.id = a.contact_id
+WHERE
+    a.deleted_at IS NOT NULL
+UNION ALL
+SELECT
+    'stage',
+    s.id,
+    s.name,
+    NULL,
+    s.owner_id,
+    NULL,
+    s.organization_id,
+    s.deleted_at
+FROM
+    stages s
+WHERE
+    s.deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP VIEW IF EXISTS trash_items;
+
+DROP INDEX IF EXISTS idx_stages_deleted_at;
+
+DROP INDEX IF EXISTS idx_appointments_deleted_at;
+
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+
+DROP INDEX IF EXISTS idx_deals_deleted_at;
+
+ALTER TABLE stages DROP COLUMN deleted_at;
+
+ALTER TABLE appointments DROP COLUMN deleted_at;
+
+ALTER TABLE tasks DROP COLUMN deleted_at;
+
+ALTER TABLE deals DROP COLUMN deleted_at;
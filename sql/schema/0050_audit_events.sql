@@ -0,0 +1,26 @@
+-- +goose Up
+-- One row per change made through the API: who made it, how they signed in,
+-- the record it touched and the fields that changed, as
+-- {"field": {"before": ..., "after": ...}}.
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    auth_method VARCHAR(20) NOT NULL CHECK (auth_method IN ('session', 'api_key')),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (
+        action IN ('create', 'update', 'delete', 'restore', 'purge')
+    ),
+    changes JSONB NOT NULL DEFAULT '{}',
+    request_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_entity ON audit_events(entity_type, entity_id, created_at DESC);
+
+CREATE INDEX idx_audit_events_actor ON audit_events(actor_id, created_at DESC);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Changes to the fields agents follow over time, one row per field changed:
+-- a contact's status, source, timeframe, price range and owner, and a deal's
+-- price, stage, closing date and commission. Values are kept as JSON the
+-- way the audit log snapshots them.
+CREATE TABLE field_history (
+    "id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    "contact_id" UUID REFERENCES contacts(id) ON DELETE CASCADE,
+    "deal_id" UUID REFERENCES deals(id) ON DELETE CASCADE,
+    "field" VARCHAR(50) NOT NULL,
+    "old_value" JSONB NOT NULL DEFAULT 'null',
+    "new_value" JSONB NOT NULL DEFAULT 'null',
+    "changed_by" UUID REFERENCES users(id) ON DELETE SET NULL,
+    "changed_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(contact_id, deal_id) = 1)
+);
+
+CREATE INDEX idx_field_history_contact_id ON field_history(contact_id, changed_at DESC)
+WHERE
+    contact_id IS NOT NULL;
+
+CREATE INDEX idx_field_history_deal_id ON field_history(deal_id, changed_at DESC)
+WHERE
+    deal_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS field_history;
@@ -0,0 +1,38 @@
+-- +goose Up
+-- A recurring task. The series holds the RRULE, anchored at dtstart, and the
+-- fields every occurrence starts with. Occurrences are ordinary tasks
+-- created one at a time: the next is added when the pending one is
+-- completed, cancelled or deleted.
+CREATE TABLE task_series (
+    "id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    "rrule" TEXT NOT NULL,
+    "dtstart" TIMESTAMPTZ NOT NULL,
+    "contact_id" UUID REFERENCES contacts(id) ON DELETE CASCADE,
+    "assigned_to_id" UUID REFERENCES users(id) ON DELETE SET NULL,
+    "title" VARCHAR(255) NOT NULL,
+    "type" task_type DEFAULT 'follow-up',
+    "priority" task_priority DEFAULT 'normal',
+    "note" TEXT DEFAULT NULL,
+    "cancelled_at" TIMESTAMPTZ DEFAULT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- occurrence_date is the time the rule scheduled the occurrence for. It
+-- stays put when the occurrence alone is moved to another date.
+ALTER TABLE tasks
+ADD COLUMN series_id UUID REFERENCES task_series(id) ON DELETE SET NULL,
+ADD COLUMN occurrence_date TIMESTAMPTZ DEFAULT NULL;
+
+CREATE UNIQUE INDEX idx_tasks_series_occurrence ON tasks(series_id, occurrence_date)
+WHERE
+    series_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tasks_series_occurrence;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS occurrence_date,
+DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS task_series;
@@ -0,0 +1,74 @@
+-- +goose Up
+-- Templates of tasks applied to a contact together, such as the calls,
+-- texts and emails every new buyer lead gets over its first weeks. Plans are
+-- personal, or shared with an organization when organization_id is set.
+CREATE TABLE action_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    organization_id UUID REFERENCES organization(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT DEFAULT NULL,
+    -- Applied automatically to contacts this tag is assigned to
+    trigger_tag_id UUID REFERENCES tags(id) ON DELETE SET NULL,
+    -- Applied automatically to leads arriving via webhook, only from
+    -- lead_sources unless it's empty
+    apply_to_leads BOOLEAN NOT NULL DEFAULT FALSE,
+    lead_sources TEXT [] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_action_plans_trigger_tag_id ON action_plans(trigger_tag_id)
+WHERE
+    trigger_tag_id IS NOT NULL;
+
+-- The tasks of a plan, due offset_days after the plan is applied
+CREATE TABLE action_plan_steps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action_plan_id UUID NOT NULL REFERENCES action_plans(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    task_type task_type NOT NULL DEFAULT 'follow-up',
+    offset_days INTEGER NOT NULL DEFAULT 0 CHECK (offset_days >= 0),
+    priority task_priority NOT NULL DEFAULT 'normal',
+    note_template TEXT NOT NULL DEFAULT '',
+    UNIQUE (action_plan_id, position)
+);
+
+-- A plan applied to a contact. It is paused once the contact is reached,
+-- which cancels the steps still pending.
+CREATE TABLE contact_action_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    action_plan_id UUID NOT NULL REFERENCES action_plans(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused')),
+    started_at TIMESTAMPTZ NOT NULL,
+    applied_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    paused_at TIMESTAMPTZ,
+    pause_reason VARCHAR(50),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A plan runs at most once at a time for a contact
+CREATE UNIQUE INDEX one_active_contact_action_plan ON contact_action_plans(contact_id, action_plan_id)
+WHERE
+    status = 'active';
+
+ALTER TABLE tasks
+ADD COLUMN contact_action_plan_id UUID REFERENCES contact_action_plans(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_contact_action_plan_id ON tasks(contact_action_plan_id)
+WHERE
+    contact_action_plan_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tasks_contact_action_plan_id;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS contact_action_plan_id;
+
+DROP TABLE IF EXISTS contact_action_plans;
+
+DROP TABLE IF EXISTS action_plan_steps;
+
+DROP TABLE IF EXISTS action_plans;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- How a user is reminded of their upcoming tasks and appointments. Users
+-- without a row get the server's default lead times, without emails.
+CREATE TABLE reminder_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Minutes ahead of the due time a reminder is sent, one per entry
+    task_lead_minutes INTEGER [] NOT NULL,
+    appointment_lead_minutes INTEGER [] NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every reminder sent. A reminder is identified by its record, due time and
+-- lead time, so it fires once however often the scheduler runs or restarts,
+-- and fires again when the record is rescheduled.
+CREATE TABLE reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    appointment_id UUID REFERENCES appointments(id) ON DELETE CASCADE,
+    due_at TIMESTAMPTZ NOT NULL,
+    lead_minutes INTEGER NOT NULL,
+    notification_id UUID REFERENCES notifications(id) ON DELETE SET NULL,
+    -- The email is sent after the reminder is saved and retried until it
+    -- goes through, the record is due or it has failed too often
+    send_email BOOLEAN NOT NULL DEFAULT FALSE,
+    email_sent_at TIMESTAMPTZ,
+    email_attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(task_id, appointment_id) = 1)
+);
+
+CREATE UNIQUE INDEX one_task_reminder ON reminders(task_id, due_at, lead_minutes)
+WHERE
+    task_id IS NOT NULL;
+
+CREATE UNIQUE INDEX one_appointment_reminder ON reminders(appointment_id, due_at, lead_minutes)
+WHERE
+    appointment_id IS NOT NULL;
+
+CREATE INDEX idx_reminders_unsent_email ON reminders(created_at)
+WHERE
+    send_email
+    AND email_sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS reminders;
+
+DROP TABLE IF EXISTS reminder_preferences;
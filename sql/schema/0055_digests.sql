@@ -0,0 +1,16 @@
+-- +goose Up
+-- The daily agenda email. Users without a row get it at 7am UTC; last_sent_on
+-- is the user's local date of the last digest, so it goes out once a day
+-- across restarts.
+CREATE TABLE digest_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    send_hour INTEGER NOT NULL DEFAULT 7 CHECK (send_hour BETWEEN 0 AND 23),
+    -- IANA time zone name, such as America/Los_Angeles
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    last_sent_on DATE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS digest_preferences;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- The IANA time zone "today", "this week" and the daily digest are computed
+-- in for the user. It replaces the digest's own time zone.
+ALTER TABLE
+    users
+ADD
+    COLUMN "timeZone" TEXT DEFAULT 'UTC' NOT NULL;
+
+UPDATE
+    users u
+SET
+    "timeZone" = p.time_zone
+FROM
+    digest_preferences p
+WHERE
+    p.user_id = u.id;
+
+ALTER TABLE
+    digest_preferences DROP COLUMN time_zone;
+
+-- +goose Down
+ALTER TABLE
+    digest_preferences
+ADD
+    COLUMN time_zone TEXT NOT NULL DEFAULT 'UTC';
+
+UPDATE
+    digest_preferences p
+SET
+    time_zone = u."timeZone"
+FROM
+    users u
+WHERE
+    u.id = p.user_id;
+
+ALTER TABLE
+    users DROP COLUMN "timeZone";
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Contacts an import job creates are attributed to the user who uploaded
+-- the file, signed in as "import".
+ALTER TABLE audit_events
+    DROP CONSTRAINT audit_events_auth_method_check,
+    ADD CONSTRAINT audit_events_auth_method_check CHECK (auth_method IN ('session', 'api_key', 'import'));
+
+-- +goose Down
+DELETE FROM audit_events
+WHERE auth_method = 'import';
+
+ALTER TABLE audit_events
+    DROP CONSTRAINT audit_events_auth_method_check,
+    ADD CONSTRAINT audit_events_auth_method_check CHECK (auth_method IN ('session', 'api_key'));
@@ -0,0 +1,26 @@
+-- +goose Up
+-- A worker holds a running job for as long as it keeps extending the lease.
+-- Jobs whose lease has expired were left behind by a worker that stopped and
+-- are claimed again, resuming after their last committed chunk.
+ALTER TABLE import_jobs
+ADD COLUMN lease_id UUID,
+ADD COLUMN locked_until TIMESTAMPTZ;
+
+-- Jobs already running have no lease to wait for
+UPDATE
+    import_jobs
+SET
+    locked_until = CURRENT_TIMESTAMP
+WHERE
+    status = 'running';
+
+CREATE INDEX idx_import_jobs_locked_until ON import_jobs (locked_until)
+WHERE
+    status = 'running';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_import_jobs_locked_until;
+
+ALTER TABLE import_jobs
+DROP COLUMN locked_until,
+DROP COLUMN lease_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Deleting the surviving contact used to delete its merge history, and with
+-- it the only snapshot of the contacts merged into it. The history is now
+-- kept without a surviving contact.
+ALTER TABLE contact_merges
+    ALTER COLUMN surviving_contact_id DROP NOT NULL,
+    DROP CONSTRAINT contact_merges_surviving_contact_id_fkey,
+    ADD CONSTRAINT contact_merges_surviving_contact_id_fkey FOREIGN KEY (surviving_contact_id) REFERENCES contacts(id) ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM contact_merges
+WHERE surviving_contact_id IS NULL;
+
+ALTER TABLE contact_merges
+    ALTER COLUMN surviving_contact_id SET NOT NULL,
+    DROP CONSTRAINT contact_merges_surviving_contact_id_fkey,
+    ADD CONSTRAINT contact_merges_surviving_contact_id_fkey FOREIGN KEY (surviving_contact_id) REFERENCES contacts(id) ON DELETE CASCADE;